@@ -1,10 +1,12 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -12,6 +14,8 @@ import (
 
 	"calwatch/internal/alerts"
 	"calwatch/internal/config"
+	"calwatch/internal/control"
+	"calwatch/internal/localday"
 	"calwatch/internal/notifications"
 	"calwatch/internal/parser"
 	"calwatch/internal/storage"
@@ -23,22 +27,35 @@ type CalWatch struct {
 	config             *config.Config
 	eventStorage       storage.EventStorage
 	stateManager       storage.StateManager
-	parser             parser.CalDAVParser
+	directories        []*watchedDirectory
 	watcher            *watcher.CalDAVWatcher
 	alertManager       *alerts.AlertManager
 	notificationManager *notifications.NotificationManager
+	outbox             *notifications.Outbox
+	pauses             *notifications.Pauses
+	controlServer      *control.Server
 	alertScheduler     alerts.AlertScheduler
+	changeAlerter      *alerts.ChangeAlerter // Nil unless change alerts are enabled
 	
 	// Synchronization
 	stopChan   chan struct{}
+	wakeChan   chan struct{} // Delivers alerts released by resuming
 	wg         sync.WaitGroup
 	isRunning  bool
 }
 
+// watchedDirectory ties a configured directory to the parser for its type
+type watchedDirectory struct {
+	path      string
+	extension string // File extension handled in this directory (.ics or .vcf)
+	parser    parser.CalDAVParser
+}
+
 // NewCalWatch creates a new CalWatch instance
 func NewCalWatch() *CalWatch {
 	return &CalWatch{
 		stopChan: make(chan struct{}),
+		wakeChan: make(chan struct{}, 1),
 	}
 }
 
@@ -55,6 +72,13 @@ func (cw *CalWatch) Initialize() error {
 
 	fmt.Fprintf(os.Stderr, "Loaded configuration with %d directories\n", len(cfg.Directories))
 
+	// Use the configured zone for local day boundaries
+	location, err := cfg.Location()
+	if err != nil {
+		return fmt.Errorf("failed to load timezone: %w", err)
+	}
+	localday.SetLocation(location)
+
 	// Initialize state manager
 	stateManager, err := storage.NewXDGStateManager()
 	if err != nil {
@@ -70,20 +94,62 @@ func (cw *CalWatch) Initialize() error {
 	// Initialize event storage
 	cw.eventStorage = storage.NewMemoryEventStorage()
 
-	// Initialize parser
-	cw.parser = parser.NewGocalParser()
+	// Initialize a parser per directory, so events carry the directory's
+	// template and automatic alerts
+	for _, dirConfig := range cfg.Directories {
+		directory, err := cw.newWatchedDirectory(dirConfig, location)
+		if err != nil {
+			return fmt.Errorf("failed to set up directory %s: %w", dirConfig.Directory, err)
+		}
+		cw.directories = append(cw.directories, directory)
+	}
 
 	// Initialize notification manager
 	cw.notificationManager = notifications.NewNotificationManager(cfg.Notification)
+	cw.notificationManager.SetEventResolver(cw.eventStorage.GetEvent)
+
+	// Initialize the outbox, replaying alerts that were not delivered before the last shutdown
+	cw.outbox, err = notifications.NewXDGOutbox(cw.notificationManager)
+	if err != nil {
+		return fmt.Errorf("failed to create outbox: %w", err)
+	}
+	cw.outbox.SetEventResolver(cw.eventStorage.GetEvent)
+	quietHours := notifications.NewQuietHours(cfg.QuietHours, cfg.Directories)
+	quietHours.SetEventStorage(cw.eventStorage)
+	cw.outbox.SetQuietHours(quietHours)
+	focus := notifications.NewFocus(cfg.Focus)
+	focus.SetEventStorage(cw.eventStorage)
+	cw.outbox.SetFocus(focus)
+	cw.pauses = notifications.NewPauses(cfg.Pause, cw.stateManager)
+	cw.outbox.SetPauses(cw.pauses)
+	if err := cw.outbox.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load outbox, pending alerts are lost: %v\n", err)
+	}
 
 	// Initialize alert scheduler and manager
 	scheduler := alerts.NewMinuteBasedScheduler()
 	scheduler.SetEventStorage(cw.eventStorage)
 	scheduler.SetDirectoryConfigs(cfg.Directories)
 	scheduler.SetStateManager(cw.stateManager)
+	scheduler.SetDigestConfig(cfg.Digest)
 	cw.alertScheduler = scheduler
 	cw.alertManager = alerts.NewAlertManager(scheduler)
 
+	// Initialize change alerts, listening to storage changes after the initial scan
+	if cfg.ChangeAlerts.Enable {
+		cw.changeAlerter, err = alerts.NewChangeAlerter(cfg.ChangeAlerts)
+		if err != nil {
+			return fmt.Errorf("failed to create change alerter: %w", err)
+		}
+	}
+
+	// Initialize the socket the calwatch command talks to the daemon on
+	socketPath, err := control.SocketPath()
+	if err != nil {
+		return fmt.Errorf("failed to get control socket path: %w", err)
+	}
+	cw.controlServer = control.NewServer(socketPath, cw.handleControl)
+
 	// Initialize file watcher
 	cw.watcher, err = watcher.NewCalDAVWatcher(cw.handleFileChange)
 	if err != nil {
@@ -101,6 +167,90 @@ func (cw *CalWatch) Initialize() error {
 	return nil
 }
 
+// newWatchedDirectory creates the calendar and parser for a configured directory
+func (cw *CalWatch) newWatchedDirectory(dirConfig config.DirectoryConfig, location *time.Location) (*watchedDirectory, error) {
+	automaticAlerts, err := storage.ConvertConfigAlerts(dirConfig.AutomaticAlerts)
+	if err != nil {
+		return nil, err
+	}
+	allDayAlerts, err := storage.ConvertAllDayAlerts(dirConfig.AllDayAlerts)
+	if err != nil {
+		return nil, err
+	}
+	calendar := cw.eventStorage.EnsureCalendar(dirConfig.Directory, dirConfig.Template, automaticAlerts)
+	calendar.UpdateAllDayAlerts(allDayAlerts)
+
+	if dirConfig.IsContacts() {
+		vcardParser := parser.NewVCardParser()
+		vcardParser.SetTimeZone(location)
+		vcardParser.SetCalendar(calendar)
+		return &watchedDirectory{path: dirConfig.Directory, extension: ".vcf", parser: vcardParser}, nil
+	}
+
+	// Use the configured zone for floating times
+	icsParser := parser.NewICSParser()
+	icsParser.SetTimeZone(location)
+	icsParser.SetCalendar(calendar)
+	return &watchedDirectory{path: dirConfig.Directory, extension: ".ics", parser: icsParser}, nil
+}
+
+// directoryForFile returns the watched directory a file belongs to, or nil
+// if the file is not one the directory's parser handles
+func (cw *CalWatch) directoryForFile(path string) *watchedDirectory {
+	var match *watchedDirectory
+	for _, directory := range cw.directories {
+		prefix := filepath.Clean(directory.path) + string(filepath.Separator)
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		// Prefer the most specific directory for nested configurations
+		if match == nil || len(directory.path) > len(match.path) {
+			match = directory
+		}
+	}
+
+	if match == nil || !strings.HasSuffix(strings.ToLower(path), match.extension) {
+		return nil
+	}
+	return match
+}
+
+// scanDirectory parses the files of a watched directory into the event
+// storage and returns the number of events found
+func (cw *CalWatch) scanDirectory(directory *watchedDirectory) int {
+	totalEvents := 0
+
+	// Use ParseFile for each individual file to get proper file tracking
+	filepath.Walk(directory.path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // Continue processing other files
+		}
+
+		// Skip directories and files of other types
+		if info.IsDir() || !strings.HasSuffix(strings.ToLower(info.Name()), directory.extension) {
+			return nil
+		}
+
+		events, parseErr := directory.parser.ParseFile(path)
+		if parseErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to parse file %s: %v\n", path, parseErr)
+			return nil
+		}
+
+		// Add events to storage with file tracking
+		for _, event := range events {
+			if err := cw.eventStorage.UpsertEventWithFile(event, path); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to store event %s: %v\n", event.GetUID(), err)
+			}
+		}
+
+		totalEvents += len(events)
+		return nil
+	})
+
+	return totalEvents
+}
+
 // Start starts the CalWatch daemon
 func (cw *CalWatch) Start() error {
 	if cw.isRunning {
@@ -114,6 +264,11 @@ func (cw *CalWatch) Start() error {
 		return fmt.Errorf("initial scan failed: %w", err)
 	}
 
+	// Alert about changes from now on, the initial scan only loads the calendars
+	if cw.changeAlerter != nil {
+		cw.eventStorage.SetChangeListener(cw.changeAlerter.EventsChanged)
+	}
+
 	// Check for wake-up and process missed events if enabled
 	if err := cw.handleWakeupDetection(); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: wake-up detection failed: %v\n", err)
@@ -128,6 +283,11 @@ func (cw *CalWatch) Start() error {
 	cw.wg.Add(1)
 	go cw.processAlerts()
 
+	// Accept commands like pause and status
+	if err := cw.controlServer.Start(); err != nil {
+		return fmt.Errorf("failed to start control socket: %w", err)
+	}
+
 	cw.isRunning = true
 
 	fmt.Fprintf(os.Stderr, "CalWatch daemon started successfully\n")
@@ -154,10 +314,18 @@ func (cw *CalWatch) Stop() error {
 	// Signal all goroutines to stop
 	close(cw.stopChan)
 
+	// Stop accepting commands
+	if err := cw.controlServer.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error stopping control socket: %v\n", err)
+	}
+
 	// Stop alert manager
 	if err := cw.alertManager.Stop(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error stopping alert manager: %v\n", err)
 	}
+	if cw.changeAlerter != nil {
+		cw.changeAlerter.Stop()
+	}
 
 	// Stop file watcher
 	if err := cw.watcher.Stop(); err != nil {
@@ -174,46 +342,19 @@ func (cw *CalWatch) Stop() error {
 	return nil
 }
 
-// performInitialScan scans all configured directories for existing ICS files
+// performInitialScan scans all configured directories for existing calendar and contact files
 func (cw *CalWatch) performInitialScan() error {
 	fmt.Fprintf(os.Stderr, "Performing initial scan of calendar directories...\n")
 
 	totalEvents := 0
 
-	for _, dirConfig := range cw.config.Directories {
-		fmt.Fprintf(os.Stderr, "Scanning directory: %s\n", dirConfig.Directory)
-
-		// Use ParseFile for each individual file to get proper file tracking
-		filepath.Walk(dirConfig.Directory, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return nil // Continue processing other files
-			}
-
-			// Skip directories and non-ICS files
-			if info.IsDir() || !strings.HasSuffix(strings.ToLower(info.Name()), ".ics") {
-				return nil
-			}
-
-			events, parseErr := cw.parser.ParseFile(path)
-			if parseErr != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to parse file %s: %v\n", path, parseErr)
-				return nil
-			}
-
-			// Add events to storage with file tracking
-			for _, event := range events {
-				if err := cw.eventStorage.UpsertEventWithFile(event, path); err != nil {
-					fmt.Fprintf(os.Stderr, "Warning: failed to store event %s: %v\n", event.GetUID(), err)
-				}
-			}
-
-			totalEvents += len(events)
-			return nil
-		})
+	for _, directory := range cw.directories {
+		fmt.Fprintf(os.Stderr, "Scanning directory: %s\n", directory.path)
+		totalEvents += cw.scanDirectory(directory)
 	}
 
 	// Regenerate daily index for today
-	today := time.Now().Truncate(24 * time.Hour)
+	today := localday.Today().Start()
 	if err := cw.eventStorage.RegenerateIndex(today); err != nil {
 		return fmt.Errorf("failed to regenerate daily index: %w", err)
 	}
@@ -254,19 +395,16 @@ func (cw *CalWatch) handleWakeupDetection() error {
 		return nil
 	}
 
-	fmt.Fprintf(os.Stderr, "Found %d missed events, sending notifications...\n", len(missedAlerts))
+	fmt.Fprintf(os.Stderr, "Found %d missed events, queueing notifications...\n", len(missedAlerts))
 
-	// Send missed event notifications
 	for _, alertRequest := range missedAlerts {
-		fmt.Fprintf(os.Stderr, "Sending missed alert for event: %s (was due %s ago)\n", 
+		fmt.Fprintf(os.Stderr, "Queueing missed alert for event: %s (was due %s ago)\n", 
 			alertRequest.Event.GetSummary(), alertRequest.AlertOffset.String())
+	}
 
-		// For now, use regular notification method
-		// TODO: Extend NotificationManager to support context-aware notifications for missed events
-		if err := cw.notificationManager.SendNotification(alertRequest); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to send missed notification for event %s: %v\n", 
-				alertRequest.Event.GetSummary(), err)
-		}
+	// Missed alerts are delivered by processAlerts once it starts
+	if err := cw.outbox.Enqueue(missedAlerts); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to persist missed alerts: %v\n", err)
 	}
 
 	fmt.Fprintf(os.Stderr, "Missed event processing complete\n")
@@ -279,18 +417,22 @@ func (cw *CalWatch) handleFileChange(event watcher.FileChangeEvent) {
 
 	switch event.Operation {
 	case watcher.FileCreated, watcher.FileModified:
+		directory := cw.directoryForFile(event.Path)
+		if directory == nil {
+			fmt.Fprintf(os.Stderr, "Ignoring %s: not handled by the directory type\n", event.Path)
+			return
+		}
+
 		// Parse the changed file
-		events, err := cw.parser.ParseFile(event.Path)
+		events, err := directory.parser.ParseFile(event.Path)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error parsing file %s: %v\n", event.Path, err)
 			return
 		}
 
-		// Update events in storage with file tracking
-		for _, parsedEvent := range events {
-			if err := cw.eventStorage.UpsertEventWithFile(parsedEvent, event.Path); err != nil {
-				fmt.Fprintf(os.Stderr, "Error storing event %s: %v\n", parsedEvent.GetUID(), err)
-			}
+		// Replace the file's events so removed events and completed tasks disappear
+		if err := cw.eventStorage.ReplaceEventsForFile(event.Path, events); err != nil {
+			fmt.Fprintf(os.Stderr, "Error storing events from %s: %v\n", event.Path, err)
 		}
 
 		fmt.Fprintf(os.Stderr, "Updated %d events from %s\n", len(events), event.Path)
@@ -313,18 +455,32 @@ func (cw *CalWatch) handleFileChange(event watcher.FileChangeEvent) {
 	}
 
 	// Regenerate daily index after changes
-	today := time.Now().Truncate(24 * time.Hour)
+	today := localday.Today().Start()
 	if err := cw.eventStorage.RegenerateIndex(today); err != nil {
 		fmt.Fprintf(os.Stderr, "Error regenerating daily index: %v\n", err)
 	}
+
+	// Replace open notifications of moved or cancelled events
+	cw.notificationManager.RefreshEvents()
 }
 
-// processAlerts handles alert notifications
+// processAlerts queues alert notifications in the outbox and delivers them,
+// retrying failed deliveries
 func (cw *CalWatch) processAlerts() {
 	defer cw.wg.Done()
 
 	alertChan := cw.alertManager.GetAlertChannel()
 
+	// Never ready if change alerts are disabled
+	var changeChan <-chan []alerts.AlertRequest
+	if cw.changeAlerter != nil {
+		changeChan = cw.changeAlerter.GetAlertChannel()
+	}
+
+	// Fires right away to deliver alerts replayed from the outbox
+	retry := time.NewTimer(0)
+	defer retry.Stop()
+
 	for {
 		select {
 		case alertRequests, ok := <-alertChan:
@@ -333,16 +489,33 @@ func (cw *CalWatch) processAlerts() {
 				return
 			}
 
-			// Process each alert request
 			for _, request := range alertRequests {
 				fmt.Fprintf(os.Stderr, "Sending alert for event: %s (in %s)\n", 
 					request.Event.GetSummary(), request.AlertOffset.String())
+			}
 
-				if err := cw.notificationManager.SendNotification(request); err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to send notification for event %s: %v\n", 
-						request.Event.GetSummary(), err)
-				}
+			// Persist before sending, so alerts survive failures and restarts
+			if err := cw.outbox.Enqueue(alertRequests); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to persist alerts: %v\n", err)
 			}
+			cw.deliverAlerts(retry)
+
+		case alertRequests := <-changeChan:
+			for _, request := range alertRequests {
+				fmt.Fprintf(os.Stderr, "Sending %s change alert for event: %s\n",
+					request.Change.Kind, request.Event.GetSummary())
+			}
+
+			if err := cw.outbox.Enqueue(alertRequests); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to persist change alerts: %v\n", err)
+			}
+			cw.deliverAlerts(retry)
+
+		case <-retry.C:
+			cw.deliverAlerts(retry)
+
+		case <-cw.wakeChan:
+			cw.deliverAlerts(retry)
 
 		case <-cw.stopChan:
 			return
@@ -350,6 +523,17 @@ func (cw *CalWatch) processAlerts() {
 	}
 }
 
+// deliverAlerts sends due alerts from the outbox and schedules the next retry
+func (cw *CalWatch) deliverAlerts(retry *time.Timer) {
+	if err := cw.outbox.Deliver(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to update outbox: %v\n", err)
+	}
+
+	if next, ok := cw.outbox.NextAttempt(); ok {
+		retry.Reset(time.Until(next))
+	}
+}
+
 // PrintStatus prints current daemon status
 func (cw *CalWatch) PrintStatus() {
 	if !cw.isRunning {
@@ -357,26 +541,154 @@ func (cw *CalWatch) PrintStatus() {
 		return
 	}
 
-	watchedDirs := cw.watcher.GetWatchedDirectories()
-	totalEvents := len(cw.eventStorage.GetAllEvents())
+	printStatus(cw.status())
+}
 
+// status collects the daemon status
+func (cw *CalWatch) status() *control.Status {
+	now := time.Now()
+	return &control.Status{
+		Directories:  cw.watcher.GetWatchedDirectories(),
+		Events:       len(cw.eventStorage.GetAllEvents()),
+		TodaysEvents: len(cw.eventStorage.GetEventsForDay(localday.Today().Start())),
+		Upcoming:     len(cw.eventStorage.GetUpcomingEvents(now, 24*time.Hour)),
+		Queued:       cw.outbox.Len(),
+		Held:         cw.outbox.Held(),
+		Pauses:       cw.pauses.Active(now),
+	}
+}
+
+// printStatus prints the status of the daemon
+func printStatus(status *control.Status) {
 	fmt.Printf("CalWatch Status:\n")
 	fmt.Printf("  Status: Running\n")
-	fmt.Printf("  Watched directories: %d\n", len(watchedDirs))
-	fmt.Printf("  Total events: %d\n", totalEvents)
-	
-	for _, dir := range watchedDirs {
+	fmt.Printf("  Watched directories: %d\n", len(status.Directories))
+	fmt.Printf("  Total events: %d\n", status.Events)
+
+	for _, dir := range status.Directories {
 		fmt.Printf("    - %s\n", dir)
 	}
 
-	// Get today's events
-	today := time.Now().Truncate(24 * time.Hour)
-	todaysEvents := cw.eventStorage.GetEventsForDay(today)
-	fmt.Printf("  Today's events: %d\n", len(todaysEvents))
+	fmt.Printf("  Today's events: %d\n", status.TodaysEvents)
+	fmt.Printf("  Upcoming (24h): %d\n", status.Upcoming)
+	fmt.Printf("  Queued alerts: %d\n", status.Queued)
 
-	// Get upcoming events
-	upcoming := cw.eventStorage.GetUpcomingEvents(time.Now(), 24*time.Hour)
-	fmt.Printf("  Upcoming (24h): %d\n", len(upcoming))
+	for _, pause := range status.Pauses {
+		fmt.Printf("  %s\n", describePause(pause))
+	}
+	if status.Held > 0 {
+		fmt.Printf("  Held back alerts: %d\n", status.Held)
+	}
+}
+
+// describePause describes a pause, e.g. "Muted work until 17:00"
+func describePause(pause storage.Pause) string {
+	description := "Paused"
+	if pause.Calendar != storage.AllCalendars {
+		description = "Muted " + filepath.Base(pause.Calendar)
+	}
+
+	switch {
+	case pause.Until.IsZero():
+		return description + " until resumed"
+	case localday.Of(pause.Until).Equal(localday.Today()):
+		return description + " until " + pause.Until.Format("15:04")
+	default:
+		return description + " until " + pause.Until.Format("Mon 2006-01-02 15:04")
+	}
+}
+
+// handleControl answers a command sent by the calwatch command
+func (cw *CalWatch) handleControl(request control.Request) control.Response {
+	now := time.Now()
+
+	switch request.Command {
+	case control.CommandStatus:
+		return control.Response{Status: cw.status()}
+
+	case control.CommandPause:
+		calendar, err := cw.calendarNamed(request.Calendar)
+		if err != nil {
+			return control.Response{Error: err.Error()}
+		}
+		// Times of day are in the configured zone
+		until, err := control.ParseUntil(request.Until, now.In(localday.Location()))
+		if err != nil {
+			return control.Response{Error: err.Error()}
+		}
+		if err := cw.pauses.Pause(calendar, until, now); err != nil {
+			return control.Response{Error: err.Error()}
+		}
+
+		pause := storage.Pause{Calendar: calendar, Since: now, Until: until}
+		fmt.Fprintf(os.Stderr, "%s\n", describePause(pause))
+		cw.wake()
+		return control.Response{Pauses: []storage.Pause{pause}}
+
+	case control.CommandResume:
+		calendar, err := cw.calendarNamed(request.Calendar)
+		if err != nil {
+			return control.Response{Error: err.Error()}
+		}
+		resumed, err := cw.pauses.Resume(calendar, now)
+		if err != nil {
+			return control.Response{Error: err.Error()}
+		}
+		fmt.Fprintf(os.Stderr, "Resumed %d pause(s)\n", len(resumed))
+		cw.wake()
+		return control.Response{Pauses: resumed}
+
+	case control.CommandAgenda, control.CommandUpcoming:
+		agenda, err := cw.agenda(request, now)
+		if err != nil {
+			return control.Response{Error: err.Error()}
+		}
+		return control.Response{Agenda: agenda}
+
+	default:
+		return control.Response{Error: fmt.Sprintf("unknown command %q", request.Command)}
+	}
+}
+
+// agenda answers an agenda or upcoming request from the event storage
+func (cw *CalWatch) agenda(request control.Request, now time.Time) ([]notifications.AgendaEntry, error) {
+	calendars := make([]string, 0, len(request.Calendars))
+	for _, name := range request.Calendars {
+		calendar, err := cw.calendarNamed(name)
+		if err != nil {
+			return nil, err
+		}
+		calendars = append(calendars, calendar)
+	}
+	request.Calendars = calendars
+
+	return control.BuildAgenda(cw.eventStorage.GetAllEvents(), request, now)
+}
+
+// calendarNamed returns the configured directory of a calendar given by
+// directory or name, e.g. "work" for ~/.calendars/work
+func (cw *CalWatch) calendarNamed(name string) (string, error) {
+	if name == storage.AllCalendars {
+		return name, nil
+	}
+
+	expanded := config.DirectoryConfig{Directory: name}
+	expanded.ExpandPath()
+	for _, directory := range cw.config.Directories {
+		path := filepath.Clean(directory.Directory)
+		if path == filepath.Clean(expanded.Directory) || filepath.Base(path) == name {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("unknown calendar %q", name)
+}
+
+// wake makes processAlerts deliver alerts released by a command
+func (cw *CalWatch) wake() {
+	select {
+	case cw.wakeChan <- struct{}{}:
+	default:
+	}
 }
 
 // setupSignalHandling sets up graceful shutdown on SIGINT/SIGTERM
@@ -391,13 +703,171 @@ func (cw *CalWatch) setupSignalHandling() {
 	}()
 }
 
+// sendCommand sends a request to the running daemon, exiting on failure
+func sendCommand(request control.Request) control.Response {
+	socketPath, err := control.SocketPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	response, err := control.Send(socketPath, request)
+	if err == control.ErrNotRunning {
+		fmt.Println("CalWatch daemon is not running")
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return response
+}
+
+// loadCalendars parses the configured directories without starting the
+// daemon, for commands that work when it is not running
+func loadCalendars() (*CalWatch, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	location, err := cfg.Location()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load timezone: %w", err)
+	}
+	localday.SetLocation(location)
+
+	cw := NewCalWatch()
+	cw.config = cfg
+	cw.eventStorage = storage.NewMemoryEventStorage()
+	for _, dirConfig := range cfg.Directories {
+		directory, err := cw.newWatchedDirectory(dirConfig, location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up directory %s: %w", dirConfig.Directory, err)
+		}
+		cw.directories = append(cw.directories, directory)
+		cw.scanDirectory(directory)
+	}
+	return cw, nil
+}
+
+// runAgenda lists occurrences for the agenda and upcoming commands, asking
+// the running daemon or parsing the calendars if it is not running
+func runAgenda(command string, args []string) error {
+	request := control.Request{Command: command}
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	format := flags.String("format", notifications.AgendaFormatText, "Output format: text, json or a template like '{{.StartTime}} {{.Summary}}'")
+	flags.Var((*calendarList)(&request.Calendars), "calendar", "Only list events of this calendar, may be repeated")
+	if command == control.CommandAgenda {
+		flags.StringVar(&request.From, "from", "", "First day: a date like 2024-01-15, today, tomorrow or +7 (default today)")
+		flags.StringVar(&request.To, "to", "", "Last day (default the first day)")
+	} else if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		limit, err := strconv.Atoi(args[0])
+		if err != nil || limit <= 0 {
+			return fmt.Errorf("expected a number of events, got %q", args[0])
+		}
+		request.Limit, args = limit, args[1:]
+	}
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	var agenda []notifications.AgendaEntry
+	socketPath, err := control.SocketPath()
+	if err != nil {
+		return err
+	}
+	response, err := control.Send(socketPath, request)
+	switch {
+	case err == control.ErrNotRunning:
+		cw, loadErr := loadCalendars()
+		if loadErr != nil {
+			return loadErr
+		}
+		agenda, err = cw.agenda(request, time.Now())
+	case err == nil:
+		agenda = response.Agenda
+	}
+	if err != nil {
+		return err
+	}
+
+	return notifications.WriteAgenda(os.Stdout, agenda, *format)
+}
+
+// calendarList is a repeatable --calendar flag
+type calendarList []string
+
+func (l *calendarList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *calendarList) Set(calendar string) error {
+	*l = append(*l, calendarArg(calendar))
+	return nil
+}
+
+// calendarArg returns a calendar given on the command line, making
+// directories absolute as the daemon runs elsewhere
+func calendarArg(calendar string) string {
+	if !strings.ContainsRune(calendar, filepath.Separator) || strings.HasPrefix(calendar, "~") {
+		return calendar
+	}
+	if absolute, err := filepath.Abs(calendar); err == nil {
+		return absolute
+	}
+	return calendar
+}
+
 func main() {
 	// Parse command line arguments
 	if len(os.Args) > 1 {
 		switch os.Args[1] {
 		case "status":
-			// TODO: Implement status checking (connect to running daemon)
-			fmt.Println("Status checking not implemented yet")
+			response := sendCommand(control.Request{Command: control.CommandStatus})
+			printStatus(response.Status)
+			return
+		case "pause", "mute":
+			request := control.Request{Command: control.CommandPause, Calendar: storage.AllCalendars}
+			args := os.Args[2:]
+			if os.Args[1] == "mute" {
+				if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+					fmt.Fprintf(os.Stderr, "Usage: calwatch mute <calendar> [duration | --until HH:MM]\n")
+					os.Exit(1)
+				}
+				request.Calendar, args = calendarArg(args[0]), args[1:]
+			}
+			until, err := control.ParsePauseArgs(args)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			request.Until = until
+
+			response := sendCommand(request)
+			for _, pause := range response.Pauses {
+				fmt.Println(describePause(pause))
+			}
+			return
+		case "resume":
+			request := control.Request{Command: control.CommandResume, Calendar: storage.AllCalendars}
+			if len(os.Args) > 2 {
+				request.Calendar = calendarArg(os.Args[2])
+			}
+
+			response := sendCommand(request)
+			if len(response.Pauses) == 0 {
+				fmt.Println("Nothing to resume")
+			}
+			for _, pause := range response.Pauses {
+				fmt.Printf("Resumed: %s\n", describePause(pause))
+			}
+			return
+		case "agenda", "upcoming":
+			if err := runAgenda(os.Args[1], os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
 			return
 		case "stop":
 			// TODO: Implement daemon stopping (send signal to running daemon)
@@ -427,6 +897,16 @@ func main() {
 			fmt.Println("  calwatch          Start the daemon")
 			fmt.Println("  calwatch init     Create default configuration and templates")
 			fmt.Println("  calwatch status   Show daemon status")
+			fmt.Println("  calwatch pause [duration | --until HH:MM]")
+			fmt.Println("                    Hold back alerts, until resumed without a duration")
+			fmt.Println("  calwatch mute <calendar> [duration | --until HH:MM]")
+			fmt.Println("                    Hold back the alerts of a calendar")
+			fmt.Println("  calwatch resume [calendar]")
+			fmt.Println("                    End pauses, or the mute of a calendar")
+			fmt.Println("  calwatch agenda [--from DAY] [--to DAY] [--calendar NAME] [--format FORMAT]")
+			fmt.Println("                    List the events of days, today by default")
+			fmt.Println("  calwatch upcoming [N] [--calendar NAME] [--format FORMAT]")
+			fmt.Println("                    List the next N events, 10 by default")
 			fmt.Println("  calwatch stop     Stop the daemon")
 			fmt.Println("  calwatch help     Show this help")
 			return
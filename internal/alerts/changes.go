@@ -0,0 +1,256 @@
+package alerts
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"calwatch/internal/config"
+	"calwatch/internal/storage"
+)
+
+// Kinds of event changes
+const (
+	ChangeNew       = "new"       // Invitation or event added within the alert window
+	ChangeMoved     = "moved"     // Start time or duration changed
+	ChangeLocation  = "location"  // Location changed
+	ChangeCancelled = "cancelled" // Event or occurrence deleted
+)
+
+// EventChange describes how an event changed for change alerts, with the
+// previous values for templates showing old vs new
+type EventChange struct {
+	Kind        string    `json:"kind"`
+	OldStart    time.Time `json:"old_start,omitempty"`
+	OldEnd      time.Time `json:"old_end,omitempty"`
+	OldLocation string    `json:"old_location,omitempty"`
+}
+
+// Quiet period after the last storage update before changes are alerted,
+// so that the changes of a sync are alerted together or not at all
+const changeQuietPeriod = 2 * time.Second
+
+// ChangeAlerter turns storage updates into alerts about new, moved and
+// cancelled events. Changes are collected until the storage has been quiet
+// for a moment; larger batches are resyncs and not alerted.
+type ChangeAlerter struct {
+	config    config.ChangeAlertsConfig
+	within    time.Duration
+	alertChan chan []AlertRequest
+	stopChan  chan struct{}
+	now       func() time.Time
+
+	pending []storage.EventChange
+	timer   *time.Timer
+	stopped bool
+	mutex   sync.Mutex
+}
+
+// NewChangeAlerter creates a change alerter for the validated configuration
+func NewChangeAlerter(cfg config.ChangeAlertsConfig) (*ChangeAlerter, error) {
+	within, err := cfg.Within.Duration()
+	if err != nil {
+		return nil, fmt.Errorf("invalid change alert window: %w", err)
+	}
+
+	return &ChangeAlerter{
+		config:    cfg,
+		within:    within,
+		alertChan: make(chan []AlertRequest, 10), // Buffered channel
+		stopChan:  make(chan struct{}),
+		now:       time.Now,
+	}, nil
+}
+
+// GetAlertChannel returns the channel for receiving change alerts
+func (c *ChangeAlerter) GetAlertChannel() <-chan []AlertRequest {
+	return c.alertChan
+}
+
+// EventsChanged collects storage changes, used as the storage change listener
+func (c *ChangeAlerter) EventsChanged(changes []storage.EventChange) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.pending = append(c.pending, changes...)
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.timer = time.AfterFunc(changeQuietPeriod, c.flush)
+}
+
+// Stop discards pending changes and alerts waiting for the consumer
+func (c *ChangeAlerter) Stop() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.pending = nil
+	if !c.stopped {
+		c.stopped = true
+		close(c.stopChan)
+	}
+}
+
+// flush alerts the collected changes
+func (c *ChangeAlerter) flush() {
+	c.mutex.Lock()
+	changes := c.pending
+	c.pending = nil
+	c.mutex.Unlock()
+
+	alertRequests := c.detect(changes, c.now())
+	if len(alertRequests) == 0 {
+		return
+	}
+	if len(alertRequests) > c.config.MaxChanges {
+		fmt.Fprintf(os.Stderr, "Ignoring %d event changes at once as a resync\n", len(alertRequests))
+		return
+	}
+
+	// Wait for the consumer instead of dropping the alerts
+	select {
+	case c.alertChan <- alertRequests:
+	case <-c.stopChan:
+	}
+}
+
+// detect returns the alerts for changed events with an occurrence starting
+// within the configured window
+func (c *ChangeAlerter) detect(changes []storage.EventChange, now time.Time) []AlertRequest {
+	end := now.Add(c.within)
+
+	// Events after the changes, to pair occurrences with their series
+	current := make(map[string]storage.Event)
+	for _, change := range changes {
+		if change.New != nil {
+			current[change.New.GetUID()] = change.New
+		}
+	}
+
+	var alertRequests []AlertRequest
+	add := func(event storage.Event, eventTime time.Time, change EventChange) {
+		// Moved occurrences are alerted if they were or are within the window
+		within := func(t time.Time) bool { return !t.IsZero() && !t.Before(now) && !t.After(end) }
+		if !c.config.Alerts(change.Kind) || !(within(eventTime) || change.Kind == ChangeMoved && within(change.OldStart)) {
+			return
+		}
+		alertRequests = append(alertRequests, AlertRequest{
+			Event:       event,
+			AlertOffset: eventTime.Sub(now).Round(time.Minute),
+			Template:    c.config.Template,
+			Important:   c.config.Important,
+			EventTime:   eventTime,
+			Change:      &change,
+		})
+	}
+
+	for _, change := range changes {
+		switch {
+		case change.Old == nil:
+			c.detectAdded(change.New, current, now, end, add)
+		case change.New == nil:
+			// Removing a modified occurrence reverts it to the series
+			if _, recurrenceID := splitInstanceUID(change.Old.GetUID()); recurrenceID.IsZero() {
+				for _, occurrence := range firstOccurrence(change.Old, now, end) {
+					add(change.Old, occurrence, EventChange{Kind: ChangeCancelled})
+				}
+			}
+		default:
+			c.detectModified(change.Old, change.New, current, now, end, add)
+		}
+	}
+
+	return alertRequests
+}
+
+// detectAdded detects new events and occurrences of recurring events that
+// were moved or relocated on their own
+func (c *ChangeAlerter) detectAdded(event storage.Event, current map[string]storage.Event, now, end time.Time, add func(storage.Event, time.Time, EventChange)) {
+	master, recurrenceID := splitInstanceUID(event.GetUID())
+	if recurrenceID.IsZero() {
+		for _, occurrence := range firstOccurrence(event, now, end) {
+			add(event, occurrence, EventChange{Kind: ChangeNew})
+		}
+		return
+	}
+
+	duration := event.GetEndTime().Sub(event.GetStartTime())
+	if !event.GetStartTime().Equal(recurrenceID) {
+		add(event, event.GetStartTime(), EventChange{Kind: ChangeMoved, OldStart: recurrenceID, OldEnd: recurrenceID.Add(duration)})
+		return
+	}
+	if series, exists := current[master]; exists && series.GetLocation() != event.GetLocation() {
+		add(event, event.GetStartTime(), EventChange{Kind: ChangeLocation, OldLocation: series.GetLocation()})
+	}
+}
+
+// detectModified detects moved, relocated and cancelled occurrences of an
+// event that was updated
+func (c *ChangeAlerter) detectModified(old, new storage.Event, current map[string]storage.Event, now, end time.Time, add func(storage.Event, time.Time, EventChange)) {
+	shift := new.GetStartTime().Sub(old.GetStartTime())
+	oldDuration := old.GetEndTime().Sub(old.GetStartTime())
+
+	if shift != 0 || new.GetEndTime().Sub(new.GetStartTime()) != oldDuration {
+		// Alert about the next occurrence, also when it moved out of the window
+		occurrences := firstOccurrence(new, now, end)
+		if len(occurrences) == 0 {
+			for _, occurrence := range firstOccurrence(old, now, end) {
+				occurrences = append(occurrences, occurrence.Add(shift))
+			}
+		}
+		for _, occurrence := range occurrences {
+			oldStart := occurrence.Add(-shift)
+			add(new, occurrence, EventChange{Kind: ChangeMoved, OldStart: oldStart, OldEnd: oldStart.Add(oldDuration)})
+		}
+		return
+	}
+
+	// Occurrences removed from a series, unless modified on their own
+	for _, occurrence := range old.OccurredWithin(now, end) {
+		instanceUID := new.GetUID() + "/" + occurrence.UTC().Format("20060102T150405Z")
+		if _, modified := current[instanceUID]; !modified && !storage.OccursAt(new, occurrence) {
+			add(old, occurrence, EventChange{Kind: ChangeCancelled})
+		}
+	}
+
+	if old.GetLocation() != new.GetLocation() {
+		for _, occurrence := range firstOccurrence(new, now, end) {
+			add(new, occurrence, EventChange{Kind: ChangeLocation, OldLocation: old.GetLocation()})
+		}
+	}
+}
+
+// firstOccurrence returns the first occurrence of an event within a time
+// range, empty if there is none
+func firstOccurrence(event storage.Event, start, end time.Time) []time.Time {
+	occurrences := event.OccurredWithin(start, end)
+	if len(occurrences) == 0 {
+		return nil
+	}
+	first := occurrences[0]
+	for _, occurrence := range occurrences[1:] {
+		if occurrence.Before(first) {
+			first = occurrence
+		}
+	}
+	return []time.Time{first}
+}
+
+// splitInstanceUID splits the UID of an occurrence modified on its own into
+// the UID of its series and its RECURRENCE-ID, zero for other events
+func splitInstanceUID(uid string) (string, time.Time) {
+	index := strings.LastIndex(uid, "/")
+	if index < 0 {
+		return uid, time.Time{}
+	}
+	recurrenceID, err := time.Parse("20060102T150405Z", uid[index+1:])
+	if err != nil {
+		return uid, time.Time{}
+	}
+	return uid[:index], recurrenceID
+}
@@ -0,0 +1,213 @@
+package alerts
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"calwatch/internal/config"
+	"calwatch/internal/recurrence"
+	"calwatch/internal/storage"
+)
+
+func newTestChangeAlerter(t *testing.T, changesConfig config.ChangeAlertsConfig) *ChangeAlerter {
+	t.Helper()
+	if err := changesConfig.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	alerter, err := NewChangeAlerter(changesConfig)
+	if err != nil {
+		t.Fatalf("NewChangeAlerter() error = %v", err)
+	}
+	return alerter
+}
+
+func TestChangeAlerter_Detect(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	tomorrow := now.Add(24 * time.Hour)
+	calendar := storage.NewCalendar("/test/path", "", []storage.Alert{})
+	newEvent := func(uid, location string, start time.Time, rec recurrence.Recurrence) *storage.CalendarEvent {
+		return storage.NewCalendarEvent(uid, "Team Meeting", "", location, start, start.Add(time.Hour),
+			time.UTC, rec, calendar, []storage.Alert{})
+	}
+	weekly, err := recurrence.ParseRRule("FREQ=WEEKLY")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	series := newEvent("series", "Room 1", tomorrow.Add(-7*24*time.Hour), weekly)
+	exception := newEvent("series", "Room 1", tomorrow.Add(-7*24*time.Hour), weekly)
+	exception.ExDates = []time.Time{tomorrow}
+	relocatedSeries := newEvent("series", "Room 2", tomorrow.Add(-7*24*time.Hour), weekly)
+
+	tests := []struct {
+		name         string
+		changes      []storage.EventChange
+		changeKinds  []string
+		expected     []string    // Expected change kinds
+		expectedTime []time.Time // Expected occurrences
+		expectedOld  time.Time   // Expected old start of the first alert
+	}{
+		{"new event",
+			[]storage.EventChange{{New: newEvent("a", "Room 1", tomorrow, nil)}},
+			nil, []string{ChangeNew}, []time.Time{tomorrow}, time.Time{}},
+		{"new event outside the window",
+			[]storage.EventChange{{New: newEvent("a", "Room 1", now.Add(3*24*time.Hour), nil)}},
+			nil, nil, nil, time.Time{}},
+		{"moved",
+			[]storage.EventChange{{Old: newEvent("a", "Room 1", tomorrow, nil), New: newEvent("a", "Room 1", tomorrow.Add(2*time.Hour), nil)}},
+			nil, []string{ChangeMoved}, []time.Time{tomorrow.Add(2 * time.Hour)}, tomorrow},
+		{"moved out of the window",
+			[]storage.EventChange{{Old: newEvent("a", "Room 1", tomorrow, nil), New: newEvent("a", "Room 1", tomorrow.Add(7*24*time.Hour), nil)}},
+			nil, []string{ChangeMoved}, []time.Time{tomorrow.Add(7 * 24 * time.Hour)}, tomorrow},
+		{"location changed",
+			[]storage.EventChange{{Old: newEvent("a", "Room 1", tomorrow, nil), New: newEvent("a", "Room 2", tomorrow, nil)}},
+			nil, []string{ChangeLocation}, []time.Time{tomorrow}, time.Time{}},
+		{"location changes disabled",
+			[]storage.EventChange{{Old: newEvent("a", "Room 1", tomorrow, nil), New: newEvent("a", "Room 2", tomorrow, nil)}},
+			[]string{ChangeMoved, ChangeCancelled}, nil, nil, time.Time{}},
+		{"reparsed without changes",
+			[]storage.EventChange{{Old: series, New: newEvent("series", "Room 1", tomorrow.Add(-7*24*time.Hour), weekly)}},
+			nil, nil, nil, time.Time{}},
+		{"deleted",
+			[]storage.EventChange{{Old: newEvent("a", "Room 1", tomorrow, nil)}},
+			nil, []string{ChangeCancelled}, []time.Time{tomorrow}, time.Time{}},
+		{"deleted in the past",
+			[]storage.EventChange{{Old: newEvent("a", "Room 1", now.Add(-time.Hour), nil)}},
+			nil, nil, nil, time.Time{}},
+		{"occurrence cancelled",
+			[]storage.EventChange{{Old: series, New: exception}},
+			nil, []string{ChangeCancelled}, []time.Time{tomorrow}, time.Time{}},
+		{"occurrence moved",
+			[]storage.EventChange{
+				{Old: series, New: exception},
+				{New: newEvent("series/20240116T090000Z", "Room 1", tomorrow.Add(3*time.Hour), nil)},
+			},
+			nil, []string{ChangeMoved}, []time.Time{tomorrow.Add(3 * time.Hour)}, tomorrow},
+		{"occurrence relocated",
+			[]storage.EventChange{
+				{Old: series, New: exception},
+				{New: newEvent("series/20240116T090000Z", "Room 3", tomorrow, nil)},
+			},
+			nil, []string{ChangeLocation}, []time.Time{tomorrow}, time.Time{}},
+		{"series relocated",
+			[]storage.EventChange{{Old: series, New: relocatedSeries}},
+			nil, []string{ChangeLocation}, []time.Time{tomorrow}, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerter := newTestChangeAlerter(t, config.ChangeAlertsConfig{Enable: true, Changes: tt.changeKinds, Template: "change.tpl"})
+
+			alertRequests := alerter.detect(tt.changes, now)
+			if len(alertRequests) != len(tt.expected) {
+				t.Fatalf("Expected %d alerts, got %d", len(tt.expected), len(alertRequests))
+			}
+			for i, request := range alertRequests {
+				if request.Change.Kind != tt.expected[i] || !request.EventTime.Equal(tt.expectedTime[i]) {
+					t.Errorf("Expected %s change at %v, got %s at %v", tt.expected[i], tt.expectedTime[i], request.Change.Kind, request.EventTime)
+				}
+				if request.Template != "change.tpl" {
+					t.Errorf("Expected change template, got %q", request.Template)
+				}
+			}
+			if len(alertRequests) > 0 && !alertRequests[0].Change.OldStart.Equal(tt.expectedOld) {
+				t.Errorf("Expected old start %v, got %v", tt.expectedOld, alertRequests[0].Change.OldStart)
+			}
+		})
+	}
+}
+
+func TestChangeAlerter_SuppressesResyncs(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	calendar := storage.NewCalendar("/test/path", "", []storage.Alert{})
+	newChanges := func(count int) []storage.EventChange {
+		var changes []storage.EventChange
+		for i := 0; i < count; i++ {
+			start := now.Add(time.Duration(i+1) * time.Hour)
+			changes = append(changes, storage.EventChange{New: storage.NewCalendarEvent(
+				"uid", "Meeting", "", "", start, start.Add(time.Hour), time.UTC, nil, calendar, []storage.Alert{})})
+		}
+		return changes
+	}
+
+	alerter := newTestChangeAlerter(t, config.ChangeAlertsConfig{Enable: true, MaxChanges: 3})
+	alerter.now = func() time.Time { return now }
+
+	// A sync with a few changes is alerted
+	alerter.EventsChanged(newChanges(2))
+	alerter.flush()
+	select {
+	case alertRequests := <-alerter.GetAlertChannel():
+		if len(alertRequests) != 2 {
+			t.Errorf("Expected 2 change alerts, got %d", len(alertRequests))
+		}
+	default:
+		t.Error("Expected change alerts")
+	}
+
+	// Bulk changes, e.g. a calendar resync, are not
+	alerter.EventsChanged(newChanges(4))
+	alerter.flush()
+	select {
+	case alertRequests := <-alerter.GetAlertChannel():
+		t.Errorf("Expected no change alerts for a resync, got %d", len(alertRequests))
+	default:
+	}
+	alerter.Stop()
+}
+
+func TestChangeAlerter_WaitsForConsumer(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	calendar := storage.NewCalendar("/test/path", "", []storage.Alert{})
+	newChange := func(i int) []storage.EventChange {
+		start := now.Add(time.Duration(i+1) * time.Minute)
+		return []storage.EventChange{{New: storage.NewCalendarEvent(
+			fmt.Sprintf("uid-%d", i), "Meeting", "", "", start, start.Add(time.Hour), time.UTC, nil, calendar, []storage.Alert{})}}
+	}
+
+	alerter := newTestChangeAlerter(t, config.ChangeAlertsConfig{Enable: true})
+	alerter.now = func() time.Time { return now }
+
+	// More batches than the channel holds, with nobody reading yet
+	const batches = 15
+	flushed := make(chan struct{})
+	go func() {
+		for i := 0; i < batches; i++ {
+			alerter.EventsChanged(newChange(i))
+			alerter.flush()
+		}
+		close(flushed)
+	}()
+
+	// Flushes wait once the channel is full
+	for len(alerter.alertChan) < cap(alerter.alertChan) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-flushed:
+		t.Fatal("Expected flushes to wait for the consumer")
+	default:
+	}
+
+	for i := 0; i < batches; i++ {
+		select {
+		case alertRequests := <-alerter.GetAlertChannel():
+			if len(alertRequests) != 1 || alertRequests[0].Event.GetUID() != fmt.Sprintf("uid-%d", i) {
+				t.Fatalf("Expected the change alert for uid-%d, got %+v", i, alertRequests)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %d batches of change alerts, got %d", batches, i)
+		}
+	}
+	<-flushed
+
+	// After stopping, flushes no longer wait
+	for i := 0; i < cap(alerter.alertChan); i++ {
+		alerter.alertChan <- nil
+	}
+	alerter.Stop()
+	alerter.pending = newChange(0)
+	alerter.flush()
+}
@@ -0,0 +1,107 @@
+package alerts
+
+import (
+	"sort"
+	"time"
+
+	"calwatch/internal/config"
+	"calwatch/internal/localday"
+	"calwatch/internal/storage"
+)
+
+// AgendaItem is an event occurrence listed in an agenda digest
+type AgendaItem struct {
+	Event storage.Event
+	Start time.Time // Start of the occurrence
+}
+
+// Digests sent later than this after their time are late
+const digestLateAfter = 2 * time.Minute
+
+// SetDigestConfig sets the configuration of the daily agenda digest
+func (s *MinuteBasedScheduler) SetDigestConfig(digestConfig config.DigestConfig) {
+	s.digestConfig = digestConfig
+}
+
+// checkDigest returns today's digest if it became due after lastTick. Digests
+// of earlier days are not caught up, their agenda is over.
+func (s *MinuteBasedScheduler) checkDigest(lastTick, now time.Time) []AlertRequest {
+	if !s.digestConfig.Enable || s.eventStorage == nil {
+		return nil
+	}
+
+	today := localday.Of(now)
+	due := s.digestConfig.At(today.Start())
+	if !s.digestConfig.OnDay(today.Weekday()) || s.digestDay.Equal(today) ||
+		!due.After(lastTick) || due.After(now) {
+		return nil
+	}
+	s.digestDay = today
+
+	agenda := s.agendaFor(today)
+	if len(agenda) == 0 && s.digestConfig.SkipEmpty {
+		return nil
+	}
+
+	return []AlertRequest{{
+		Event:     storage.NewDigestEvent("calwatch-digest/"+today.Key(), "Agenda for "+today.Weekday().String(), due),
+		Template:  s.digestConfig.Template,
+		Late:      now.Sub(due) > digestLateAfter,
+		EventTime: due,
+		Digest:    true,
+		Agenda:    agenda,
+	}}
+}
+
+// agendaFor returns the occurrences of all events on a day sorted by start,
+// including those that started on an earlier day and are still ongoing
+func (s *MinuteBasedScheduler) agendaFor(day localday.Day) []AgendaItem {
+	// The daily index only holds events starting on the day
+	return Agenda(s.eventStorage.GetAllEvents(), day.Start(), day.End())
+}
+
+// Agenda returns the occurrences of events between from and to sorted by
+// start, including those that started before from and are still ongoing
+func Agenda(events []storage.Event, from, to time.Time) []AgendaItem {
+	var agenda []AgendaItem
+
+	for _, event := range events {
+		duration := event.GetEndTime().Sub(event.GetStartTime())
+		for _, start := range event.OccurredWithin(from.Add(-duration), to) {
+			if !start.Before(to) || (duration > 0 && !start.Add(duration).After(from)) {
+				continue
+			}
+			agenda = append(agenda, AgendaItem{Event: event, Start: start})
+		}
+	}
+
+	sort.SliceStable(agenda, func(i, j int) bool {
+		if !agenda[i].Start.Equal(agenda[j].Start) {
+			return agenda[i].Start.Before(agenda[j].Start)
+		}
+		return agenda[i].Event.GetSummary() < agenda[j].Event.GetSummary()
+	})
+	return agenda
+}
+
+// Occurrences starting this many days ahead are searched for upcoming events,
+// widening the search until enough are found
+var upcomingSearchDays = []int{1, 7, 31, 366}
+
+// Upcoming returns the next limit occurrences of events starting at or after
+// from, sorted by start
+func Upcoming(events []storage.Event, from time.Time, limit int) []AgendaItem {
+	var upcoming []AgendaItem
+	for _, days := range upcomingSearchDays {
+		upcoming = upcoming[:0]
+		for _, item := range Agenda(events, from, localday.Of(from).AddDays(days).Start()) {
+			if !item.Start.Before(from) {
+				upcoming = append(upcoming, item)
+			}
+		}
+		if len(upcoming) >= limit {
+			return upcoming[:limit]
+		}
+	}
+	return upcoming
+}
@@ -0,0 +1,124 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"calwatch/internal/config"
+	"calwatch/internal/localday"
+	"calwatch/internal/storage"
+)
+
+func newDigestTestScheduler(t *testing.T, digestConfig config.DigestConfig, events ...storage.Event) *MinuteBasedScheduler {
+	t.Helper()
+	if err := digestConfig.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	eventStorage := storage.NewMemoryEventStorage()
+	for _, event := range events {
+		eventStorage.UpsertEvent(event)
+	}
+	scheduler := NewMinuteBasedScheduler()
+	scheduler.SetEventStorage(eventStorage)
+	scheduler.SetDigestConfig(digestConfig)
+	return scheduler
+}
+
+func TestMinuteBasedScheduler_CheckDigest(t *testing.T) {
+	monday := localday.Date(2024, 1, 15)
+	due := monday.Start().Add(8*time.Hour + 30*time.Minute)
+	calendar := storage.NewCalendar("/test/path", "", []storage.Alert{})
+	newEvent := func(uid string, start time.Time, duration time.Duration) storage.Event {
+		return storage.NewCalendarEvent(uid, uid, "", "", start, start.Add(duration), time.UTC, nil, calendar, []storage.Alert{})
+	}
+	events := []storage.Event{
+		newEvent("lunch", monday.Start().Add(12*time.Hour), time.Hour),
+		newEvent("standup", monday.Start().Add(9*time.Hour), 15*time.Minute),
+		newEvent("conference", monday.Start().Add(-24*time.Hour), 48*time.Hour), // Started yesterday
+		newEvent("yesterday", monday.Start().Add(-12*time.Hour), time.Hour),
+		newEvent("tomorrow", monday.End().Add(9*time.Hour), time.Hour),
+	}
+
+	tests := []struct {
+		name           string
+		config         config.DigestConfig
+		events         []storage.Event
+		lastTick       time.Time
+		now            time.Time
+		expectedAgenda []string // nil if no digest is expected
+		expectedLate   bool
+	}{
+		{"due", config.DigestConfig{Enable: true, Time: "08:30"}, events,
+			due.Add(-time.Minute), due, []string{"conference", "standup", "lunch"}, false},
+		{"not due yet", config.DigestConfig{Enable: true, Time: "08:30"}, events,
+			due.Add(-2 * time.Minute), due.Add(-time.Minute), nil, false},
+		{"disabled", config.DigestConfig{Time: "08:30"}, events,
+			due.Add(-time.Minute), due, nil, false},
+		{"other weekday", config.DigestConfig{Enable: true, Time: "08:30", Days: []string{"sat", "Sunday"}}, events,
+			due.Add(-time.Minute), due, nil, false},
+		{"workday", config.DigestConfig{Enable: true, Time: "08:30", Days: []string{"mon", "tue", "wed", "thu", "fri"}}, events,
+			due.Add(-time.Minute), due, []string{"conference", "standup", "lunch"}, false},
+		{"empty day", config.DigestConfig{Enable: true, Time: "08:30"}, nil,
+			due.Add(-time.Minute), due, []string{}, false},
+		{"empty day skipped", config.DigestConfig{Enable: true, Time: "08:30", SkipEmpty: true}, nil,
+			due.Add(-time.Minute), due, nil, false},
+		{"caught up after sleep", config.DigestConfig{Enable: true, Time: "08:30"}, events,
+			due.Add(-10 * time.Hour), due.Add(90 * time.Minute), []string{"conference", "standup", "lunch"}, true},
+		{"yesterday's digest not caught up", config.DigestConfig{Enable: true, Time: "08:30"}, events,
+			due.Add(-30 * time.Hour), due.Add(-time.Hour), nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := newDigestTestScheduler(t, tt.config, tt.events...)
+
+			digests := scheduler.checkDigest(tt.lastTick, tt.now)
+			if tt.expectedAgenda == nil {
+				if len(digests) != 0 {
+					t.Errorf("Expected no digest, got %d", len(digests))
+				}
+				return
+			}
+			if len(digests) != 1 {
+				t.Fatalf("Expected 1 digest, got %d", len(digests))
+			}
+
+			digest := digests[0]
+			if !digest.Digest || digest.Late != tt.expectedLate || !digest.EventTime.Equal(due) {
+				t.Errorf("Expected digest due at %v (late %v), got %+v", due, tt.expectedLate, digest)
+			}
+			var agenda []string
+			for _, item := range digest.Agenda {
+				agenda = append(agenda, item.Event.GetUID())
+			}
+			if len(agenda) != len(tt.expectedAgenda) {
+				t.Fatalf("Expected agenda %v, got %v", tt.expectedAgenda, agenda)
+			}
+			for i := range agenda {
+				if agenda[i] != tt.expectedAgenda[i] {
+					t.Errorf("Expected agenda %v, got %v", tt.expectedAgenda, agenda)
+					break
+				}
+			}
+
+			// Sent once per day
+			if again := scheduler.checkDigest(tt.lastTick, tt.now); len(again) != 0 {
+				t.Errorf("Expected the digest only once, got it again")
+			}
+		})
+	}
+}
+
+func TestMinuteBasedScheduler_CheckMissedDigest(t *testing.T) {
+	monday := localday.Date(2024, 1, 15)
+	due := monday.Start().Add(8 * time.Hour)
+	scheduler := newDigestTestScheduler(t, config.DigestConfig{Enable: true})
+
+	// The digest is caught up even if missed events are skipped
+	wakeup := config.WakeupHandlingConfig{Enable: true, MissedEventPolicy: "skip", MaxMissedDays: 7}
+	missed := scheduler.CheckMissedAlerts(due.Add(-12*time.Hour), due.Add(time.Hour), wakeup)
+	if len(missed) != 1 || !missed[0].Digest || !missed[0].Late {
+		t.Errorf("Expected a late digest, got %+v", missed)
+	}
+}
@@ -85,6 +85,11 @@ func (pc *PriorityClassifier) ClassifyEvent(event storage.Event) EventPriority {
 		priority = PriorityHigh
 	}
 	
+	// Tasks carry an explicit PRIORITY (1-4 high, 5 medium, 6-9 low)
+	if task, ok := event.(*storage.TaskEvent); ok && task.IsHighPriority() && priority < PriorityHigh {
+		priority = PriorityHigh
+	}
+	
 	// Lower priority for all-day events (usually less urgent)
 	if pc.isAllDayEvent(event) && priority > PriorityLow {
 		priority = PriorityLow
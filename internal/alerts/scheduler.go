@@ -2,20 +2,27 @@ package alerts
 
 import (
 	"fmt"
-	"os"
 	"time"
 
 	"calwatch/internal/config"
+	"calwatch/internal/localday"
 	"calwatch/internal/storage"
 )
 
 // AlertRequest represents a request to send a notification
 type AlertRequest struct {
 	Event       storage.Event
-	AlertOffset time.Duration
+	AlertOffset time.Duration     // Time from the alert to the event start, negative after the start
+	AlertKind   storage.AlertKind // What the alert is relative to
 	Template    string
-	Important   bool // Whether this alert is marked as important
-	Late        bool // Whether this alert is firing late
+	Important   bool      // Whether this alert is marked as important
+	Late        bool      // Whether this alert is firing late
+	EventTime   time.Time // Start of the event occurrence the alert is for
+	Description string    // Description of the alert, e.g. from the VALARM
+	Change      *EventChange // Set for alerts about changed events
+	Digest      bool         // Daily agenda digest instead of an event alert
+	Agenda      []AgendaItem // Occurrences listed in a digest
+	Silent      bool         // Deliver with low urgency and without sound, e.g. during quiet hours
 }
 
 // AlertScheduler manages alert timing and scheduling logic
@@ -37,6 +44,8 @@ type MinuteBasedScheduler struct {
 	stateManager        storage.StateManager
 	priorityClassifier  *PriorityClassifier
 	lastCheckTime       time.Time
+	digestConfig        config.DigestConfig
+	digestDay           localday.Day // Day the last digest was sent for
 }
 
 // NewMinuteBasedScheduler creates a new minute-based alert scheduler
@@ -69,7 +78,7 @@ func (s *MinuteBasedScheduler) CheckAlerts() []AlertRequest {
 	}
 
 	now := time.Now()
-	today := now.Truncate(24 * time.Hour)
+	today := localday.Of(now)
 	
 	// Get the last tick time - use current time minus 1 minute as fallback for first run
 	var lastTick time.Time
@@ -82,8 +91,8 @@ func (s *MinuteBasedScheduler) CheckAlerts() []AlertRequest {
 	
 	// Get events for today and tomorrow (to catch alerts for events starting early tomorrow)
 	var allEvents []storage.Event
-	allEvents = append(allEvents, s.eventStorage.GetEventsForDay(today)...)
-	allEvents = append(allEvents, s.eventStorage.GetEventsForDay(today.Add(24*time.Hour))...)
+	allEvents = append(allEvents, s.eventStorage.GetEventsForDay(today.Start())...)
+	allEvents = append(allEvents, s.eventStorage.GetEventsForDay(today.Next().Start())...)
 
 	var alertRequests []AlertRequest
 
@@ -92,6 +101,9 @@ func (s *MinuteBasedScheduler) CheckAlerts() []AlertRequest {
 		alertRequests = append(alertRequests, s.checkEventAlerts(event, lastTick, now)...)
 	}
 
+	// The daily digest is due alongside the regular alerts
+	alertRequests = append(alertRequests, s.checkDigest(lastTick, now)...)
+
 	s.lastCheckTime = now.Truncate(time.Minute)
 	
 	// Update last alert tick in state manager if available
@@ -112,23 +124,26 @@ func (s *MinuteBasedScheduler) checkEventAlerts(event storage.Event, lastTick, n
 	
 	for _, occurrence := range occurrences {
 		// Check if alert was already sent for this occurrence
-		if state := event.GetAlertState(occurrence.Offset); state == storage.AlertSent {
+		if state := event.GetAlertState(occurrence.Key()); state == storage.AlertSent {
 			continue // Skip already sent alerts
 		}
 		
 		// Mark alert as sent to prevent duplicates
-		event.SetAlertState(occurrence.Offset, storage.AlertSent)
+		event.SetAlertState(occurrence.Key(), storage.AlertSent)
 
-		// Find the appropriate template by looking up the event's calendar
-		template := s.getTemplateForEvent(event)
+		// Find the appropriate template for the alert and the event's calendar
+		template := s.getTemplateForAlert(event, occurrence, occurrence.Late)
 
 		// Create alert request with occurrence context
 		request := AlertRequest{
 			Event:       event,
 			AlertOffset: occurrence.Offset,
+			AlertKind:   occurrence.Kind,
 			Template:    template,
 			Important:   occurrence.Important,
 			Late:        occurrence.Late,
+			EventTime:   occurrence.EventTime,
+			Description: occurrence.Description,
 		}
 		requests = append(requests, request)
 	}
@@ -136,10 +151,24 @@ func (s *MinuteBasedScheduler) checkEventAlerts(event storage.Event, lastTick, n
 	return requests
 }
 
+// getTemplateForAlert selects the template configured for the alert's
+// context, falling back to the template of the event's calendar
+func (s *MinuteBasedScheduler) getTemplateForAlert(event storage.Event, occurrence storage.Occurrence, late bool) string {
+	allDay := false
+	if calEvent := storage.BaseCalendarEvent(event); calEvent != nil {
+		allDay = calEvent.AllDay
+	}
+
+	if template := occurrence.Templates.Select(late, occurrence.Important, allDay); template != "" {
+		return template
+	}
+	return s.getTemplateForEvent(event)
+}
+
 // getTemplateForEvent finds the appropriate template for an event by checking its calendar
 func (s *MinuteBasedScheduler) getTemplateForEvent(event storage.Event) string {
 	// If we have Calendar-aware events, try to get template from the Calendar
-	if calEvent, ok := event.(*storage.CalendarEvent); ok {
+	if calEvent, ok := event.(storage.CalendarMember); ok {
 		if calendar := calEvent.GetCalendar(); calendar != nil {
 			return calendar.GetTemplate()
 		}
@@ -187,9 +216,12 @@ func (s *MinuteBasedScheduler) CheckMissedAlerts(lastTick, currentTime time.Time
 		return nil
 	}
 	
+	// Catch up on today's digest regardless of the policy for events
+	digest := s.checkDigest(lastTick, currentTime)
+	
 	// Skip if policy is to skip missed events
 	if wakeupConfig.MissedEventPolicy == "skip" {
-		return nil
+		return digest
 	}
 	
 	// Calculate missed period with limits
@@ -229,7 +261,7 @@ func (s *MinuteBasedScheduler) CheckMissedAlerts(lastTick, currentTime time.Time
 	}
 	
 	// Apply policy-based filtering
-	return s.applyMissedEventPolicy(missedAlerts, wakeupConfig)
+	return append(s.applyMissedEventPolicy(missedAlerts, wakeupConfig), digest...)
 }
 
 // checkMissedEventAlerts checks if alerts should have fired for a specific event occurrence
@@ -246,16 +278,19 @@ func (s *MinuteBasedScheduler) checkMissedEventAlerts(event storage.Event, occur
 			continue
 		}
 		
-		// Find the appropriate template for this event
-		template := s.getTemplateForEvent(event)
+		// Find the appropriate template for this alert
+		template := s.getTemplateForAlert(event, occ, true)
 		
 		// This alert was missed, create a missed alert request
 		request := AlertRequest{
 			Event:       event,
 			AlertOffset: occ.Offset,
+			AlertKind:   occ.Kind,
 			Template:    template,
 			Important:   occ.Important,
 			Late:        true, // All missed alerts are by definition late
+			EventTime:   occ.EventTime,
+			Description: occ.Description,
 		}
 		requests = append(requests, request)
 	}
@@ -372,9 +407,10 @@ func (s *AdvancedAlertScheduler) CheckAlerts() []AlertRequest {
 
 // getAlertKey creates a unique key for an alert to track duplicates
 func (s *AdvancedAlertScheduler) getAlertKey(request AlertRequest) string {
-	return fmt.Sprintf("%s:%s:%s", 
+	return fmt.Sprintf("%s:%s:%s:%s", 
 		request.Event.GetUID(), 
 		request.Event.GetStartTime().Format("2006-01-02T15:04:05"),
+		request.AlertKind,
 		request.AlertOffset.String(),
 	)
 }
@@ -421,14 +457,13 @@ func (s *MinuteBasedScheduler) GetAlertStats() AlertStats {
 	stats.UpcomingEvents = len(upcomingEvents)
 
 	// Count pending and sent alerts for today's events
-	today := now.Truncate(24 * time.Hour)
-	todaysEvents := s.eventStorage.GetEventsForDay(today)
+	today := localday.Of(now)
+	todaysEvents := s.eventStorage.GetEventsForDay(today.Start())
 
 	for _, event := range todaysEvents {
-		// Use self-contained GetAllAlerts to count alerts
-		allAlerts := event.GetAllAlerts()
-		for _, alert := range allAlerts {
-			alertState := event.GetAlertState(alert.Offset)
+		// Count the alerts firing today
+		for _, occurrence := range event.OccurrencesWithin(today.Start(), today.End()) {
+			alertState := event.GetAlertState(occurrence.Key())
 			switch alertState {
 			case storage.AlertPending:
 				stats.PendingAlerts++
@@ -499,12 +534,13 @@ func (am *AlertManager) run() {
 			// Check for alerts
 			alertRequests := am.scheduler.CheckAlerts()
 			if len(alertRequests) > 0 {
-				// Send alerts through channel (non-blocking)
+				// Send alerts through channel, waiting for the consumer instead of dropping them
 				select {
 				case am.alertChan <- alertRequests:
-				default:
-					// Channel is full, drop the alerts (shouldn't happen with buffered channel)
-					fmt.Fprintf(os.Stderr, "Alert channel full, dropping %d alerts\n", len(alertRequests))
+				case <-am.stopChan:
+					timer.Stop()
+					close(am.alertChan)
+					return
 				}
 			}
 
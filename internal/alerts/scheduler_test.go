@@ -135,6 +135,45 @@ func TestMinuteBasedScheduler_CheckAlerts(t *testing.T) {
 	}
 }
 
+func TestMinuteBasedScheduler_GetTemplateForAlert(t *testing.T) {
+	scheduler := NewMinuteBasedScheduler()
+	calendar := storage.NewCalendar("/test/path", "calendar.tpl", nil)
+	start := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	event := storage.NewCalendarEvent("template-event", "Meeting", "", "", start, start.Add(time.Hour),
+		time.UTC, &recurrence.NoRecurrence{}, calendar, []storage.Alert{})
+	allDayEvent := storage.NewCalendarEvent("all-day-event", "Holiday", "", "", start, start.Add(24*time.Hour),
+		time.UTC, &recurrence.NoRecurrence{}, calendar, []storage.Alert{})
+	allDayEvent.AllDay = true
+
+	templates := storage.AlertTemplates{Default: "heads-up.tpl", Late: "late.tpl", Important: "important.tpl", AllDay: "all-day.tpl"}
+
+	tests := []struct {
+		name      string
+		event     storage.Event
+		templates storage.AlertTemplates
+		late      bool
+		important bool
+		expected  string
+	}{
+		{"calendar fallback", event, storage.AlertTemplates{}, false, false, "calendar.tpl"},
+		{"calendar fallback when late", event, storage.AlertTemplates{}, true, true, "calendar.tpl"},
+		{"alert template", event, templates, false, false, "heads-up.tpl"},
+		{"late template", event, templates, true, true, "late.tpl"},
+		{"important template", allDayEvent, templates, false, true, "important.tpl"},
+		{"all-day template", allDayEvent, templates, false, false, "all-day.tpl"},
+		{"missing late template", event, storage.AlertTemplates{Default: "heads-up.tpl"}, true, false, "heads-up.tpl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			occurrence := storage.Occurrence{Templates: tt.templates, Important: tt.important}
+			if got := scheduler.getTemplateForAlert(tt.event, occurrence, tt.late); got != tt.expected {
+				t.Errorf("Expected template %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
 func TestMinuteBasedScheduler_CheckAlertsNoEvents(t *testing.T) {
 	scheduler := NewMinuteBasedScheduler()
 	eventStorage := storage.NewMemoryEventStorage()
@@ -206,7 +245,7 @@ func TestAdvancedAlertScheduler_DuplicatePrevention(t *testing.T) {
 	}
 
 	// Reset the event's alert state to simulate the same condition
-	event.SetAlertState(5*time.Minute, storage.AlertPending)
+	event.SetAlertState(storage.AlertKey{EventTime: eventTime.UTC(), Offset: 5 * time.Minute}, storage.AlertPending)
 
 	// Second check immediately should not return alerts (due to history tracking)
 	alerts = scheduler.CheckAlerts()
@@ -215,6 +254,46 @@ func TestAdvancedAlertScheduler_DuplicatePrevention(t *testing.T) {
 	}
 }
 
+func TestMinuteBasedScheduler_RecurringAlerts(t *testing.T) {
+	scheduler := NewMinuteBasedScheduler()
+	scheduler.SetEventStorage(storage.NewMemoryEventStorage())
+
+	daily, err := recurrence.ParseRRule("FREQ=DAILY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	calendar := storage.NewCalendar("/test/path", "", []storage.Alert{{Offset: 15 * time.Minute}})
+	event := storage.NewCalendarEvent("standup", "Standup", "", "", start, start.Add(15*time.Minute),
+		time.UTC, daily, calendar, []storage.Alert{})
+
+	// Each day's occurrence is alerted once
+	for day := 0; day < 3; day++ {
+		alertTime := start.AddDate(0, 0, day).Add(-15 * time.Minute)
+		for _, window := range [][2]time.Time{
+			{alertTime.Add(-time.Minute), alertTime},
+			{alertTime.Add(-time.Minute), alertTime.Add(time.Minute)}, // Overlapping window
+		} {
+			requests := scheduler.checkEventAlerts(event, window[0], window[1])
+			expected := 1
+			if window[1].After(alertTime) {
+				expected = 0
+			}
+			if len(requests) != expected {
+				t.Fatalf("Day %d: expected %d alerts, got %d", day+1, expected, len(requests))
+			}
+		}
+	}
+
+	// States of past occurrences are pruned
+	if state := event.GetAlertState(storage.AlertKey{EventTime: start.AddDate(0, 0, 2), Offset: 15 * time.Minute}); state != storage.AlertSent {
+		t.Errorf("Expected the last day's alert to be sent, got %v", state)
+	}
+	if state := event.GetAlertState(storage.AlertKey{EventTime: start, Offset: 15 * time.Minute}); state != storage.AlertPending {
+		t.Errorf("Expected the first day's state to be pruned, got %v", state)
+	}
+}
+
 func TestMinuteBasedScheduler_GetAlertStats(t *testing.T) {
 	scheduler := NewMinuteBasedScheduler()
 	eventStorage := storage.NewMemoryEventStorage()
@@ -2,8 +2,12 @@ package config
 
 import (
 	"fmt"
+	"net/mail"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/adrg/xdg"
@@ -12,17 +16,44 @@ import (
 
 // Config represents the application configuration
 type Config struct {
+	Timezone       string              `yaml:"timezone,omitempty"` // IANA zone for local day boundaries, defaults to the system zone
 	Directories    []DirectoryConfig   `yaml:"directories"`
 	Notification   NotificationConfig  `yaml:"notification"`
 	WakeupHandling WakeupHandlingConfig `yaml:"wakeup_handling"`
+	ChangeAlerts   ChangeAlertsConfig  `yaml:"change_alerts,omitempty"`
+	Digest         DigestConfig        `yaml:"digest,omitempty"`
+	QuietHours     QuietHoursConfig    `yaml:"quiet_hours,omitempty"`
+	Pause          PauseConfig         `yaml:"pause,omitempty"`
+	Focus          FocusConfig         `yaml:"focus,omitempty"`
 	Logging        LoggingConfig       `yaml:"logging"`
 }
 
+// Directory types
+const (
+	DirectoryTypeCalendar = "calendar" // .ics calendar vdir (default)
+	DirectoryTypeContacts = "contacts" // .vcf address book vdir, alerts for birthdays and anniversaries
+)
+
 // DirectoryConfig represents configuration for a single CalDAV directory
 type DirectoryConfig struct {
 	Directory       string        `yaml:"directory"`
+	Type            string        `yaml:"type,omitempty"` // "calendar" (default) or "contacts"
 	Template        string        `yaml:"template"`
 	AutomaticAlerts []AlertConfig `yaml:"automatic_alerts"`
+	AllDayAlerts    []AllDayAlertConfig `yaml:"all_day_alerts,omitempty"` // Replace the automatic alerts for all-day events
+	QuietHours      *QuietHoursConfig   `yaml:"quiet_hours,omitempty"`    // Replaces the global quiet hours for this calendar
+}
+
+// AllDayAlertConfig represents an alert for all-day events at a time of day
+type AllDayAlertConfig struct {
+	Time       string `yaml:"time"`                  // Local time of day "HH:MM"
+	DaysBefore int    `yaml:"days_before,omitempty"` // 0 for the day of the event
+	Important  bool   `yaml:"important"`
+
+	// Templates for this alert, falling back to the directory template
+	Template          string `yaml:"template,omitempty"`
+	LateTemplate      string `yaml:"late_template,omitempty"`
+	ImportantTemplate string `yaml:"important_template,omitempty"`
 }
 
 // AlertConfig represents an alert timing configuration
@@ -30,6 +61,14 @@ type AlertConfig struct {
 	Value     int  `yaml:"value"`
 	Unit      string `yaml:"unit"`
 	Important bool `yaml:"important"`
+	When      string `yaml:"when,omitempty"` // What the alert is relative to, defaults to before_start
+	Time      string `yaml:"time,omitempty"` // Time of day "HH:MM" of ongoing reminders
+
+	// Templates for this alert, falling back to the directory template
+	Template          string `yaml:"template,omitempty"`
+	LateTemplate      string `yaml:"late_template,omitempty"`      // Missed or delayed alerts
+	ImportantTemplate string `yaml:"important_template,omitempty"` // Important alerts
+	AllDayTemplate    string `yaml:"all_day_template,omitempty"`   // All-day events and birthdays
 }
 
 // DurationConfig represents a user-friendly duration configuration
@@ -44,6 +83,88 @@ type NotificationConfig struct {
 	Backend          string         `yaml:"backend"`
 	Duration         DurationConfig `yaml:"duration"`
 	DurationWhenLate DurationConfig `yaml:"duration_when_late"`
+	Exec             ExecConfig     `yaml:"exec,omitempty"`    // Only used by the "exec" backend
+	Webhook          WebhookConfig  `yaml:"webhook,omitempty"` // Only used by the "webhook" backend
+	Email            EmailConfig    `yaml:"email,omitempty"`   // Only used by the "email" backend
+	Desktop          DesktopConfig  `yaml:"desktop,omitempty"` // Used by the "dbus" and "notify-send" backends
+
+	// Named backends replace the single backend above when configured
+	Backends map[string]BackendConfig `yaml:"backends,omitempty"`
+	Routes   []RouteConfig            `yaml:"routes,omitempty"` // Without routes every alert goes to all backends
+}
+
+// DesktopConfig configures desktop notifications. Hints the notification
+// server does not support are ignored.
+type DesktopConfig struct {
+	Image          string `yaml:"image,omitempty"`           // Absolute path of an image shown with alerts
+	Sound          string `yaml:"sound,omitempty"`           // Sound theme name, e.g. "message-new-instant"
+	ImportantSound string `yaml:"important_sound,omitempty"` // Sound for important alerts, defaults to sound
+	Category       string `yaml:"category,omitempty"`        // Notification category, e.g. "x-calwatch.alert"
+	Countdown      bool   `yaml:"countdown,omitempty"`       // Update open notifications every minute until the event starts ("dbus" only)
+}
+
+// BackendConfig configures a named notification backend
+type BackendConfig struct {
+	Type    string        `yaml:"type"` // "notify-send", "dbus", "exec", "webhook" or "email"
+	Exec    ExecConfig    `yaml:"exec,omitempty"`
+	Webhook WebhookConfig `yaml:"webhook,omitempty"`
+	Email   EmailConfig   `yaml:"email,omitempty"`
+}
+
+// RouteConfig sends alerts matching all of its conditions to the listed
+// backends. Empty conditions match every alert.
+type RouteConfig struct {
+	Backends   []string     `yaml:"backends"`
+	Calendars  []string     `yaml:"calendars,omitempty"`  // Calendar directories (full path or directory name)
+	Important  *bool        `yaml:"important,omitempty"`  // Match alerts marked (or not marked) important
+	Late       *bool        `yaml:"late,omitempty"`       // Match late (or on-time) alerts
+	Priorities []string     `yaml:"priorities,omitempty"` // Event priorities: "low", "normal", "high", "critical"
+	After      string       `yaml:"after,omitempty"`      // Local time of day "HH:MM" from which the route applies
+	Before     string       `yaml:"before,omitempty"`     // Local time of day "HH:MM" until which the route applies
+	MinOffset  *AlertConfig `yaml:"min_offset,omitempty"` // Match alerts at least this long before the event (e.g. 1 day)
+}
+
+// ExecConfig configures the "exec" backend, which runs a command per alert
+type ExecConfig struct {
+	Command string         `yaml:"command"`
+	Args    []string       `yaml:"args,omitempty"`
+	Input   string         `yaml:"input,omitempty"`   // "env" (default) or "json" to also write the data to stdin
+	Timeout DurationConfig `yaml:"timeout,omitempty"` // Defaults to 10 seconds
+}
+
+// WebhookConfig configures the "webhook" backend, which sends alerts to an HTTP endpoint
+type WebhookConfig struct {
+	URL           string            `yaml:"url"`
+	Method        string            `yaml:"method,omitempty"`         // Defaults to POST
+	Format        string            `yaml:"format,omitempty"`         // "json" (default), "form" or "text"
+	Headers       map[string]string `yaml:"headers,omitempty"`        // Values are templates
+	Fields        map[string]string `yaml:"fields,omitempty"`         // JSON or form fields, values are templates
+	Payload       string            `yaml:"payload,omitempty"`        // Template for the whole request body, overrides fields
+	ImportantOnly bool              `yaml:"important_only,omitempty"` // Only send alerts marked important
+	Timeout       DurationConfig    `yaml:"timeout,omitempty"`        // Per request (default 10 seconds)
+}
+
+// EmailConfig configures the "email" backend, which mails alerts with the
+// event attached as an .ics file
+type EmailConfig struct {
+	From      string         `yaml:"from"`
+	To        []string       `yaml:"to"`
+	Subject   string         `yaml:"subject,omitempty"`   // Template, defaults to "Reminder: {{.Title}} in {{.AlertOffset}}"
+	Body      string         `yaml:"body,omitempty"`      // Template, defaults to the rendered notification
+	Transport string         `yaml:"transport,omitempty"` // "smtp" (default) or "sendmail"
+	SMTP      SMTPConfig     `yaml:"smtp,omitempty"`
+	Sendmail  string         `yaml:"sendmail,omitempty"` // Path of the sendmail binary (default /usr/sbin/sendmail)
+	Timeout   DurationConfig `yaml:"timeout,omitempty"`  // Defaults to 30 seconds
+}
+
+// SMTPConfig configures the SMTP server used by the "email" backend
+type SMTPConfig struct {
+	Host         string `yaml:"host"`
+	Port         int    `yaml:"port,omitempty"`     // Defaults to 587, or 465 for "tls"
+	Security     string `yaml:"security,omitempty"` // "starttls" (default), "tls" or "none"
+	Username     string `yaml:"username,omitempty"`
+	Password     string `yaml:"password,omitempty"`
+	PasswordFile string `yaml:"password_file,omitempty"` // Read on every send, instead of password
 }
 
 // WakeupHandlingConfig represents wake-up detection and missed event handling
@@ -55,6 +176,267 @@ type WakeupHandlingConfig struct {
 	MaxCatchupTime     DurationConfig `yaml:"max_catchup_time"`
 }
 
+// ChangeAlertsConfig configures alerts about new, moved and cancelled events
+type ChangeAlertsConfig struct {
+	Enable     bool        `yaml:"enable"`
+	Changes    []string    `yaml:"changes,omitempty"`     // "new", "moved", "location" and/or "cancelled", defaults to all
+	Within     AlertConfig `yaml:"within,omitempty"`      // Only alert about events starting within this time, defaults to 2 days
+	Template   string      `yaml:"template,omitempty"`    // Defaults to the built-in change template
+	Important  bool        `yaml:"important,omitempty"`
+	MaxChanges int         `yaml:"max_changes,omitempty"` // More changes at once are a resync and not alerted, defaults to 10
+}
+
+// Kinds of event changes that can be alerted
+var changeKinds = []string{"new", "moved", "location", "cancelled"}
+
+// Validate checks the change alert configuration and applies defaults
+func (c *ChangeAlertsConfig) Validate() error {
+	if len(c.Changes) == 0 {
+		c.Changes = append([]string(nil), changeKinds...)
+	}
+	for _, change := range c.Changes {
+		if !isChangeKind(change) {
+			return fmt.Errorf("unsupported change %q, expected one of %s", change, strings.Join(changeKinds, ", "))
+		}
+	}
+
+	if c.Within.Unit == "" {
+		c.Within = AlertConfig{Value: 2, Unit: "days"}
+	}
+	if c.Within.Value <= 0 {
+		return fmt.Errorf("within: value must be positive")
+	}
+	if _, err := c.Within.Duration(); err != nil {
+		return fmt.Errorf("within: %w", err)
+	}
+
+	if c.MaxChanges <= 0 {
+		c.MaxChanges = 10
+	}
+	return nil
+}
+
+// isChangeKind reports whether a change kind is supported
+func isChangeKind(change string) bool {
+	for _, kind := range changeKinds {
+		if change == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Alerts reports whether changes of the given kind are alerted
+func (c ChangeAlertsConfig) Alerts(change string) bool {
+	for _, enabled := range c.Changes {
+		if enabled == change {
+			return true
+		}
+	}
+	return false
+}
+
+// DigestConfig configures the daily agenda digest listing the day's events
+type DigestConfig struct {
+	Enable    bool     `yaml:"enable"`
+	Time      string   `yaml:"time,omitempty"`       // Local time of day "HH:MM", defaults to 08:00
+	Days      []string `yaml:"days,omitempty"`       // Weekdays, e.g. [mon, tue, wed, thu, fri], defaults to every day
+	Template  string   `yaml:"template,omitempty"`   // Agenda template, defaults to the built-in agenda
+	SkipEmpty bool     `yaml:"skip_empty,omitempty"` // No digest on days without events
+}
+
+// Validate checks the digest configuration and applies defaults
+func (d *DigestConfig) Validate() error {
+	if d.Time == "" {
+		d.Time = "08:00"
+	}
+	if _, err := parseTimeOfDay(d.Time); err != nil {
+		return fmt.Errorf("time: %w", err)
+	}
+
+	for _, day := range d.Days {
+		if _, err := parseWeekday(day); err != nil {
+			return fmt.Errorf("days: %w", err)
+		}
+	}
+	return nil
+}
+
+// OnDay reports whether the digest is sent on the given weekday
+func (d DigestConfig) OnDay(weekday time.Weekday) bool {
+	if len(d.Days) == 0 {
+		return true
+	}
+	for _, day := range d.Days {
+		if parsed, err := parseWeekday(day); err == nil && parsed == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// At returns the time the digest is due on the day starting at midnight
+func (d DigestConfig) At(midnight time.Time) time.Time {
+	minute, err := parseTimeOfDay(d.Time)
+	if err != nil {
+		return midnight
+	}
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day(), minute/60, minute%60, 0, 0, midnight.Location())
+}
+
+// HoldPolicy is what happens to alerts held back by quiet hours or busy events
+type HoldPolicy string
+
+// Policies for held back alerts
+const (
+	HoldDefer  HoldPolicy = "defer"  // Deliver as a digest when the hold ends
+	HoldDrop   HoldPolicy = "drop"   // Do not deliver
+	HoldSilent HoldPolicy = "silent" // Deliver with low urgency and without sound
+)
+
+// Validate checks the policy and defaults to deferring
+func (p *HoldPolicy) Validate() error {
+	if *p == "" {
+		*p = HoldDefer
+	}
+	if *p != HoldDefer && *p != HoldDrop && *p != HoldSilent {
+		return fmt.Errorf("action must be '%s', '%s' or '%s', got: %s", HoldDefer, HoldDrop, HoldSilent, *p)
+	}
+	return nil
+}
+
+// QuietHoursConfig holds back alerts during nights, weekends and holidays
+type QuietHoursConfig struct {
+	Enable          bool                `yaml:"enable"`
+	Periods         []QuietPeriodConfig `yaml:"periods,omitempty"`
+	HolidayCalendar string              `yaml:"holiday_calendar,omitempty"` // Days with events in this calendar are quiet
+	Action          HoldPolicy          `yaml:"action,omitempty"`           // "defer" (default), "drop" or "silent"
+	AllowImportant  bool                `yaml:"allow_important,omitempty"`  // Important alerts break through
+	Template        string              `yaml:"template,omitempty"`         // Template of the digest of deferred alerts
+}
+
+// QuietPeriodConfig is a quiet time range recurring every week
+type QuietPeriodConfig struct {
+	Days []string `yaml:"days,omitempty"` // Weekdays the period starts on, defaults to every day
+	From string   `yaml:"from,omitempty"` // Local time of day "HH:MM", whole days without from and to
+	To   string   `yaml:"to,omitempty"`   // Ends on the next day if not after from
+}
+
+// Validate checks the quiet hours configuration and applies defaults
+func (q *QuietHoursConfig) Validate() error {
+	if err := q.Action.Validate(); err != nil {
+		return err
+	}
+
+	for i, period := range q.Periods {
+		if (period.From == "") != (period.To == "") {
+			return fmt.Errorf("period %d: from and to must be set together", i)
+		}
+		if period.From != "" {
+			if _, err := parseTimeOfDay(period.From); err != nil {
+				return fmt.Errorf("period %d: from: %w", i, err)
+			}
+			if _, err := parseTimeOfDay(period.To); err != nil {
+				return fmt.Errorf("period %d: to: %w", i, err)
+			}
+		}
+		for _, day := range period.Days {
+			if _, err := parseWeekday(day); err != nil {
+				return fmt.Errorf("period %d: days: %w", i, err)
+			}
+		}
+	}
+
+	if q.HolidayCalendar != "" {
+		directory := DirectoryConfig{Directory: q.HolidayCalendar}
+		if err := directory.ExpandPath(); err != nil {
+			return fmt.Errorf("holiday_calendar: %w", err)
+		}
+		q.HolidayCalendar = directory.Directory
+	}
+	return nil
+}
+
+// OnDay reports whether the period starts on the given weekday
+func (p QuietPeriodConfig) OnDay(weekday time.Weekday) bool {
+	return DigestConfig{Days: p.Days}.OnDay(weekday)
+}
+
+// Range returns the start and end of the period as time since midnight. The
+// period ends on the next day if the end is not after the start, whole day
+// periods last from midnight to midnight.
+func (p QuietPeriodConfig) Range() (from, to time.Duration) {
+	fromMinute, _ := parseTimeOfDay(p.From)
+	toMinute, _ := parseTimeOfDay(p.To)
+	return time.Duration(fromMinute) * time.Minute, time.Duration(toMinute) * time.Minute
+}
+
+// Policies for alerts while paused or muted
+const (
+	PauseDrop   = "drop"   // Do not deliver
+	PauseDigest = "digest" // Deliver together as one digest when the pause ends
+	PauseReplay = "replay" // Deliver each alert on its own, late, when the pause ends
+)
+
+// PauseConfig sets what happens to alerts while paused with "calwatch pause"
+// or "calwatch mute"
+type PauseConfig struct {
+	Policy   string `yaml:"policy,omitempty"`   // "digest" (default), "replay" or "drop"
+	Template string `yaml:"template,omitempty"` // Template of the digest of queued alerts
+}
+
+// Validate checks the pause configuration and applies defaults
+func (p *PauseConfig) Validate() error {
+	if p.Policy == "" {
+		p.Policy = PauseDigest
+	}
+	if p.Policy != PauseDigest && p.Policy != PauseReplay && p.Policy != PauseDrop {
+		return fmt.Errorf("policy must be '%s', '%s' or '%s', got: %s", PauseDigest, PauseReplay, PauseDrop, p.Policy)
+	}
+	return nil
+}
+
+// FocusConfig holds back non-important alerts while a busy event is in
+// progress, e.g. a meeting in a work calendar
+type FocusConfig struct {
+	Enable     bool       `yaml:"enable"`
+	Calendars  []string   `yaml:"calendars,omitempty"`  // Calendars whose events are busy, by name or directory
+	Categories []string   `yaml:"categories,omitempty"` // Events with any of these categories are busy
+	Action     HoldPolicy `yaml:"action,omitempty"`     // "defer" (default), "drop" or "silent", like quiet hours
+	Template   string     `yaml:"template,omitempty"`   // Template of the digest of deferred alerts
+}
+
+// Validate checks the focus configuration and applies defaults
+func (f *FocusConfig) Validate() error {
+	if err := f.Action.Validate(); err != nil {
+		return err
+	}
+	if f.Enable && len(f.Calendars) == 0 && len(f.Categories) == 0 {
+		return fmt.Errorf("calendars or categories must be set")
+	}
+
+	for i, calendar := range f.Calendars {
+		directory := DirectoryConfig{Directory: calendar}
+		if err := directory.ExpandPath(); err != nil {
+			return fmt.Errorf("calendars: %w", err)
+		}
+		f.Calendars[i] = directory.Directory
+	}
+	return nil
+}
+
+// parseWeekday parses a weekday name, e.g. "mon" or "Monday"
+func parseWeekday(value string) (time.Weekday, error) {
+	name := strings.ToLower(value)
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		full := strings.ToLower(weekday.String())
+		if name == full || name == full[:3] {
+			return weekday, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", value)
+}
+
 // LoggingConfig represents logging configuration
 type LoggingConfig struct {
 	Level string `yaml:"level"`
@@ -77,6 +459,71 @@ func (a AlertConfig) Duration() (time.Duration, error) {
 	}
 }
 
+// When alerts fire relative to their event
+const (
+	AlertBeforeStart = "before_start" // Value and unit before the start
+	AlertAtStart     = "at_start"     // When the event starts
+	AlertBeforeEnd   = "before_end"   // Value and unit before the end
+	AlertAtEnd       = "at_end"       // When the event is over
+	AlertOngoing     = "ongoing"      // Daily at a time of day while a multi-day event is ongoing
+)
+
+// Validate validates an automatic alert for when it fires
+func (a AlertConfig) Validate() error {
+	switch a.When {
+	case "", AlertBeforeStart, AlertBeforeEnd:
+		if a.Value <= 0 {
+			return fmt.Errorf("value must be positive")
+		}
+		if _, err := a.Duration(); err != nil {
+			return err
+		}
+	case AlertAtStart, AlertAtEnd, AlertOngoing:
+		if a.Value != 0 || a.Unit != "" {
+			return fmt.Errorf("%s alerts take no value or unit", a.When)
+		}
+	default:
+		return fmt.Errorf("when must be one of %s, %s, %s, %s or %s, got: %s",
+			AlertBeforeStart, AlertAtStart, AlertBeforeEnd, AlertAtEnd, AlertOngoing, a.When)
+	}
+
+	if a.When == AlertOngoing {
+		if _, err := a.DailyAt(); err != nil {
+			return err
+		}
+	} else if a.Time != "" {
+		return fmt.Errorf("time is only supported for %s alerts", AlertOngoing)
+	}
+	return nil
+}
+
+// Validate validates an all-day alert
+func (a AllDayAlertConfig) Validate() error {
+	if a.DaysBefore < 0 {
+		return fmt.Errorf("days_before cannot be negative")
+	}
+	_, err := a.At()
+	return err
+}
+
+// At returns the time of day of the alert as time since midnight
+func (a AllDayAlertConfig) At() (time.Duration, error) {
+	minutes, err := parseTimeOfDay(a.Time)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(minutes) * time.Minute, nil
+}
+
+// DailyAt returns the time of day of ongoing reminders as time since midnight
+func (a AlertConfig) DailyAt() (time.Duration, error) {
+	minutes, err := parseTimeOfDay(a.Time)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(minutes) * time.Minute, nil
+}
+
 // IsUntilDismissed returns true if this duration is of type "until_dismissed"
 func (d DurationConfig) IsUntilDismissed() bool {
 	return d.Type == "until_dismissed"
@@ -149,6 +596,287 @@ func (d DurationConfig) Validate() error {
 	return nil
 }
 
+// BackendNames returns the names of the configured backends. Without named
+// backends this is the single legacy backend.
+func (n NotificationConfig) BackendNames() []string {
+	if len(n.Backends) == 0 {
+		return []string{n.Backend}
+	}
+
+	names := make([]string, 0, len(n.Backends))
+	for name := range n.Backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateBackend validates a backend type and its type specific configuration
+func validateBackend(backendType string, exec *ExecConfig, webhook *WebhookConfig, email *EmailConfig) error {
+	switch backendType {
+	case "notify-send", "dbus":
+	case "exec":
+		if err := exec.Validate(); err != nil {
+			return fmt.Errorf("exec: %w", err)
+		}
+	case "webhook":
+		if err := webhook.Validate(); err != nil {
+			return fmt.Errorf("webhook: %w", err)
+		}
+	case "email":
+		if err := email.Validate(); err != nil {
+			return fmt.Errorf("email: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported notification backend: %s", backendType)
+	}
+	return nil
+}
+
+// Validate checks a routing rule against the available backends and
+// expands its calendar paths
+func (r *RouteConfig) Validate(backendNames []string) error {
+	if len(r.Backends) == 0 {
+		return fmt.Errorf("at least one backend must be listed")
+	}
+	for _, name := range r.Backends {
+		found := false
+		for _, available := range backendNames {
+			if name == available {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown backend: %s", name)
+		}
+	}
+
+	for i, calendar := range r.Calendars {
+		directory := DirectoryConfig{Directory: calendar}
+		if err := directory.ExpandPath(); err != nil {
+			return fmt.Errorf("calendar %s: %w", calendar, err)
+		}
+		r.Calendars[i] = directory.Directory
+	}
+
+	validPriorities := map[string]bool{
+		"low":      true,
+		"normal":   true,
+		"high":     true,
+		"critical": true,
+	}
+	for _, priority := range r.Priorities {
+		if !validPriorities[priority] {
+			return fmt.Errorf("invalid priority: %s", priority)
+		}
+	}
+
+	if r.After != "" {
+		if _, err := parseTimeOfDay(r.After); err != nil {
+			return fmt.Errorf("after: %w", err)
+		}
+	}
+	if r.Before != "" {
+		if _, err := parseTimeOfDay(r.Before); err != nil {
+			return fmt.Errorf("before: %w", err)
+		}
+	}
+
+	if r.MinOffset != nil {
+		if _, err := r.MinOffset.Duration(); err != nil {
+			return fmt.Errorf("min_offset: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// InTimeWindow reports whether the local time of day of t lies within the
+// route's after/before window. Windows with after later than before span
+// midnight (e.g. after 18:00, before 08:00).
+func (r RouteConfig) InTimeWindow(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	after, afterErr := parseTimeOfDay(r.After)
+	before, beforeErr := parseTimeOfDay(r.Before)
+	hasAfter, hasBefore := r.After != "" && afterErr == nil, r.Before != "" && beforeErr == nil
+
+	switch {
+	case hasAfter && hasBefore && after > before:
+		return minute >= after || minute < before
+	case hasAfter && hasBefore:
+		return minute >= after && minute < before
+	case hasAfter:
+		return minute >= after
+	case hasBefore:
+		return minute < before
+	default:
+		return true
+	}
+}
+
+// parseTimeOfDay parses "HH:MM" into minutes after midnight
+func parseTimeOfDay(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// Validate validates the ExecConfig and applies defaults
+func (e *ExecConfig) Validate() error {
+	if e.Command == "" {
+		return fmt.Errorf("command cannot be empty")
+	}
+
+	if e.Input == "" {
+		e.Input = "env"
+	}
+	if e.Input != "env" && e.Input != "json" {
+		return fmt.Errorf("input must be 'env' or 'json', got: %s", e.Input)
+	}
+
+	if e.Timeout.Type == "" {
+		e.Timeout = DurationConfig{
+			Type:  "timed",
+			Value: 10,
+			Unit:  "seconds",
+		}
+	}
+	if e.Timeout.IsUntilDismissed() {
+		return fmt.Errorf("timeout must be of type 'timed'")
+	}
+	if err := e.Timeout.Validate(); err != nil {
+		return fmt.Errorf("timeout: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates the WebhookConfig and applies defaults
+func (w *WebhookConfig) Validate() error {
+	parsed, err := url.Parse(w.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute http(s) URL, got: %q", w.URL)
+	}
+
+	if w.Method == "" {
+		w.Method = "POST"
+	}
+	w.Method = strings.ToUpper(w.Method)
+
+	if w.Format == "" {
+		w.Format = "json"
+	}
+	if w.Format != "json" && w.Format != "form" && w.Format != "text" {
+		return fmt.Errorf("format must be 'json', 'form' or 'text', got: %s", w.Format)
+	}
+
+	if w.Timeout.Type == "" {
+		w.Timeout = DurationConfig{
+			Type:  "timed",
+			Value: 10,
+			Unit:  "seconds",
+		}
+	}
+	if w.Timeout.IsUntilDismissed() {
+		return fmt.Errorf("timeout must be of type 'timed'")
+	}
+	if err := w.Timeout.Validate(); err != nil {
+		return fmt.Errorf("timeout: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates the EmailConfig and applies defaults
+func (e *EmailConfig) Validate() error {
+	if _, err := mail.ParseAddress(e.From); err != nil {
+		return fmt.Errorf("invalid from address %q: %w", e.From, err)
+	}
+	if len(e.To) == 0 {
+		return fmt.Errorf("at least one recipient must be configured")
+	}
+	for _, to := range e.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("invalid recipient %q: %w", to, err)
+		}
+	}
+
+	if e.Transport == "" {
+		e.Transport = "smtp"
+	}
+	switch e.Transport {
+	case "smtp":
+		if e.SMTP.Host == "" {
+			return fmt.Errorf("smtp host cannot be empty")
+		}
+		if e.SMTP.Security == "" {
+			e.SMTP.Security = "starttls"
+		}
+		if e.SMTP.Security != "starttls" && e.SMTP.Security != "tls" && e.SMTP.Security != "none" {
+			return fmt.Errorf("smtp security must be 'starttls', 'tls' or 'none', got: %s", e.SMTP.Security)
+		}
+		if e.SMTP.Port == 0 {
+			e.SMTP.Port = 587
+			if e.SMTP.Security == "tls" {
+				e.SMTP.Port = 465
+			}
+		}
+		if e.SMTP.Port < 0 || e.SMTP.Port > 65535 {
+			return fmt.Errorf("invalid smtp port: %d", e.SMTP.Port)
+		}
+		if e.SMTP.PasswordFile != "" {
+			directory := DirectoryConfig{Directory: e.SMTP.PasswordFile}
+			if err := directory.ExpandPath(); err != nil {
+				return fmt.Errorf("smtp password_file: %w", err)
+			}
+			e.SMTP.PasswordFile = directory.Directory
+		}
+	case "sendmail":
+		if e.Sendmail == "" {
+			e.Sendmail = "/usr/sbin/sendmail"
+		}
+	default:
+		return fmt.Errorf("transport must be 'smtp' or 'sendmail', got: %s", e.Transport)
+	}
+
+	if e.Timeout.Type == "" {
+		e.Timeout = DurationConfig{
+			Type:  "timed",
+			Value: 30,
+			Unit:  "seconds",
+		}
+	}
+	if e.Timeout.IsUntilDismissed() {
+		return fmt.Errorf("timeout must be of type 'timed'")
+	}
+	if err := e.Timeout.Validate(); err != nil {
+		return fmt.Errorf("timeout: %w", err)
+	}
+
+	return nil
+}
+
+// Location returns the configured local timezone, falling back to the system zone
+func (c *Config) Location() (*time.Location, error) {
+	if c.Timezone == "" || c.Timezone == "Local" {
+		return time.Local, nil
+	}
+	
+	location, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %s: %w", c.Timezone, err)
+	}
+	return location, nil
+}
+
+// IsContacts returns true if the directory holds vCard address books
+func (d DirectoryConfig) IsContacts() bool {
+	return d.Type == DirectoryTypeContacts
+}
+
 // ExpandPath expands ~ and environment variables in paths
 func (d *DirectoryConfig) ExpandPath() error {
 	expanded := os.ExpandEnv(d.Directory)
@@ -169,6 +897,11 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("at least one directory must be configured")
 	}
 
+	// Validate timezone
+	if _, err := c.Location(); err != nil {
+		return err
+	}
+
 	for i, dir := range c.Directories {
 		if dir.Directory == "" {
 			return fmt.Errorf("directory %d: directory path cannot be empty", i)
@@ -184,23 +917,50 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("directory %d: directory does not exist: %s", i, c.Directories[i].Directory)
 		}
 
+		// Validate directory type
+		if dir.Type == "" {
+			c.Directories[i].Type = DirectoryTypeCalendar
+		} else if dir.Type != DirectoryTypeCalendar && dir.Type != DirectoryTypeContacts {
+			return fmt.Errorf("directory %d: unsupported directory type: %s", i, dir.Type)
+		}
+
 		// Validate alert configurations
 		for j, alert := range dir.AutomaticAlerts {
-			if alert.Value <= 0 {
-				return fmt.Errorf("directory %d, alert %d: value must be positive", i, j)
-			}
-			if _, err := alert.Duration(); err != nil {
+			if err := alert.Validate(); err != nil {
 				return fmt.Errorf("directory %d, alert %d: %w", i, j, err)
 			}
 		}
+		for j, alert := range dir.AllDayAlerts {
+			if err := alert.Validate(); err != nil {
+				return fmt.Errorf("directory %d, all-day alert %d: %w", i, j, err)
+			}
+		}
+		if dir.QuietHours != nil {
+			if err := dir.QuietHours.Validate(); err != nil {
+				return fmt.Errorf("directory %d, quiet_hours: %w", i, err)
+			}
+		}
 	}
 
 	// Validate and apply defaults for notification configuration
 	if c.Notification.Backend == "" {
 		c.Notification.Backend = "notify-send"
 	}
-	if c.Notification.Backend != "notify-send" {
-		return fmt.Errorf("unsupported notification backend: %s", c.Notification.Backend)
+	if err := validateBackend(c.Notification.Backend, &c.Notification.Exec, &c.Notification.Webhook, &c.Notification.Email); err != nil {
+		return fmt.Errorf("notification: %w", err)
+	}
+
+	// Validate named backends and routing rules
+	for name, backend := range c.Notification.Backends {
+		if err := validateBackend(backend.Type, &backend.Exec, &backend.Webhook, &backend.Email); err != nil {
+			return fmt.Errorf("notification backend %s: %w", name, err)
+		}
+		c.Notification.Backends[name] = backend
+	}
+	for i := range c.Notification.Routes {
+		if err := c.Notification.Routes[i].Validate(c.Notification.BackendNames()); err != nil {
+			return fmt.Errorf("notification route %d: %w", i, err)
+		}
 	}
 
 	// Apply defaults for notification duration
@@ -259,6 +1019,22 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("wakeup_handling max_catchup_time: %w", err)
 	}
 
+	if err := c.ChangeAlerts.Validate(); err != nil {
+		return fmt.Errorf("change_alerts: %w", err)
+	}
+	if err := c.Digest.Validate(); err != nil {
+		return fmt.Errorf("digest: %w", err)
+	}
+	if err := c.QuietHours.Validate(); err != nil {
+		return fmt.Errorf("quiet_hours: %w", err)
+	}
+	if err := c.Pause.Validate(); err != nil {
+		return fmt.Errorf("pause: %w", err)
+	}
+	if err := c.Focus.Validate(); err != nil {
+		return fmt.Errorf("focus: %w", err)
+	}
+
 	// Validate logging level
 	if c.Logging.Level == "" {
 		c.Logging.Level = "info"
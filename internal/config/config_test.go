@@ -55,6 +55,58 @@ func TestAlertConfig_Duration(t *testing.T) {
 	}
 }
 
+func TestAlertConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		alert   AlertConfig
+		wantErr bool
+	}{
+		{"before start", AlertConfig{Value: 5, Unit: "minutes"}, false},
+		{"before start without value", AlertConfig{Unit: "minutes"}, true},
+		{"at start", AlertConfig{When: AlertAtStart}, false},
+		{"at start with value", AlertConfig{When: AlertAtStart, Value: 5, Unit: "minutes"}, true},
+		{"before end", AlertConfig{When: AlertBeforeEnd, Value: 5, Unit: "minutes"}, false},
+		{"before end with invalid unit", AlertConfig{When: AlertBeforeEnd, Value: 5, Unit: "weeks"}, true},
+		{"at end", AlertConfig{When: AlertAtEnd}, false},
+		{"ongoing", AlertConfig{When: AlertOngoing, Time: "09:00"}, false},
+		{"ongoing without time", AlertConfig{When: AlertOngoing}, true},
+		{"time on other alerts", AlertConfig{Value: 5, Unit: "minutes", Time: "09:00"}, true},
+		{"invalid when", AlertConfig{When: "after_end"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.alert.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AlertConfig.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAllDayAlertConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		alert   AllDayAlertConfig
+		wantErr bool
+	}{
+		{"on the day", AllDayAlertConfig{Time: "09:00"}, false},
+		{"day before", AllDayAlertConfig{Time: "18:00", DaysBefore: 1}, false},
+		{"missing time", AllDayAlertConfig{DaysBefore: 1}, true},
+		{"invalid time", AllDayAlertConfig{Time: "25:00"}, true},
+		{"negative days", AllDayAlertConfig{Time: "09:00", DaysBefore: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.alert.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AllDayAlertConfig.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestDirectoryConfig_ExpandPath(t *testing.T) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -178,6 +230,350 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "invalid timezone",
+			config: Config{
+				Timezone: "Mars/Olympus_Mons",
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "contacts directory",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir, Type: "contacts", Template: "birthday.tpl"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid directory type",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir, Type: "tasks"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "exec backend",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{
+					Backend: "exec",
+					Exec:    ExecConfig{Command: "espeak", Input: "json"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "exec backend without command",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{Backend: "exec"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "exec backend with invalid input",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{
+					Backend: "exec",
+					Exec:    ExecConfig{Command: "espeak", Input: "xml"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "webhook backend",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{
+					Backend: "webhook",
+					Webhook: WebhookConfig{URL: "https://ntfy.sh/calwatch", Format: "text"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "webhook backend with relative url",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{
+					Backend: "webhook",
+					Webhook: WebhookConfig{URL: "ntfy.sh/calwatch"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "webhook backend with invalid format",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{
+					Backend: "webhook",
+					Webhook: WebhookConfig{URL: "https://ntfy.sh/calwatch", Format: "xml"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "email backend via smtp",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{
+					Backend: "email",
+					Email: EmailConfig{
+						From: "Calwatch <calwatch@example.com>",
+						To:   []string{"jane@example.com"},
+						SMTP: SMTPConfig{Host: "smtp.example.com", Username: "jane", Password: "secret"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "email backend via sendmail",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{
+					Backend: "email",
+					Email:   EmailConfig{From: "calwatch@example.com", To: []string{"jane@example.com"}, Transport: "sendmail"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "email backend without recipients",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{
+					Backend: "email",
+					Email:   EmailConfig{From: "calwatch@example.com", SMTP: SMTPConfig{Host: "smtp.example.com"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "email backend with invalid security",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{
+					Backend: "email",
+					Email: EmailConfig{
+						From: "calwatch@example.com",
+						To:   []string{"jane@example.com"},
+						SMTP: SMTPConfig{Host: "smtp.example.com", Security: "ssl3"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown backend",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{Backend: "carrier-pigeon"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "named backends with routes",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{
+					Backends: map[string]BackendConfig{
+						"desktop": {Type: "dbus"},
+						"phone":   {Type: "webhook", Webhook: WebhookConfig{URL: "https://ntfy.sh/calwatch"}},
+					},
+					Routes: []RouteConfig{
+						{Backends: []string{"desktop"}},
+						{Backends: []string{"phone"}, Calendars: []string{"work"}, Priorities: []string{"high", "critical"}, After: "18:00", Before: "08:00"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "named backend with invalid configuration",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{
+					Backends: map[string]BackendConfig{
+						"speech": {Type: "exec"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "route to unknown backend",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{
+					Backends: map[string]BackendConfig{"desktop": {Type: "dbus"}},
+					Routes:   []RouteConfig{{Backends: []string{"phone"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "route with invalid priority",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{
+					Routes: []RouteConfig{{Backends: []string{"notify-send"}, Priorities: []string{"urgent"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "route with invalid minimum offset",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{
+					Routes: []RouteConfig{{Backends: []string{"notify-send"}, MinOffset: &AlertConfig{Value: 1, Unit: "weeks"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "route with invalid time of day",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Notification: NotificationConfig{
+					Routes: []RouteConfig{{Backends: []string{"notify-send"}, After: "6pm"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "change alerts with invalid change",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				ChangeAlerts: ChangeAlertsConfig{Enable: true, Changes: []string{"moved", "renamed"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "change alerts with invalid window",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				ChangeAlerts: ChangeAlertsConfig{Enable: true, Within: AlertConfig{Value: 1, Unit: "weeks"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "digest with invalid time",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Digest: DigestConfig{Enable: true, Time: "8:30am"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "digest with invalid day",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Digest: DigestConfig{Enable: true, Days: []string{"mon", "workdays"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "quiet hours without end",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				QuietHours: QuietHoursConfig{Enable: true, Periods: []QuietPeriodConfig{{From: "22:00"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "quiet hours with invalid action",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				QuietHours: QuietHoursConfig{Enable: true, Action: "snooze"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid pause policy",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Pause: PauseConfig{Policy: "snooze"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "focus without calendars or categories",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir},
+				},
+				Focus: FocusConfig{Enable: true},
+			},
+			wantErr: true,
+		},
+		{
+			name: "directory quiet hours with invalid day",
+			config: Config{
+				Directories: []DirectoryConfig{
+					{Directory: tempDir, QuietHours: &QuietHoursConfig{Enable: true, Periods: []QuietPeriodConfig{{Days: []string{"weekend"}}}}},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid alert unit",
 			config: Config{
@@ -204,6 +600,59 @@ func TestConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestChangeAlertsConfig_Validate(t *testing.T) {
+	changes := ChangeAlertsConfig{Enable: true}
+	if err := changes.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	if len(changes.Changes) != 4 || !changes.Alerts("new") || !changes.Alerts("cancelled") {
+		t.Errorf("Expected all changes to be alerted by default, got %v", changes.Changes)
+	}
+	if within, _ := changes.Within.Duration(); within != 48*time.Hour {
+		t.Errorf("Expected default window of 2 days, got %v", within)
+	}
+	if changes.MaxChanges != 10 {
+		t.Errorf("Expected default max changes 10, got %d", changes.MaxChanges)
+	}
+
+	changes = ChangeAlertsConfig{Enable: true, Changes: []string{"moved"}}
+	if err := changes.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if changes.Alerts("new") || !changes.Alerts("moved") {
+		t.Errorf("Expected only moved events to be alerted, got %v", changes.Changes)
+	}
+}
+
+func TestDigestConfig(t *testing.T) {
+	digest := DigestConfig{Enable: true, Days: []string{"mon", "Tuesday", "FRI"}}
+	if err := digest.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("Europe/Berlin zone not available")
+	}
+	// Local time of day, also on the day the clocks change
+	midnight := time.Date(2024, 3, 31, 0, 0, 0, 0, berlin)
+	if due := digest.At(midnight); !due.Equal(time.Date(2024, 3, 31, 8, 0, 0, 0, berlin)) {
+		t.Errorf("Expected default time 08:00, got %v", due)
+	}
+
+	for weekday, expected := range map[time.Weekday]bool{
+		time.Monday: true, time.Tuesday: true, time.Wednesday: false, time.Friday: true, time.Sunday: false,
+	} {
+		if digest.OnDay(weekday) != expected {
+			t.Errorf("OnDay(%v) = %v, expected %v", weekday, !expected, expected)
+		}
+	}
+	if !(DigestConfig{}).OnDay(time.Sunday) {
+		t.Error("Expected the digest on every day without days configured")
+	}
+}
+
 func TestDefaultConfig(t *testing.T) {
 	config := DefaultConfig()
 	
@@ -218,4 +667,38 @@ func TestDefaultConfig(t *testing.T) {
 	if config.Logging.Level != "info" {
 		t.Errorf("DefaultConfig() logging level = %v, want info", config.Logging.Level)
 	}
-}
\ No newline at end of file
+}
+func TestRouteConfig_InTimeWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		after    string
+		before   string
+		time     string
+		expected bool
+	}{
+		{"no window", "", "", "12:00", true},
+		{"after only, inside", "18:00", "", "18:00", true},
+		{"after only, outside", "18:00", "", "17:59", false},
+		{"before only, inside", "", "08:00", "07:59", true},
+		{"before only, outside", "", "08:00", "08:00", false},
+		{"daytime window, inside", "09:00", "17:00", "12:30", true},
+		{"daytime window, outside", "09:00", "17:00", "17:30", false},
+		{"overnight window, evening", "18:00", "08:00", "23:15", true},
+		{"overnight window, morning", "18:00", "08:00", "06:00", true},
+		{"overnight window, outside", "18:00", "08:00", "12:00", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, err := time.Parse("15:04", tt.time)
+			if err != nil {
+				t.Fatalf("invalid test time %q: %v", tt.time, err)
+			}
+
+			route := RouteConfig{After: tt.after, Before: tt.before}
+			if got := route.InTimeWindow(at); got != tt.expected {
+				t.Errorf("InTimeWindow(%s) = %v, want %v", tt.time, got, tt.expected)
+			}
+		})
+	}
+}
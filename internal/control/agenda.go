@@ -0,0 +1,83 @@
+package control
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/localday"
+	"calwatch/internal/notifications"
+	"calwatch/internal/storage"
+)
+
+// Number of occurrences listed by the upcoming command by default
+const DefaultUpcomingLimit = 10
+
+// BuildAgenda answers agenda and upcoming requests with the occurrences of
+// events in the requested calendars, or all calendars if none are requested
+func BuildAgenda(events []storage.Event, request Request, now time.Time) ([]notifications.AgendaEntry, error) {
+	if len(request.Calendars) > 0 {
+		var selected []storage.Event
+		for _, event := range events {
+			if storage.InCalendars(event, request.Calendars) {
+				selected = append(selected, event)
+			}
+		}
+		events = selected
+	}
+
+	switch request.Command {
+	case CommandUpcoming:
+		limit := request.Limit
+		if limit <= 0 {
+			limit = DefaultUpcomingLimit
+		}
+		return notifications.NewAgendaEntries(alerts.Upcoming(events, now, limit), now), nil
+
+	case CommandAgenda:
+		today := localday.Of(now)
+		from, err := ParseDay(request.From, today)
+		if err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+		to := from
+		if request.To != "" {
+			if to, err = ParseDay(request.To, today); err != nil {
+				return nil, fmt.Errorf("to: %w", err)
+			}
+		}
+		if to.Before(from) {
+			return nil, fmt.Errorf("to must not be before from")
+		}
+		return notifications.NewAgendaEntries(alerts.Agenda(events, from.Start(), to.End()), now), nil
+	}
+
+	return nil, fmt.Errorf("unknown agenda command %q", request.Command)
+}
+
+// ParseDay parses a day relative to today: "today", "tomorrow", "yesterday",
+// a number of days like "+7" or a date like "2024-01-15". Empty values are today.
+func ParseDay(value string, today localday.Day) (localday.Day, error) {
+	switch value = strings.ToLower(strings.TrimSpace(value)); value {
+	case "", "today":
+		return today, nil
+	case "tomorrow":
+		return today.Next(), nil
+	case "yesterday":
+		return today.Prev(), nil
+	}
+
+	if value[0] == '+' || value[0] == '-' {
+		if days, err := strconv.Atoi(value); err == nil {
+			return today.AddDays(days), nil
+		}
+	}
+
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return localday.Day{}, fmt.Errorf("expected a date like 2024-01-15, today, tomorrow or +7, got %q", value)
+	}
+	return localday.Date(date.Year(), date.Month(), date.Day()), nil
+}
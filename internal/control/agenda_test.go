@@ -0,0 +1,101 @@
+package control
+
+import (
+	"testing"
+	"time"
+
+	"calwatch/internal/localday"
+	"calwatch/internal/storage"
+)
+
+func TestParseDay(t *testing.T) {
+	today := localday.Date(2024, 1, 15)
+
+	tests := []struct {
+		value    string
+		expected localday.Day
+		wantErr  bool
+	}{
+		{"", today, false},
+		{"today", today, false},
+		{"Tomorrow", localday.Date(2024, 1, 16), false},
+		{"yesterday", localday.Date(2024, 1, 14), false},
+		{"+7", localday.Date(2024, 1, 22), false},
+		{"-15", localday.Date(2023, 12, 31), false},
+		{"2024-02-29", localday.Date(2024, 2, 29), false},
+		{"next week", localday.Day{}, true},
+		{"+", localday.Day{}, true},
+		{"2024-02-30", localday.Day{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			day, err := ParseDay(tt.value, today)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !day.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, day)
+			}
+		})
+	}
+}
+
+func TestBuildAgenda(t *testing.T) {
+	monday := localday.Date(2024, 1, 15)
+	at := func(day localday.Day, hour int) time.Time {
+		return day.Start().Add(time.Duration(hour) * time.Hour)
+	}
+	now := at(monday, 8)
+
+	work := storage.NewCalendar("/calendars/work", "", []storage.Alert{{Offset: 15 * time.Minute}})
+	personal := storage.NewCalendar("/calendars/personal", "", []storage.Alert{})
+	events := []storage.Event{
+		storage.NewCalendarEvent("standup", "Standup", "", "", at(monday, 9), at(monday, 10), localday.Location(), nil, work, []storage.Alert{}),
+		storage.NewCalendarEvent("review", "Review", "", "", at(monday.Next(), 14), at(monday.Next(), 15), localday.Location(), nil, work, []storage.Alert{}),
+		storage.NewCalendarEvent("dentist", "Dentist", "", "", at(monday, 17), at(monday, 18), localday.Location(), nil, personal, []storage.Alert{}),
+	}
+
+	tests := []struct {
+		name     string
+		request  Request
+		expected []string
+		wantErr  bool
+	}{
+		{"today", Request{Command: CommandAgenda}, []string{"Standup", "Dentist"}, false},
+		{"days", Request{Command: CommandAgenda, From: "today", To: "tomorrow"}, []string{"Standup", "Dentist", "Review"}, false},
+		{"calendar", Request{Command: CommandAgenda, To: "+1", Calendars: []string{"work"}}, []string{"Standup", "Review"}, false},
+		{"upcoming", Request{Command: CommandUpcoming, Limit: 2}, []string{"Standup", "Dentist"}, false},
+		{"upcoming in a calendar", Request{Command: CommandUpcoming, Calendars: []string{"/calendars/personal"}}, []string{"Dentist"}, false},
+		{"to before from", Request{Command: CommandAgenda, From: "tomorrow", To: "today"}, nil, true},
+		{"invalid day", Request{Command: CommandAgenda, From: "someday"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agenda, err := BuildAgenda(events, tt.request, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildAgenda() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var summaries []string
+			for _, entry := range agenda {
+				summaries = append(summaries, entry.Summary)
+			}
+			if len(summaries) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, summaries)
+			}
+			for i := range summaries {
+				if summaries[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, summaries)
+				}
+			}
+		})
+	}
+
+	// Entries list the alerts of the occurrence
+	agenda, _ := BuildAgenda(events, Request{Command: CommandUpcoming, Limit: 1}, now)
+	if len(agenda) != 1 || len(agenda[0].Alerts) != 1 || !agenda[0].Alerts[0].Time.Equal(at(monday, 9).Add(-15*time.Minute)) {
+		t.Errorf("Expected the standup alert 15 minutes before, got %+v", agenda)
+	}
+}
@@ -0,0 +1,228 @@
+package control
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adrg/xdg"
+
+	"calwatch/internal/notifications"
+	"calwatch/internal/storage"
+)
+
+// Commands understood by the daemon
+const (
+	CommandStatus   = "status"
+	CommandPause    = "pause"    // Pauses all calendars or mutes one
+	CommandResume   = "resume"   // Resumes all calendars or unmutes one
+	CommandAgenda   = "agenda"   // Lists occurrences between two days
+	CommandUpcoming = "upcoming" // Lists the next occurrences
+)
+
+// Connections are closed if a request takes longer than this
+const connectionTimeout = 5 * time.Second
+
+// ErrNotRunning is returned by Send if no daemon listens on the socket
+var ErrNotRunning = errors.New("calwatch daemon is not running")
+
+// Request is a command sent to the running daemon
+type Request struct {
+	Command  string `json:"command"`
+	Calendar string `json:"calendar,omitempty"` // storage.AllCalendars for all calendars
+	Until    string `json:"until,omitempty"`    // End of a pause for ParseUntil, empty until resumed
+
+	// Agenda listings
+	From      string   `json:"from,omitempty"` // First day for ParseDay, default today
+	To        string   `json:"to,omitempty"`   // Last day for ParseDay, default the first day
+	Calendars []string `json:"calendars,omitempty"`
+	Limit     int      `json:"limit,omitempty"` // Number of upcoming occurrences
+}
+
+// Response is the daemon's answer to a request
+type Response struct {
+	Error  string                      `json:"error,omitempty"`
+	Status *Status                     `json:"status,omitempty"`
+	Pauses []storage.Pause             `json:"pauses,omitempty"` // Pauses that were started or resumed
+	Agenda []notifications.AgendaEntry `json:"agenda,omitempty"`
+}
+
+// Status describes the running daemon
+type Status struct {
+	Directories  []string        `json:"directories"`
+	Events       int             `json:"events"`
+	TodaysEvents int             `json:"todays_events"`
+	Upcoming     int             `json:"upcoming"` // Events in the next 24 hours
+	Queued       int             `json:"queued"`   // Alerts waiting for delivery
+	Held         int             `json:"held"`     // Alerts held back by pauses
+	Pauses       []storage.Pause `json:"pauses,omitempty"`
+}
+
+// Handler answers a request
+type Handler func(request Request) Response
+
+// Server answers requests of the calwatch command on a Unix socket
+type Server struct {
+	path     string
+	handler  Handler
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+// SocketPath returns the path of the daemon's socket in the XDG runtime directory
+func SocketPath() (string, error) {
+	path, err := xdg.RuntimeFile("calwatch/calwatch.sock")
+	if err != nil {
+		return "", fmt.Errorf("failed to get XDG runtime socket path: %w", err)
+	}
+	return path, nil
+}
+
+// NewServer creates a server listening on the socket at path
+func NewServer(path string, handler Handler) *Server {
+	return &Server{path: path, handler: handler}
+}
+
+// Start listens on the socket, replacing a socket left over by a daemon that
+// did not shut down cleanly
+func (s *Server) Start() error {
+	if _, err := Send(s.path, Request{Command: CommandStatus}); err == nil {
+		return fmt.Errorf("another daemon is listening on %s", s.path)
+	}
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.path, err)
+	}
+	s.listener = listener
+
+	s.wg.Add(1)
+	go s.serve()
+	return nil
+}
+
+// Stop stops listening and waits for open connections
+func (s *Server) Stop() error {
+	if s.listener == nil {
+		return nil
+	}
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+// serve accepts connections until the listener is closed
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				fmt.Fprintf(os.Stderr, "Error accepting control connection: %v\n", err)
+			}
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// handle answers the single request sent on a connection
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(connectionTimeout))
+
+	var request Request
+	if err := json.NewDecoder(conn).Decode(&request); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading control request: %v\n", err)
+		return
+	}
+
+	if err := json.NewEncoder(conn).Encode(s.handler(request)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error answering %s request: %v\n", request.Command, err)
+	}
+}
+
+// Send sends a request to the daemon listening on the socket at path and
+// returns its response. Errors reported by the daemon are returned as error.
+func Send(path string, request Request) (Response, error) {
+	conn, err := net.DialTimeout("unix", path, connectionTimeout)
+	if err != nil {
+		return Response{}, ErrNotRunning
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(connectionTimeout))
+
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return Response{}, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	var response Response
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return Response{}, fmt.Errorf("failed to read response: %w", err)
+	}
+	if response.Error != "" {
+		return response, errors.New(response.Error)
+	}
+	return response, nil
+}
+
+// ParseUntil parses the end of a pause, either a duration from now like "1h"
+// or "90m" or a time of day in now's zone like "17:00", which is tomorrow if
+// it already passed today. Empty values pause until resumed.
+func ParseUntil(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		if duration <= 0 {
+			return time.Time{}, fmt.Errorf("duration must be positive, got %s", value)
+		}
+		return now.Add(duration), nil
+	}
+
+	clock, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a duration like 1h or a time like 17:00, got %q", value)
+	}
+	until := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !until.After(now) {
+		until = time.Date(now.Year(), now.Month(), now.Day()+1, clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	}
+	return until, nil
+}
+
+// ParsePauseArgs returns the end of a pause from the arguments of the pause
+// and mute commands: nothing to pause until resumed, a duration or time of
+// day, or --until followed by either
+func ParsePauseArgs(args []string) (string, error) {
+	switch {
+	case len(args) == 0:
+		return "", nil
+	case len(args) == 1 && strings.HasPrefix(args[0], "--until="):
+		return strings.TrimPrefix(args[0], "--until="), nil
+	case len(args) == 1 && !strings.HasPrefix(args[0], "-"):
+		return args[0], nil
+	case len(args) == 2 && args[0] == "--until":
+		return args[1], nil
+	default:
+		return "", fmt.Errorf("unexpected arguments %q", strings.Join(args, " "))
+	}
+}
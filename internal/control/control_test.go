@@ -0,0 +1,118 @@
+package control
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"calwatch/internal/storage"
+)
+
+func TestParseUntil(t *testing.T) {
+	now := time.Date(2024, 1, 15, 14, 20, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{"", time.Time{}, false},
+		{"1h", now.Add(time.Hour), false},
+		{"1h30m", now.Add(90 * time.Minute), false},
+		{"17:00", time.Date(2024, 1, 15, 17, 0, 0, 0, time.UTC), false},
+		{"09:00", time.Date(2024, 1, 16, 9, 0, 0, 0, time.UTC), false}, // Tomorrow
+		{"14:20", time.Date(2024, 1, 16, 14, 20, 0, 0, time.UTC), false},
+		{"-1h", time.Time{}, true},
+		{"5pm", time.Time{}, true},
+		{"25:00", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			until, err := ParseUntil(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseUntil() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !until.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, until)
+			}
+		})
+	}
+}
+
+func TestParsePauseArgs(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+		wantErr  bool
+	}{
+		{nil, "", false},
+		{[]string{"1h"}, "1h", false},
+		{[]string{"--until", "17:00"}, "17:00", false},
+		{[]string{"--until=17:00"}, "17:00", false},
+		{[]string{"--until"}, "", true},
+		{[]string{"1h", "2h"}, "", true},
+	}
+
+	for _, tt := range tests {
+		until, err := ParsePauseArgs(tt.args)
+		if (err != nil) != tt.wantErr || until != tt.expected {
+			t.Errorf("ParsePauseArgs(%q) = %q, %v, expected %q (error %v)", tt.args, until, err, tt.expected, tt.wantErr)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calwatch.sock")
+	if _, err := Send(path, Request{Command: CommandStatus}); err != ErrNotRunning {
+		t.Fatalf("Expected ErrNotRunning without a daemon, got %v", err)
+	}
+
+	until := time.Date(2024, 1, 15, 17, 0, 0, 0, time.UTC)
+	server := NewServer(path, func(request Request) Response {
+		switch request.Command {
+		case CommandStatus:
+			return Response{Status: &Status{Events: 3}}
+		case CommandPause:
+			return Response{Pauses: []storage.Pause{{Calendar: request.Calendar, Until: until}}}
+		}
+		return Response{Error: "unknown command"}
+	})
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	response, err := Send(path, Request{Command: CommandStatus})
+	if err != nil || response.Status == nil || response.Status.Events != 3 {
+		t.Errorf("Expected the status, got %+v, %v", response, err)
+	}
+	response, err = Send(path, Request{Command: CommandPause, Calendar: "work", Until: "17:00"})
+	if err != nil || len(response.Pauses) != 1 || response.Pauses[0].Calendar != "work" || !response.Pauses[0].Until.Equal(until) {
+		t.Errorf("Expected the pause, got %+v, %v", response, err)
+	}
+	if _, err := Send(path, Request{Command: "reload"}); err == nil || err.Error() != "unknown command" {
+		t.Errorf("Expected the daemon's error, got %v", err)
+	}
+
+	// A second daemon does not take over the socket
+	if err := NewServer(path, nil).Start(); err == nil {
+		t.Error("Expected an error for a socket in use")
+	}
+
+	if err := server.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if _, err := Send(path, Request{Command: CommandStatus}); err != ErrNotRunning {
+		t.Errorf("Expected ErrNotRunning after stopping, got %v", err)
+	}
+
+	// The socket can be listened on again after stopping
+	restarted := NewServer(path, func(request Request) Response { return Response{Status: &Status{}} })
+	if err := restarted.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer restarted.Stop()
+	if _, err := Send(path, Request{Command: CommandStatus}); err != nil {
+		t.Errorf("Send() error = %v", err)
+	}
+}
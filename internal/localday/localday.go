@@ -0,0 +1,184 @@
+package localday
+
+import (
+	"sync"
+	"time"
+)
+
+// zone is the configured local time zone used to decide day boundaries
+var (
+	zone      = time.Local
+	zoneMutex sync.RWMutex
+)
+
+// SetLocation sets the time zone that defines local day boundaries
+func SetLocation(loc *time.Location) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	zoneMutex.Lock()
+	defer zoneMutex.Unlock()
+
+	zone = loc
+}
+
+// Location returns the time zone that defines local day boundaries
+func Location() *time.Location {
+	zoneMutex.RLock()
+	defer zoneMutex.RUnlock()
+
+	return zone
+}
+
+// Day represents a calendar day in a specific time zone.
+// Unlike time.Truncate(24*time.Hour), which truncates to UTC midnight, a Day
+// starts at local midnight and may last 23 or 25 hours around DST changes.
+type Day struct {
+	year  int
+	month time.Month
+	day   int
+	loc   *time.Location
+}
+
+// Of returns the local day containing t in the configured zone
+func Of(t time.Time) Day {
+	return In(t, Location())
+}
+
+// In returns the day containing t in the given zone
+func In(t time.Time, loc *time.Location) Day {
+	if loc == nil {
+		loc = Location()
+	}
+	local := t.In(loc)
+	return Day{year: local.Year(), month: local.Month(), day: local.Day(), loc: loc}
+}
+
+// Date returns the day for the given calendar date in the configured zone
+func Date(year int, month time.Month, day int) Day {
+	// Normalize overflowing values (e.g. January 32nd) the same way time.Date does
+	normalized := time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+	return Day{year: normalized.Year(), month: normalized.Month(), day: normalized.Day(), loc: Location()}
+}
+
+// Today returns the current local day
+func Today() Day {
+	return Of(time.Now())
+}
+
+// Start returns the first instant of the day (local midnight)
+func (d Day) Start() time.Time {
+	return time.Date(d.year, d.month, d.day, 0, 0, 0, 0, d.location())
+}
+
+// End returns the first instant of the following day (exclusive bound)
+func (d Day) End() time.Time {
+	return d.Next().Start()
+}
+
+// At returns the local time at a time since midnight on the day, keeping
+// the wall clock time on DST changes
+func (d Day) At(at time.Duration) time.Time {
+	return time.Date(d.year, d.month, d.day, int(at/time.Hour), int(at%time.Hour/time.Minute), 0, 0, d.location())
+}
+
+// Length returns the real duration of the day (23, 24 or 25 hours)
+func (d Day) Length() time.Duration {
+	return d.End().Sub(d.Start())
+}
+
+// Contains reports whether t falls within [Start, End)
+func (d Day) Contains(t time.Time) bool {
+	return !t.Before(d.Start()) && t.Before(d.End())
+}
+
+// AddDays returns the day n calendar days later (or earlier for negative n)
+func (d Day) AddDays(n int) Day {
+	normalized := time.Date(d.year, d.month, d.day+n, 12, 0, 0, 0, time.UTC)
+	return Day{year: normalized.Year(), month: normalized.Month(), day: normalized.Day(), loc: d.loc}
+}
+
+// Next returns the following day
+func (d Day) Next() Day {
+	return d.AddDays(1)
+}
+
+// Prev returns the preceding day
+func (d Day) Prev() Day {
+	return d.AddDays(-1)
+}
+
+// Equal reports whether both days denote the same calendar date
+func (d Day) Equal(other Day) bool {
+	return d.year == other.year && d.month == other.month && d.day == other.day
+}
+
+// Before reports whether d is an earlier calendar date than other
+func (d Day) Before(other Day) bool {
+	return d.ordinal() < other.ordinal()
+}
+
+// After reports whether d is a later calendar date than other
+func (d Day) After(other Day) bool {
+	return d.ordinal() > other.ordinal()
+}
+
+// Year returns the year of the day
+func (d Day) Year() int {
+	return d.year
+}
+
+// Month returns the month of the day
+func (d Day) Month() time.Month {
+	return d.month
+}
+
+// DayOfMonth returns the day of the month
+func (d Day) DayOfMonth() int {
+	return d.day
+}
+
+// Weekday returns the day of the week
+func (d Day) Weekday() time.Weekday {
+	return time.Date(d.year, d.month, d.day, 12, 0, 0, 0, time.UTC).Weekday()
+}
+
+// Key formats the day as YYYY-MM-DD for use as a map key
+func (d Day) Key() string {
+	return time.Date(d.year, d.month, d.day, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+}
+
+// String returns the YYYY-MM-DD representation of the day
+func (d Day) String() string {
+	return d.Key()
+}
+
+// DaysBetween returns the number of calendar days from a to b, independent
+// of DST transitions in between
+func DaysBetween(a, b Day) int {
+	return int((b.ordinal() - a.ordinal()) / int64(24*time.Hour))
+}
+
+// SameDay reports whether a and b fall on the same local day
+func SameDay(a, b time.Time) bool {
+	return Of(a).Equal(Of(b))
+}
+
+// StartOf returns local midnight of the day containing t
+func StartOf(t time.Time) time.Time {
+	return Of(t).Start()
+}
+
+// ordinal returns a monotonically increasing value per calendar date
+func (d Day) ordinal() int64 {
+	return time.Date(d.year, d.month, d.day, 0, 0, 0, 0, time.UTC).UnixNano()
+}
+
+// location returns the day's zone, falling back to the configured zone
+func (d Day) location() *time.Location {
+	if d.loc != nil {
+		return d.loc
+	}
+	return Location()
+}
@@ -0,0 +1,140 @@
+package localday
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func withLocation(t *testing.T, loc *time.Location) {
+	t.Helper()
+	previous := Location()
+	SetLocation(loc)
+	t.Cleanup(func() { SetLocation(previous) })
+}
+
+func TestOf_UsesLocalMidnight(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	withLocation(t, berlin)
+
+	// 23:30 UTC on Oct 15 is already 01:30 on Oct 16 in Berlin
+	instant := time.Date(2023, 10, 15, 23, 30, 0, 0, time.UTC)
+	day := Of(instant)
+
+	if day.Key() != "2023-10-16" {
+		t.Errorf("Expected day 2023-10-16, got %s", day.Key())
+	}
+
+	expectedStart := time.Date(2023, 10, 16, 0, 0, 0, 0, berlin)
+	if !day.Start().Equal(expectedStart) {
+		t.Errorf("Expected start %v, got %v", expectedStart, day.Start())
+	}
+
+	if !day.Contains(instant) {
+		t.Error("Day should contain the instant it was created from")
+	}
+}
+
+func TestOf_WestOfUTC(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	withLocation(t, newYork)
+
+	// 02:00 UTC on Oct 16 is still 22:00 on Oct 15 in New York
+	instant := time.Date(2023, 10, 16, 2, 0, 0, 0, time.UTC)
+	if key := Of(instant).Key(); key != "2023-10-15" {
+		t.Errorf("Expected day 2023-10-15, got %s", key)
+	}
+}
+
+func TestDay_LengthAcrossDST(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	withLocation(t, berlin)
+
+	tests := []struct {
+		name     string
+		day      Day
+		expected time.Duration
+	}{
+		{"spring forward", Date(2024, time.March, 31), 23 * time.Hour},
+		{"regular day", Date(2024, time.June, 1), 24 * time.Hour},
+		{"fall back", Date(2024, time.October, 27), 25 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.day.Length(); got != tt.expected {
+				t.Errorf("Length() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDay_AtAcrossDST(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	withLocation(t, berlin)
+
+	tests := []struct {
+		name     string
+		day      Day
+		at       time.Duration
+		expected time.Time
+	}{
+		{"spring forward", Date(2024, time.March, 31), 9 * time.Hour, time.Date(2024, 3, 31, 9, 0, 0, 0, berlin)},
+		{"regular day", Date(2024, time.June, 1), 13*time.Hour + 30*time.Minute, time.Date(2024, 6, 1, 13, 30, 0, 0, berlin)},
+		{"fall back", Date(2024, time.October, 27), 9 * time.Hour, time.Date(2024, 10, 27, 9, 0, 0, 0, berlin)},
+		{"midnight", Date(2024, time.October, 27), 0, time.Date(2024, 10, 27, 0, 0, 0, 0, berlin)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.day.At(tt.at); !got.Equal(tt.expected) {
+				t.Errorf("At(%v) = %v, want %v", tt.at, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDaysBetween(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	withLocation(t, berlin)
+
+	start := Date(2024, time.March, 30)
+	end := Date(2024, time.April, 2)
+
+	if got := DaysBetween(start, end); got != 3 {
+		t.Errorf("DaysBetween() = %d, want 3", got)
+	}
+
+	if got := DaysBetween(end, start); got != -3 {
+		t.Errorf("DaysBetween() reversed = %d, want -3", got)
+	}
+}
+
+func TestDay_AddDaysAndCompare(t *testing.T) {
+	day := Date(2023, time.December, 31)
+	next := day.Next()
+
+	if next.Key() != "2024-01-01" {
+		t.Errorf("Expected next day 2024-01-01, got %s", next.Key())
+	}
+
+	if !day.Before(next) || !next.After(day) {
+		t.Error("Expected day to be before the following day")
+	}
+
+	if !next.Prev().Equal(day) {
+		t.Error("Prev() of next day should equal the original day")
+	}
+
+	if next.Weekday() != time.Monday {
+		t.Errorf("Expected 2024-01-01 to be a Monday, got %v", next.Weekday())
+	}
+}
@@ -0,0 +1,144 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/localday"
+	"calwatch/internal/storage"
+)
+
+// Output formats of agendas, any other format is a template
+const (
+	AgendaFormatText = "text"
+	AgendaFormatJSON = "json"
+)
+
+// AgendaEntry is an event occurrence listed by the agenda and upcoming
+// commands, with the template variables of an alert for the occurrence
+type AgendaEntry struct {
+	TemplateData
+	Alerts []AgendaAlert `json:"alerts"`
+}
+
+// AgendaAlert is an alert of a listed occurrence
+type AgendaAlert struct {
+	Time      time.Time `json:"time"`
+	Kind      string    `json:"kind"` // "before_start", "at_start", ...
+	Important bool      `json:"important"`
+}
+
+// NewAgendaEntries creates the entries listing agenda items with their alerts
+func NewAgendaEntries(agenda []alerts.AgendaItem, now time.Time) []AgendaEntry {
+	entries := make([]AgendaEntry, 0, len(agenda))
+	for _, item := range agenda {
+		entry := AgendaEntry{
+			TemplateData: newTemplateData(alerts.AlertRequest{Event: item.Event, EventTime: item.Start}, now),
+			Alerts:       []AgendaAlert{},
+		}
+		// Not about a single alert
+		entry.AlertKind, entry.AlertOffset = "", ""
+
+		if calendarEvent := storage.BaseCalendarEvent(item.Event); calendarEvent != nil {
+			for _, alert := range calendarEvent.AlertsFor(item.Start) {
+				entry.Alerts = append(entry.Alerts, AgendaAlert{
+					Time:      alert.AlertTime.In(localday.Location()),
+					Kind:      alert.Kind.String(),
+					Important: alert.Important,
+				})
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// WriteAgenda writes agenda entries as text grouped by day, as JSON or with
+// a template executed per entry, e.g. "{{.StartTime}} {{.Summary}}"
+func WriteAgenda(w io.Writer, entries []AgendaEntry, format string) error {
+	switch format {
+	case AgendaFormatText, "":
+		return writeAgendaText(w, entries)
+
+	case AgendaFormatJSON:
+		if entries == nil {
+			entries = []AgendaEntry{} // An empty list rather than null
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(entries); err != nil {
+			return fmt.Errorf("failed to encode agenda: %w", err)
+		}
+		return nil
+	}
+
+	if !strings.Contains(format, "{{") {
+		return fmt.Errorf("unknown format %q, expected %s, %s or a template", format, AgendaFormatText, AgendaFormatJSON)
+	}
+	tmpl, err := template.New("agenda").Funcs(templateFuncs).Parse(format)
+	if err != nil {
+		return fmt.Errorf("failed to parse format template: %w", err)
+	}
+	for _, entry := range entries {
+		if err := tmpl.Execute(w, entry); err != nil {
+			return fmt.Errorf("failed to execute format template: %w", err)
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeAgendaText writes agenda entries grouped by day, one line per entry
+func writeAgendaText(w io.Writer, entries []AgendaEntry) error {
+	var builder strings.Builder
+	if len(entries) == 0 {
+		builder.WriteString("No events\n")
+	}
+
+	date := ""
+	for _, entry := range entries {
+		// Ongoing events are listed under the day they started
+		if entry.Date != date {
+			if date != "" {
+				builder.WriteString("\n")
+			}
+			date = entry.Date
+			fmt.Fprintf(&builder, "%s, %s\n", entry.Weekday, entry.Date)
+		}
+
+		when := "All day    "
+		if !entry.AllDay {
+			when = entry.StartTime + "-" + entry.EndTime
+		}
+		fmt.Fprintf(&builder, "  %s  %s", when, entry.Summary)
+		if entry.Location != "" {
+			fmt.Fprintf(&builder, " @ %s", entry.Location)
+		}
+		if entry.Calendar != "" {
+			fmt.Fprintf(&builder, " [%s]", entry.Calendar)
+		}
+
+		var times []string
+		for _, alert := range entry.Alerts {
+			layout := "15:04"
+			if alert.Time.Format("2006-01-02") != entry.Date {
+				layout = "Jan 2 15:04"
+			}
+			times = append(times, alert.Time.Format(layout))
+		}
+		if len(times) > 0 {
+			fmt.Fprintf(&builder, " (alerts %s)", strings.Join(times, ", "))
+		}
+		builder.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
@@ -0,0 +1,71 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/localday"
+	"calwatch/internal/storage"
+)
+
+func TestWriteAgenda(t *testing.T) {
+	monday := localday.Date(2024, 1, 15)
+	at := func(day localday.Day, hour int) time.Time {
+		return day.Start().Add(time.Duration(hour) * time.Hour)
+	}
+
+	work := storage.NewCalendar("/calendars/work", "", []storage.Alert{{Offset: 15 * time.Minute}})
+	standup := storage.NewCalendarEvent("standup", "Standup", "", "Room 1", at(monday, 9), at(monday, 10), localday.Location(), nil, work, []storage.Alert{})
+	offsite := storage.NewCalendarEvent("offsite", "Offsite", "", "", monday.Next().Start(), monday.Next().End(), localday.Location(), nil, work, []storage.Alert{})
+	offsite.AllDay = true
+	entries := NewAgendaEntries([]alerts.AgendaItem{
+		{Event: standup, Start: standup.StartTime},
+		{Event: offsite, Start: offsite.StartTime},
+	}, at(monday, 8))
+
+	tests := []struct {
+		name     string
+		entries  []AgendaEntry
+		format   string
+		expected string
+		wantErr  bool
+	}{
+		{"text", entries, AgendaFormatText,
+			"Monday, 2024-01-15\n  09:00-10:00  Standup @ Room 1 [work] (alerts 08:45)\n\n" +
+				"Tuesday, 2024-01-16\n  All day      Offsite [work] (alerts Jan 15 23:45)\n", false},
+		{"no events", nil, AgendaFormatText, "No events\n", false},
+		{"template", entries, "{{.Date}} {{.StartTime}} {{.Summary}}", "2024-01-15 09:00 Standup\n2024-01-16 00:00 Offsite\n", false},
+		{"no events as JSON", nil, AgendaFormatJSON, "[]\n", false},
+		{"unknown format", entries, "xml", "", true},
+		{"invalid template", entries, "{{.Summary", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			err := WriteAgenda(&output, tt.entries, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteAgenda() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && output.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, output.String())
+			}
+		})
+	}
+
+	var output bytes.Buffer
+	if err := WriteAgenda(&output, entries, AgendaFormatJSON); err != nil {
+		t.Fatalf("WriteAgenda() error = %v", err)
+	}
+	var decoded []AgendaEntry
+	if err := json.Unmarshal(output.Bytes(), &decoded); err != nil {
+		t.Fatalf("Expected JSON, got %v: %s", err, output.String())
+	}
+	if len(decoded) != 2 || decoded[0].Summary != "Standup" || len(decoded[0].Alerts) != 1 || !strings.Contains(output.String(), `"kind": "before_start"`) {
+		t.Errorf("Expected the entries with their alerts, got %s", output.String())
+	}
+}
@@ -0,0 +1,49 @@
+package notifications
+
+import (
+	"html"
+	"regexp"
+)
+
+// Capabilities are the optional features a desktop notification server
+// advertises. Templates see them as .Caps, e.g. {{if .Caps.Markup}}<b>{{end}}.
+// They are all false for backends that are not desktop notification servers.
+type Capabilities struct {
+	Markup      bool // Body may contain <b>, <i>, <u>, <a href> and <img>
+	Hyperlinks  bool // Links in the body are clickable
+	Actions     bool // Notifications may have action buttons
+	Persistence bool // Notifications are kept after expiring, e.g. in a history
+	IconStatic  bool // Notification icons are shown
+	Sound       bool // Notifications may play sounds
+}
+
+// parseCapabilities interprets the capabilities returned by GetCapabilities
+func parseCapabilities(capabilities []string) Capabilities {
+	var caps Capabilities
+	for _, capability := range capabilities {
+		switch capability {
+		case "body-markup":
+			caps.Markup = true
+		case "body-hyperlinks":
+			caps.Hyperlinks = true
+		case "actions":
+			caps.Actions = true
+		case "persistence":
+			caps.Persistence = true
+		case "icon-static":
+			caps.IconStatic = true
+		case "sound":
+			caps.Sound = true
+		}
+	}
+	return caps
+}
+
+// Markup tags defined by the desktop notification specification
+var markupTagPattern = regexp.MustCompile(`(?i)</?(b|i|u|a|img)(\s[^>]*)?/?>`)
+
+// stripMarkup turns a notification body rendered as markup into plain text
+// for servers without markup support
+func stripMarkup(body string) string {
+	return html.UnescapeString(markupTagPattern.ReplaceAllString(body, ""))
+}
@@ -0,0 +1,172 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"calwatch/internal/config"
+	"calwatch/internal/storage"
+)
+
+func TestParseCapabilities(t *testing.T) {
+	caps := parseCapabilities([]string{"body", "body-markup", "actions", "persistence", "sound", "x-vendor-feature"})
+	expected := Capabilities{Markup: true, Actions: true, Persistence: true, Sound: true}
+	if caps != expected {
+		t.Errorf("Expected %+v, got %+v", expected, caps)
+	}
+
+	if caps := parseCapabilities(nil); caps != (Capabilities{}) {
+		t.Errorf("Expected no capabilities, got %+v", caps)
+	}
+}
+
+func TestStripMarkup(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{"<b>Team Meeting</b> at <i>Room 1</i>", "Team Meeting at Room 1"},
+		{`<a href="https://example.com/call">Join</a><img src="icon.png"/>`, "Join"},
+		{"Q&amp;A &lt;draft&gt;", "Q&A <draft>"},
+		{"1 < 2 and <unknown> tags stay", "1 < 2 and <unknown> tags stay"},
+	}
+
+	for _, tt := range tests {
+		if got := stripMarkup(tt.body); got != tt.expected {
+			t.Errorf("stripMarkup(%q) = %q, expected %q", tt.body, got, tt.expected)
+		}
+	}
+}
+
+func TestDBusNotifier_Capabilities(t *testing.T) {
+	desktop := config.DesktopConfig{
+		Image:          "/usr/share/icons/calendar.png",
+		Sound:          "message-new-instant",
+		ImportantSound: "alarm-clock-elapsed",
+		Category:       "x-calwatch.alert",
+	}
+
+	tests := []struct {
+		name          string
+		capabilities  Capabilities
+		important     bool
+		silent        bool
+		expectedBody  string
+		expectedImage string // Empty if no image hint is expected
+		expectedSound string // Empty if no sound hint is expected
+	}{
+		{"markup and sound", Capabilities{Markup: true, IconStatic: true, Sound: true}, false, false, "<b>Team Meeting</b> &amp; more", desktop.Image, "message-new-instant"},
+		{"important sound", Capabilities{Markup: true, IconStatic: true, Sound: true}, true, false, "<b>Team Meeting</b> &amp; more", desktop.Image, "alarm-clock-elapsed"},
+		{"silent", Capabilities{Markup: true, IconStatic: true, Sound: true}, true, true, "<b>Team Meeting</b> &amp; more", desktop.Image, ""},
+		{"minimal server", Capabilities{}, false, false, "Team Meeting & more", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := newDBusNotifier(nil)
+			notifier.renderer.capabilities = tt.capabilities
+			notifier.renderer.templates.templates["markup.tpl"] = template.Must(template.New("markup.tpl").Parse(
+				`{{if .Caps.Markup}}<b>{{.Summary}}</b> &amp; more{{else}}{{.Summary}} & more{{end}}`))
+			notifierConfig := defaultDesktopConfig("dbus")
+			notifierConfig.Desktop = desktop
+			notifier.SetConfig(notifierConfig)
+
+			request := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+			request.Template = "markup.tpl"
+			request.Important = tt.important
+			request.Silent = tt.silent
+			rendered, err := notifier.renderer.render(NotificationRequest{AlertRequest: request})
+			if err != nil {
+				t.Fatalf("render() error = %v", err)
+			}
+
+			dbusNotification := notifier.dbusNotification(rendered)
+			if dbusNotification.Body != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, dbusNotification.Body)
+			}
+			image, hasImage := dbusNotification.Hints["image-path"]
+			if hasImage != (tt.expectedImage != "") || (hasImage && image.Value() != tt.expectedImage) {
+				t.Errorf("Expected image-path hint %q, got %v", tt.expectedImage, image.Value())
+			}
+			if got := dbusNotification.Hints["category"].Value(); got != desktop.Category {
+				t.Errorf("Expected category hint %q, got %v", desktop.Category, got)
+			}
+
+			sound, hasSound := dbusNotification.Hints["sound-name"]
+			if tt.expectedSound == "" {
+				if hasSound {
+					t.Errorf("Expected no sound hint, got %v", sound.Value())
+				}
+			} else if !hasSound || sound.Value() != tt.expectedSound {
+				t.Errorf("Expected sound hint %q, got %v", tt.expectedSound, sound.Value())
+			}
+		})
+	}
+}
+
+func TestRenderer_MarkupEscaping(t *testing.T) {
+	source := `{{define "title"}}{{.Summary}}{{end}}<b>{{.Summary}}</b> @ {{escape .Location}}{{if .Description}}
+{{.Description | stripHTML | upper}}{{end}}`
+
+	tests := []struct {
+		name         string
+		capabilities Capabilities
+		expectedBody string
+	}{
+		{"markup", Capabilities{Markup: true}, "<b>R&amp;D &lt;sync&gt;</b> @ Lab &#34;A&#34; &amp; B\nNOTES &amp; &lt;TODOS&gt;"},
+		{"plain", Capabilities{}, "R&D <sync> @ Lab \"A\" & B\nNOTES & <TODOS>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := newDBusNotifier(nil)
+			notifier.renderer.capabilities = tt.capabilities
+			notifier.renderer.templates.templates["markup.tpl"] = template.Must(template.New("markup.tpl").Funcs(templateFuncs).Parse(source))
+
+			request := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+			request.Template = "markup.tpl"
+			request.Event = storage.NewCalendarEvent("rd-sync", "R&D <sync>", "<p>Notes &amp; &lt;todos&gt;</p>", `Lab "A" & B`,
+				request.Event.GetStartTime(), request.Event.GetEndTime(), time.UTC, nil, nil, []storage.Alert{})
+			rendered, err := notifier.renderer.render(NotificationRequest{AlertRequest: request})
+			if err != nil {
+				t.Fatalf("render() error = %v", err)
+			}
+
+			// Titles are plain text for all servers
+			if rendered.Title != "R&D <sync>" {
+				t.Errorf("Expected the plain title, got %q", rendered.Title)
+			}
+			if body := notifier.dbusNotification(rendered).Body; body != tt.expectedBody {
+				t.Errorf("Expected body %q, got %q", tt.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestDBusNotifier_PlainTextBody(t *testing.T) {
+	// Event text that looks like markup is kept on servers without markup
+	summary := "Q&amp;A <b>prep</b>"
+	notifier := newDBusNotifier(nil)
+	request := newTestAlertRequest("/test/path", summary, withDetails("", "Room &lt;1&gt;"))
+	rendered, err := notifier.renderer.render(NotificationRequest{AlertRequest: request})
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	dbusNotification := notifier.dbusNotification(rendered)
+	if dbusNotification.Summary != summary {
+		t.Errorf("Expected title %q, got %q", summary, dbusNotification.Summary)
+	}
+	if !strings.Contains(dbusNotification.Body, summary+" at Room &lt;1&gt;") {
+		t.Errorf("Expected the literal event text in the body, got %q", dbusNotification.Body)
+	}
+
+	// Bodies of other transports are never treated as markup
+	rendered.Markup = false
+	rendered.Body = "Fish &amp; Chips"
+	if body := notifier.dbusNotification(rendered).Body; body != "Fish &amp; Chips" {
+		t.Errorf("Expected a plain text body to be kept, got %q", body)
+	}
+}
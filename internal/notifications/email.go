@@ -0,0 +1,323 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"calwatch/internal/config"
+	"calwatch/internal/parser"
+	"calwatch/internal/storage"
+)
+
+// Subject template used when none is configured, alerts relative to the end
+// or at the start have no offset to the start
+const defaultEmailSubject = `Reminder: {{.Title}}{{if and (eq .AlertKind "before_start") .AlertOffset}} in {{.AlertOffset}}{{end}}`
+
+// Timeout used when the email configuration does not provide a valid one
+const defaultEmailTimeout = 30 * time.Second
+
+// Maximum line length of base64 encoded attachments
+const base64LineLength = 76
+
+// EmailNotifier implements Notifier by mailing alerts via SMTP or a local
+// sendmail, with the event attached as an .ics file
+type EmailNotifier struct {
+	*transportNotifier
+	emailConfig config.EmailConfig
+
+	// Parsed templates from the email configuration
+	subject *template.Template
+	body    *template.Template // nil to send the rendered notification
+}
+
+// NewEmailNotifier creates an email notifier, parsing the configured templates
+func NewEmailNotifier(emailConfig config.EmailConfig) (*EmailNotifier, error) {
+	notifier := &EmailNotifier{}
+	notifier.transportNotifier = newTransportNotifier(notifier)
+	if err := notifier.setEmailConfig(emailConfig); err != nil {
+		return nil, err
+	}
+	return notifier, nil
+}
+
+// SetConfig sets the notification configuration
+func (n *EmailNotifier) SetConfig(config config.NotificationConfig) {
+	n.transportNotifier.SetConfig(config)
+	if err := n.setEmailConfig(config.Email); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: invalid email configuration: %v\n", err)
+	}
+}
+
+// setEmailConfig applies the email configuration and parses its templates
+func (n *EmailNotifier) setEmailConfig(emailConfig config.EmailConfig) error {
+	subjectText := emailConfig.Subject
+	if subjectText == "" {
+		subjectText = defaultEmailSubject
+	}
+	subject, err := template.New("subject").Funcs(templateFuncs).Parse(subjectText)
+	if err != nil {
+		return fmt.Errorf("failed to parse email subject template: %w", err)
+	}
+
+	var body *template.Template
+	if emailConfig.Body != "" {
+		body, err = template.New("body").Funcs(templateFuncs).Parse(emailConfig.Body)
+		if err != nil {
+			return fmt.Errorf("failed to parse email body template: %w", err)
+		}
+	}
+
+	n.emailConfig = emailConfig
+	n.subject, n.body = subject, body
+	return nil
+}
+
+// Deliver composes the email and sends it via SMTP or sendmail
+func (n *EmailNotifier) Deliver(notification Notification) error {
+	from, err := mail.ParseAddress(n.emailConfig.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address %q: %w", n.emailConfig.From, err)
+	}
+	recipients := make([]*mail.Address, 0, len(n.emailConfig.To))
+	for _, to := range n.emailConfig.To {
+		recipient, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("invalid recipient %q: %w", to, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("no email recipients configured")
+	}
+
+	// Digests list other events, their own event is only a placeholder
+	event := notification.Request.Event
+	if notification.Request.Digest || len(notification.Request.Agenda) > 0 {
+		event = nil
+	}
+
+	message, err := n.composeMessage(notification.payload(), event, from, recipients)
+	if err != nil {
+		return err
+	}
+
+	timeout, err := n.emailConfig.Timeout.ToDuration()
+	if err != nil {
+		timeout = defaultEmailTimeout
+	}
+
+	addresses := make([]string, len(recipients))
+	for i, recipient := range recipients {
+		addresses[i] = recipient.Address
+	}
+
+	if n.emailConfig.Transport == "sendmail" {
+		return n.sendmail(from.Address, addresses, message, timeout)
+	}
+	return n.sendSMTP(from.Address, addresses, message, timeout)
+}
+
+// composeMessage builds a multipart MIME message with the rendered text and
+// the event as text/calendar attachment, if there is an event to attach
+func (n *EmailNotifier) composeMessage(payload alertPayload, event storage.Event, from *mail.Address, recipients []*mail.Address) ([]byte, error) {
+	var subject bytes.Buffer
+	if err := n.subject.Execute(&subject, payload); err != nil {
+		return nil, fmt.Errorf("failed to render email subject: %w", err)
+	}
+
+	body := payload.Body
+	if n.body != nil {
+		var buf bytes.Buffer
+		if err := n.body.Execute(&buf, payload); err != nil {
+			return nil, fmt.Errorf("failed to render email body: %w", err)
+		}
+		body = buf.String()
+	}
+
+	to := make([]string, len(recipients))
+	for i, recipient := range recipients {
+		to[i] = recipient.String()
+	}
+
+	var message bytes.Buffer
+	parts := multipart.NewWriter(&message)
+
+	// Header values are encoded, so newlines from templates can't inject headers
+	fmt.Fprintf(&message, "From: %s\r\n", from.String())
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "Message-ID: %s\r\n", newMessageID(from.Address))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", parts.Boundary())
+
+	text, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create email body: %w", err)
+	}
+	encoder := quotedprintable.NewWriter(text)
+	encoder.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	encoder.Close()
+
+	if event != nil {
+		if err := writeAttachment(parts, event); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish email: %w", err)
+	}
+
+	return message.Bytes(), nil
+}
+
+// writeAttachment adds an event to a message as text/calendar attachment
+func writeAttachment(parts *multipart.Writer, event storage.Event) error {
+	var calendar bytes.Buffer
+	if err := parser.WriteEvent(&calendar, event); err != nil {
+		return err
+	}
+
+	filename := "event.ics"
+	if _, isTask := event.(*storage.TaskEvent); isTask {
+		filename = "task.ics"
+	}
+	attachment, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {fmt.Sprintf("text/calendar; charset=utf-8; method=PUBLISH; name=%q", filename)},
+		"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", filename)},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create email attachment: %w", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(calendar.Bytes())
+	for len(encoded) > base64LineLength {
+		attachment.Write([]byte(encoded[:base64LineLength] + "\r\n"))
+		encoded = encoded[base64LineLength:]
+	}
+	attachment.Write([]byte(encoded + "\r\n"))
+	return nil
+}
+
+// newMessageID creates a unique Message-ID in the domain of the sender
+func newMessageID(from string) string {
+	domain := "calwatch.localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		domain = from[at+1:]
+	}
+
+	random := make([]byte, 12)
+	rand.Read(random)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().Unix(), hex.EncodeToString(random), domain)
+}
+
+// sendSMTP delivers the message to the configured SMTP server
+func (n *EmailNotifier) sendSMTP(from string, recipients []string, message []byte, timeout time.Duration) error {
+	smtpConfig := n.emailConfig.SMTP
+	address := net.JoinHostPort(smtpConfig.Host, strconv.Itoa(smtpConfig.Port))
+
+	tlsConfig := &tls.Config{ServerName: smtpConfig.Host}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	var err error
+	if smtpConfig.Security == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %w", address, err)
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	client, err := smtp.NewClient(conn, smtpConfig.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP handshake with %s failed: %w", address, err)
+	}
+	defer client.Close()
+
+	if smtpConfig.Security == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server %s does not support STARTTLS", address)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS with %s failed: %w", address, err)
+		}
+	}
+
+	if smtpConfig.Username != "" {
+		password, err := n.smtpPassword()
+		if err != nil {
+			return err
+		}
+		if err := client.Auth(smtp.PlainAuth("", smtpConfig.Username, password, smtpConfig.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("SMTP server rejected sender %s: %w", from, err)
+	}
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("SMTP server rejected recipient %s: %w", recipient, err)
+		}
+	}
+
+	data, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := data.Write(message); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if err := data.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected email: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// smtpPassword returns the configured password, reading it from the password file if set
+func (n *EmailNotifier) smtpPassword() (string, error) {
+	if n.emailConfig.SMTP.PasswordFile == "" {
+		return n.emailConfig.SMTP.Password, nil
+	}
+
+	content, err := os.ReadFile(n.emailConfig.SMTP.PasswordFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read SMTP password file: %w", err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// sendmail pipes the message to the local sendmail binary
+func (n *EmailNotifier) sendmail(from string, recipients []string, message []byte, timeout time.Duration) error {
+	// sendmail expects local line endings
+	message = bytes.ReplaceAll(message, []byte("\r\n"), []byte("\n"))
+
+	args := append([]string{"-i", "-f", from, "--"}, recipients...)
+	return runCommand(n.emailConfig.Sendmail, args, nil, bytes.NewReader(message), timeout)
+}
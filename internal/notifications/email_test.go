@@ -0,0 +1,366 @@
+package notifications
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/config"
+	"calwatch/internal/storage"
+)
+
+// fakeSMTPServer is a minimal SMTP server that records what it receives
+type fakeSMTPServer struct {
+	listener   net.Listener
+	extensions []string
+
+	mutex       sync.Mutex
+	credentials string // Decoded AUTH PLAIN response
+	from        string
+	to          []string
+	data        string
+}
+
+// newFakeSMTPServer starts a fake SMTP server advertising the given extensions
+func newFakeSMTPServer(t *testing.T, extensions ...string) *fakeSMTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := &fakeSMTPServer{listener: listener, extensions: extensions}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	return server
+}
+
+// smtpConfig returns an SMTP configuration pointing at the fake server
+func (s *fakeSMTPServer) smtpConfig() config.SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	return config.SMTPConfig{Host: host, Port: portNumber, Security: "none"}
+}
+
+// serve handles a single SMTP session
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("220 localhost fake SMTP")
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		s.mutex.Lock()
+		switch strings.ToUpper(fields[0]) {
+		case "EHLO":
+			for _, extension := range s.extensions {
+				text.PrintfLine("250-%s", extension)
+			}
+			text.PrintfLine("250 localhost")
+		case "AUTH":
+			decoded, _ := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			s.credentials = string(decoded)
+			text.PrintfLine("235 Authentication successful")
+		case "MAIL":
+			s.from = line
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			if strings.Contains(line, "unknown@") {
+				text.PrintfLine("550 No such user")
+				break
+			}
+			s.to = append(s.to, line)
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 Go ahead")
+			data, _ := text.ReadDotBytes()
+			s.data = string(data)
+			text.PrintfLine("250 Queued")
+		case "QUIT":
+			text.PrintfLine("221 Bye")
+			s.mutex.Unlock()
+			return
+		default:
+			text.PrintfLine("502 Not implemented")
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// readEmail parses a message and returns its subject, text body and .ics attachment
+func readEmail(t *testing.T, data string) (string, string, string) {
+	t.Helper()
+
+	message, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to parse email: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("Failed to decode subject: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Expected multipart/mixed, got %q (%v)", mediaType, err)
+	}
+
+	var body, attachment string
+	parts := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read part: %v", err)
+		}
+		content, _ := io.ReadAll(part)
+
+		switch {
+		case strings.HasPrefix(part.Header.Get("Content-Type"), "text/plain"):
+			// multipart.Reader decodes quoted-printable and line endings transparently
+			body = string(content)
+		case strings.HasPrefix(part.Header.Get("Content-Type"), "text/calendar"):
+			decoded, err := base64.StdEncoding.DecodeString(strings.NewReplacer("\r", "", "\n", "").Replace(string(content)))
+			if err != nil {
+				t.Fatalf("Failed to decode attachment: %v", err)
+			}
+			attachment = string(decoded)
+		}
+	}
+
+	return subject, body, attachment
+}
+
+func TestEmailNotifier_SMTP(t *testing.T) {
+	server := newFakeSMTPServer(t, "AUTH PLAIN")
+
+	smtpConfig := server.smtpConfig()
+	smtpConfig.Username = "calwatch"
+	smtpConfig.Password = "secret"
+
+	notifier, err := NewEmailNotifier(config.EmailConfig{
+		From:      "Calwatch <calwatch@example.com>",
+		To:        []string{"jane@example.com", "John <john@example.com>"},
+		Transport: "smtp",
+		SMTP:      smtpConfig,
+	})
+	if err != nil {
+		t.Fatalf("NewEmailNotifier() error = %v", err)
+	}
+
+	if err := notifier.SendNotification(newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if server.credentials != "\x00calwatch\x00secret" {
+		t.Errorf("Unexpected credentials %q", server.credentials)
+	}
+	if server.from != "MAIL FROM:<calwatch@example.com>" {
+		t.Errorf("Unexpected sender %q", server.from)
+	}
+	if len(server.to) != 2 || !strings.Contains(server.to[1], "<john@example.com>") {
+		t.Errorf("Unexpected recipients %v", server.to)
+	}
+
+	subject, body, attachment := readEmail(t, server.data)
+	if subject != "Reminder: Team Meeting in 15 minutes" {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	if !strings.HasPrefix(body, "Team Meeting at Room 1\n") {
+		t.Errorf("Unexpected body %q", body)
+	}
+	for _, line := range []string{"BEGIN:VEVENT", "UID:test-uid", "SUMMARY:Team Meeting", "DTSTART:20240115T200000Z"} {
+		if !strings.Contains(attachment, line+"\r\n") {
+			t.Errorf("Expected %q in attachment:\n%s", line, attachment)
+		}
+	}
+}
+
+func TestEmailNotifier_Templates(t *testing.T) {
+	server := newFakeSMTPServer(t)
+
+	notifier, err := NewEmailNotifier(config.EmailConfig{
+		From:    "calwatch@example.com",
+		To:      []string{"jane@example.com"},
+		Subject: "Morgen: {{.Title}} – {{.AlertOffset}}",
+		Body:    "{{.Title}}\n{{.Description}}\nurgency={{.Urgency}}",
+		SMTP:    server.smtpConfig(),
+	})
+	if err != nil {
+		t.Fatalf("NewEmailNotifier() error = %v", err)
+	}
+
+	if err := notifier.SendNotification(newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	subject, body, _ := readEmail(t, server.data)
+	if subject != "Morgen: Team Meeting – 15 minutes" {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	if body != "Team Meeting\nWeekly sync\nurgency=critical" {
+		t.Errorf("Unexpected body %q", body)
+	}
+}
+
+func TestEmailNotifier_SMTPErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		security string
+		to       []string
+		expected string
+	}{
+		{"STARTTLS not offered", "starttls", []string{"jane@example.com"}, "does not support STARTTLS"},
+		{"recipient rejected", "none", []string{"unknown@example.com"}, "rejected recipient"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeSMTPServer(t)
+			smtpConfig := server.smtpConfig()
+			smtpConfig.Security = tt.security
+
+			notifier, err := NewEmailNotifier(config.EmailConfig{
+				From: "calwatch@example.com",
+				To:   tt.to,
+				SMTP: smtpConfig,
+			})
+			if err != nil {
+				t.Fatalf("NewEmailNotifier() error = %v", err)
+			}
+
+			err = notifier.SendNotification(newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant))
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestEmailNotifier_Digest(t *testing.T) {
+	server := newFakeSMTPServer(t)
+
+	notifier, err := NewEmailNotifier(config.EmailConfig{
+		From: "calwatch@example.com",
+		To:   []string{"jane@example.com"},
+		SMTP: server.smtpConfig(),
+	})
+	if err != nil {
+		t.Fatalf("NewEmailNotifier() error = %v", err)
+	}
+
+	meeting := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+	digest := alerts.AlertRequest{
+		Event:  storage.NewDigestEvent("calwatch-digest/2024-01-15", "Agenda for Monday", meeting.EventTime.Add(-6*time.Hour)),
+		Digest: true,
+		Agenda: []alerts.AgendaItem{{Event: meeting.Event, Start: meeting.Event.GetStartTime()}},
+	}
+	if err := notifier.SendNotification(digest); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	// The digest's placeholder event is not attached
+	_, body, attachment := readEmail(t, server.data)
+	if !strings.Contains(body, "Team Meeting") {
+		t.Errorf("Expected the agenda in the body, got %q", body)
+	}
+	if attachment != "" {
+		t.Errorf("Expected no attachment for a digest, got:\n%s", attachment)
+	}
+}
+
+func TestEmailNotifier_Sendmail(t *testing.T) {
+	dir := t.TempDir()
+	sendmail := filepath.Join(dir, "sendmail")
+	script := "#!/bin/sh\necho \"$@\" > \"" + filepath.Join(dir, "args") + "\"\ncat > \"" + filepath.Join(dir, "message") + "\"\n"
+	if err := os.WriteFile(sendmail, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake sendmail: %v", err)
+	}
+
+	notifier, err := NewEmailNotifier(config.EmailConfig{
+		From:      "calwatch@example.com",
+		To:        []string{"jane@example.com"},
+		Transport: "sendmail",
+		Sendmail:  sendmail,
+	})
+	if err != nil {
+		t.Fatalf("NewEmailNotifier() error = %v", err)
+	}
+
+	if err := notifier.SendNotification(newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatalf("Fake sendmail was not run: %v", err)
+	}
+	if strings.TrimSpace(string(args)) != "-i -f calwatch@example.com -- jane@example.com" {
+		t.Errorf("Unexpected sendmail arguments %q", args)
+	}
+
+	message, err := os.ReadFile(filepath.Join(dir, "message"))
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	if strings.Contains(string(message), "\r\n") {
+		t.Error("Expected local line endings for sendmail")
+	}
+	if _, _, attachment := readEmail(t, string(message)); !strings.Contains(attachment, "UID:test-uid") {
+		t.Errorf("Expected event attachment, got %q", attachment)
+	}
+}
+
+func TestNewEmailNotifier_InvalidTemplates(t *testing.T) {
+	configs := []config.EmailConfig{
+		{From: "calwatch@example.com", To: []string{"jane@example.com"}, Subject: "{{.Title"},
+		{From: "calwatch@example.com", To: []string{"jane@example.com"}, Body: "{{if}}"},
+	}
+
+	for _, emailConfig := range configs {
+		if _, err := NewEmailNotifier(emailConfig); err == nil {
+			t.Errorf("Expected error for config %+v", emailConfig)
+		}
+	}
+}
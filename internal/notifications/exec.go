@@ -0,0 +1,146 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"calwatch/internal/config"
+)
+
+// Timeout used when the exec configuration does not provide a valid one
+const defaultExecTimeout = 10 * time.Second
+
+// Prefix of the environment variables passed to the command
+const execEnvPrefix = "CALWATCH_"
+
+// ExecNotifier implements Notifier by running a user-configured command per alert
+type ExecNotifier struct {
+	*transportNotifier
+	execConfig config.ExecConfig
+}
+
+// NewExecNotifier creates a notifier that runs the configured command
+func NewExecNotifier(execConfig config.ExecConfig) *ExecNotifier {
+	notifier := &ExecNotifier{execConfig: execConfig}
+	notifier.transportNotifier = newTransportNotifier(notifier)
+	return notifier
+}
+
+// SetConfig sets the notification configuration
+func (n *ExecNotifier) SetConfig(config config.NotificationConfig) {
+	n.transportNotifier.SetConfig(config)
+	n.execConfig = config.Exec
+}
+
+// Deliver runs the command for a notification
+func (n *ExecNotifier) Deliver(notification Notification) error {
+	return n.run(notification.payload())
+}
+
+// run executes the command with the payload and reports timeouts and failures
+func (n *ExecNotifier) run(payload alertPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification data: %w", err)
+	}
+
+	env, err := execEnvironment(data)
+	if err != nil {
+		return err
+	}
+
+	timeout, err := n.execConfig.Timeout.ToDuration()
+	if err != nil {
+		timeout = defaultExecTimeout
+	}
+
+	var stdin io.Reader
+	if n.execConfig.Input == "json" {
+		stdin = bytes.NewReader(data)
+	}
+
+	return runCommand(n.execConfig.Command, n.execConfig.Args, env, stdin, timeout)
+}
+
+// runCommand runs a command with additional environment variables and
+// optional stdin, killing it when the timeout expires. Failures include the
+// command's stderr.
+func runCommand(command string, args []string, env []string, stdin io.Reader, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = stdin
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	// Don't wait for background processes that inherited stderr after a timeout
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("command %s timed out after %v", command, timeout)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		message := fmt.Sprintf("command %s exited with status %d", command, exitErr.ExitCode())
+		if output := strings.TrimSpace(stderr.String()); output != "" {
+			message += ": " + output
+		}
+		return errors.New(message)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run command %s: %w", command, err)
+	}
+
+	return nil
+}
+
+// execEnvironment converts the JSON payload into CALWATCH_<NAME>=value variables
+func execEnvironment(data []byte) ([]string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode notification data: %w", err)
+	}
+
+	env := make([]string, 0, len(fields))
+	for name, value := range fields {
+		env = append(env, execEnvPrefix+strings.ToUpper(name)+"="+formatEnvValue(value))
+	}
+	sort.Strings(env)
+
+	return env, nil
+}
+
+// formatEnvValue formats a decoded JSON value for an environment variable
+func formatEnvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = formatEnvValue(item)
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
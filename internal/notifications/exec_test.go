@@ -0,0 +1,249 @@
+package notifications
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/config"
+	"calwatch/internal/recurrence"
+	"calwatch/internal/storage"
+)
+
+// testRequestOption changes the event or the alert of a test alert request
+type testRequestOption func(event *storage.CalendarEvent, request *alerts.AlertRequest)
+
+// newTestAlertRequest creates an alert 15 minutes before a one-hour event at
+// 20:00 UTC on January 15, 2024 in the given calendar directory
+func newTestAlertRequest(calendarPath, summary string, options ...testRequestOption) alerts.AlertRequest {
+	startTime := time.Date(2024, 1, 15, 20, 0, 0, 0, time.UTC)
+	event := storage.NewCalendarEvent(
+		"test-uid",
+		summary,
+		"",
+		"",
+		startTime,
+		startTime.Add(time.Hour),
+		time.UTC,
+		&recurrence.NoRecurrence{},
+		storage.NewCalendar(calendarPath, "", []storage.Alert{}),
+		[]storage.Alert{},
+	)
+
+	request := alerts.AlertRequest{
+		Event:       event,
+		AlertOffset: 15 * time.Minute,
+		EventTime:   startTime,
+	}
+	for _, option := range options {
+		option(event, &request)
+	}
+	return request
+}
+
+// withDetails sets the description and location of the event
+func withDetails(description, location string) testRequestOption {
+	return func(event *storage.CalendarEvent, request *alerts.AlertRequest) {
+		event.Description = description
+		event.Location = location
+	}
+}
+
+// withUID sets the UID of the event
+func withUID(uid string) testRequestOption {
+	return func(event *storage.CalendarEvent, request *alerts.AlertRequest) {
+		event.UID = uid
+	}
+}
+
+// startingAt moves the one-hour event and its alert to start
+func startingAt(start time.Time) testRequestOption {
+	return func(event *storage.CalendarEvent, request *alerts.AlertRequest) {
+		event.StartTime = start
+		event.EndTime = start.Add(time.Hour)
+		request.EventTime = start
+	}
+}
+
+// withRecurrence sets the recurrence of the event
+func withRecurrence(rec recurrence.Recurrence) testRequestOption {
+	return func(event *storage.CalendarEvent, request *alerts.AlertRequest) {
+		event.Recurrence = rec
+	}
+}
+
+// asImportant marks the alert as important
+func asImportant(event *storage.CalendarEvent, request *alerts.AlertRequest) {
+	request.Important = true
+}
+
+// asLate marks the alert as late
+func asLate(event *storage.CalendarEvent, request *alerts.AlertRequest) {
+	request.Late = true
+}
+
+// newShellExecNotifier creates an exec notifier running a shell script
+func newShellExecNotifier(script, input string, timeout config.DurationConfig) *ExecNotifier {
+	return NewExecNotifier(config.ExecConfig{
+		Command: "/bin/sh",
+		Args:    []string{"-c", script},
+		Input:   input,
+		Timeout: timeout,
+	})
+}
+
+func TestExecNotifier_Environment(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "env.txt")
+	notifier := newShellExecNotifier(
+		`printf '%s|%s|%s|%s|%s' "$CALWATCH_TITLE" "$CALWATCH_SUMMARY" "$CALWATCH_ALERT_OFFSET" "$CALWATCH_URGENCY" "$CALWATCH_IMPORTANT" > "`+output+`"`,
+		"env", config.DurationConfig{})
+
+	if err := notifier.SendNotification(newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read command output: %v", err)
+	}
+
+	expected := "Team Meeting|Team Meeting|15 minutes|critical|true"
+	if string(content) != expected {
+		t.Errorf("Expected environment %q, got %q", expected, string(content))
+	}
+}
+
+func TestExecNotifier_JSONInput(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "payload.json")
+	notifier := newShellExecNotifier(`cat > "`+output+`"`, "json", config.DurationConfig{})
+
+	if err := notifier.SendNotification(newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read command output: %v", err)
+	}
+
+	var payload alertPayload
+	if err := json.Unmarshal(content, &payload); err != nil {
+		t.Fatalf("Failed to decode payload %q: %v", content, err)
+	}
+
+	if payload.Summary != "Team Meeting" || payload.Location != "Room 1" || payload.UID != "test-uid" {
+		t.Errorf("Unexpected template data in payload: %+v", payload.TemplateData)
+	}
+	if !strings.Contains(payload.Body, "Team Meeting at Room 1") {
+		t.Errorf("Expected rendered body, got %q", payload.Body)
+	}
+	if payload.Urgency != "critical" || !payload.Important || payload.Late {
+		t.Errorf("Unexpected alert flags in payload: urgency=%s important=%v late=%v",
+			payload.Urgency, payload.Important, payload.Late)
+	}
+}
+
+func TestExecNotifier_Failures(t *testing.T) {
+	tests := []struct {
+		name        string
+		script      string
+		timeout     config.DurationConfig
+		errContains []string
+	}{
+		{
+			name:        "exit code",
+			script:      "echo 'speaker not found' >&2; exit 3",
+			errContains: []string{"exited with status 3", "speaker not found"},
+		},
+		{
+			name:        "timeout",
+			script:      "sleep 5",
+			timeout:     config.DurationConfig{Type: "timed", Value: 100, Unit: "milliseconds"},
+			errContains: []string{"timed out after 100ms"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := newShellExecNotifier(tt.script, "env", tt.timeout)
+
+			started := time.Now()
+			err := notifier.SendNotification(newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant))
+			if err == nil {
+				t.Fatal("Expected error but got none")
+			}
+			for _, expected := range tt.errContains {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("Expected error to contain %q, got %v", expected, err)
+				}
+			}
+			if elapsed := time.Since(started); elapsed > 3*time.Second {
+				t.Errorf("Command was not stopped in time, took %v", elapsed)
+			}
+		})
+	}
+
+	t.Run("missing command", func(t *testing.T) {
+		notifier := NewExecNotifier(config.ExecConfig{Command: "/nonexistent/calwatch-hook"})
+		if err := notifier.SendNotification(newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)); err == nil {
+			t.Error("Expected error for missing command")
+		}
+	})
+}
+
+func TestExecEnvironment(t *testing.T) {
+	data, err := json.Marshal(alertPayload{
+		Title: "Title",
+		TemplateData: TemplateData{
+			Attendees:       []string{"alice@example.com", "bob@example.com"},
+			PercentComplete: 40,
+			IsTask:          true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode payload: %v", err)
+	}
+
+	env, err := execEnvironment(data)
+	if err != nil {
+		t.Fatalf("execEnvironment() error = %v", err)
+	}
+
+	expected := []string{
+		"CALWATCH_TITLE=Title",
+		"CALWATCH_ATTENDEES=alice@example.com, bob@example.com",
+		"CALWATCH_PERCENT_COMPLETE=40",
+		"CALWATCH_IS_TASK=true",
+		"CALWATCH_DUE=",
+	}
+	for _, variable := range expected {
+		found := false
+		for _, actual := range env {
+			if actual == variable {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected %s in environment %v", variable, env)
+		}
+	}
+}
+
+func TestNewNotificationManager_ExecBackend(t *testing.T) {
+	manager := NewNotificationManager(config.NotificationConfig{
+		Backend: "exec",
+		Exec:    config.ExecConfig{Command: "true"},
+	})
+
+	if len(manager.backends) != 1 {
+		t.Fatalf("Expected 1 backend, got %d", len(manager.backends))
+	}
+	if _, ok := manager.backends[0].notifier.(*ExecNotifier); !ok {
+		t.Errorf("Expected ExecNotifier, got %T", manager.backends[0].notifier)
+	}
+}
@@ -0,0 +1,90 @@
+package notifications
+
+import (
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/config"
+	"calwatch/internal/storage"
+)
+
+// Focus holds back non-important alerts while a busy event is in progress:
+// an opaque event with a time of day in one of the configured calendars or
+// with one of the configured categories
+type Focus struct {
+	config config.FocusConfig
+	events storage.EventStorage
+}
+
+// NewFocus creates the focus rule from its configuration
+func NewFocus(focusConfig config.FocusConfig) *Focus {
+	return &Focus{config: focusConfig}
+}
+
+// SetEventStorage sets the storage busy events are looked up in
+func (f *Focus) SetEventStorage(events storage.EventStorage) {
+	f.events = events
+}
+
+// Check returns the action for an alert at now and the end of the busy
+// events in progress, or "" if the alert is delivered as usual. Alerts about
+// a busy event in progress, e.g. before its end, are delivered.
+func (f *Focus) Check(request alerts.AlertRequest, now time.Time) (config.HoldPolicy, time.Time) {
+	if !f.config.Enable || f.events == nil || request.Important {
+		return "", time.Time{}
+	}
+
+	busy, until := f.busyAt(now)
+	if len(busy) == 0 {
+		return "", time.Time{}
+	}
+	for _, event := range busy {
+		if request.Event != nil && event.GetUID() == request.Event.GetUID() {
+			return "", time.Time{}
+		}
+	}
+	return f.config.Action, until
+}
+
+// busyAt returns the busy events in progress at t and when the last of them ends
+func (f *Focus) busyAt(t time.Time) ([]storage.Event, time.Time) {
+	var busy []storage.Event
+	var until time.Time
+
+	for _, event := range f.events.GetAllEvents() {
+		if !f.isBusy(event) {
+			continue
+		}
+		duration := event.GetEndTime().Sub(event.GetStartTime())
+		for _, start := range event.OccurredWithin(t.Add(-duration), t) {
+			end := start.Add(duration)
+			if start.After(t) || !end.After(t) {
+				continue
+			}
+			busy = append(busy, event)
+			if end.After(until) {
+				until = end
+			}
+		}
+	}
+	return busy, until
+}
+
+// isBusy reports whether an event holds back alerts while in progress.
+// All-day events, tasks and transparent events do not block time.
+func (f *Focus) isBusy(event storage.Event) bool {
+	calendarEvent, ok := event.(*storage.CalendarEvent)
+	if !ok || calendarEvent.AllDay || calendarEvent.Transparent || !event.GetEndTime().After(event.GetStartTime()) {
+		return false
+	}
+
+	if len(f.config.Calendars) > 0 && storage.InCalendars(event, f.config.Calendars) {
+		return true
+	}
+	for _, category := range f.config.Categories {
+		if calendarEvent.HasCategory(category) {
+			return true
+		}
+	}
+	return false
+}
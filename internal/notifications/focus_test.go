@@ -0,0 +1,109 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/config"
+	"calwatch/internal/localday"
+	"calwatch/internal/storage"
+)
+
+func TestFocus_Check(t *testing.T) {
+	monday := localday.Date(2024, 1, 15)
+	at := func(hour, minute int) time.Time {
+		return monday.Start().Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	work := storage.NewCalendar("/calendars/work", "", []storage.Alert{})
+	personal := storage.NewCalendar("/calendars/personal", "", []storage.Alert{})
+	newEvent := func(uid string, calendar *storage.Calendar, start, end time.Time) *storage.CalendarEvent {
+		return storage.NewCalendarEvent(uid, uid, "", "", start, end, localday.Location(), nil, calendar, []storage.Alert{})
+	}
+	meeting := newEvent("meeting", work, at(14, 0), at(15, 0))
+	overrun := newEvent("overrun", work, at(14, 30), at(15, 30))
+	focusTime := newEvent("focus-time", work, at(16, 0), at(17, 0))
+	focusTime.Transparent = true
+	talk := newEvent("talk", personal, at(18, 0), at(19, 0))
+	talk.Categories = []string{"Presentation"}
+	offsite := newEvent("offsite", work, monday.Start(), monday.End())
+	offsite.AllDay = true
+
+	events := storage.NewMemoryEventStorage()
+	for _, event := range []storage.Event{meeting, overrun, focusTime, talk, offsite} {
+		events.UpsertEvent(event)
+	}
+
+	focusConfig := config.FocusConfig{Enable: true, Calendars: []string{"work"}, Categories: []string{"presentation"}}
+	if err := focusConfig.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	focus := NewFocus(focusConfig)
+	focus.SetEventStorage(events)
+
+	reminder := newTestAlertRequest("/calendars/personal", "Dentist")
+	endOfMeeting := alerts.AlertRequest{Event: meeting, EventTime: meeting.StartTime, AlertKind: storage.AlertBeforeEnd}
+	tests := []struct {
+		name           string
+		request        alerts.AlertRequest
+		now            time.Time
+		expectedAction config.HoldPolicy
+		expectedUntil  time.Time
+	}{
+		{"in a meeting", reminder, at(14, 10), config.HoldDefer, at(15, 0)},
+		{"in overlapping meetings", reminder, at(14, 45), config.HoldDefer, at(15, 30)},
+		{"meeting ended", reminder, at(15, 30), "", time.Time{}},
+		{"about the meeting itself", endOfMeeting, at(14, 55), "", time.Time{}},
+		{"important", newTestAlertRequest("/calendars/personal", "Dentist", asImportant), at(14, 10), "", time.Time{}},
+		{"transparent event", reminder, at(16, 30), "", time.Time{}},
+		{"category", reminder, at(18, 0), config.HoldDefer, at(19, 0)},
+		{"all-day event", reminder, at(12, 0), "", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, until := focus.Check(tt.request, tt.now)
+			if action != tt.expectedAction || !until.Equal(tt.expectedUntil) {
+				t.Errorf("Expected %q until %v, got %q until %v", tt.expectedAction, tt.expectedUntil, action, until)
+			}
+		})
+	}
+}
+
+func TestOutbox_Focus(t *testing.T) {
+	now := time.Date(2024, 1, 15, 19, 45, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 15, 20, 30, 0, 0, time.UTC)
+
+	events := storage.NewMemoryEventStorage()
+	events.UpsertEvent(storage.NewCalendarEvent("call", "Customer call", "", "", now.Add(-45*time.Minute), end,
+		time.UTC, nil, storage.NewCalendar("/calendars/calls", "", []storage.Alert{}), []storage.Alert{}))
+
+	focusConfig := config.FocusConfig{Enable: true, Calendars: []string{"calls"}}
+	if err := focusConfig.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	focus := NewFocus(focusConfig)
+	focus.SetEventStorage(events)
+
+	desktop := &recordingNotifier{}
+	outbox := newOutboxTestOutbox(t, newOutboxTestManager(map[string]*recordingNotifier{"desktop": desktop}), &now)
+	outbox.SetFocus(focus)
+
+	if err := outbox.Enqueue([]alerts.AlertRequest{newTestAlertRequest("/calendars/work", "Meeting")}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	outbox.Deliver()
+	if desktop.sent != 0 {
+		t.Fatalf("Expected the alert to be held back during the call")
+	}
+	if next, ok := outbox.NextAttempt(); !ok || !next.Equal(end) {
+		t.Errorf("Expected delivery at the end of the call at %v, got %v", end, next)
+	}
+
+	now = end
+	outbox.Deliver()
+	if desktop.sent != 1 || !desktop.last.Digest || desktop.last.Event.GetSummary() != "Alerts while busy" || len(desktop.last.Agenda) != 1 {
+		t.Errorf("Expected a digest of the alert after the call, got %d sent: %+v", desktop.sent, desktop.last)
+	}
+}
@@ -0,0 +1,138 @@
+package notifications
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"calwatch/internal/localday"
+)
+
+// templateFuncs are available in all notification templates. Functions take
+// the piped value last, e.g. {{.Description | stripHTML | truncate 80}}.
+var templateFuncs = template.FuncMap{
+	"truncate":         truncate,
+	"wrap":             wrap,
+	"stripHTML":        stripHTML,
+	"formatTime":       formatTime,
+	"humanizeDuration": formatDuration,
+	"default":          defaultValue,
+	"join":             join,
+	"plural":           plural,
+	"upper":            strings.ToUpper,
+	"lower":            strings.ToLower,
+	"trim":             strings.TrimSpace,
+	"escape":           html.EscapeString, // HTML for other backends, desktop markup is escaped automatically
+}
+
+// truncate shortens s to at most length characters, ending with "…" if cut
+func truncate(length int, s string) string {
+	if length <= 0 || utf8.RuneCountInString(s) <= length {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimRight(string(runes[:length-1]), " ") + "…"
+}
+
+// wrap breaks s into lines of at most width characters at word boundaries,
+// keeping existing line breaks
+func wrap(width int, s string) string {
+	if width <= 0 {
+		return s
+	}
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		var wrapped strings.Builder
+		length := 0
+		for _, word := range strings.Fields(line) {
+			wordLength := utf8.RuneCountInString(word)
+			if length > 0 && length+1+wordLength > width {
+				wrapped.WriteString("\n")
+				length = 0
+			} else if length > 0 {
+				wrapped.WriteString(" ")
+				length++
+			}
+			wrapped.WriteString(word)
+			length += wordLength
+		}
+		lines[i] = wrapped.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	htmlBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|tr|h[1-6])>`)
+	htmlTagPattern   = regexp.MustCompile(`<[^>]*>`)
+	blankLinePattern = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+`)
+)
+
+// stripHTML converts HTML, as found in descriptions from Outlook or Teams
+// invitations, to plain text
+func stripHTML(s string) string {
+	s = htmlBreakPattern.ReplaceAllString(s, "\n")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = strings.ReplaceAll(s, " ", " ")
+	s = blankLinePattern.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
+// formatTime formats t with a Go layout in the named time zone, the local
+// time zone if empty, e.g. {{.Start | formatTime "15:04 MST" "America/New_York"}}
+func formatTime(layout, zone string, t time.Time) (string, error) {
+	location := localday.Location()
+	if zone != "" {
+		var err error
+		location, err = time.LoadLocation(zone)
+		if err != nil {
+			return "", fmt.Errorf("unknown time zone %q: %w", zone, err)
+		}
+	}
+	return t.In(location).Format(layout), nil
+}
+
+// defaultValue returns value, or fallback if value is empty,
+// e.g. {{.Location | default "somewhere"}}
+func defaultValue(fallback, value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return fallback
+	case string:
+		if v == "" {
+			return fallback
+		}
+	case []string:
+		if len(v) == 0 {
+			return fallback
+		}
+	case int:
+		if v == 0 {
+			return fallback
+		}
+	case bool:
+		if !v {
+			return fallback
+		}
+	}
+	return value
+}
+
+// join joins a list of strings, e.g. {{.Attendees | join ", "}}
+func join(separator string, list []string) string {
+	return strings.Join(list, separator)
+}
+
+// plural returns singular if count is 1 and plural otherwise,
+// e.g. {{len .Attendees}} {{plural (len .Attendees) "attendee" "attendees"}}
+func plural(count int, singular, plural string) string {
+	if count == 1 {
+		return singular
+	}
+	return plural
+}
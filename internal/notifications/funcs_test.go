@@ -0,0 +1,57 @@
+package notifications
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+	"time"
+
+	"calwatch/internal/localday"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	localday.SetLocation(time.UTC)
+	defer localday.SetLocation(time.Local)
+
+	data := TemplateData{
+		Summary:     "Quarterly Planning",
+		Description: `<p>Agenda:<br/>Budget &amp; goals</p><div>Dial-in&nbsp;below</div>`,
+		Attendees:   []string{"alice@example.com", "bob@example.com"},
+		Start:       time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"truncate", `{{.Summary | truncate 10}}`, "Quarterly…"},
+		{"truncate short", `{{.Summary | truncate 50}}`, "Quarterly Planning"},
+		{"wrap", `{{"the quick brown fox jumps" | wrap 10}}`, "the quick\nbrown fox\njumps"},
+		{"stripHTML", `{{.Description | stripHTML}}`, "Agenda:\nBudget & goals\nDial-in below"},
+		{"formatTime local", `{{.Start | formatTime "15:04" ""}}`, "14:30"},
+		{"formatTime zone", `{{.Start | formatTime "15:04 MST" "America/New_York"}}`, "09:30 EST"},
+		{"humanizeDuration", `{{humanizeDuration 5400000000000}}`, "1 hour"},
+		{"default empty", `{{.Location | default "somewhere"}}`, "somewhere"},
+		{"default set", `{{.Summary | default "somewhere"}}`, "Quarterly Planning"},
+		{"join", `{{.Attendees | join ", "}}`, "alice@example.com, bob@example.com"},
+		{"plural", `{{len .Attendees}} {{plural (len .Attendees) "attendee" "attendees"}}`, "2 attendees"},
+		{"upper lower", `{{upper "Hi"}} {{lower "Hi"}}`, "HI hi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := template.New(tt.name).Funcs(templateFuncs).Parse(tt.template)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, data); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package notifications
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/esiqveland/notify"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/storage"
+)
+
+// liveNotification is a notification on screen that later alerts for the
+// same event occurrence replace instead of stacking up
+type liveNotification struct {
+	id           uint32 // ID assigned by the notification server
+	notification Notification
+	shown        time.Time
+}
+
+// liveUpdate is a change to an open notification, collected while holding
+// liveMutex and made after releasing it, so a slow notification server does
+// not hold up alerts and closed signals
+type liveUpdate struct {
+	id      uint32 // Notification to replace or close
+	title   string // Title shown, for warnings
+	close   bool
+	request alerts.AlertRequest
+	late    bool
+	urgency UrgencyLevel
+	change  string
+}
+
+// occurrenceKey identifies the event occurrence an alert is for
+func occurrenceKey(request alerts.AlertRequest) string {
+	eventTime := request.EventTime
+	if eventTime.IsZero() {
+		eventTime = request.Event.GetStartTime()
+	}
+	return request.Event.GetUID() + "@" + eventTime.UTC().Format(time.RFC3339)
+}
+
+// occurrenceStart returns the start of the occurrence an alert is for
+func occurrenceStart(request alerts.AlertRequest) time.Time {
+	if request.EventTime.IsZero() {
+		return request.Event.GetStartTime()
+	}
+	return request.EventTime
+}
+
+// SetEventResolver sets the lookup used to detect deleted events, whose
+// notifications are closed
+func (d *DBusNotifier) SetEventResolver(resolve func(uid string) (storage.Event, bool)) {
+	d.liveMutex.Lock()
+	defer d.liveMutex.Unlock()
+	d.resolve = resolve
+}
+
+// send shows a notification, replacing the one shown for the same event
+// occurrence, and remembers its ID
+func (d *DBusNotifier) send(notification Notification) error {
+	dbusNotification := d.dbusNotification(notification)
+
+	// Error notifications are not about an event and are never replaced
+	if notification.Request.Event == nil {
+		_, err := d.notifier.SendNotification(dbusNotification)
+		return err
+	}
+
+	key := occurrenceKey(notification.Request)
+
+	d.liveMutex.Lock()
+	if live, exists := d.live[key]; exists {
+		dbusNotification.ReplacesID = live.id
+	}
+	d.liveMutex.Unlock()
+
+	id, err := d.notifier.SendNotification(dbusNotification)
+	if err != nil {
+		return err
+	}
+
+	d.liveMutex.Lock()
+	defer d.liveMutex.Unlock()
+
+	// Change alerts replace the alert shown for the occurrence but are not
+	// updated themselves, their event already changed. Digests are not
+	// about a calendar event.
+	if notification.Request.Change != nil || notification.Request.Digest {
+		delete(d.live, key)
+		return nil
+	}
+
+	d.live[key] = &liveNotification{id: id, notification: notification, shown: d.renderer.now()}
+	return nil
+}
+
+// handleClosed forgets notifications that expired or were dismissed
+func (d *DBusNotifier) handleClosed(signal *notify.NotificationClosedSignal) {
+	d.liveMutex.Lock()
+	defer d.liveMutex.Unlock()
+
+	for key, live := range d.live {
+		if live.id == signal.ID {
+			delete(d.live, key)
+		}
+	}
+}
+
+// RefreshEvents updates the open notifications after calendar changes,
+// replacing notifications of moved and cancelled events
+func (d *DBusNotifier) RefreshEvents() {
+	d.refresh(d.renderer.now())
+}
+
+// refresh updates the open notifications: notifications of changed events
+// are replaced with an updated version and those of deleted events with a
+// cancelled version, notifications of events that started since they were
+// shown are closed and the others get a new countdown if enabled
+func (d *DBusNotifier) refresh(now time.Time) {
+	// Talk to the notification server after releasing the lock
+	for _, update := range d.collectUpdates(now) {
+		d.apply(update)
+	}
+}
+
+// collectUpdates returns the changes refresh makes to open notifications and
+// updates which notifications are tracked
+func (d *DBusNotifier) collectUpdates(now time.Time) []liveUpdate {
+	d.liveMutex.Lock()
+	defer d.liveMutex.Unlock()
+
+	var updates []liveUpdate
+	for key, live := range d.live {
+		request := live.notification.Request
+
+		if d.resolve != nil {
+			current, exists := d.currentOccurrence(request)
+			switch {
+			case !exists:
+				updates = append(updates, d.replace(key, live, request, changeCancelled))
+				continue
+			case occurrenceChanged(request, current):
+				updates = append(updates, d.replace(key, live, current, changeUpdated))
+				continue
+			default:
+				// Reparsed without changes
+				live.notification.Request.Event = current.Event
+			}
+		}
+
+		start := occurrenceStart(request)
+		end := start.Add(request.Event.GetEndTime().Sub(request.Event.GetStartTime()))
+
+		switch {
+		case !now.Before(start) && live.shown.Before(start):
+			delete(d.live, key)
+			updates = append(updates, liveUpdate{id: live.id, title: live.notification.Title, close: true})
+
+		case !now.Before(end):
+			// Shown after the start, left to expire or be dismissed
+			delete(d.live, key)
+
+		case d.config.Desktop.Countdown && now.Before(start) && request.AlertKind == storage.AlertBeforeStart:
+			request.AlertOffset = start.Sub(now).Round(time.Minute)
+			updates = append(updates, newLiveUpdate(live, request, live.notification.Late, live.notification.Urgency, ""))
+		}
+	}
+	return updates
+}
+
+// currentOccurrence returns the alert request for the current version of
+// the occurrence an open notification is about, following moved events and
+// occurrences of recurring events that were modified on their own
+func (d *DBusNotifier) currentOccurrence(request alerts.AlertRequest) (alerts.AlertRequest, bool) {
+	uid := request.Event.GetUID()
+	eventTime := occurrenceStart(request)
+
+	if current, exists := d.resolve(uid); exists {
+		// Moving an event moves all of its occurrences
+		moved := eventTime.Add(current.GetStartTime().Sub(request.Event.GetStartTime()))
+		if storage.OccursAt(current, moved) {
+			request.Event, request.EventTime = current, moved
+			return request, true
+		}
+	}
+
+	// Occurrences modified on their own are stored as separate events
+	instanceUID := uid + "/" + eventTime.UTC().Format("20060102T150405Z")
+	if instance, exists := d.resolve(instanceUID); exists {
+		request.Event, request.EventTime = instance, instance.GetStartTime()
+		return request, true
+	}
+
+	return request, false
+}
+
+// occurrenceChanged reports whether the occurrence shown to the user changed
+func occurrenceChanged(shown, current alerts.AlertRequest) bool {
+	return !occurrenceStart(shown).Equal(occurrenceStart(current)) ||
+		shown.Event.GetEndTime().Sub(shown.Event.GetStartTime()) != current.Event.GetEndTime().Sub(current.Event.GetStartTime()) ||
+		shown.Event.GetSummary() != current.Event.GetSummary() ||
+		shown.Event.GetLocation() != current.Event.GetLocation()
+}
+
+// replace returns the replacement of a notification after its event
+// changed. Cancelled notifications are no longer tracked and expire like
+// normal ones. Must be called with liveMutex held.
+func (d *DBusNotifier) replace(key string, live *liveNotification, request alerts.AlertRequest, change string) liveUpdate {
+	delete(d.live, key)
+
+	if change == changeCancelled {
+		return newLiveUpdate(live, request, false, UrgencyNormal, change)
+	}
+
+	update := newLiveUpdate(live, request, live.notification.Late, live.notification.Urgency, change)
+	live.notification.Request = request
+	d.live[occurrenceKey(request)] = live
+	return update
+}
+
+// newLiveUpdate creates the update re-rendering an open notification for the request
+func newLiveUpdate(live *liveNotification, request alerts.AlertRequest, late bool, urgency UrgencyLevel, change string) liveUpdate {
+	return liveUpdate{
+		id:      live.id,
+		title:   live.notification.Title,
+		request: request,
+		late:    late,
+		urgency: urgency,
+		change:  change,
+	}
+}
+
+// apply closes an open notification, or re-renders and replaces it
+func (d *DBusNotifier) apply(update liveUpdate) {
+	if update.close {
+		if _, err := d.notifier.CloseNotification(update.id); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close notification for %s: %v\n", update.title, err)
+		}
+		return
+	}
+
+	notification, err := d.renderer.render(NotificationRequest{
+		AlertRequest: update.request,
+		Context:      NotificationContext{IsLate: update.late, Change: update.change},
+		Urgency:      update.urgency,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to update notification for %s: %v\n", update.title, err)
+		return
+	}
+
+	dbusNotification := d.dbusNotification(notification)
+	dbusNotification.ReplacesID = update.id
+	if _, err := d.notifier.SendNotification(dbusNotification); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to update notification for %s: %v\n", update.title, err)
+	}
+}
+
+// refreshLoop refreshes the open notifications at every minute until the
+// notifier is closed
+func (d *DBusNotifier) refreshLoop() {
+	timer := time.NewTimer(time.Until(time.Now().Truncate(time.Minute).Add(time.Minute)))
+	defer timer.Stop()
+
+	for {
+		select {
+		case now := <-timer.C:
+			d.refresh(now)
+			timer.Reset(time.Until(now.Truncate(time.Minute).Add(time.Minute)))
+		case <-d.stop:
+			return
+		}
+	}
+}
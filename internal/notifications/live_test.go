@@ -0,0 +1,292 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/esiqveland/notify"
+
+	"calwatch/internal/recurrence"
+	"calwatch/internal/storage"
+)
+
+// fakeNotificationServer records the notifications sent to it like a
+// desktop notification server, assigning IDs to new notifications
+type fakeNotificationServer struct {
+	sent   []notify.Notification
+	closed []uint32
+	nextID uint32
+}
+
+func (f *fakeNotificationServer) SendNotification(n notify.Notification) (uint32, error) {
+	f.sent = append(f.sent, n)
+	if n.ReplacesID != 0 {
+		return n.ReplacesID, nil
+	}
+	f.nextID++
+	return f.nextID, nil
+}
+
+func (f *fakeNotificationServer) GetCapabilities() ([]string, error) {
+	return []string{"body"}, nil
+}
+
+func (f *fakeNotificationServer) GetServerInformation() (notify.ServerInformation, error) {
+	return notify.ServerInformation{Name: "fake"}, nil
+}
+
+func (f *fakeNotificationServer) CloseNotification(id uint32) (bool, error) {
+	f.closed = append(f.closed, id)
+	return true, nil
+}
+
+func (f *fakeNotificationServer) Close() error {
+	return nil
+}
+
+// newLiveTestNotifier creates a D-Bus notifier with a fake server and a clock
+func newLiveTestNotifier(now *time.Time) (*DBusNotifier, *fakeNotificationServer) {
+	server := &fakeNotificationServer{}
+	notifier := newDBusNotifier(server)
+	notifier.renderer.now = func() time.Time { return *now }
+	return notifier, server
+}
+
+func TestDBusNotifier_ReplacesOccurrenceNotifications(t *testing.T) {
+	request := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+	start := request.Event.GetStartTime()
+	now := start.Add(-15 * time.Minute)
+	notifier, server := newLiveTestNotifier(&now)
+
+	if err := notifier.SendNotification(request); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+
+	// The 5 minute alert replaces the 15 minute one
+	now = start.Add(-5 * time.Minute)
+	request.AlertOffset = 5 * time.Minute
+	if err := notifier.SendNotification(request); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+
+	// The next occurrence gets its own notification
+	request.EventTime = start.Add(7 * 24 * time.Hour)
+	if err := notifier.SendNotification(request); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+
+	replaces := make([]uint32, len(server.sent))
+	for i, sent := range server.sent {
+		replaces[i] = sent.ReplacesID
+	}
+	if len(replaces) != 3 || replaces[0] != 0 || replaces[1] != 1 || replaces[2] != 0 {
+		t.Errorf("Expected ReplacesID 0, 1, 0, got %v", replaces)
+	}
+	if len(notifier.live) != 2 {
+		t.Errorf("Expected 2 open notifications, got %d", len(notifier.live))
+	}
+
+	// Dismissed notifications are forgotten
+	notifier.handleClosed(&notify.NotificationClosedSignal{ID: 1, Reason: notify.ReasonDismissedByUser})
+	if len(notifier.live) != 1 {
+		t.Errorf("Expected 1 open notification after dismissal, got %d", len(notifier.live))
+	}
+}
+
+func TestDBusNotifier_Refresh(t *testing.T) {
+	tests := []struct {
+		name            string
+		shownBefore     time.Duration // Time before the start the alert was shown
+		refreshAfter    time.Duration // Time after the start of the refresh
+		countdown       bool
+		expectClosed    bool
+		expectOpen      bool
+		expectCountdown string // Expected in the updated body, empty if no update
+	}{
+		{"countdown", 15 * time.Minute, -4 * time.Minute, true, false, true, "(4 minutes warning)"},
+		{"no countdown", 15 * time.Minute, -4 * time.Minute, false, false, true, ""},
+		{"closed at start", 15 * time.Minute, 0, true, true, false, ""},
+		{"shown at start kept", 0, 10 * time.Minute, false, false, true, ""},
+		{"shown at start forgotten at end", 0, time.Hour, false, false, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+			start := request.Event.GetStartTime()
+			now := start.Add(-tt.shownBefore)
+			notifier, server := newLiveTestNotifier(&now)
+
+			notifierConfig := defaultDesktopConfig("dbus")
+			notifierConfig.Desktop.Countdown = tt.countdown
+			notifier.SetConfig(notifierConfig)
+			notifier.SetEventResolver(func(uid string) (storage.Event, bool) {
+				return request.Event, true
+			})
+
+			request.AlertOffset = tt.shownBefore
+			if err := notifier.SendNotification(request); err != nil {
+				t.Fatalf("SendNotification() error = %v", err)
+			}
+
+			now = start.Add(tt.refreshAfter)
+			notifier.refresh(now)
+
+			if closed := len(server.closed) == 1; closed != tt.expectClosed {
+				t.Errorf("Expected closed %v, got closed IDs %v", tt.expectClosed, server.closed)
+			}
+			if open := len(notifier.live) == 1; open != tt.expectOpen {
+				t.Errorf("Expected open %v, got %d open notifications", tt.expectOpen, len(notifier.live))
+			}
+
+			if tt.expectCountdown == "" {
+				if len(server.sent) != 1 {
+					t.Errorf("Expected no update, got %d notifications", len(server.sent))
+				}
+				return
+			}
+			if len(server.sent) != 2 {
+				t.Fatalf("Expected an update, got %d notifications", len(server.sent))
+			}
+			update := server.sent[1]
+			if update.ReplacesID != 1 || !strings.Contains(update.Body, tt.expectCountdown) {
+				t.Errorf("Expected update of notification 1 with %q, got %d: %q", tt.expectCountdown, update.ReplacesID, update.Body)
+			}
+		})
+	}
+}
+
+func TestDBusNotifier_EventChanges(t *testing.T) {
+	request := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+	start := request.Event.GetStartTime()
+	newEvent := func(uid, summary string, start time.Time, rec recurrence.Recurrence) storage.Event {
+		return newTestAlertRequest("/test/path", summary, withDetails("Weekly sync", "Room 1"), withUID(uid),
+			startingAt(start), withRecurrence(rec)).Event
+	}
+	weekly, err := recurrence.ParseRRule("FREQ=WEEKLY")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		shown         storage.Event
+		current       map[string]storage.Event // Events by UID after the change
+		expectedTitle string                   // Empty if no update is expected
+		expectedStart time.Time                // Start of the occurrence tracked afterwards, zero if no longer tracked
+	}{
+		{"unchanged", request.Event, map[string]storage.Event{"test-uid": newEvent("test-uid", "Team Meeting", start, nil)},
+			"", start},
+		{"moved", request.Event, map[string]storage.Event{"test-uid": newEvent("test-uid", "Team Meeting", start.Add(time.Hour), nil)},
+			"Updated: Team Meeting", start.Add(time.Hour)},
+		{"renamed", request.Event, map[string]storage.Event{"test-uid": newEvent("test-uid", "Team Sync", start, nil)},
+			"Updated: Team Sync", start},
+		{"deleted", request.Event, map[string]storage.Event{},
+			"Cancelled: Team Meeting", time.Time{}},
+		{"series moved", newEvent("test-uid", "Team Meeting", start.Add(-7*24*time.Hour), weekly),
+			map[string]storage.Event{"test-uid": newEvent("test-uid", "Team Meeting", start.Add(-7*24*time.Hour+30*time.Minute), weekly)},
+			"Updated: Team Meeting", start.Add(30 * time.Minute)},
+		{"occurrence moved", newEvent("test-uid", "Team Meeting", start.Add(-7*24*time.Hour), weekly),
+			map[string]storage.Event{
+				"test-uid":                  newEvent("test-uid", "Team Meeting", start.Add(-14*24*time.Hour), &recurrence.NoRecurrence{}),
+				"test-uid/20240115T200000Z": newEvent("test-uid/20240115T200000Z", "Team Meeting", start.Add(2*time.Hour), nil),
+			},
+			"Updated: Team Meeting", start.Add(2 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := start.Add(-15 * time.Minute)
+			notifier, server := newLiveTestNotifier(&now)
+			notifier.SetEventResolver(func(uid string) (storage.Event, bool) {
+				event, exists := tt.current[uid]
+				return event, exists
+			})
+
+			request := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+			request.Event, request.EventTime = tt.shown, start
+			if err := notifier.SendNotification(request); err != nil {
+				t.Fatalf("SendNotification() error = %v", err)
+			}
+
+			notifier.RefreshEvents()
+
+			if tt.expectedTitle == "" {
+				if len(server.sent) != 1 {
+					t.Errorf("Expected no update, got %d notifications", len(server.sent))
+				}
+			} else {
+				if len(server.sent) != 2 {
+					t.Fatalf("Expected an update, got %d notifications", len(server.sent))
+				}
+				update := server.sent[1]
+				if update.ReplacesID != 1 || update.Summary != tt.expectedTitle {
+					t.Errorf("Expected update of notification 1 titled %q, got %d: %q", tt.expectedTitle, update.ReplacesID, update.Summary)
+				}
+			}
+
+			if tt.expectedStart.IsZero() {
+				if len(notifier.live) != 0 {
+					t.Errorf("Expected no open notifications, got %d", len(notifier.live))
+				}
+				return
+			}
+			if len(notifier.live) != 1 {
+				t.Fatalf("Expected 1 open notification, got %d", len(notifier.live))
+			}
+			for _, live := range notifier.live {
+				if got := occurrenceStart(live.notification.Request); !got.Equal(tt.expectedStart) {
+					t.Errorf("Expected tracked occurrence at %v, got %v", tt.expectedStart, got)
+				}
+			}
+		})
+	}
+}
+
+// blockingNotificationServer is a fake notification server that does not
+// answer until released
+type blockingNotificationServer struct {
+	fakeNotificationServer
+	sending chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingNotificationServer) SendNotification(n notify.Notification) (uint32, error) {
+	b.sending <- struct{}{}
+	<-b.release
+	return b.fakeNotificationServer.SendNotification(n)
+}
+
+func TestDBusNotifier_SlowServer(t *testing.T) {
+	request := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+	now := request.Event.GetStartTime().Add(-15 * time.Minute)
+	server := &blockingNotificationServer{sending: make(chan struct{}), release: make(chan struct{})}
+	notifier := newDBusNotifier(server)
+	notifier.renderer.now = func() time.Time { return now }
+
+	sent := make(chan error)
+	go func() { sent <- notifier.SendNotification(request) }()
+	<-server.sending
+
+	// Closed signals and refreshes go on while the server is busy
+	handled := make(chan struct{})
+	go func() {
+		notifier.handleClosed(&notify.NotificationClosedSignal{ID: 42})
+		notifier.refresh(now)
+		close(handled)
+	}()
+	select {
+	case <-handled:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the notifier not to wait for the notification server")
+	}
+
+	close(server.release)
+	if err := <-sent; err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+	if len(notifier.live) != 1 {
+		t.Errorf("Expected the notification to be tracked, got %d", len(notifier.live))
+	}
+}
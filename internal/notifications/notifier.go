@@ -1,12 +1,13 @@
 package notifications
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
@@ -15,6 +16,8 @@ import (
 	"github.com/godbus/dbus/v5"
 	"calwatch/internal/alerts"
 	"calwatch/internal/config"
+	"calwatch/internal/localday"
+	"calwatch/internal/recurrence"
 	"calwatch/internal/storage"
 )
 
@@ -27,25 +30,106 @@ const (
 	UrgencyCritical                     // 2 - D-Bus Critical
 )
 
-// TemplateData represents the data available to notification templates
+// String returns the notify-send name of the urgency level
+func (u UrgencyLevel) String() string {
+	switch u {
+	case UrgencyLow:
+		return "low"
+	case UrgencyCritical:
+		return "critical"
+	default:
+		return "normal"
+	}
+}
+
+// TemplateData represents the data available to notification templates.
+// The JSON names are also used by the exec backend (CALWATCH_<NAME> variables).
 type TemplateData struct {
-	Summary     string
-	Description string
-	Location    string
-	StartTime   string
-	EndTime     string
-	Duration    string
-	Organizer   string
-	Attendees   []string
-	AlertOffset string
-	UID         string
+	Summary     string   `json:"summary"`
+	Description string   `json:"description"`
+	Location    string   `json:"location"`
+	StartTime   string   `json:"start_time"` // Local time of day, e.g. "14:30"
+	EndTime     string   `json:"end_time"`
+	Duration    string   `json:"duration"`
+	Organizer   string   `json:"organizer"`
+	Attendees   []string `json:"attendees"`
+	AlertOffset string   `json:"alert_offset"` // Time until the start, or the end for before_end alerts
+	AlertKind   string   `json:"alert_kind"`   // "before_start", "at_start", "before_end", "at_end", "ongoing" or "all_day"
+	UID         string   `json:"uid"`
+
+	// Occurrence the alert is for, in the configured local timezone
+	Start      time.Time `json:"start"` // For custom formats, e.g. {{.Start.Format "Mon 2 Jan 15:04"}}
+	End        time.Time `json:"end"`
+	Date       string    `json:"date"`     // e.g. "2024-01-15"
+	Weekday    string    `json:"weekday"`  // e.g. "Monday"
+	Relative   string    `json:"relative"` // e.g. "in 15 minutes", "tomorrow at 10:00"
+	AllDay     bool      `json:"all_day"`
+	Calendar   string    `json:"calendar"`   // Display name of the calendar
+	Recurrence string    `json:"recurrence"` // e.g. "every week on Monday", empty for single events
+
+	// Alert details
+	Late             bool   `json:"late"`              // Missed or delayed alert
+	Important        bool   `json:"important"`
+	AlertDescription string `json:"alert_description"` // VALARM description
+	Change           string `json:"change"`            // "new", "moved", "location" or "cancelled" for change alerts, "updated" or "cancelled" if the event changed after the alert
+
+	// Previous values for change alerts, empty if unchanged
+	OldStart     time.Time `json:"old_start"`
+	OldEnd       time.Time `json:"old_end"`
+	OldStartTime string    `json:"old_start_time"`
+	OldDate      string    `json:"old_date"`
+	OldRelative  string    `json:"old_relative"`
+	OldLocation  string    `json:"old_location"`
+
+	// Occurrences of the day sorted by start for agenda digests, e.g.
+	// {{range .Agenda}}{{.StartTime}} {{.Summary}}{{end}}
+	Digest bool           `json:"digest"`
+	Agenda []TemplateData `json:"agenda,omitempty"`
+
+	// Capabilities of the desktop notification server, e.g. {{if .Caps.Markup}}
+	Caps Capabilities `json:"-"`
+
+	// Task fields (VTODO reminders), empty for regular events
+	IsTask          bool   `json:"is_task"`
+	Due             string `json:"due"`      // Due date, e.g. "2024-01-15 17:00"
+	Status          string `json:"status"`   // NEEDS-ACTION, IN-PROCESS, ...
+	PercentComplete int    `json:"percent_complete"`
+	Priority        string `json:"priority"` // "high", "medium", "low" or empty if undefined
+}
+
+// Built-in template used when no template is configured or loading fails
+const defaultTemplateText = `{{.Summary}}{{if .Location}} at {{.Location}}{{end}}
+{{if eq .AlertKind "at_start"}}Starting now, until {{.EndTime}}{{else if and (eq .AlertKind "before_end") .AlertOffset}}Ends at {{.EndTime}} ({{.AlertOffset}} left){{else if or (eq .AlertKind "at_end") (eq .AlertKind "before_end")}}Ended at {{.EndTime}}{{else if eq .AlertKind "ongoing"}}Ongoing until {{.End.Format "Mon 2 Jan 15:04"}}{{else if eq .AlertKind "all_day"}}All day {{.Relative}}{{else}}{{if .Due}}Due: {{.Due}}{{else}}Starts: {{.StartTime}}{{end}}{{if .AlertOffset}} ({{.AlertOffset}} warning){{end}}{{end}}`
+
+// Built-in template for change alerts showing the old and new values
+const defaultChangeTemplateText = `{{.Summary}}{{if .Location}} at {{.Location}}{{end}}
+{{if eq .Change "moved"}}Moved from {{.OldRelative}} to {{.Relative}}{{else if eq .Change "location"}}Location changed from {{default "unknown" .OldLocation}}, {{.Relative}}{{else if eq .Change "cancelled"}}Was {{.Relative}}{{else}}Starts {{.Relative}}{{end}}`
+
+// Built-in template for agenda digests
+const defaultAgendaTemplateText = `{{range .Agenda}}{{if .AllDay}}All day{{else}}{{.StartTime}}{{end}}  {{.Summary}}{{if .Location}} at {{.Location}}{{end}}
+{{else}}No events today
+{{end}}`
+
+// Title prefixes of change alerts
+var changeTitlePrefixes = map[string]string{
+	alerts.ChangeNew:       "New: ",
+	alerts.ChangeMoved:     "Moved: ",
+	alerts.ChangeLocation:  "Location changed: ",
+	alerts.ChangeCancelled: "Cancelled: ",
 }
 
 // NotificationContext provides context about the notification type
 type NotificationContext struct {
-	IsLate bool // Whether this is a missed/late notification
+	IsLate bool   // Whether this is a missed/late notification
+	Change string // changeUpdated or changeCancelled when replacing a notification after its event changed
 }
 
+// Changes of events with open notifications
+const (
+	changeUpdated   = "updated"
+	changeCancelled = "cancelled"
+)
+
 // NotificationRequest combines an alert request with notification context
 type NotificationRequest struct {
 	AlertRequest alerts.AlertRequest
@@ -64,241 +148,201 @@ type Notifier interface {
 
 // NotifySendNotifier implements Notifier using notify-send
 type NotifySendNotifier struct {
-	config        config.NotificationConfig
-	templates     map[string]*template.Template
-	defaultTemplate *template.Template
+	*transportNotifier
 }
 
 // NewNotifySendNotifier creates a new notify-send based notifier
 func NewNotifySendNotifier() *NotifySendNotifier {
-	notifier := &NotifySendNotifier{
-		templates: make(map[string]*template.Template),
-		config: config.NotificationConfig{
-			Backend: "notify-send",
-			Duration: config.DurationConfig{
-				Type:  "timed",
-				Value: 5,
-				Unit:  "seconds",
-			},
-			DurationWhenLate: config.DurationConfig{
-				Type: "until_dismissed",
-			},
-		},
-	}
-
-	// Load default template
-	notifier.defaultTemplate = notifier.createDefaultTemplate()
+	notifier := &NotifySendNotifier{}
+	notifier.transportNotifier = newTransportNotifier(notifier)
+	notifier.notifyErrors = true
+	notifier.config = defaultDesktopConfig("notify-send")
 
 	return notifier
 }
 
-// SetConfig sets the notification configuration
-func (n *NotifySendNotifier) SetConfig(config config.NotificationConfig) {
-	n.config = config
-}
-
-// SendNotification sends a notification for an alert request (using normal duration)
-func (n *NotifySendNotifier) SendNotification(request alerts.AlertRequest) error {
-	// Map AlertRequest flags to NotificationRequest
-	urgency := UrgencyNormal
-	if request.Important {
-		urgency = UrgencyCritical
+// defaultDesktopConfig returns the durations desktop notifiers use until configured
+func defaultDesktopConfig(backend string) config.NotificationConfig {
+	return config.NotificationConfig{
+		Backend: backend,
+		Duration: config.DurationConfig{
+			Type:  "timed",
+			Value: 5,
+			Unit:  "seconds",
+		},
+		DurationWhenLate: config.DurationConfig{
+			Type: "until_dismissed",
+		},
 	}
-	
-	return n.SendNotificationWithContext(NotificationRequest{
-		AlertRequest: request,
-		Context:      NotificationContext{IsLate: request.Late},
-		Urgency:      urgency,
-	})
 }
 
-// SendNotificationWithContext sends a notification with context (normal vs late)
-func (n *NotifySendNotifier) SendNotificationWithContext(request NotificationRequest) error {
-	// Create template data from the event
-	data := n.createTemplateData(request.AlertRequest.Event, request.AlertRequest.AlertOffset)
+// newTemplateData creates the template data shared by all notifiers for the
+// occurrence an alert is for
+func newTemplateData(request alerts.AlertRequest, now time.Time) TemplateData {
+	event := request.Event
+	duration := event.GetEndTime().Sub(event.GetStartTime())
 
-	// Get the template to use
-	tmpl, err := n.getTemplate(request.AlertRequest.Template)
-	if err != nil {
-		// If template loading fails, send error notification and fall back to default
-		n.sendErrorNotification(request.AlertRequest, err)
-		tmpl = n.defaultTemplate
+	startTime := request.EventTime
+	if startTime.IsZero() {
+		startTime = event.GetStartTime()
 	}
 
-	// Render the template
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		// If template execution fails, send error notification and fall back to default
-		n.sendErrorNotification(request.AlertRequest, fmt.Errorf("template execution failed: %w", err))
-		
-		// Use default template
-		buf.Reset()
-		if err := n.defaultTemplate.Execute(&buf, data); err != nil {
-			return fmt.Errorf("failed to execute default template: %w", err)
-		}
-	}
-
-	// Send the notification with appropriate duration and urgency
-	return n.sendDesktopNotificationWithUrgency(data.Summary, buf.String(), request.Context, request.Urgency)
-}
+	// Format times in the configured local timezone
+	localStart := startTime.In(localday.Location())
+	localEnd := startTime.Add(duration).In(localday.Location())
 
-// createTemplateData creates template data from an event
-func (n *NotifySendNotifier) createTemplateData(event storage.Event, alertOffset time.Duration) TemplateData {
-	startTime := event.GetStartTime()
-	endTime := event.GetEndTime()
-	duration := endTime.Sub(startTime)
-
-	// Format times in local timezone
-	localStart := startTime.In(time.Local)
-	localEnd := endTime.In(time.Local)
-
-	return TemplateData{
+	data := TemplateData{
 		Summary:     event.GetSummary(),
 		Description: event.GetDescription(),
 		Location:    event.GetLocation(),
 		StartTime:   localStart.Format("15:04"),
 		EndTime:     localEnd.Format("15:04"),
 		Duration:    formatDuration(duration),
-		AlertOffset: formatDuration(alertOffset),
+		AlertOffset: formatDuration(request.AlertOffset),
+		AlertKind:   request.AlertKind.String(),
 		UID:         event.GetUID(),
 		// TODO: Add organizer and attendees when available in storage.Event
 		Organizer:   "",
 		Attendees:   []string{},
-	}
-}
 
-// getTemplate retrieves or loads a template by name
-func (n *NotifySendNotifier) getTemplate(templateName string) (*template.Template, error) {
-	// If empty template name, use default
-	if templateName == "" {
-		return n.defaultTemplate, nil
-	}
+		Start:   localStart,
+		End:     localEnd,
+		Date:    localStart.Format("2006-01-02"),
+		Weekday: localStart.Weekday().String(),
 
-	// Check if template is already loaded
-	if tmpl, exists := n.templates[templateName]; exists {
-		return tmpl, nil
+		Late:             request.Late,
+		Important:        request.Important,
+		AlertDescription: request.Description,
 	}
 
-	// Try to load template from XDG config directories
-	templatePath, err := xdg.SearchConfigFile(filepath.Join("calwatch", "templates", templateName))
-	if err != nil {
-		// Try to load from the default templates directory
-		templatePath = filepath.Join("templates", templateName)
+	if member, ok := event.(storage.CalendarMember); ok && member.GetCalendar() != nil {
+		data.Calendar = member.GetCalendar().DisplayName()
 	}
-
-	tmpl, err := n.LoadTemplate(templatePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load template %s: %w", templateName, err)
+	if calendarEvent := storage.BaseCalendarEvent(event); calendarEvent != nil {
+		data.AllDay = calendarEvent.AllDay
+		data.Recurrence = recurrence.Describe(calendarEvent.Recurrence, calendarEvent.StartTime)
 	}
+	data.Relative = relativeTime(localStart, data.AllDay, now)
 
-	// Cache the template
-	n.templates[templateName] = tmpl
-	return tmpl, nil
-}
-
-// LoadTemplate loads a template from a file path
-func (n *NotifySendNotifier) LoadTemplate(path string) (*template.Template, error) {
-	// Check if file exists
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return nil, fmt.Errorf("template file does not exist: %s", path)
+	// Only alerts before the start or end have an offset to show
+	switch request.AlertKind {
+	case storage.AlertBeforeStart:
+		if request.AlertOffset < 0 {
+			data.AlertOffset = ""
+		}
+	case storage.AlertBeforeEnd:
+		data.AlertOffset = ""
+		if left := duration + request.AlertOffset; left > 0 {
+			data.AlertOffset = formatDuration(left)
+		}
+	case storage.AlertAtStart, storage.AlertAtEnd, storage.AlertOngoing, storage.AlertAllDay:
+		data.AlertOffset = ""
 	}
 
-	// Read template content
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read template file %s: %w", path, err)
+	if request.Digest {
+		data.Digest = true
+		data.Agenda = make([]TemplateData, 0, len(request.Agenda))
+		for _, item := range request.Agenda {
+			data.Agenda = append(data.Agenda, newTemplateData(alerts.AlertRequest{Event: item.Event, EventTime: item.Start}, now))
+		}
 	}
 
-	// Parse template
-	tmpl, err := template.New(filepath.Base(path)).Parse(string(content))
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	if change := request.Change; change != nil {
+		data.Change = change.Kind
+		data.OldLocation = change.OldLocation
+		if !change.OldStart.IsZero() {
+			data.OldStart = change.OldStart.In(localday.Location())
+			data.OldEnd = change.OldEnd.In(localday.Location())
+			data.OldStartTime = data.OldStart.Format("15:04")
+			data.OldDate = data.OldStart.Format("2006-01-02")
+			data.OldRelative = relativeTime(data.OldStart, data.AllDay, now)
+		}
 	}
 
-	return tmpl, nil
-}
+	addTaskData(&data, event)
 
-// ValidateTemplate validates a template with sample data
-func (n *NotifySendNotifier) ValidateTemplate(tmpl *template.Template, data TemplateData) error {
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return fmt.Errorf("template validation failed: %w", err)
-	}
-	return nil
+	return data
 }
 
-// createDefaultTemplate creates the built-in default template
-func (n *NotifySendNotifier) createDefaultTemplate() *template.Template {
-	defaultTemplateText := `{{.Summary}}{{if .Location}} at {{.Location}}{{end}}
-Starts: {{.StartTime}} ({{.AlertOffset}} warning)`
-
-	tmpl, err := template.New("default").Parse(defaultTemplateText)
-	if err != nil {
-		// This should never happen with our static template
-		panic(fmt.Sprintf("Failed to create default template: %v", err))
+// relativeTime describes when an occurrence starts relative to now, e.g.
+// "in 15 minutes", "tomorrow at 10:00" or "started 5 minutes ago"
+func relativeTime(start time.Time, allDay bool, now time.Time) string {
+	days := localday.DaysBetween(localday.Of(now), localday.Of(start))
+	clock := " at " + start.Format("15:04")
+	if allDay {
+		clock = ""
 	}
 
-	return tmpl
-}
-
-// sendErrorNotification sends a notification about template errors
-func (n *NotifySendNotifier) sendErrorNotification(request alerts.AlertRequest, err error) {
-	title := "Calendar Notification Error"
-	message := fmt.Sprintf("Event: %s at %s\nTemplate Error: %s\nTemplate: %s",
-		request.Event.GetSummary(),
-		request.Event.GetStartTime().Format("15:04"),
-		err.Error(),
-		request.Template,
-	)
-
-	// Use notify-send directly to avoid infinite recursion
-	n.sendDesktopNotification(title, message)
+	until := start.Sub(now).Round(time.Minute)
+	switch {
+	case allDay && days == 0:
+		return "today"
+	case !allDay && until < 0:
+		return "started " + formatDuration(-until) + " ago"
+	case !allDay && until == 0:
+		return "now"
+	case !allDay && until < time.Hour:
+		return "in " + formatDuration(until)
+	case days == 0:
+		return "today" + clock
+	case days == 1:
+		return "tomorrow" + clock
+	case days > 1 && days < 7:
+		return "on " + start.Weekday().String() + clock
+	case days < 0:
+		return "on " + start.Format("2 January") + clock
+	}
+	return "on " + start.Format("Monday, 2 January") + clock
 }
 
-// sendDesktopNotification sends a notification using notify-send (backward compatibility)
-func (n *NotifySendNotifier) sendDesktopNotification(title, message string) error {
-	return n.sendDesktopNotificationWithContext(title, message, NotificationContext{IsLate: false})
-}
+// addTaskData fills the task specific template fields for VTODO reminders
+func addTaskData(data *TemplateData, event storage.Event) {
+	task, ok := event.(*storage.TaskEvent)
+	if !ok {
+		return
+	}
 
-// sendDesktopNotificationWithContext sends a notification with context-appropriate duration
-func (n *NotifySendNotifier) sendDesktopNotificationWithContext(title, message string, context NotificationContext) error {
-	return n.sendDesktopNotificationWithUrgency(title, message, context, UrgencyNormal)
+	data.IsTask = true
+	data.Status = task.Status
+	data.PercentComplete = task.PercentComplete
+	data.Priority = task.PriorityLabel()
+	if task.Due != nil {
+		// Reminders are anchored at the due date of the occurrence
+		data.Due = data.Start.Format("2006-01-02 15:04")
+	}
 }
 
-// sendDesktopNotificationWithUrgency sends a notification with full context including urgency
-func (n *NotifySendNotifier) sendDesktopNotificationWithUrgency(title, message string, context NotificationContext, urgency UrgencyLevel) error {
-	// Choose duration based on context
-	var durationConfig config.DurationConfig
-	if context.IsLate {
-		durationConfig = n.config.DurationWhenLate
-	} else {
-		durationConfig = n.config.Duration
-	}
-	
-	// Convert duration to milliseconds
-	durationMs, err := durationConfig.ToMilliseconds()
-	if err != nil {
-		// Fallback to 5 seconds if conversion fails
-		durationMs = 5000
-	}
-	
+// Deliver sends a notification using notify-send, with the duration and urgency it calls for
+func (n *NotifySendNotifier) Deliver(notification Notification) error {
 	// Map urgency level to notify-send urgency flag
 	urgencyFlag := map[UrgencyLevel]string{
 		UrgencyLow:      "--urgency=low",
 		UrgencyNormal:   "--urgency=normal",
 		UrgencyCritical: "--urgency=critical",
-	}[urgency]
+	}[notification.Urgency]
 	
 	// Prepare notify-send command
 	args := []string{
 		"notify-send",
 		"--app-name=calwatch",
 		urgencyFlag,
-		fmt.Sprintf("--expire-time=%d", durationMs),
+		fmt.Sprintf("--expire-time=%d", n.expireMilliseconds(notification.Late)),
+	}
+
+	// notify-send cannot query capabilities, the server ignores unsupported hints
+	desktop := n.config.Desktop
+	if desktop.Image != "" {
+		args = append(args, "--hint=string:image-path:"+desktop.Image)
+	}
+	if desktop.Category != "" {
+		args = append(args, "--category="+desktop.Category)
+	}
+	if sound := desktopSound(desktop, notification); sound != "" {
+		args = append(args, "--hint=string:sound-name:"+sound)
 	}
 
 	// Add title and message
-	args = append(args, title, message)
+	args = append(args, notification.Title, notification.Body)
 
 	// Execute notify-send
 	cmd := exec.Command(args[0], args[1:]...)
@@ -344,14 +388,19 @@ func formatDuration(d time.Duration) string {
 
 // DBusNotifier implements Notifier using D-Bus directly
 type DBusNotifier struct {
-	config        config.NotificationConfig
-	templates     map[string]*template.Template
-	defaultTemplate *template.Template
-	conn          *dbus.Conn
-	notifier      notify.Notifier
+	*transportNotifier
+	conn     *dbus.Conn
+	notifier notify.Notifier
+
+	// Open notifications by event occurrence, see live.go
+	live      map[string]*liveNotification
+	resolve   func(uid string) (storage.Event, bool)
+	liveMutex sync.Mutex
+	stop      chan struct{}
 }
 
-// NewDBusNotifier creates a new D-Bus based notifier
+// NewDBusNotifier creates a new D-Bus based notifier and queries the
+// capabilities of the notification server
 func NewDBusNotifier() (*DBusNotifier, error) {
 	// Connect to session D-Bus
 	conn, err := dbus.ConnectSessionBus()
@@ -359,237 +408,74 @@ func NewDBusNotifier() (*DBusNotifier, error) {
 		return nil, fmt.Errorf("failed to connect to session D-Bus: %w", err)
 	}
 
+	dbusNotifier := newDBusNotifier(nil)
+	dbusNotifier.conn = conn
+
 	// Create notifier
-	notifier, err := notify.New(conn)
+	notifier, err := notify.New(conn, notify.WithOnClosed(dbusNotifier.handleClosed))
 	if err != nil {
 		conn.Close()
 		return nil, fmt.Errorf("failed to create D-Bus notifier: %w", err)
 	}
+	dbusNotifier.notifier = notifier
 
-	dbusNotifier := &DBusNotifier{
-		templates: make(map[string]*template.Template),
-		config: config.NotificationConfig{
-			Backend: "dbus",
-			Duration: config.DurationConfig{
-				Type:  "timed",
-				Value: 5,
-				Unit:  "seconds",
-			},
-			DurationWhenLate: config.DurationConfig{
-				Type: "until_dismissed",
-			},
-		},
-		conn:     conn,
-		notifier: notifier,
-	}
-
-	// Load default template
-	dbusNotifier.defaultTemplate = dbusNotifier.createDefaultTemplate()
-
-	return dbusNotifier, nil
-}
-
-// Close closes the D-Bus connection
-func (d *DBusNotifier) Close() error {
-	if d.conn != nil {
-		return d.conn.Close()
-	}
-	return nil
-}
-
-// SetConfig sets the notification configuration
-func (d *DBusNotifier) SetConfig(config config.NotificationConfig) {
-	d.config = config
-}
-
-// SendNotification sends a notification for an alert request (using normal duration)
-func (d *DBusNotifier) SendNotification(request alerts.AlertRequest) error {
-	// Map AlertRequest flags to NotificationRequest
-	urgency := UrgencyNormal
-	if request.Important {
-		urgency = UrgencyCritical
-	}
-	
-	return d.SendNotificationWithContext(NotificationRequest{
-		AlertRequest: request,
-		Context:      NotificationContext{IsLate: request.Late},
-		Urgency:      urgency,
-	})
-}
-
-// SendNotificationWithContext sends a notification with context (normal vs late)
-func (d *DBusNotifier) SendNotificationWithContext(request NotificationRequest) error {
-	// Create template data from the event
-	data := d.createTemplateData(request.AlertRequest.Event, request.AlertRequest.AlertOffset)
-
-	// Get the template to use
-	tmpl, err := d.getTemplate(request.AlertRequest.Template)
+	capabilities, err := notifier.GetCapabilities()
 	if err != nil {
-		// If template loading fails, send error notification and fall back to default
-		d.sendErrorNotification(request.AlertRequest, err)
-		tmpl = d.defaultTemplate
+		// Assume a minimal server, markup is stripped
+		fmt.Fprintf(os.Stderr, "Warning: failed to query notification server capabilities: %v\n", err)
 	}
+	dbusNotifier.renderer.capabilities = parseCapabilities(capabilities)
 
-	// Render the template
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		// If template execution fails, send error notification and fall back to default
-		d.sendErrorNotification(request.AlertRequest, fmt.Errorf("template execution failed: %w", err))
-		
-		// Use default template
-		buf.Reset()
-		if err := d.defaultTemplate.Execute(&buf, data); err != nil {
-			return fmt.Errorf("failed to execute default template: %w", err)
-		}
-	}
+	go dbusNotifier.refreshLoop()
 
-	// Send the notification with appropriate duration and urgency
-	return d.sendDesktopNotificationWithUrgency(data.Summary, buf.String(), request.Context, request.Urgency)
-}
-
-// createTemplateData creates template data from an event
-func (d *DBusNotifier) createTemplateData(event storage.Event, alertOffset time.Duration) TemplateData {
-	startTime := event.GetStartTime()
-	endTime := event.GetEndTime()
-	duration := endTime.Sub(startTime)
-
-	// Format times in local timezone
-	localStart := startTime.In(time.Local)
-	localEnd := endTime.In(time.Local)
-
-	return TemplateData{
-		Summary:     event.GetSummary(),
-		Description: event.GetDescription(),
-		Location:    event.GetLocation(),
-		StartTime:   localStart.Format("15:04"),
-		EndTime:     localEnd.Format("15:04"),
-		Duration:    formatDuration(duration),
-		AlertOffset: formatDuration(alertOffset),
-		UID:         event.GetUID(),
-		// TODO: Add organizer and attendees when available in storage.Event
-		Organizer:   "",
-		Attendees:   []string{},
-	}
+	return dbusNotifier, nil
 }
 
-// getTemplate retrieves or loads a template by name
-func (d *DBusNotifier) getTemplate(templateName string) (*template.Template, error) {
-	// If empty template name, use default
-	if templateName == "" {
-		return d.defaultTemplate, nil
-	}
-
-	// Check if template is already loaded
-	if tmpl, exists := d.templates[templateName]; exists {
-		return tmpl, nil
-	}
-
-	// Try to load template from XDG config directories
-	templatePath, err := xdg.SearchConfigFile(filepath.Join("calwatch", "templates", templateName))
-	if err != nil {
-		// Try to load from the default templates directory
-		templatePath = filepath.Join("templates", templateName)
-	}
-
-	tmpl, err := d.LoadTemplate(templatePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load template %s: %w", templateName, err)
+// newDBusNotifier creates a D-Bus notifier sending to the given notification server
+func newDBusNotifier(notifier notify.Notifier) *DBusNotifier {
+	dbusNotifier := &DBusNotifier{
+		notifier: notifier,
+		live:     make(map[string]*liveNotification),
+		stop:     make(chan struct{}),
 	}
-
-	// Cache the template
-	d.templates[templateName] = tmpl
-	return tmpl, nil
+	dbusNotifier.transportNotifier = newTransportNotifier(dbusNotifier)
+	dbusNotifier.notifyErrors = true
+	dbusNotifier.renderer.markup = true
+	dbusNotifier.config = defaultDesktopConfig("dbus")
+	return dbusNotifier
 }
 
-// LoadTemplate loads a template from a file path
-func (d *DBusNotifier) LoadTemplate(path string) (*template.Template, error) {
-	// Check if file exists
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return nil, fmt.Errorf("template file does not exist: %s", path)
-	}
-
-	// Read template content
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read template file %s: %w", path, err)
-	}
-
-	// Parse template
-	tmpl, err := template.New(filepath.Base(path)).Parse(string(content))
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+// Close stops updating notifications and closes the D-Bus connection
+func (d *DBusNotifier) Close() error {
+	close(d.stop)
+	if d.notifier != nil {
+		d.notifier.Close()
 	}
-
-	return tmpl, nil
-}
-
-// ValidateTemplate validates a template with sample data
-func (d *DBusNotifier) ValidateTemplate(tmpl *template.Template, data TemplateData) error {
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return fmt.Errorf("template validation failed: %w", err)
+	if d.conn != nil {
+		return d.conn.Close()
 	}
 	return nil
 }
 
-// createDefaultTemplate creates the built-in default template
-func (d *DBusNotifier) createDefaultTemplate() *template.Template {
-	defaultTemplateText := `{{.Summary}}{{if .Location}} at {{.Location}}{{end}}
-Starts: {{.StartTime}} ({{.AlertOffset}} warning)`
-
-	tmpl, err := template.New("default").Parse(defaultTemplateText)
-	if err != nil {
-		// This should never happen with our static template
-		panic(fmt.Sprintf("Failed to create default template: %v", err))
+// Deliver sends a notification over D-Bus, with the duration and urgency it
+// calls for, replacing the notification shown for an earlier alert of the
+// same event occurrence
+func (d *DBusNotifier) Deliver(notification Notification) error {
+	if err := d.send(notification); err != nil {
+		return fmt.Errorf("failed to send D-Bus notification: %w", err)
 	}
 
-	return tmpl
-}
-
-// sendErrorNotification sends a notification about template errors
-func (d *DBusNotifier) sendErrorNotification(request alerts.AlertRequest, err error) {
-	title := "Calendar Notification Error"
-	message := fmt.Sprintf("Event: %s at %s\nTemplate Error: %s\nTemplate: %s",
-		request.Event.GetSummary(),
-		request.Event.GetStartTime().Format("15:04"),
-		err.Error(),
-		request.Template,
-	)
-
-	// Use D-Bus directly to avoid infinite recursion
-	d.sendDesktopNotification(title, message)
-}
-
-// sendDesktopNotification sends a notification using D-Bus (backward compatibility)
-func (d *DBusNotifier) sendDesktopNotification(title, message string) error {
-	return d.sendDesktopNotificationWithContext(title, message, NotificationContext{IsLate: false})
+	return nil
 }
 
-// sendDesktopNotificationWithContext sends a notification with context-appropriate duration
-func (d *DBusNotifier) sendDesktopNotificationWithContext(title, message string, context NotificationContext) error {
-	return d.sendDesktopNotificationWithUrgency(title, message, context, UrgencyNormal)
-}
+// dbusNotification converts a notification for the notification server,
+// stripping markup and leaving out images and sounds the server does not support
+func (d *DBusNotifier) dbusNotification(notification Notification) notify.Notification {
+	capabilities := d.renderer.capabilities
 
-// sendDesktopNotificationWithUrgency sends a notification with full context including urgency
-func (d *DBusNotifier) sendDesktopNotificationWithUrgency(title, message string, context NotificationContext, urgency UrgencyLevel) error {
-	// Choose duration based on context
-	var durationConfig config.DurationConfig
-	if context.IsLate {
-		durationConfig = d.config.DurationWhenLate
-	} else {
-		durationConfig = d.config.Duration
-	}
-	
-	// Convert duration to milliseconds
-	durationMs, err := durationConfig.ToMilliseconds()
-	if err != nil {
-		// Fallback to 5 seconds if conversion fails
-		durationMs = 5000
-	}
-	
 	// Map urgency level to D-Bus urgency hint
 	hints := map[string]dbus.Variant{}
-	switch urgency {
+	switch notification.Urgency {
 	case UrgencyLow:
 		hints["urgency"] = dbus.MakeVariant(byte(0))
 	case UrgencyNormal:
@@ -597,90 +483,207 @@ func (d *DBusNotifier) sendDesktopNotificationWithUrgency(title, message string,
 	case UrgencyCritical:
 		hints["urgency"] = dbus.MakeVariant(byte(2))
 	}
-	
-	notification := notify.Notification{
+
+	desktop := d.config.Desktop
+	if desktop.Image != "" && capabilities.IconStatic {
+		hints["image-path"] = dbus.MakeVariant(desktop.Image)
+	}
+	if desktop.Category != "" {
+		hints["category"] = dbus.MakeVariant(desktop.Category)
+	}
+	if sound := desktopSound(desktop, notification); sound != "" && capabilities.Sound {
+		hints["sound-name"] = dbus.MakeVariant(sound)
+	}
+
+	body := notification.Body
+	if notification.Markup && !capabilities.Markup {
+		body = stripMarkup(body)
+	}
+
+	return notify.Notification{
 		AppName:       "calwatch",
 		ReplacesID:    0,
 		AppIcon:       "calendar",
-		Summary:       title,
-		Body:          message,
+		Summary:       notification.Title,
+		Body:          body,
 		Actions:       []notify.Action{},
 		Hints:         hints,
-		ExpireTimeout: time.Duration(durationMs) * time.Millisecond,
+		ExpireTimeout: time.Duration(d.expireMilliseconds(notification.Late)) * time.Millisecond,
 	}
+}
 
-	_, err = d.notifier.SendNotification(notification)
-	if err != nil {
-		return fmt.Errorf("failed to send D-Bus notification: %w", err)
+// desktopSound returns the sound configured for a notification, or ""
+func desktopSound(desktop config.DesktopConfig, notification Notification) string {
+	if notification.Silent {
+		return ""
 	}
-
-	return nil
+	if notification.Important && desktop.ImportantSound != "" {
+		return desktop.ImportantSound
+	}
+	return desktop.Sound
 }
 
 // NotificationManager coordinates multiple notifiers
 type NotificationManager struct {
-	notifiers []Notifier
-	config    config.NotificationConfig
+	backends   []*notificationBackend
+	routes     []config.RouteConfig
+	classifier *alerts.PriorityClassifier
+	now        func() time.Time // Clock for time of day routing
+	config     config.NotificationConfig
 }
 
-// NewNotificationManager creates a new notification manager
+// notificationBackend is a notifier with the name it is routed by
+type notificationBackend struct {
+	name     string
+	notifier Notifier
+}
+
+// NewNotificationManager creates a new notification manager with either the
+// named backends or the single configured backend
 func NewNotificationManager(config config.NotificationConfig) *NotificationManager {
 	manager := &NotificationManager{
-		config:    config,
-		notifiers: make([]Notifier, 0),
+		config:     config,
+		routes:     config.Routes,
+		classifier: alerts.NewPriorityClassifier(),
+		now:        time.Now,
+	}
+
+	if len(config.Backends) == 0 {
+		manager.AddNamedNotifier(config.Backend, newNotifier(config))
+		return manager
+	}
+
+	for _, name := range config.BackendNames() {
+		backend := config.Backends[name]
+
+		// Named backends share the duration settings
+		backendConfig := config
+		backendConfig.Backend = backend.Type
+		backendConfig.Exec = backend.Exec
+		backendConfig.Webhook = backend.Webhook
+		backendConfig.Email = backend.Email
+		manager.AddNamedNotifier(name, newNotifier(backendConfig))
 	}
 
-	// Add default notifier based on backend
+	return manager
+}
+
+// newNotifier creates the notifier for the configured backend type
+func newNotifier(config config.NotificationConfig) Notifier {
 	switch strings.ToLower(config.Backend) {
-	case "notify-send":
-		notifier := NewNotifySendNotifier()
+	case "exec":
+		notifier := NewExecNotifier(config.Exec)
 		notifier.SetConfig(config)
-		manager.AddNotifier(notifier)
-	case "dbus", "":
-		// Try D-Bus first, fallback to notify-send if D-Bus fails
-		if dbusNotifier, err := NewDBusNotifier(); err == nil {
-			dbusNotifier.SetConfig(config)
-			manager.AddNotifier(dbusNotifier)
+		return notifier
+	case "webhook":
+		if webhookNotifier, err := NewWebhookNotifier(config.Webhook); err == nil {
+			webhookNotifier.SetConfig(config)
+			return webhookNotifier
 		} else {
-			fmt.Fprintf(os.Stderr, "Failed to initialize D-Bus notifier, falling back to notify-send: %v\n", err)
-			notifier := NewNotifySendNotifier()
-			notifier.SetConfig(config)
-			manager.AddNotifier(notifier)
+			fmt.Fprintf(os.Stderr, "Failed to initialize webhook notifier, falling back to notify-send: %v\n", err)
 		}
+	case "email":
+		if emailNotifier, err := NewEmailNotifier(config.Email); err == nil {
+			emailNotifier.SetConfig(config)
+			return emailNotifier
+		} else {
+			fmt.Fprintf(os.Stderr, "Failed to initialize email notifier, falling back to notify-send: %v\n", err)
+		}
+	case "notify-send":
+		notifier := NewNotifySendNotifier()
+		notifier.SetConfig(config)
+		return notifier
 	default:
 		// Try D-Bus first, fallback to notify-send if D-Bus fails
 		if dbusNotifier, err := NewDBusNotifier(); err == nil {
 			dbusNotifier.SetConfig(config)
-			manager.AddNotifier(dbusNotifier)
+			return dbusNotifier
 		} else {
 			fmt.Fprintf(os.Stderr, "Failed to initialize D-Bus notifier, falling back to notify-send: %v\n", err)
-			notifier := NewNotifySendNotifier()
-			notifier.SetConfig(config)
-			manager.AddNotifier(notifier)
 		}
 	}
 
-	return manager
+	notifier := NewNotifySendNotifier()
+	notifier.SetConfig(config)
+	return notifier
 }
 
-// AddNotifier adds a notifier to the manager
+// AddNotifier adds an unnamed notifier to the manager
 func (nm *NotificationManager) AddNotifier(notifier Notifier) {
-	nm.notifiers = append(nm.notifiers, notifier)
+	nm.AddNamedNotifier(fmt.Sprintf("notifier-%d", len(nm.backends)+1), notifier)
 }
 
-// SendNotification sends a notification using all configured notifiers
+// AddNamedNotifier adds a notifier that routing rules can refer to by name
+func (nm *NotificationManager) AddNamedNotifier(name string, notifier Notifier) {
+	nm.backends = append(nm.backends, &notificationBackend{name: name, notifier: notifier})
+}
+
+// SendNotification sends a notification to the backends selected by the
+// routing rules. Failures are reported per backend.
 func (nm *NotificationManager) SendNotification(request alerts.AlertRequest) error {
-	var lastError error
+	backends := nm.selectBackends(request)
+	if len(backends) == 0 {
+		fmt.Fprintf(os.Stderr, "No notification route matched alert for %s\n", request.Event.GetSummary())
+		return nil
+	}
 
-	for _, notifier := range nm.notifiers {
-		if err := notifier.SendNotification(request); err != nil {
-			lastError = err
+	var errs []error
+	for _, backend := range backends {
+		if err := backend.notifier.SendNotification(request); err != nil {
 			// Log error but continue with other notifiers
-			fmt.Fprintf(os.Stderr, "Notification failed: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Notification via %s failed: %v\n", backend.name, err)
+			errs = append(errs, fmt.Errorf("backend %s: %w", backend.name, err))
 		}
 	}
 
-	return lastError
+	return errors.Join(errs...)
+}
+
+// BackendsFor returns the names of the backends the routing rules select for an alert
+func (nm *NotificationManager) BackendsFor(request alerts.AlertRequest) []string {
+	backends := nm.selectBackends(request)
+	names := make([]string, len(backends))
+	for i, backend := range backends {
+		names[i] = backend.name
+	}
+	return names
+}
+
+// SendToBackend sends a notification through a single named backend
+func (nm *NotificationManager) SendToBackend(name string, request alerts.AlertRequest) error {
+	for _, backend := range nm.backends {
+		if backend.name == name {
+			return backend.notifier.SendNotification(request)
+		}
+	}
+	return fmt.Errorf("unknown notification backend: %s", name)
+}
+
+// liveNotifier is implemented by backends that keep notifications on screen
+// up to date with the calendar
+type liveNotifier interface {
+	SetEventResolver(resolve func(uid string) (storage.Event, bool))
+	RefreshEvents()
+}
+
+// SetEventResolver sets the event lookup of backends that keep notifications
+// on screen up to date
+func (nm *NotificationManager) SetEventResolver(resolve func(uid string) (storage.Event, bool)) {
+	for _, backend := range nm.backends {
+		if live, ok := backend.notifier.(liveNotifier); ok {
+			live.SetEventResolver(resolve)
+		}
+	}
+}
+
+// RefreshEvents updates open notifications after calendar changes, e.g.
+// replacing those of moved or cancelled events
+func (nm *NotificationManager) RefreshEvents() {
+	for _, backend := range nm.backends {
+		if live, ok := backend.notifier.(liveNotifier); ok {
+			live.RefreshEvents()
+		}
+	}
 }
 
 // CreateDefaultTemplates creates default template files in the user's config directory
@@ -698,7 +701,7 @@ func CreateDefaultTemplates() error {
 	// Default template content
 	templates := map[string]string{
 		"default.tpl": `{{.Summary}}{{if .Location}} at {{.Location}}{{end}}
-Starts: {{.StartTime}} ({{.AlertOffset}} warning)`,
+{{if .Due}}Due: {{.Due}}{{else}}Starts: {{.StartTime}}{{end}} ({{.AlertOffset}} warning)`,
 
 		"detailed.tpl": `📅 {{.Summary}}
 🕐 {{.StartTime}} - {{.EndTime}} ({{.Duration}}){{if .Location}}
@@ -711,6 +714,10 @@ Starts: {{.StartTime}} ({{.AlertOffset}} warning)`,
 
 		"family.tpl": `👨‍👩‍👧‍👦 {{.Summary}}{{if .Location}} at {{.Location}}{{end}}
 Starts in {{.AlertOffset}}`,
+
+		"birthday.tpl": `🎂 {{.Summary}}{{if .Description}}
+{{.Description}}{{end}}
+In {{.AlertOffset}}`,
 	}
 
 	for filename, content := range templates {
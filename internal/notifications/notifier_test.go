@@ -10,13 +10,12 @@ import (
 
 	"calwatch/internal/alerts"
 	"calwatch/internal/config"
+	"calwatch/internal/localday"
 	"calwatch/internal/recurrence"
 	"calwatch/internal/storage"
 )
 
-func TestNotifySendNotifier_CreateTemplateData(t *testing.T) {
-	notifier := NewNotifySendNotifier()
-
+func TestNewTemplateData(t *testing.T) {
 	// Create test event
 	startTime := time.Date(2023, 10, 15, 14, 30, 0, 0, time.UTC)
 	endTime := startTime.Add(time.Hour)
@@ -40,7 +39,7 @@ func TestNotifySendNotifier_CreateTemplateData(t *testing.T) {
 	alertOffset := 15 * time.Minute
 
 	// Create template data
-	data := notifier.createTemplateData(event, alertOffset)
+	data := newTemplateData(alerts.AlertRequest{Event: event, AlertOffset: alertOffset}, startTime.Add(-alertOffset))
 
 	// Check basic fields
 	if data.Summary != "Team Meeting" {
@@ -185,7 +184,7 @@ func TestNotifySendNotifier_DefaultTemplate(t *testing.T) {
 		AlertOffset: "15 minutes",
 	}
 
-	err := notifier.ValidateTemplate(notifier.defaultTemplate, data)
+	err := notifier.ValidateTemplate(notifier.renderer.templates.defaultTemplate, data)
 	if err != nil {
 		t.Errorf("Default template validation failed: %v", err)
 	}
@@ -300,4 +299,197 @@ func TestNotifySendNotifier_SetConfig(t *testing.T) {
 	if actualMs != expectedMs {
 		t.Errorf("Expected duration %d ms, got %d ms", expectedMs, actualMs)
 	}
-}
\ No newline at end of file
+}
+func TestNewTemplateDataForTask(t *testing.T) {
+	due := time.Date(2023, 10, 15, 17, 0, 0, 0, time.Local)
+	event := storage.NewCalendarEvent(
+		"task-uid",
+		"Submit report",
+		"",
+		"",
+		due,
+		due,
+		time.Local,
+		&recurrence.NoRecurrence{},
+		storage.NewCalendar("/test/tasks", "", []storage.Alert{}),
+		[]storage.Alert{},
+	)
+	task := storage.NewTaskEvent(event, &due, nil, "IN-PROCESS", 40, 2)
+
+	data := newTemplateData(alerts.AlertRequest{Event: task, AlertOffset: 30 * time.Minute}, due.Add(-30*time.Minute))
+
+	if !data.IsTask {
+		t.Error("Expected IsTask to be set for tasks")
+	}
+	if data.Due != "2023-10-15 17:00" {
+		t.Errorf("Expected due '2023-10-15 17:00', got '%s'", data.Due)
+	}
+	if data.Priority != "high" || data.PercentComplete != 40 || data.Status != "IN-PROCESS" {
+		t.Errorf("Unexpected task fields: priority %q, percent %d, status %q", data.Priority, data.PercentComplete, data.Status)
+	}
+
+	var buf strings.Builder
+	if err := newTemplateCache().defaultTemplate.Execute(&buf, data); err != nil {
+		t.Fatalf("Default template failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Due: 2023-10-15 17:00") {
+		t.Errorf("Expected default template to show due date, got %q", buf.String())
+	}
+}
+
+func TestNewTemplateData_Occurrence(t *testing.T) {
+	localday.SetLocation(time.UTC)
+	defer localday.SetLocation(time.Local)
+
+	calendarDir := filepath.Join(t.TempDir(), "f00ba4")
+	if err := os.Mkdir(calendarDir, 0755); err != nil {
+		t.Fatalf("Failed to create calendar directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(calendarDir, "displayname"), []byte("Work"), 0644); err != nil {
+		t.Fatalf("Failed to write displayname: %v", err)
+	}
+
+	// Weekly on Mondays since January, alert for a Monday in March
+	firstStart := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	event := storage.NewCalendarEvent(
+		"weekly-uid",
+		"Standup",
+		"",
+		"",
+		firstStart,
+		firstStart.Add(30*time.Minute),
+		time.UTC,
+		recurrence.NewWeeklyRecurrence(1, []time.Weekday{time.Monday}, nil, nil),
+		storage.NewCalendar(calendarDir, "", []storage.Alert{}),
+		[]storage.Alert{},
+	)
+	occurrence := time.Date(2024, 3, 18, 10, 0, 0, 0, time.UTC)
+
+	data := newTemplateData(alerts.AlertRequest{
+		Event:       event,
+		AlertOffset: 24 * time.Hour,
+		Important:   true,
+		Late:        true,
+		EventTime:   occurrence,
+		Description: "Prepare demo",
+	}, occurrence.Add(-24*time.Hour))
+
+	if !data.Start.Equal(occurrence) || !data.End.Equal(occurrence.Add(30*time.Minute)) {
+		t.Errorf("Expected occurrence %v - %v, got %v - %v", occurrence, occurrence.Add(30*time.Minute), data.Start, data.End)
+	}
+	if data.Date != "2024-03-18" || data.Weekday != "Monday" || data.StartTime != "10:00" || data.EndTime != "10:30" {
+		t.Errorf("Unexpected date fields: %s %s %s-%s", data.Date, data.Weekday, data.StartTime, data.EndTime)
+	}
+	if data.Relative != "tomorrow at 10:00" {
+		t.Errorf("Expected relative time 'tomorrow at 10:00', got %q", data.Relative)
+	}
+	if data.Calendar != "Work" {
+		t.Errorf("Expected calendar 'Work', got %q", data.Calendar)
+	}
+	if data.Recurrence != "every week on Monday" {
+		t.Errorf("Expected recurrence 'every week on Monday', got %q", data.Recurrence)
+	}
+	if !data.Late || !data.Important || data.AlertDescription != "Prepare demo" || data.AllDay {
+		t.Errorf("Unexpected alert fields: late %v, important %v, description %q, all day %v",
+			data.Late, data.Important, data.AlertDescription, data.AllDay)
+	}
+
+	// Existing templates keep working with the occurrence date
+	tmpl := template.Must(template.New("custom").Parse(`{{.Summary}} {{.Relative}} ({{.Start.Format "Mon 2 Jan"}}, {{.Recurrence}})`))
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatalf("Template failed: %v", err)
+	}
+	if buf.String() != "Standup tomorrow at 10:00 (Mon 18 Mar, every week on Monday)" {
+		t.Errorf("Unexpected rendering %q", buf.String())
+	}
+}
+
+func TestNewTemplateData_AlertKinds(t *testing.T) {
+	localday.SetLocation(time.UTC)
+	defer localday.SetLocation(time.Local)
+
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	newEvent := func(end time.Time) storage.Event {
+		return storage.NewCalendarEvent("uid", "Workshop", "", "", start, end, time.UTC, nil,
+			storage.NewCalendar("/test/path", "", []storage.Alert{}), []storage.Alert{})
+	}
+
+	tests := []struct {
+		name           string
+		kind           storage.AlertKind
+		alertOffset    time.Duration // Time from the alert to the start
+		end            time.Time
+		expectedOffset string
+		expectedBody   string
+	}{
+		{"before start", storage.AlertBeforeStart, 15 * time.Minute, start.Add(time.Hour),
+			"15 minutes", "Workshop\nStarts: 09:00 (15 minutes warning)"},
+		{"at start", storage.AlertAtStart, 0, start.Add(time.Hour),
+			"", "Workshop\nStarting now, until 10:00"},
+		{"before end", storage.AlertBeforeEnd, -45 * time.Minute, start.Add(time.Hour),
+			"15 minutes", "Workshop\nEnds at 10:00 (15 minutes left)"},
+		{"after start", storage.AlertBeforeStart, -5 * time.Minute, start.Add(time.Hour),
+			"", "Workshop\nStarts: 09:00"},
+		{"at end", storage.AlertAtEnd, -time.Hour, start.Add(time.Hour),
+			"", "Workshop\nEnded at 10:00"},
+		{"after end", storage.AlertBeforeEnd, -70 * time.Minute, start.Add(time.Hour),
+			"", "Workshop\nEnded at 10:00"},
+		{"ongoing", storage.AlertOngoing, -23 * time.Hour, start.Add(50 * time.Hour),
+			"", "Workshop\nOngoing until Wed 17 Jan 11:00"},
+	}
+
+	notifier := NewNotifySendNotifier()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := alerts.AlertRequest{Event: newEvent(tt.end), AlertOffset: tt.alertOffset, AlertKind: tt.kind, EventTime: start}
+			data := newTemplateData(request, start.Add(-tt.alertOffset))
+			if data.AlertKind != tt.kind.String() || data.AlertOffset != tt.expectedOffset {
+				t.Errorf("Expected %s alert with offset %q, got %s with %q", tt.kind, tt.expectedOffset, data.AlertKind, data.AlertOffset)
+			}
+
+			var buf strings.Builder
+			if err := notifier.renderer.templates.defaultTemplate.Execute(&buf, data); err != nil {
+				t.Fatalf("Default template failed: %v", err)
+			}
+			if buf.String() != tt.expectedBody {
+				t.Errorf("Expected %q, got %q", tt.expectedBody, buf.String())
+			}
+		})
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	localday.SetLocation(time.UTC)
+	defer localday.SetLocation(time.Local)
+
+	// Monday, 15 January 2024
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		allDay   bool
+		expected string
+	}{
+		{"now", now.Add(20 * time.Second), false, "now"},
+		{"minutes", now.Add(15 * time.Minute), false, "in 15 minutes"},
+		{"rounded to the minute", now.Add(14*time.Minute + 45*time.Second), false, "in 15 minutes"},
+		{"later today", now.Add(3 * time.Hour), false, "today at 12:00"},
+		{"tomorrow", now.Add(25 * time.Hour), false, "tomorrow at 10:00"},
+		{"this week", now.Add(3 * 24 * time.Hour), false, "on Thursday at 09:00"},
+		{"next week", now.Add(10 * 24 * time.Hour), false, "on Thursday, 25 January at 09:00"},
+		{"started", now.Add(-5 * time.Minute), false, "started 5 minutes ago"},
+		{"all day today", time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), true, "today"},
+		{"all day tomorrow", time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC), true, "tomorrow"},
+		{"all day this week", time.Date(2024, 1, 19, 0, 0, 0, 0, time.UTC), true, "on Friday"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := relativeTime(tt.start, tt.allDay, now); got != tt.expected {
+				t.Errorf("relativeTime() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,696 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/adrg/xdg"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/config"
+	"calwatch/internal/localday"
+	"calwatch/internal/recurrence"
+	"calwatch/internal/storage"
+)
+
+// Retry delays for failed deliveries, doubled per attempt
+const (
+	outboxInitialBackoff = 15 * time.Second
+	outboxMaxBackoff     = 5 * time.Minute
+)
+
+// Events without a duration (tasks, reminders) are considered ongoing this long
+const outboxMinEventLength = 15 * time.Minute
+
+// Alerts delivered later than this after being enqueued are marked late
+const outboxLateThreshold = time.Minute
+
+// OutboxItem is an alert waiting to be delivered to one or more backends
+type OutboxItem struct {
+	ID          string                     `json:"id"`
+	Event       EventSnapshot              `json:"event"`
+	AlertOffset time.Duration              `json:"alert_offset"`
+	AlertKind   storage.AlertKind          `json:"alert_kind,omitempty"`
+	Template    string                     `json:"template,omitempty"`
+	Description string                     `json:"description,omitempty"` // Alert description, e.g. from the VALARM
+	Important   bool                       `json:"important"`
+	Late        bool                       `json:"late"`
+	Change      *alerts.EventChange        `json:"change,omitempty"` // Set for alerts about changed events
+	Digest      bool                       `json:"digest,omitempty"`
+	Agenda      []EventSnapshot            `json:"agenda,omitempty"` // Occurrences listed in a digest
+	Silent      bool                       `json:"silent,omitempty"`
+	Deferred    time.Time                  `json:"deferred,omitempty"` // Not delivered before, e.g. the end of quiet hours
+	Paused      string                     `json:"paused,omitempty"`   // Calendar of the pause holding the alert back
+	Enqueued    time.Time                  `json:"enqueued"`
+	Expires     time.Time                  `json:"expires"` // End of the event, after which the alert is dropped
+	Pending     map[string]*OutboxDelivery `json:"pending"` // Backends the alert still has to be delivered to
+
+	event  storage.Event       // Live event, not persisted
+	agenda []alerts.AgendaItem // Live agenda, not persisted
+}
+
+// OutboxDelivery tracks the delivery of an item to a single backend
+type OutboxDelivery struct {
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// EventSnapshot holds the event details needed to notify about an alert
+// after a restart, if the event cannot be found in the calendars anymore
+type EventSnapshot struct {
+	UID         string    `json:"uid"`
+	Summary     string    `json:"summary"`
+	Description string    `json:"description,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	Start       time.Time `json:"start"` // Start of the occurrence the alert is for
+	End         time.Time `json:"end"`
+	AllDay      bool      `json:"all_day,omitempty"`
+	Calendar    string    `json:"calendar,omitempty"` // Calendar directory
+}
+
+// outboxFile is the persisted form of the outbox
+type outboxFile struct {
+	Items []*OutboxItem `json:"items"`
+}
+
+// Outbox persists alerts until every backend selected by the routing rules
+// delivered them. Failed deliveries are retried with exponential backoff per
+// backend, so alerts survive a desktop session that isn't ready yet and
+// daemon restarts. Alerts for events that have ended expire.
+type Outbox struct {
+	manager  *NotificationManager
+	filePath string
+	items    []*OutboxItem
+	resolve  func(uid string) (storage.Event, bool)
+	quiet    *QuietHours // Nil without quiet hours
+	pauses   *Pauses     // Nil if alerts cannot be paused
+	focus    *Focus      // Nil without a focus rule
+	now      func() time.Time
+
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+
+	mutex sync.Mutex
+}
+
+// NewOutbox creates an outbox delivering through the manager's backends,
+// persisted at filePath
+func NewOutbox(manager *NotificationManager, filePath string) *Outbox {
+	return &Outbox{
+		manager:        manager,
+		filePath:       filePath,
+		now:            time.Now,
+		initialBackoff: outboxInitialBackoff,
+		maxBackoff:     outboxMaxBackoff,
+	}
+}
+
+// NewXDGOutbox creates an outbox persisted in the XDG state directory
+func NewXDGOutbox(manager *NotificationManager) (*Outbox, error) {
+	filePath, err := xdg.StateFile("calwatch/outbox.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get XDG outbox file path: %w", err)
+	}
+	return NewOutbox(manager, filePath), nil
+}
+
+// SetEventResolver sets the lookup used to find the current version of
+// events for alerts loaded from disk
+func (o *Outbox) SetEventResolver(resolve func(uid string) (storage.Event, bool)) {
+	o.resolve = resolve
+}
+
+// SetQuietHours sets the quiet hours applied to alerts when they are queued
+func (o *Outbox) SetQuietHours(quiet *QuietHours) {
+	o.quiet = quiet
+}
+
+// SetPauses sets the pauses holding back alerts when they are queued
+func (o *Outbox) SetPauses(pauses *Pauses) {
+	o.pauses = pauses
+}
+
+// SetFocus sets the focus rule applied to alerts when they are queued
+func (o *Outbox) SetFocus(focus *Focus) {
+	o.focus = focus
+}
+
+// Load reads pending alerts left over from a previous run and makes them due
+func (o *Outbox) Load() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	data, err := os.ReadFile(o.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	var file outboxFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse outbox %s: %w", o.filePath, err)
+	}
+
+	// Retry right away, whatever failed before the restart may work now
+	now := o.now()
+	o.items = nil
+	for _, item := range file.Items {
+		if item == nil || len(item.Pending) == 0 {
+			continue
+		}
+		for _, delivery := range item.Pending {
+			delivery.NextAttempt = now
+			if item.Deferred.After(now) {
+				delivery.NextAttempt = item.Deferred
+			}
+		}
+		o.items = append(o.items, item)
+	}
+	return nil
+}
+
+// Enqueue persists alerts for delivery to the backends selected by the
+// routing rules. Alerts that are already queued are ignored, alerts while
+// paused, busy or during quiet hours are dropped, held back or silenced.
+func (o *Outbox) Enqueue(requests []alerts.AlertRequest) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	now := o.now()
+	queued := make(map[string]bool, len(o.items))
+	for _, item := range o.items {
+		queued[item.ID] = true
+	}
+
+	for _, request := range requests {
+		var held *storage.Pause
+		if o.pauses != nil {
+			if pause, paused := o.pauses.Check(request, now); paused {
+				switch o.pauses.Policy() {
+				case config.PauseDrop:
+					fmt.Fprintf(os.Stderr, "Dropping alert for %s while paused\n", request.Event.GetSummary())
+					continue
+				case config.PauseDigest:
+					fmt.Fprintf(os.Stderr, "Queueing alert for %s until the pause ends\n", request.Event.GetSummary())
+					o.queuePausedLocked(request, pause, now)
+					continue
+				case config.PauseReplay:
+					fmt.Fprintf(os.Stderr, "Holding back alert for %s until the pause ends\n", request.Event.GetSummary())
+					held = &pause
+				}
+			}
+		}
+
+		if o.focus != nil && held == nil {
+			switch action, until := o.focus.Check(request, now); action {
+			case config.HoldDrop:
+				fmt.Fprintf(os.Stderr, "Dropping alert for %s while busy\n", request.Event.GetSummary())
+				continue
+			case config.HoldDefer:
+				fmt.Fprintf(os.Stderr, "Deferring alert for %s until the busy event ends at %s\n",
+					request.Event.GetSummary(), until.Format("2006-01-02 15:04"))
+				event := storage.NewDigestEvent("calwatch-focus/"+until.UTC().Format("20060102T150405Z"), "Alerts while busy", until)
+				o.deferLocked(request, event, o.focus.config.Template, until, now)
+				continue
+			case config.HoldSilent:
+				request.Silent = true
+			}
+		}
+
+		if o.quiet != nil && held == nil {
+			switch action, until := o.quiet.Check(request, now); action {
+			case config.HoldDrop:
+				fmt.Fprintf(os.Stderr, "Dropping alert for %s during quiet hours\n", request.Event.GetSummary())
+				continue
+			case config.HoldDefer:
+				fmt.Fprintf(os.Stderr, "Deferring alert for %s until the end of quiet hours at %s\n",
+					request.Event.GetSummary(), until.Format("2006-01-02 15:04"))
+				event := storage.NewDigestEvent("calwatch-quiet/"+until.UTC().Format("20060102T150405Z"), "Alerts during quiet hours", until)
+				o.deferLocked(request, event, o.quiet.scheduleFor(request.Event).Template, until, now)
+				continue
+			case config.HoldSilent:
+				request.Silent = true
+			}
+		}
+
+		item := newOutboxItem(request, now)
+		if queued[item.ID] {
+			continue
+		}
+
+		backends := o.manager.BackendsFor(request)
+		if len(backends) == 0 {
+			fmt.Fprintf(os.Stderr, "No notification route matched alert for %s\n", request.Event.GetSummary())
+			continue
+		}
+		for _, name := range backends {
+			item.Pending[name] = &OutboxDelivery{NextAttempt: now}
+		}
+		if held != nil {
+			o.holdLocked(item, *held)
+		}
+
+		o.items = append(o.items, item)
+		queued[item.ID] = true
+	}
+
+	return o.saveLocked()
+}
+
+// deferLocked adds an alert to the digest delivered as event at until, e.g.
+// the end of the quiet hours, creating the digest for the first alert
+func (o *Outbox) deferLocked(request alerts.AlertRequest, event storage.Event, template string, until, now time.Time) {
+	digest, created := o.collectLocked(event, request, now)
+	if !created {
+		return
+	}
+
+	digest.Template = template
+	digest.Deferred = until
+	if !o.addDigestLocked(digest, until, now) {
+		fmt.Fprintf(os.Stderr, "No notification route matched the digest %q\n", event.GetSummary())
+	}
+}
+
+// queuePausedLocked adds an alert to the digest delivered when the pause
+// ends, creating the digest for the first alert
+func (o *Outbox) queuePausedLocked(request alerts.AlertRequest, pause storage.Pause, now time.Time) {
+	digest, created := o.collectLocked(newPauseDigestEvent(pause), request, now)
+	if !created {
+		return
+	}
+
+	digest.Template = o.pauses.config.Template
+	if !o.addDigestLocked(digest, now, now) {
+		fmt.Fprintf(os.Stderr, "No notification route matched the pause digest\n")
+		return
+	}
+	o.holdLocked(digest, pause)
+}
+
+// collectLocked adds an alert to the agenda of the queued digest delivered
+// as event. If the digest is not queued yet, a new digest listing the alert
+// is returned for the caller to add.
+func (o *Outbox) collectLocked(event storage.Event, request alerts.AlertRequest, now time.Time) (*OutboxItem, bool) {
+	start := request.EventTime
+	if start.IsZero() {
+		start = request.Event.GetStartTime()
+	}
+	snapshot := newEventSnapshot(request.Event, start)
+
+	digest := newOutboxItem(alerts.AlertRequest{
+		Event:     event,
+		EventTime: event.GetStartTime(),
+		Digest:    true,
+	}, now)
+	for _, item := range o.items {
+		if item.ID != digest.ID {
+			continue
+		}
+		for _, queued := range item.Agenda {
+			if queued.UID == snapshot.UID && queued.Start.Equal(snapshot.Start) {
+				return item, false
+			}
+		}
+		item.Agenda = append(item.Agenda, snapshot)
+		item.agenda = append(item.agenda, alerts.AgendaItem{Event: request.Event, Start: start})
+		return item, false
+	}
+
+	digest.Agenda = []EventSnapshot{snapshot}
+	digest.agenda = []alerts.AgendaItem{{Event: request.Event, Start: start}}
+	return digest, true
+}
+
+// addDigestLocked queues a digest for the backends selected by the routing
+// rules, reporting false if no route matched
+func (o *Outbox) addDigestLocked(digest *OutboxItem, due, now time.Time) bool {
+	for _, name := range o.manager.BackendsFor(o.requestFor(digest, now)) {
+		digest.Pending[name] = &OutboxDelivery{NextAttempt: due}
+	}
+	if len(digest.Pending) == 0 {
+		return false
+	}
+	o.items = append(o.items, digest)
+	return true
+}
+
+// holdLocked holds back an item until the pause ends or is resumed
+func (o *Outbox) holdLocked(item *OutboxItem, pause storage.Pause) {
+	item.Paused = pause.Calendar
+	for _, delivery := range item.Pending {
+		delivery.NextAttempt = pause.Until
+	}
+}
+
+// releaseLocked makes items due whose pause ended. Held back alerts are late,
+// digests of queued alerts are delivered until the end of the day. (must be
+// called with lock held)
+func (o *Outbox) releaseLocked(now time.Time) {
+	for _, item := range o.items {
+		if item.Paused == "" {
+			continue
+		}
+		if o.pauses != nil {
+			if pause, paused := o.pauses.holding(item.Paused, now); paused {
+				// The pause may have been extended
+				o.holdLocked(item, pause)
+				continue
+			}
+		}
+
+		item.Paused = ""
+		item.Deferred = now
+		if item.Digest {
+			item.Expires = localday.Of(now).End()
+		} else {
+			item.Late = true
+		}
+		for _, delivery := range item.Pending {
+			delivery.NextAttempt = now
+		}
+	}
+}
+
+// newOutboxItem creates an item with a snapshot of the alert's event
+func newOutboxItem(request alerts.AlertRequest, now time.Time) *OutboxItem {
+	event := request.Event
+	start := request.EventTime
+	if start.IsZero() {
+		start = event.GetStartTime()
+	}
+	snapshot := newEventSnapshot(event, start)
+
+	expires := snapshot.End
+	if !snapshot.End.After(start) {
+		expires = start.Add(outboxMinEventLength)
+	}
+
+	id := fmt.Sprintf("%s|%s|%s", event.GetUID(), start.UTC().Format(time.RFC3339), request.AlertOffset)
+	if request.AlertKind != storage.AlertBeforeStart {
+		id += "|" + request.AlertKind.String()
+	}
+	if request.Change != nil {
+		id += "|" + request.Change.Kind
+	}
+
+	var agenda []EventSnapshot
+	for _, item := range request.Agenda {
+		agenda = append(agenda, newEventSnapshot(item.Event, item.Start))
+	}
+
+	return &OutboxItem{
+		ID:          id,
+		Event:       snapshot,
+		AlertOffset: request.AlertOffset,
+		AlertKind:   request.AlertKind,
+		Template:    request.Template,
+		Description: request.Description,
+		Important:   request.Important,
+		Late:        request.Late,
+		Change:      request.Change,
+		Digest:      request.Digest,
+		Agenda:      agenda,
+		Silent:      request.Silent,
+		Enqueued:    now,
+		Expires:     expires,
+		Pending:     make(map[string]*OutboxDelivery),
+		event:       event,
+		agenda:      request.Agenda,
+	}
+}
+
+// newEventSnapshot captures the occurrence of an event starting at start
+func newEventSnapshot(event storage.Event, start time.Time) EventSnapshot {
+	allDay := false
+	if calendarEvent := storage.BaseCalendarEvent(event); calendarEvent != nil {
+		allDay = calendarEvent.AllDay
+	}
+
+	calendarPath := ""
+	if member, ok := event.(storage.CalendarMember); ok && member.GetCalendar() != nil {
+		calendarPath = member.GetCalendar().Path
+	}
+
+	return EventSnapshot{
+		UID:         event.GetUID(),
+		Summary:     event.GetSummary(),
+		Description: event.GetDescription(),
+		Location:    event.GetLocation(),
+		Start:       start,
+		End:         start.Add(event.GetEndTime().Sub(event.GetStartTime())),
+		AllDay:      allDay,
+		Calendar:    calendarPath,
+	}
+}
+
+// outboxAttempt is a single delivery of an item to a backend
+type outboxAttempt struct {
+	item    *OutboxItem
+	backend string
+	request alerts.AlertRequest
+	err     error
+}
+
+// Deliver attempts all deliveries that are due, schedules retries for failed
+// ones and drops alerts whose event has ended
+func (o *Outbox) Deliver() error {
+	o.mutex.Lock()
+	now := o.now()
+	o.releaseLocked(now)
+	o.expireLocked(now)
+
+	var attempts []*outboxAttempt
+	for _, item := range o.items {
+		if item.Paused != "" {
+			continue
+		}
+		for backend, delivery := range item.Pending {
+			if delivery.NextAttempt.After(now) {
+				continue
+			}
+			attempts = append(attempts, &outboxAttempt{
+				item:    item,
+				backend: backend,
+				request: o.requestFor(item, now),
+			})
+		}
+	}
+	o.mutex.Unlock()
+
+	if len(attempts) == 0 {
+		return nil
+	}
+
+	// Send without holding the lock, backends may take a while
+	for _, attempt := range attempts {
+		attempt.err = o.manager.SendToBackend(attempt.backend, attempt.request)
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for _, attempt := range attempts {
+		delivery, exists := attempt.item.Pending[attempt.backend]
+		if !exists {
+			continue
+		}
+		if attempt.err == nil {
+			delete(attempt.item.Pending, attempt.backend)
+			continue
+		}
+
+		var permanent *PermanentError
+		if errors.As(attempt.err, &permanent) {
+			fmt.Fprintf(os.Stderr, "Notification via %s failed permanently, dropping it: %v\n", attempt.backend, attempt.err)
+			delete(attempt.item.Pending, attempt.backend)
+			continue
+		}
+
+		delivery.Attempts++
+		delivery.LastError = attempt.err.Error()
+		delivery.NextAttempt = now.Add(o.backoff(delivery.Attempts))
+		fmt.Fprintf(os.Stderr, "Notification via %s failed (attempt %d), retrying at %s: %v\n",
+			attempt.backend, delivery.Attempts, delivery.NextAttempt.Format("15:04:05"), attempt.err)
+	}
+
+	o.removeDeliveredLocked()
+	return o.saveLocked()
+}
+
+// requestFor rebuilds the alert request for an item, preferring the live event
+func (o *Outbox) requestFor(item *OutboxItem, now time.Time) alerts.AlertRequest {
+	if item.event == nil && o.resolve != nil {
+		if event, ok := o.resolve(item.Event.UID); ok {
+			item.event = event
+		}
+	}
+	if item.event == nil {
+		item.event = item.Event.toEvent(item.Template)
+	}
+	if item.agenda == nil {
+		// Agendas are restored from their snapshots after a restart
+		for _, snapshot := range item.Agenda {
+			item.agenda = append(item.agenda, alerts.AgendaItem{Event: snapshot.toEvent(""), Start: snapshot.Start})
+		}
+	}
+
+	// Deferred alerts are due at the end of the deferral
+	due := item.Enqueued
+	if item.Deferred.After(due) {
+		due = item.Deferred
+	}
+
+	return alerts.AlertRequest{
+		Event:       item.event,
+		AlertOffset: item.AlertOffset,
+		AlertKind:   item.AlertKind,
+		Template:    item.Template,
+		Important:   item.Important,
+		Late:        item.Late || now.Sub(due) > outboxLateThreshold,
+		EventTime:   item.Event.Start,
+		Description: item.Description,
+		Change:      item.Change,
+		Digest:      item.Digest,
+		Agenda:      item.agenda,
+		Silent:      item.Silent,
+	}
+}
+
+// toEvent creates a stand-in event from the snapshot
+func (s EventSnapshot) toEvent(template string) storage.Event {
+	event := storage.NewCalendarEvent(
+		s.UID,
+		s.Summary,
+		s.Description,
+		s.Location,
+		s.Start,
+		s.End,
+		s.Start.Location(),
+		&recurrence.NoRecurrence{},
+		storage.NewCalendar(s.Calendar, template, []storage.Alert{}),
+		[]storage.Alert{},
+	)
+	event.AllDay = s.AllDay
+	return event
+}
+
+// backoff returns the retry delay after the given number of failed attempts
+func (o *Outbox) backoff(attempts int) time.Duration {
+	delay := o.initialBackoff
+	for i := 1; i < attempts && delay < o.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > o.maxBackoff {
+		delay = o.maxBackoff
+	}
+	return delay
+}
+
+// expireLocked drops alerts for events that have ended (must be called with lock held)
+func (o *Outbox) expireLocked(now time.Time) {
+	kept := o.items[:0]
+	for _, item := range o.items {
+		// Held back alerts expire once released
+		if item.Paused == "" && now.After(item.Expires) {
+			fmt.Fprintf(os.Stderr, "Dropping undelivered alert for %s: event has ended\n", item.Event.Summary)
+			continue
+		}
+		kept = append(kept, item)
+	}
+	o.items = kept
+}
+
+// removeDeliveredLocked drops items delivered to all backends (must be called with lock held)
+func (o *Outbox) removeDeliveredLocked() {
+	kept := o.items[:0]
+	for _, item := range o.items {
+		if len(item.Pending) > 0 {
+			kept = append(kept, item)
+		}
+	}
+	o.items = kept
+}
+
+// NextAttempt returns when the next pending delivery is due
+func (o *Outbox) NextAttempt() (time.Time, bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	var next time.Time
+	for _, item := range o.items {
+		for _, delivery := range item.Pending {
+			// Held back until resumed
+			if item.Paused != "" && delivery.NextAttempt.IsZero() {
+				continue
+			}
+			if next.IsZero() || delivery.NextAttempt.Before(next) {
+				next = delivery.NextAttempt
+			}
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// Len returns the number of alerts waiting for delivery
+func (o *Outbox) Len() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return len(o.items)
+}
+
+// Held returns the number of alerts held back by pauses, counting the
+// alerts in digests
+func (o *Outbox) Held() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	held := 0
+	for _, item := range o.items {
+		switch {
+		case item.Paused == "":
+		case item.Digest:
+			held += len(item.Agenda)
+		default:
+			held++
+		}
+	}
+	return held
+}
+
+// saveLocked writes the outbox to disk (must be called with lock held)
+func (o *Outbox) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(o.filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+
+	items := o.items
+	if items == nil {
+		items = []*OutboxItem{}
+	}
+	data, err := json.MarshalIndent(outboxFile{Items: items}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox: %w", err)
+	}
+
+	// Atomic write: write to temporary file first, then rename
+	tempFile := o.filePath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write temporary outbox file: %w", err)
+	}
+	if err := os.Rename(tempFile, o.filePath); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to rename outbox file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,270 @@
+package notifications
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/storage"
+)
+
+// newOutboxTestManager creates a manager routing every alert to the given backends
+func newOutboxTestManager(notifiers map[string]*recordingNotifier) *NotificationManager {
+	manager := &NotificationManager{classifier: alerts.NewPriorityClassifier(), now: time.Now}
+	for _, name := range []string{"desktop", "phone"} {
+		if notifier, ok := notifiers[name]; ok {
+			manager.AddNamedNotifier(name, notifier)
+		}
+	}
+	return manager
+}
+
+// newOutboxTestOutbox creates an outbox in a temporary directory with a fixed clock
+func newOutboxTestOutbox(t *testing.T, manager *NotificationManager, now *time.Time) *Outbox {
+	t.Helper()
+	outbox := NewOutbox(manager, filepath.Join(t.TempDir(), "outbox.json"))
+	outbox.now = func() time.Time { return *now }
+	return outbox
+}
+
+func TestOutbox_RetriesFailedBackend(t *testing.T) {
+	now := time.Date(2024, 1, 15, 19, 45, 0, 0, time.UTC)
+	desktop := &recordingNotifier{err: errors.New("session bus not available")}
+	phone := &recordingNotifier{}
+	outbox := newOutboxTestOutbox(t, newOutboxTestManager(map[string]*recordingNotifier{"desktop": desktop, "phone": phone}), &now)
+
+	if err := outbox.Enqueue([]alerts.AlertRequest{newTestAlertRequest("/calendars/work", "Meeting")}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if err := outbox.Deliver(); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+
+	if desktop.sent != 1 || phone.sent != 1 {
+		t.Fatalf("Expected one attempt per backend, got desktop=%d phone=%d", desktop.sent, phone.sent)
+	}
+	next, ok := outbox.NextAttempt()
+	if !ok || !next.Equal(now.Add(outboxInitialBackoff)) {
+		t.Errorf("Expected retry at %v, got %v (%v)", now.Add(outboxInitialBackoff), next, ok)
+	}
+
+	// Not due yet
+	now = now.Add(5 * time.Second)
+	outbox.Deliver()
+	if desktop.sent != 1 {
+		t.Errorf("Expected no attempt before the backoff elapsed, got %d", desktop.sent)
+	}
+
+	// Second failure doubles the backoff
+	now = next
+	outbox.Deliver()
+	if next, _ := outbox.NextAttempt(); !next.Equal(now.Add(2 * outboxInitialBackoff)) {
+		t.Errorf("Expected doubled backoff, next attempt at %v", next)
+	}
+
+	// Session is up now
+	desktop.err = nil
+	now, _ = outbox.NextAttempt()
+	outbox.Deliver()
+
+	if desktop.sent != 3 {
+		t.Errorf("Expected 3 attempts via desktop, got %d", desktop.sent)
+	}
+	if phone.sent != 1 {
+		t.Errorf("Expected phone to be notified only once, got %d", phone.sent)
+	}
+	if outbox.Len() != 0 {
+		t.Errorf("Expected empty outbox after delivery, got %d items", outbox.Len())
+	}
+}
+
+func TestOutbox_DropsPermanentFailures(t *testing.T) {
+	now := time.Date(2024, 1, 15, 19, 45, 0, 0, time.UTC)
+	phone := &recordingNotifier{err: &PermanentError{Err: errors.New("returned 404 Not Found")}}
+	desktop := &recordingNotifier{err: errors.New("session bus not available")}
+	outbox := newOutboxTestOutbox(t, newOutboxTestManager(map[string]*recordingNotifier{"desktop": desktop, "phone": phone}), &now)
+
+	if err := outbox.Enqueue([]alerts.AlertRequest{newTestAlertRequest("/calendars/work", "Meeting")}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	outbox.Deliver()
+	now, _ = outbox.NextAttempt()
+	outbox.Deliver()
+
+	// Transient failures are retried, permanent ones are not
+	if phone.sent != 1 || desktop.sent != 2 {
+		t.Errorf("Expected 1 phone and 2 desktop attempts, got %d and %d", phone.sent, desktop.sent)
+	}
+
+	desktop.err = nil
+	now, _ = outbox.NextAttempt()
+	outbox.Deliver()
+	if outbox.Len() != 0 {
+		t.Errorf("Expected empty outbox after delivery, got %d items", outbox.Len())
+	}
+}
+
+func TestOutbox_ReplaysAfterRestart(t *testing.T) {
+	now := time.Date(2024, 1, 15, 19, 45, 0, 0, time.UTC)
+	failing := &recordingNotifier{err: errors.New("session bus not available")}
+	outbox := newOutboxTestOutbox(t, newOutboxTestManager(map[string]*recordingNotifier{"desktop": failing}), &now)
+
+	request := newTestAlertRequest("/calendars/work", "Meeting")
+	if err := outbox.Enqueue([]alerts.AlertRequest{request, request}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	outbox.Deliver()
+	if outbox.Len() != 1 {
+		t.Fatalf("Expected duplicate alerts to be queued once, got %d items", outbox.Len())
+	}
+
+	// Restart two minutes later with a working backend
+	now = now.Add(2 * time.Minute)
+	working := &recordingNotifier{}
+	replayed := NewOutbox(newOutboxTestManager(map[string]*recordingNotifier{"desktop": working}), outbox.filePath)
+	replayed.now = func() time.Time { return now }
+	if err := replayed.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if err := replayed.Deliver(); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+
+	if working.sent != 1 {
+		t.Fatalf("Expected replayed alert to be delivered, got %d", working.sent)
+	}
+	if working.last.Event.GetSummary() != "Meeting" || working.last.AlertOffset != 15*time.Minute {
+		t.Errorf("Unexpected replayed alert %q with offset %v", working.last.Event.GetSummary(), working.last.AlertOffset)
+	}
+	if !working.last.Late {
+		t.Error("Expected replayed alert to be marked late")
+	}
+	if member, ok := working.last.Event.(storage.CalendarMember); !ok || member.GetCalendar().Path != "/calendars/work" {
+		t.Error("Expected replayed alert to keep its calendar")
+	}
+
+	// Delivered alerts are gone after the next restart
+	again := NewOutbox(newOutboxTestManager(map[string]*recordingNotifier{"desktop": working}), outbox.filePath)
+	if err := again.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if again.Len() != 0 {
+		t.Errorf("Expected no alerts after delivery, got %d", again.Len())
+	}
+}
+
+func TestOutbox_ResolvesLiveEvent(t *testing.T) {
+	now := time.Date(2024, 1, 15, 19, 45, 0, 0, time.UTC)
+	failing := &recordingNotifier{err: errors.New("session bus not available")}
+	outbox := newOutboxTestOutbox(t, newOutboxTestManager(map[string]*recordingNotifier{"desktop": failing}), &now)
+	outbox.Enqueue([]alerts.AlertRequest{newTestAlertRequest("/calendars/work", "Meeting")})
+	outbox.Deliver()
+
+	updated := newTestAlertRequest("/calendars/work", "Meeting (moved to room 2)").Event
+	working := &recordingNotifier{}
+	replayed := NewOutbox(newOutboxTestManager(map[string]*recordingNotifier{"desktop": working}), outbox.filePath)
+	replayed.now = func() time.Time { return now }
+	replayed.SetEventResolver(func(uid string) (storage.Event, bool) {
+		return updated, uid == updated.GetUID()
+	})
+	replayed.Load()
+	replayed.Deliver()
+
+	if working.last.Event != updated {
+		t.Errorf("Expected the current version of the event, got %q", working.last.Event.GetSummary())
+	}
+}
+
+func TestOutbox_ReplaysDigestAndChangeAlerts(t *testing.T) {
+	now := time.Date(2024, 1, 15, 19, 45, 0, 0, time.UTC)
+	failing := &recordingNotifier{err: errors.New("session bus not available")}
+	outbox := newOutboxTestOutbox(t, newOutboxTestManager(map[string]*recordingNotifier{"desktop": failing}), &now)
+
+	meeting := newTestAlertRequest("/calendars/work", "Meeting")
+	moved := newTestAlertRequest("/calendars/work", "Meeting")
+	moved.Change = &alerts.EventChange{Kind: alerts.ChangeMoved, OldStart: now.Add(time.Hour), OldEnd: now.Add(2 * time.Hour)}
+	digest := newTestAlertRequest("/calendars/work", "Meeting")
+	digest.Digest = true
+	digest.Agenda = []alerts.AgendaItem{{Event: meeting.Event, Start: meeting.EventTime}}
+	digest.Event = storage.NewDigestEvent("calwatch-digest/2024-01-15", "Agenda for Monday", now)
+
+	// A change alert for an occurrence is queued next to its regular alert
+	outbox.Enqueue([]alerts.AlertRequest{meeting, moved, digest})
+	outbox.Deliver()
+	if outbox.Len() != 3 {
+		t.Fatalf("Expected 3 queued alerts, got %d", outbox.Len())
+	}
+
+	working := &recordingNotifier{}
+	replayed := NewOutbox(newOutboxTestManager(map[string]*recordingNotifier{"desktop": working}), outbox.filePath)
+	replayed.now = func() time.Time { return now }
+	if err := replayed.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, item := range replayed.items {
+		request := replayed.requestFor(item, now)
+		switch {
+		case item.Change != nil:
+			if request.Change == nil || *request.Change != *moved.Change {
+				t.Errorf("Expected replayed change %+v, got %+v", moved.Change, request.Change)
+			}
+		case item.Digest:
+			if !request.Digest || len(request.Agenda) != 1 || request.Agenda[0].Event.GetSummary() != "Meeting" ||
+				!request.Agenda[0].Start.Equal(meeting.EventTime) {
+				t.Errorf("Expected replayed digest listing the meeting, got %+v", request.Agenda)
+			}
+		}
+	}
+}
+
+func TestOutbox_ExpiresEndedEvents(t *testing.T) {
+	now := time.Date(2024, 1, 15, 19, 45, 0, 0, time.UTC)
+	desktop := &recordingNotifier{err: errors.New("session bus not available")}
+	outbox := newOutboxTestOutbox(t, newOutboxTestManager(map[string]*recordingNotifier{"desktop": desktop}), &now)
+
+	outbox.Enqueue([]alerts.AlertRequest{newTestAlertRequest("/calendars/work", "Meeting")})
+	outbox.Deliver()
+
+	// Still retried while the event is running
+	desktop.err = errors.New("still down")
+	now = time.Date(2024, 1, 15, 20, 30, 0, 0, time.UTC)
+	outbox.Deliver()
+	if desktop.sent != 2 || outbox.Len() != 1 {
+		t.Fatalf("Expected retry during the event, got %d attempts and %d items", desktop.sent, outbox.Len())
+	}
+
+	// Dropped once the event has ended
+	desktop.err = nil
+	now = time.Date(2024, 1, 15, 21, 1, 0, 0, time.UTC)
+	outbox.Deliver()
+	if desktop.sent != 2 {
+		t.Errorf("Expected no delivery after the event ended, got %d attempts", desktop.sent)
+	}
+	if outbox.Len() != 0 {
+		t.Errorf("Expected expired alert to be dropped, got %d items", outbox.Len())
+	}
+}
+
+func TestOutbox_Backoff(t *testing.T) {
+	outbox := NewOutbox(nil, "")
+
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, 15 * time.Second},
+		{2, 30 * time.Second},
+		{3, time.Minute},
+		{5, 4 * time.Minute},
+		{6, 5 * time.Minute},
+		{50, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := outbox.backoff(tt.attempts); got != tt.expected {
+			t.Errorf("backoff(%d) = %v, expected %v", tt.attempts, got, tt.expected)
+		}
+	}
+}
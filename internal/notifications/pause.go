@@ -0,0 +1,122 @@
+package notifications
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/config"
+	"calwatch/internal/storage"
+)
+
+// Pauses holds back alerts while calwatch is paused or calendars are muted.
+// Pauses are recorded in the daemon state, so they survive restarts.
+type Pauses struct {
+	config config.PauseConfig
+	state  storage.StateManager
+	mutex  sync.Mutex
+}
+
+// NewPauses creates pauses recorded in the daemon state
+func NewPauses(pauseConfig config.PauseConfig, state storage.StateManager) *Pauses {
+	return &Pauses{config: pauseConfig, state: state}
+}
+
+// Policy returns what happens to alerts while paused
+func (p *Pauses) Policy() string {
+	return p.config.Policy
+}
+
+// Pause holds back the alerts of a calendar, or of all calendars for
+// storage.AllCalendars, until until or until resumed if until is zero. An
+// earlier pause of the calendar is replaced.
+func (p *Pauses) Pause(calendar string, until, now time.Time) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if !until.IsZero() && !until.After(now) {
+		return fmt.Errorf("pause must end in the future, got %s", until.Format("2006-01-02 15:04"))
+	}
+
+	pauses := []storage.Pause{{Calendar: calendar, Since: now, Until: until}}
+	for _, pause := range p.activeLocked(now) {
+		if pause.Calendar != calendar {
+			pauses = append(pauses, pause)
+		}
+	}
+	return p.state.SetPauses(pauses)
+}
+
+// Resume ends the pause of a calendar, or all pauses for
+// storage.AllCalendars, and returns the pauses that ended
+func (p *Pauses) Resume(calendar string, now time.Time) ([]storage.Pause, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var kept, resumed []storage.Pause
+	for _, pause := range p.activeLocked(now) {
+		if calendar == storage.AllCalendars || pause.Calendar == calendar {
+			resumed = append(resumed, pause)
+			continue
+		}
+		kept = append(kept, pause)
+	}
+	if len(resumed) == 0 {
+		return nil, nil
+	}
+	return resumed, p.state.SetPauses(kept)
+}
+
+// Active returns the pauses holding back alerts at now
+func (p *Pauses) Active(now time.Time) []storage.Pause {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.activeLocked(now)
+}
+
+// activeLocked returns the pauses active at now (must be called with lock held)
+func (p *Pauses) activeLocked(now time.Time) []storage.Pause {
+	var active []storage.Pause
+	for _, pause := range p.state.GetPauses() {
+		if pause.ActiveAt(now) {
+			active = append(active, pause)
+		}
+	}
+	return active
+}
+
+// Check returns the pause holding back an alert at now. Pauses of all
+// calendars also hold back digests and other alerts without a calendar.
+func (p *Pauses) Check(request alerts.AlertRequest, now time.Time) (storage.Pause, bool) {
+	for _, pause := range p.Active(now) {
+		if pause.Calendar == storage.AllCalendars || storage.InCalendars(request.Event, []string{pause.Calendar}) {
+			return pause, true
+		}
+	}
+	return storage.Pause{}, false
+}
+
+// holding returns the active pause of a calendar
+func (p *Pauses) holding(calendar string, now time.Time) (storage.Pause, bool) {
+	for _, pause := range p.Active(now) {
+		if pause.Calendar == calendar {
+			return pause, true
+		}
+	}
+	return storage.Pause{}, false
+}
+
+// newPauseDigestEvent creates the event alerts queued during a pause are
+// delivered as
+func newPauseDigestEvent(pause storage.Pause) storage.Event {
+	summary := "Alerts while paused"
+	if pause.Calendar != storage.AllCalendars {
+		summary = fmt.Sprintf("Alerts while %s was muted", filepath.Base(pause.Calendar))
+	}
+
+	uid := "calwatch-paused/" + pause.Calendar + "/" + pause.Since.UTC().Format("20060102T150405Z")
+	return storage.NewDigestEvent(uid, summary, pause.Since)
+}
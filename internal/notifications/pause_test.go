@@ -0,0 +1,179 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/config"
+	"calwatch/internal/storage"
+)
+
+// memoryStateManager keeps the daemon state in memory
+type memoryStateManager struct {
+	state storage.DaemonState
+}
+
+func (m *memoryStateManager) GetLastAlertTick() time.Time { return m.state.LastAlertTick }
+func (m *memoryStateManager) SetLastAlertTick(tick time.Time) error {
+	m.state.LastAlertTick = tick
+	return nil
+}
+func (m *memoryStateManager) GetPauses() []storage.Pause {
+	return append([]storage.Pause(nil), m.state.Pauses...)
+}
+func (m *memoryStateManager) SetPauses(pauses []storage.Pause) error {
+	m.state.Pauses = pauses
+	return nil
+}
+func (m *memoryStateManager) Load() error { return nil }
+func (m *memoryStateManager) Save() error { return nil }
+
+func TestPauses(t *testing.T) {
+	now := time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)
+	pauses := NewPauses(config.PauseConfig{Policy: config.PauseDigest}, &memoryStateManager{})
+
+	if err := pauses.Pause(storage.AllCalendars, now.Add(time.Hour), now); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if err := pauses.Pause("/calendars/work", time.Time{}, now); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if err := pauses.Pause("/calendars/work", now.Add(-time.Minute), now); err == nil {
+		t.Error("Expected an error for a pause ending in the past")
+	}
+
+	work := newTestAlertRequest("/calendars/work", "Meeting")
+	personal := newTestAlertRequest("/calendars/personal", "Dentist")
+	tests := []struct {
+		name             string
+		request          alerts.AlertRequest
+		at               time.Time
+		expectedCalendar string // Empty if not paused
+	}{
+		{"paused", personal, now.Add(30 * time.Minute), storage.AllCalendars},
+		{"pause ended", personal, now.Add(time.Hour), ""},
+		{"muted until resumed", work, now.Add(24 * time.Hour), "/calendars/work"},
+		{"before the pause", personal, now.Add(-time.Minute), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pause, paused := pauses.Check(tt.request, tt.at)
+			if paused != (tt.expectedCalendar != "") || pause.Calendar != tt.expectedCalendar {
+				t.Errorf("Expected pause %q, got %q (paused %v)", tt.expectedCalendar, pause.Calendar, paused)
+			}
+		})
+	}
+
+	// Mutes are resumed by name, pausing again replaces the pause
+	if resumed, err := pauses.Resume("/calendars/work", now); err != nil || len(resumed) != 1 {
+		t.Fatalf("Expected the mute to be resumed, got %v, %v", resumed, err)
+	}
+	if err := pauses.Pause(storage.AllCalendars, now.Add(2*time.Hour), now); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if active := pauses.Active(now); len(active) != 1 || !active[0].Until.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("Expected the extended pause only, got %+v", active)
+	}
+	if resumed, _ := pauses.Resume(storage.AllCalendars, now); len(resumed) != 1 || len(pauses.Active(now)) != 0 {
+		t.Errorf("Expected all pauses to be resumed, got %+v", resumed)
+	}
+}
+
+func TestOutbox_Pauses(t *testing.T) {
+	now := time.Date(2024, 1, 15, 19, 30, 0, 0, time.UTC)
+	newPausedOutbox := func(policy string, until time.Time) (*Outbox, *Pauses, *recordingNotifier) {
+		pauseConfig := config.PauseConfig{Policy: policy}
+		if err := pauseConfig.Validate(); err != nil {
+			t.Fatalf("Validate() error = %v", err)
+		}
+		pauses := NewPauses(pauseConfig, &memoryStateManager{})
+		if err := pauses.Pause(storage.AllCalendars, until, now); err != nil {
+			t.Fatalf("Pause() error = %v", err)
+		}
+
+		desktop := &recordingNotifier{}
+		outbox := newOutboxTestOutbox(t, newOutboxTestManager(map[string]*recordingNotifier{"desktop": desktop}), &now)
+		outbox.SetPauses(pauses)
+		return outbox, pauses, desktop
+	}
+	second := newTestAlertRequest("/calendars/personal", "Dentist", withUID("dentist-uid"), startingAt(now.Add(time.Hour)))
+
+	t.Run("digest when the pause ends", func(t *testing.T) {
+		end := now.Add(20 * time.Minute)
+		outbox, _, desktop := newPausedOutbox(config.PauseDigest, end)
+		if err := outbox.Enqueue([]alerts.AlertRequest{newTestAlertRequest("/calendars/work", "Meeting"), second, second}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		outbox.Deliver()
+		if desktop.sent != 0 || outbox.Len() != 1 || outbox.Held() != 2 {
+			t.Fatalf("Expected one digest of 2 held back alerts, got %d sent, %d queued and %d held", desktop.sent, outbox.Len(), outbox.Held())
+		}
+		if next, ok := outbox.NextAttempt(); !ok || !next.Equal(end) {
+			t.Errorf("Expected delivery at %v, got %v", end, next)
+		}
+
+		defer func(paused time.Time) { now = paused }(now)
+		now = end
+		outbox.Deliver()
+		if desktop.sent != 1 || !desktop.last.Digest || desktop.last.Late || len(desktop.last.Agenda) != 2 {
+			t.Fatalf("Expected an on-time digest of 2 alerts, got %d sent: %+v", desktop.sent, desktop.last)
+		}
+	})
+
+	t.Run("dropped", func(t *testing.T) {
+		outbox, _, desktop := newPausedOutbox(config.PauseDrop, now.Add(time.Hour))
+		outbox.Enqueue([]alerts.AlertRequest{newTestAlertRequest("/calendars/work", "Meeting")})
+		outbox.Deliver()
+		if desktop.sent != 0 || outbox.Len() != 0 {
+			t.Errorf("Expected the alert to be dropped, got %d sent and %d queued", desktop.sent, outbox.Len())
+		}
+	})
+
+	t.Run("replayed on resume", func(t *testing.T) {
+		outbox, pauses, desktop := newPausedOutbox(config.PauseReplay, time.Time{})
+		outbox.Enqueue([]alerts.AlertRequest{newTestAlertRequest("/calendars/work", "Meeting")})
+		outbox.Deliver()
+		if desktop.sent != 0 || outbox.Held() != 1 {
+			t.Fatalf("Expected the alert to be held back, got %d sent and %d held", desktop.sent, outbox.Held())
+		}
+		if next, ok := outbox.NextAttempt(); ok {
+			t.Errorf("Expected no delivery until resumed, got %v", next)
+		}
+
+		// Held back across a restart
+		replayed := NewOutbox(newOutboxTestManager(map[string]*recordingNotifier{"desktop": desktop}), outbox.filePath)
+		replayed.now = func() time.Time { return now }
+		replayed.SetPauses(pauses)
+		if err := replayed.Load(); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		replayed.Deliver()
+		if desktop.sent != 0 {
+			t.Fatalf("Expected the alert to be held back after a restart")
+		}
+
+		if _, err := pauses.Resume(storage.AllCalendars, now); err != nil {
+			t.Fatalf("Resume() error = %v", err)
+		}
+		replayed.Deliver()
+		if desktop.sent != 1 || !desktop.last.Late || desktop.last.Event.GetSummary() != "Meeting" {
+			t.Errorf("Expected the late alert on resume, got %d sent: %+v", desktop.sent, desktop.last)
+		}
+	})
+
+	t.Run("other calendar muted", func(t *testing.T) {
+		pauses := NewPauses(config.PauseConfig{Policy: config.PauseDrop}, &memoryStateManager{})
+		pauses.Pause("/calendars/personal", time.Time{}, now)
+		desktop := &recordingNotifier{}
+		outbox := newOutboxTestOutbox(t, newOutboxTestManager(map[string]*recordingNotifier{"desktop": desktop}), &now)
+		outbox.SetPauses(pauses)
+
+		outbox.Enqueue([]alerts.AlertRequest{newTestAlertRequest("/calendars/work", "Meeting"), second})
+		outbox.Deliver()
+		if desktop.sent != 1 || desktop.last.Event.GetSummary() != "Meeting" {
+			t.Errorf("Expected only the unmuted alert, got %d sent: %+v", desktop.sent, desktop.last)
+		}
+	})
+}
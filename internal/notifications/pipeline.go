@@ -0,0 +1,240 @@
+package notifications
+
+import (
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/config"
+)
+
+// Transport delivers rendered notifications. Backends only implement
+// delivery; templates, data and error handling are shared by all of them.
+type Transport interface {
+	Deliver(notification Notification) error
+}
+
+// PermanentError is a delivery error that retrying won't fix, e.g. a request
+// the server rejected. Alerts failing with it are not retried.
+type PermanentError struct {
+	Err error
+}
+
+// Error returns the message of the underlying error
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Notification is an alert rendered for delivery by a transport
+type Notification struct {
+	Title     string
+	Body      string
+	Urgency   UrgencyLevel
+	Late      bool // Missed or delayed alert, shown with the late duration
+	Important bool
+	Silent    bool // No sound, e.g. during quiet hours
+	Markup    bool // Body is markup with escaped event text
+	Data      TemplateData
+	Request   alerts.AlertRequest // Alert the notification was rendered from
+}
+
+// payload returns the notification as passed to commands, webhooks and email templates
+func (n Notification) payload() alertPayload {
+	return alertPayload{
+		Title:        n.Title,
+		Body:         n.Body,
+		Urgency:      n.Urgency.String(),
+		TemplateData: n.Data,
+	}
+}
+
+// renderer turns alert requests into notifications: it resolves the alert's
+// template, builds the template data and falls back to the default template,
+// reporting template errors
+type renderer struct {
+	templates *templateCache
+	report    func(request alerts.AlertRequest, err error)
+	now       func() time.Time // Clock for relative times
+
+	// Capabilities of the notification server, exposed to templates
+	capabilities Capabilities
+	markup       bool // Render bodies as markup, for desktop notification servers
+}
+
+// render renders an alert request with its template
+func (r *renderer) render(request NotificationRequest) (Notification, error) {
+	alert := request.AlertRequest
+	data := newTemplateData(alert, r.now())
+	data.Late = request.Context.IsLate
+	data.Caps = r.capabilities
+	if request.Context.Change != "" {
+		data.Change = request.Context.Change
+	}
+
+	// Change alerts and digests fall back to their built-in templates
+	defaultTemplate := r.templates.defaultTemplate
+	switch {
+	case alert.Change != nil:
+		defaultTemplate = r.templates.defaultChangeTemplate
+	case alert.Digest:
+		defaultTemplate = r.templates.defaultAgendaTemplate
+	}
+
+	tmpl := defaultTemplate
+	if alert.Template != "" {
+		var err error
+		if tmpl, err = r.templates.get(alert.Template); err != nil {
+			r.report(alert, err)
+			tmpl = defaultTemplate
+		}
+	}
+
+	title, body, err := r.execute(tmpl, data)
+	if err != nil {
+		r.report(alert, fmt.Errorf("template execution failed: %w", err))
+		title, body, err = r.execute(defaultTemplate, data)
+		if err != nil {
+			return Notification{}, fmt.Errorf("failed to execute default template: %w", err)
+		}
+	}
+
+	switch {
+	case request.Context.Change == changeUpdated:
+		title = "Updated: " + title
+	case request.Context.Change == changeCancelled:
+		title = "Cancelled: " + title
+	case alert.Change != nil:
+		title = changeTitlePrefixes[alert.Change.Kind] + title
+	}
+
+	return Notification{
+		Title:     title,
+		Body:      body,
+		Urgency:   request.Urgency,
+		Late:      request.Context.IsLate,
+		Important: alert.Important,
+		Silent:    alert.Silent,
+		Markup:    r.markup,
+		Data:      data,
+		Request:   alert,
+	}, nil
+}
+
+// execute renders a template, escaping event text in the body if it is
+// rendered as markup
+func (r *renderer) execute(tmpl *template.Template, data TemplateData) (title, body string, err error) {
+	if !r.markup {
+		return executeTemplate(tmpl, data)
+	}
+
+	markup, err := r.templates.markupTemplate(tmpl)
+	if err != nil {
+		return "", "", err
+	}
+	return executeMarkupTemplate(tmpl, markup, data)
+}
+
+// transportNotifier implements Notifier on top of a transport. Backends embed
+// it and implement Transport.
+type transportNotifier struct {
+	config    config.NotificationConfig
+	transport Transport
+	renderer  *renderer
+
+	// Show template errors as notifications instead of logging them,
+	// for transports that reach the user directly
+	notifyErrors bool
+}
+
+// newTransportNotifier creates a notifier rendering alerts for the transport
+func newTransportNotifier(transport Transport) *transportNotifier {
+	notifier := &transportNotifier{transport: transport}
+	notifier.renderer = &renderer{templates: newTemplateCache(), report: notifier.reportError, now: time.Now}
+	return notifier
+}
+
+// SetConfig sets the notification configuration
+func (n *transportNotifier) SetConfig(config config.NotificationConfig) {
+	n.config = config
+}
+
+// SendNotification sends a notification for an alert request, critical if
+// the alert is important and low if it is silent
+func (n *transportNotifier) SendNotification(request alerts.AlertRequest) error {
+	urgency := UrgencyNormal
+	switch {
+	case request.Silent:
+		urgency = UrgencyLow
+	case request.Important:
+		urgency = UrgencyCritical
+	}
+
+	return n.SendNotificationWithContext(NotificationRequest{
+		AlertRequest: request,
+		Context:      NotificationContext{IsLate: request.Late},
+		Urgency:      urgency,
+	})
+}
+
+// SendNotificationWithContext renders the request and hands it to the transport
+func (n *transportNotifier) SendNotificationWithContext(request NotificationRequest) error {
+	notification, err := n.renderer.render(request)
+	if err != nil {
+		return err
+	}
+
+	return n.transport.Deliver(notification)
+}
+
+// reportError tells the user that the alert's template failed and the
+// default template is used instead
+func (n *transportNotifier) reportError(request alerts.AlertRequest, err error) {
+	if !n.notifyErrors {
+		fmt.Fprintf(os.Stderr, "Warning: %v, using default template\n", err)
+		return
+	}
+
+	message := fmt.Sprintf("Event: %s at %s\nTemplate Error: %s\nTemplate: %s",
+		request.Event.GetSummary(),
+		request.Event.GetStartTime().Format("15:04"),
+		err.Error(),
+		request.Template,
+	)
+
+	// Deliver directly, the error notification itself is not rendered
+	if err := n.transport.Deliver(Notification{Title: "Calendar Notification Error", Body: message, Urgency: UrgencyNormal}); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to send template error notification: %v\n", err)
+	}
+}
+
+// expireMilliseconds returns how long a notification stays visible, 0 until dismissed
+func (n *transportNotifier) expireMilliseconds(late bool) int32 {
+	durationConfig := n.config.Duration
+	if late {
+		durationConfig = n.config.DurationWhenLate
+	}
+
+	durationMs, err := durationConfig.ToMilliseconds()
+	if err != nil {
+		// Fallback to 5 seconds if conversion fails
+		durationMs = 5000
+	}
+	return durationMs
+}
+
+// LoadTemplate loads a template from a file path
+func (n *transportNotifier) LoadTemplate(path string) (*template.Template, error) {
+	return loadTemplateFile(path)
+}
+
+// ValidateTemplate validates a template with sample data
+func (n *transportNotifier) ValidateTemplate(tmpl *template.Template, data TemplateData) error {
+	return validateTemplate(tmpl, data)
+}
@@ -0,0 +1,269 @@
+package notifications
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/storage"
+)
+
+// recordingTransport records the notifications it is asked to deliver
+type recordingTransport struct {
+	mutex         sync.Mutex
+	notifications []Notification
+}
+
+func (r *recordingTransport) Deliver(notification Notification) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.notifications = append(r.notifications, notification)
+	return nil
+}
+
+// newRecordingNotifier creates a transport notifier delivering to a recording transport
+func newRecordingNotifier(notifyErrors bool) (*transportNotifier, *recordingTransport) {
+	transport := &recordingTransport{}
+	notifier := newTransportNotifier(transport)
+	notifier.notifyErrors = notifyErrors
+	notifier.renderer.templates.templates["broken.tpl"] = template.Must(template.New("broken.tpl").Parse("{{.Summary.Missing}}"))
+	return notifier, transport
+}
+
+func TestTransportNotifier_Render(t *testing.T) {
+	notifier, transport := newRecordingNotifier(false)
+
+	request := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+	request.Late = true
+	if err := notifier.SendNotification(request); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+
+	if len(transport.notifications) != 1 {
+		t.Fatalf("Expected 1 notification, got %d", len(transport.notifications))
+	}
+	notification := transport.notifications[0]
+	if notification.Title != "Team Meeting" {
+		t.Errorf("Expected title 'Team Meeting', got %q", notification.Title)
+	}
+	if !strings.HasPrefix(notification.Body, "Team Meeting at Room 1\n") {
+		t.Errorf("Expected default template body, got %q", notification.Body)
+	}
+	if notification.Urgency != UrgencyCritical || !notification.Important || !notification.Late {
+		t.Errorf("Unexpected urgency %v, important %v, late %v", notification.Urgency, notification.Important, notification.Late)
+	}
+	if notification.Data.UID != "test-uid" || notification.Request.Event != request.Event {
+		t.Error("Expected notification to carry the template data and request")
+	}
+}
+
+func TestTransportNotifier_TemplateErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		template      string
+		notifyErrors  bool
+		expectedError string // Expected in the error notification, empty if only logged
+	}{
+		{"missing template logged", "missing.tpl", false, ""},
+		{"broken template logged", "broken.tpl", false, ""},
+		{"missing template notified", "missing.tpl", true, "failed to load template missing.tpl"},
+		{"broken template notified", "broken.tpl", true, "template execution failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier, transport := newRecordingNotifier(tt.notifyErrors)
+
+			request := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+			request.Template = tt.template
+			if err := notifier.SendNotification(request); err != nil {
+				t.Fatalf("SendNotification() error = %v", err)
+			}
+
+			expected := 1
+			if tt.expectedError != "" {
+				expected = 2
+			}
+			if len(transport.notifications) != expected {
+				t.Fatalf("Expected %d notifications, got %d", expected, len(transport.notifications))
+			}
+
+			if tt.expectedError != "" {
+				report := transport.notifications[0]
+				if report.Title != "Calendar Notification Error" || !strings.Contains(report.Body, tt.expectedError) {
+					t.Errorf("Unexpected error notification %q: %q", report.Title, report.Body)
+				}
+				if !strings.Contains(report.Body, "Template: "+tt.template) {
+					t.Errorf("Expected error notification to name the template, got %q", report.Body)
+				}
+			}
+
+			// The alert itself falls back to the default template
+			alert := transport.notifications[len(transport.notifications)-1]
+			if !strings.HasPrefix(alert.Body, "Team Meeting at Room 1\n") {
+				t.Errorf("Expected fallback to the default template, got %q", alert.Body)
+			}
+		})
+	}
+}
+
+func TestTransportNotifier_ConcurrentTemplateLoading(t *testing.T) {
+	notifier, transport := newRecordingNotifier(false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			request := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+			request.Template = "missing.tpl"
+			notifier.SendNotification(request)
+		}()
+	}
+	wg.Wait()
+
+	if len(transport.notifications) != 20 {
+		t.Errorf("Expected 20 notifications, got %d", len(transport.notifications))
+	}
+}
+
+func TestTransportNotifier_ExpireMilliseconds(t *testing.T) {
+	notifier, _ := newRecordingNotifier(true)
+	notifier.SetConfig(defaultDesktopConfig("dbus"))
+
+	if got := notifier.expireMilliseconds(false); got != int32(5*time.Second/time.Millisecond) {
+		t.Errorf("Expected 5000ms for normal notifications, got %d", got)
+	}
+	if got := notifier.expireMilliseconds(true); got != 0 {
+		t.Errorf("Expected 0 (until dismissed) for late notifications, got %d", got)
+	}
+}
+
+func TestTransportNotifier_TitleAndPartials(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, partialsDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"partials/footer.tpl": `{{define "footer"}}⏰ {{.AlertOffset}} warning{{end}}`,
+		"titled.tpl": `{{define "title"}}{{upper .Summary}}{{end}}{{define "body"}}{{.Location}}
+{{template "footer" .}}{{end}}`,
+		"plain.tpl": `{{.Location}} {{template "footer" .}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		template      string
+		expectedTitle string
+		expectedBody  string
+	}{
+		{"titled.tpl", "TEAM MEETING", "Room 1\n⏰ 15 minutes warning"},
+		{"plain.tpl", "Team Meeting", "Room 1 ⏰ 15 minutes warning"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			notifier, transport := newRecordingNotifier(false)
+			tmpl, err := loadTemplateFile(filepath.Join(dir, tt.template))
+			if err != nil {
+				t.Fatalf("loadTemplateFile() error = %v", err)
+			}
+			notifier.renderer.templates.templates[tt.template] = tmpl
+
+			request := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+			request.Template = tt.template
+			if err := notifier.SendNotification(request); err != nil {
+				t.Fatalf("SendNotification() error = %v", err)
+			}
+
+			notification := transport.notifications[0]
+			if notification.Title != tt.expectedTitle || notification.Body != tt.expectedBody {
+				t.Errorf("Expected %q / %q, got %q / %q", tt.expectedTitle, tt.expectedBody, notification.Title, notification.Body)
+			}
+		})
+	}
+}
+
+func TestTransportNotifier_ChangeAlerts(t *testing.T) {
+	start := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant).EventTime
+	tests := []struct {
+		change        alerts.EventChange
+		expectedTitle string
+		expectedBody  string // Expected part of the body
+	}{
+		{alerts.EventChange{Kind: alerts.ChangeNew}, "New: Team Meeting", "Starts "},
+		{alerts.EventChange{Kind: alerts.ChangeMoved, OldStart: start.Add(-time.Hour), OldEnd: start}, "Moved: Team Meeting", "Moved from "},
+		{alerts.EventChange{Kind: alerts.ChangeLocation, OldLocation: "Room 0"}, "Location changed: Team Meeting", "Location changed from Room 0"},
+		{alerts.EventChange{Kind: alerts.ChangeCancelled}, "Cancelled: Team Meeting", "Was "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.change.Kind, func(t *testing.T) {
+			notifier, transport := newRecordingNotifier(false)
+			notifier.renderer.now = func() time.Time { return start.Add(-24 * time.Hour) }
+
+			request := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+			change := tt.change
+			request.Change = &change
+			if err := notifier.SendNotification(request); err != nil {
+				t.Fatalf("SendNotification() error = %v", err)
+			}
+
+			notification := transport.notifications[0]
+			if notification.Title != tt.expectedTitle || !strings.Contains(notification.Body, tt.expectedBody) {
+				t.Errorf("Expected %q / %q, got %q / %q", tt.expectedTitle, tt.expectedBody, notification.Title, notification.Body)
+			}
+			if notification.Data.Change != tt.change.Kind || notification.Data.OldLocation != tt.change.OldLocation ||
+				!notification.Data.OldStart.Equal(tt.change.OldStart) {
+				t.Errorf("Expected change data for %+v, got %+v", tt.change, notification.Data)
+			}
+		})
+	}
+}
+
+func TestTransportNotifier_Digest(t *testing.T) {
+	notifier, transport := newRecordingNotifier(false)
+
+	lunch := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+	standup := newTestAlertRequest("/test/path", "Standup", withUID("standup"), startingAt(lunch.EventTime.Add(-time.Hour)))
+
+	request := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+	request.Event = storage.NewDigestEvent("calwatch-digest/2024-01-15", "Agenda for Monday", lunch.EventTime.Add(-6*time.Hour))
+	request.Template = ""
+	request.Digest = true
+	request.Agenda = []alerts.AgendaItem{
+		{Event: standup.Event, Start: standup.Event.GetStartTime()},
+		{Event: lunch.Event, Start: lunch.EventTime},
+	}
+	if err := notifier.SendNotification(request); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+
+	notification := transport.notifications[0]
+	data := notification.Data
+	if notification.Title != "Agenda for Monday" || !data.Digest || len(data.Agenda) != 2 {
+		t.Fatalf("Expected digest with 2 agenda entries, got %q with %+v", notification.Title, data.Agenda)
+	}
+	expected := data.Agenda[0].StartTime + "  Standup\n" + data.Agenda[1].StartTime + "  Team Meeting at Room 1\n"
+	if notification.Body != expected {
+		t.Errorf("Expected body %q, got %q", expected, notification.Body)
+	}
+
+	// Days without events
+	request.Agenda = nil
+	if err := notifier.SendNotification(request); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+	if body := transport.notifications[1].Body; body != "No events today\n" {
+		t.Errorf("Expected empty agenda body, got %q", body)
+	}
+}
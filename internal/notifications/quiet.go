@@ -0,0 +1,149 @@
+package notifications
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/config"
+	"calwatch/internal/localday"
+	"calwatch/internal/storage"
+)
+
+// Quiet times are looked up this many days ahead, longer quiet times end early
+const quietHorizonDays = 14
+
+// QuietHours decides what happens to alerts during quiet hours: global
+// schedules, replaced per calendar, made of weekly periods and holidays from
+// a designated calendar
+type QuietHours struct {
+	global    config.QuietHoursConfig
+	calendars map[string]config.QuietHoursConfig // By calendar directory
+	events    storage.EventStorage               // For holidays
+}
+
+// quietInterval is a time range that is quiet
+type quietInterval struct {
+	start, end time.Time
+}
+
+// NewQuietHours creates quiet hours from the global and per-directory configuration
+func NewQuietHours(global config.QuietHoursConfig, directories []config.DirectoryConfig) *QuietHours {
+	q := &QuietHours{global: global, calendars: make(map[string]config.QuietHoursConfig)}
+	for _, directory := range directories {
+		if directory.QuietHours != nil {
+			q.calendars[filepath.Clean(directory.Directory)] = *directory.QuietHours
+		}
+	}
+	return q
+}
+
+// SetEventStorage sets the storage holiday calendars are looked up in
+func (q *QuietHours) SetEventStorage(events storage.EventStorage) {
+	q.events = events
+}
+
+// Check returns the action for an alert at now and the end of the quiet
+// hours, or "" if the alert is delivered as usual
+func (q *QuietHours) Check(request alerts.AlertRequest, now time.Time) (config.HoldPolicy, time.Time) {
+	schedule := q.scheduleFor(request.Event)
+	if !schedule.Enable || (request.Important && schedule.AllowImportant) {
+		return "", time.Time{}
+	}
+
+	until, quiet := q.quietUntil(schedule, now)
+	if !quiet {
+		return "", time.Time{}
+	}
+	return schedule.Action, until
+}
+
+// scheduleFor returns the quiet hours of the event's calendar
+func (q *QuietHours) scheduleFor(event storage.Event) config.QuietHoursConfig {
+	if member, ok := event.(storage.CalendarMember); ok && member.GetCalendar() != nil {
+		if schedule, exists := q.calendars[filepath.Clean(member.GetCalendar().Path)]; exists {
+			return schedule
+		}
+	}
+	return q.global
+}
+
+// quietUntil reports whether t is quiet and when the quiet time ends, joining
+// adjacent periods like a weekend following a weeknight
+func (q *QuietHours) quietUntil(schedule config.QuietHoursConfig, t time.Time) (time.Time, bool) {
+	intervals := q.intervals(schedule, localday.Of(t).Prev(), quietHorizonDays+1)
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start.Before(intervals[j].start)
+	})
+
+	var current *quietInterval
+	for i := range intervals {
+		interval := intervals[i]
+		switch {
+		case current != nil && !interval.start.After(current.end):
+			if interval.end.After(current.end) {
+				current.end = interval.end
+			}
+		case current != nil:
+			return current.end, true
+		case !interval.start.After(t) && interval.end.After(t):
+			current = &interval
+		}
+	}
+	if current != nil {
+		return current.end, true
+	}
+	return time.Time{}, false
+}
+
+// intervals returns the quiet intervals starting on the given days
+func (q *QuietHours) intervals(schedule config.QuietHoursConfig, first localday.Day, days int) []quietInterval {
+	holidays := q.holidays(schedule.HolidayCalendar, first, days)
+
+	var intervals []quietInterval
+	for day := first; localday.DaysBetween(first, day) < days; day = day.Next() {
+		for _, period := range schedule.Periods {
+			if !period.OnDay(day.Weekday()) {
+				continue
+			}
+			from, to := period.Range()
+			end := day.At(to)
+			if to <= from {
+				end = day.Next().At(to)
+			}
+			intervals = append(intervals, quietInterval{start: day.At(from), end: end})
+		}
+		if holidays[day.Key()] {
+			intervals = append(intervals, quietInterval{start: day.Start(), end: day.End()})
+		}
+	}
+	return intervals
+}
+
+// holidays returns the days with events in the holiday calendar by day key
+func (q *QuietHours) holidays(calendar string, first localday.Day, days int) map[string]bool {
+	holidays := make(map[string]bool)
+	if calendar == "" || q.events == nil {
+		return holidays
+	}
+
+	start, end := first.Start(), first.AddDays(days).Start()
+	for _, event := range q.events.GetAllEvents() {
+		if !storage.InCalendars(event, []string{calendar}) {
+			continue
+		}
+		duration := event.GetEndTime().Sub(event.GetStartTime())
+		for _, occurrence := range event.OccurredWithin(start.Add(-duration), end) {
+			// Every day the occurrence overlaps, the end is exclusive
+			last := occurrence.Add(duration)
+			if duration > 0 {
+				last = last.Add(-time.Nanosecond)
+			}
+			for day := localday.Of(occurrence); !day.After(localday.Of(last)); day = day.Next() {
+				holidays[day.Key()] = true
+			}
+		}
+	}
+	return holidays
+}
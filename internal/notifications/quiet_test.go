@@ -0,0 +1,154 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/config"
+	"calwatch/internal/localday"
+	"calwatch/internal/storage"
+)
+
+func TestQuietHours_Check(t *testing.T) {
+	tuesday := localday.Date(2024, 1, 16)
+	at := func(day localday.Day, hour int) time.Time {
+		return day.Start().Add(time.Duration(hour) * time.Hour)
+	}
+
+	// A public holiday on Wednesday
+	events := storage.NewMemoryEventStorage()
+	holidays := storage.NewCalendar("/calendars/holidays", "", []storage.Alert{})
+	holiday := storage.NewCalendarEvent("holiday", "Holiday", "", "", tuesday.Next().Start(), tuesday.Next().End(),
+		localday.Location(), nil, holidays, []storage.Alert{})
+	holiday.AllDay = true
+	events.UpsertEvent(holiday)
+
+	global := config.QuietHoursConfig{
+		Enable: true,
+		Periods: []config.QuietPeriodConfig{
+			{From: "22:00", To: "07:00"},
+			{Days: []string{"sat", "sun"}},
+		},
+		AllowImportant: true,
+	}
+	onCall := config.QuietHoursConfig{Enable: false}
+	withHolidays := global
+	withHolidays.HolidayCalendar = "/calendars/holidays"
+	for _, schedule := range []*config.QuietHoursConfig{&global, &onCall, &withHolidays} {
+		if err := schedule.Validate(); err != nil {
+			t.Fatalf("Validate() error = %v", err)
+		}
+	}
+
+	quiet := NewQuietHours(global, []config.DirectoryConfig{
+		{Directory: "/calendars/oncall", QuietHours: &onCall},
+		{Directory: "/calendars/work", QuietHours: &withHolidays},
+	})
+	quiet.SetEventStorage(events)
+
+	tests := []struct {
+		name           string
+		calendar       string
+		important      bool
+		now            time.Time
+		expectedAction config.HoldPolicy
+		expectedUntil  time.Time
+	}{
+		{"night", "/calendars/personal", false, at(tuesday, 23), config.HoldDefer, at(tuesday.Next(), 7)},
+		{"after midnight", "/calendars/personal", false, at(tuesday, 3), config.HoldDefer, at(tuesday, 7)},
+		{"day", "/calendars/personal", false, at(tuesday, 12), "", time.Time{}},
+		{"important breaks through", "/calendars/personal", true, at(tuesday, 23), "", time.Time{}},
+		{"weekend joins the nights", "/calendars/personal", false, at(tuesday.AddDays(3), 23), config.HoldDefer, at(tuesday.AddDays(6), 7)},
+		{"calendar without quiet hours", "/calendars/oncall", false, at(tuesday, 23), "", time.Time{}},
+		{"holiday joins the nights", "/calendars/work", false, at(tuesday, 23), config.HoldDefer, at(tuesday.AddDays(2), 7)},
+		{"holiday", "/calendars/work", false, at(tuesday.Next(), 12), config.HoldDefer, at(tuesday.AddDays(2), 7)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := newTestAlertRequest(tt.calendar, "Meeting")
+			request.Important = tt.important
+
+			action, until := quiet.Check(request, tt.now)
+			if action != tt.expectedAction || !until.Equal(tt.expectedUntil) {
+				t.Errorf("Expected %q until %v, got %q until %v", tt.expectedAction, tt.expectedUntil, action, until)
+			}
+		})
+	}
+}
+
+func TestOutbox_QuietHours(t *testing.T) {
+	localday.SetLocation(time.UTC)
+	defer localday.SetLocation(time.Local)
+
+	now := time.Date(2024, 1, 15, 19, 45, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 16, 7, 0, 0, 0, time.UTC)
+	newQuietOutbox := func(action config.HoldPolicy) (*Outbox, *recordingNotifier) {
+		schedule := config.QuietHoursConfig{Enable: true, Action: action, Periods: []config.QuietPeriodConfig{{From: "19:00", To: "07:00"}}}
+		if err := schedule.Validate(); err != nil {
+			t.Fatalf("Validate() error = %v", err)
+		}
+		desktop := &recordingNotifier{}
+		outbox := newOutboxTestOutbox(t, newOutboxTestManager(map[string]*recordingNotifier{"desktop": desktop}), &now)
+		outbox.SetQuietHours(NewQuietHours(schedule, nil))
+		return outbox, desktop
+	}
+	second := newTestAlertRequest("/calendars/work", "Late show", withUID("late-uid"), startingAt(now.Add(2*time.Hour)))
+
+	t.Run("deferred as digest", func(t *testing.T) {
+		outbox, desktop := newQuietOutbox(config.HoldDefer)
+		if err := outbox.Enqueue([]alerts.AlertRequest{newTestAlertRequest("/calendars/work", "Meeting")}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		if err := outbox.Enqueue([]alerts.AlertRequest{second, second}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		outbox.Deliver()
+		if desktop.sent != 0 || outbox.Len() != 1 {
+			t.Fatalf("Expected one digest held back, got %d sent and %d queued", desktop.sent, outbox.Len())
+		}
+		if next, ok := outbox.NextAttempt(); !ok || !next.Equal(end) {
+			t.Errorf("Expected delivery at %v, got %v", end, next)
+		}
+
+		// Held back across a restart
+		replayed := NewOutbox(newOutboxTestManager(map[string]*recordingNotifier{"desktop": desktop}), outbox.filePath)
+		replayed.now = func() time.Time { return now }
+		if err := replayed.Load(); err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		replayed.Deliver()
+		if desktop.sent != 0 {
+			t.Fatalf("Expected the digest to wait for the end of quiet hours after a restart")
+		}
+
+		now = end
+		defer func() { now = time.Date(2024, 1, 15, 19, 45, 0, 0, time.UTC) }()
+		replayed.Deliver()
+		if desktop.sent != 1 || !desktop.last.Digest || desktop.last.Late || len(desktop.last.Agenda) != 2 {
+			t.Fatalf("Expected an on-time digest of 2 alerts, got %d sent: %+v", desktop.sent, desktop.last)
+		}
+		if desktop.last.Agenda[0].Event.GetSummary() != "Meeting" || desktop.last.Agenda[1].Event.GetSummary() != "Late show" {
+			t.Errorf("Unexpected deferred alerts %q, %q", desktop.last.Agenda[0].Event.GetSummary(), desktop.last.Agenda[1].Event.GetSummary())
+		}
+	})
+
+	t.Run("dropped", func(t *testing.T) {
+		outbox, desktop := newQuietOutbox(config.HoldDrop)
+		outbox.Enqueue([]alerts.AlertRequest{newTestAlertRequest("/calendars/work", "Meeting")})
+		outbox.Deliver()
+		if desktop.sent != 0 || outbox.Len() != 0 {
+			t.Errorf("Expected the alert to be dropped, got %d sent and %d queued", desktop.sent, outbox.Len())
+		}
+	})
+
+	t.Run("silent", func(t *testing.T) {
+		outbox, desktop := newQuietOutbox(config.HoldSilent)
+		outbox.Enqueue([]alerts.AlertRequest{newTestAlertRequest("/calendars/work", "Meeting")})
+		outbox.Deliver()
+		if desktop.sent != 1 || !desktop.last.Silent {
+			t.Errorf("Expected a silent alert, got %d sent: %+v", desktop.sent, desktop.last)
+		}
+	})
+}
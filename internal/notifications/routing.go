@@ -0,0 +1,73 @@
+package notifications
+
+import (
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/config"
+	"calwatch/internal/localday"
+	"calwatch/internal/storage"
+)
+
+// selectBackends returns the backends of all routes matching the request, in
+// backend order. Without routes every backend is selected.
+func (nm *NotificationManager) selectBackends(request alerts.AlertRequest) []*notificationBackend {
+	if len(nm.routes) == 0 {
+		return nm.backends
+	}
+
+	now := nm.now().In(localday.Location())
+	selected := make(map[string]bool)
+	for _, route := range nm.routes {
+		if !nm.routeMatches(route, request, now) {
+			continue
+		}
+		for _, name := range route.Backends {
+			selected[name] = true
+		}
+	}
+
+	var backends []*notificationBackend
+	for _, backend := range nm.backends {
+		if selected[backend.name] {
+			backends = append(backends, backend)
+		}
+	}
+	return backends
+}
+
+// routeMatches reports whether an alert satisfies all conditions of a route
+func (nm *NotificationManager) routeMatches(route config.RouteConfig, request alerts.AlertRequest, now time.Time) bool {
+	if route.Important != nil && *route.Important != request.Important {
+		return false
+	}
+	if route.Late != nil && *route.Late != request.Late {
+		return false
+	}
+	if route.MinOffset != nil {
+		minOffset, err := route.MinOffset.Duration()
+		if err == nil && request.AlertOffset < minOffset {
+			return false
+		}
+	}
+	if len(route.Calendars) > 0 && !storage.InCalendars(request.Event, route.Calendars) {
+		return false
+	}
+	if len(route.Priorities) > 0 {
+		priority := nm.classifier.ClassifyEvent(request.Event).String()
+		if !containsString(route.Priorities, priority) {
+			return false
+		}
+	}
+	return route.InTimeWindow(now)
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
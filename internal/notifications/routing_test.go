@@ -0,0 +1,185 @@
+package notifications
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"calwatch/internal/alerts"
+	"calwatch/internal/config"
+	"calwatch/internal/localday"
+)
+
+// recordingNotifier counts the notifications it receives
+type recordingNotifier struct {
+	sent int
+	last alerts.AlertRequest
+	err  error
+}
+
+func (r *recordingNotifier) SendNotification(request alerts.AlertRequest) error {
+	r.sent++
+	r.last = request
+	return r.err
+}
+
+func (r *recordingNotifier) SendNotificationWithContext(request NotificationRequest) error {
+	return r.SendNotification(request.AlertRequest)
+}
+
+func (r *recordingNotifier) LoadTemplate(path string) (*template.Template, error) {
+	return loadTemplateFile(path)
+}
+
+func (r *recordingNotifier) ValidateTemplate(tmpl *template.Template, data TemplateData) error {
+	return validateTemplate(tmpl, data)
+}
+
+func (r *recordingNotifier) SetConfig(config config.NotificationConfig) {}
+
+// withAlertOffset changes the alert offset of a request
+func withAlertOffset(request alerts.AlertRequest, offset time.Duration) alerts.AlertRequest {
+	request.AlertOffset = offset
+	return request
+}
+
+func TestNotificationManager_Routing(t *testing.T) {
+	localday.SetLocation(time.UTC)
+	defer localday.SetLocation(time.Local)
+
+	yes, no := true, false
+	routes := []config.RouteConfig{
+		// Desktop always
+		{Backends: []string{"desktop"}},
+		// Phone only for important work alerts after hours
+		{Backends: []string{"phone"}, Calendars: []string{"work"}, Important: &yes, After: "18:00", Before: "08:00"},
+		// Speech for late alerts that are not important
+		{Backends: []string{"speech"}, Late: &yes, Important: &no},
+		// Log critical events
+		{Backends: []string{"log"}, Priorities: []string{"critical"}},
+		// Email day-ahead alerts
+		{Backends: []string{"email"}, MinOffset: &config.AlertConfig{Value: 1, Unit: "days"}},
+	}
+
+	tests := []struct {
+		name     string
+		request  alerts.AlertRequest
+		now      time.Time
+		expected map[string]int
+	}{
+		{
+			name:     "important work alert after hours",
+			request:  newTestAlertRequest("/home/user/.calendars/work", "Deploy", asImportant),
+			now:      time.Date(2024, 1, 15, 19, 45, 0, 0, time.UTC),
+			expected: map[string]int{"desktop": 1, "phone": 1},
+		},
+		{
+			name:     "important work alert after midnight",
+			request:  newTestAlertRequest("/home/user/.calendars/work", "Deploy", asImportant),
+			now:      time.Date(2024, 1, 16, 6, 30, 0, 0, time.UTC),
+			expected: map[string]int{"desktop": 1, "phone": 1},
+		},
+		{
+			name:     "important work alert during office hours",
+			request:  newTestAlertRequest("/home/user/.calendars/work", "Deploy", asImportant),
+			now:      time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+			expected: map[string]int{"desktop": 1},
+		},
+		{
+			name:     "important personal alert after hours",
+			request:  newTestAlertRequest("/home/user/.calendars/personal", "Dinner", asImportant),
+			now:      time.Date(2024, 1, 15, 19, 45, 0, 0, time.UTC),
+			expected: map[string]int{"desktop": 1},
+		},
+		{
+			name:     "late normal alert",
+			request:  newTestAlertRequest("/home/user/.calendars/personal", "Dinner", asLate),
+			now:      time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+			expected: map[string]int{"desktop": 1, "speech": 1},
+		},
+		{
+			name:     "critical event",
+			request:  newTestAlertRequest("/home/user/.calendars/personal", "Urgent: call the bank"),
+			now:      time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+			expected: map[string]int{"desktop": 1, "log": 1},
+		},
+		{
+			name:     "day-ahead alert",
+			request:  withAlertOffset(newTestAlertRequest("/home/user/.calendars/personal", "Dinner"), 24*time.Hour),
+			now:      time.Date(2024, 1, 14, 20, 0, 0, 0, time.UTC),
+			expected: map[string]int{"desktop": 1, "email": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &NotificationManager{
+				routes:     routes,
+				classifier: alerts.NewPriorityClassifier(),
+				now:        func() time.Time { return tt.now },
+			}
+			notifiers := make(map[string]*recordingNotifier)
+			for _, name := range []string{"desktop", "phone", "speech", "log", "email"} {
+				notifiers[name] = &recordingNotifier{}
+				manager.AddNamedNotifier(name, notifiers[name])
+			}
+
+			if err := manager.SendNotification(tt.request); err != nil {
+				t.Fatalf("SendNotification() error = %v", err)
+			}
+
+			for name, notifier := range notifiers {
+				if notifier.sent != tt.expected[name] {
+					t.Errorf("Expected %d notifications via %s, got %d", tt.expected[name], name, notifier.sent)
+				}
+			}
+		})
+	}
+}
+
+func TestNotificationManager_PerBackendErrors(t *testing.T) {
+	manager := &NotificationManager{classifier: alerts.NewPriorityClassifier(), now: time.Now}
+	failing := &recordingNotifier{err: errors.New("connection refused")}
+	working := &recordingNotifier{}
+	manager.AddNamedNotifier("phone", failing)
+	manager.AddNamedNotifier("desktop", working)
+
+	err := manager.SendNotification(newTestAlertRequest("/calendars/work", "Meeting"))
+	if err == nil {
+		t.Fatal("Expected error from failing backend")
+	}
+	if !strings.Contains(err.Error(), "backend phone: connection refused") {
+		t.Errorf("Expected error to name the failing backend, got %v", err)
+	}
+	if strings.Contains(err.Error(), "desktop") {
+		t.Errorf("Expected no error for working backend, got %v", err)
+	}
+	if working.sent != 1 {
+		t.Errorf("Expected working backend to be notified despite the failure, got %d", working.sent)
+	}
+}
+
+func TestNewNotificationManager_NamedBackends(t *testing.T) {
+	manager := NewNotificationManager(config.NotificationConfig{
+		Backend: "notify-send",
+		Backends: map[string]config.BackendConfig{
+			"speech": {Type: "exec", Exec: config.ExecConfig{Command: "espeak"}},
+			"phone":  {Type: "webhook", Webhook: config.WebhookConfig{URL: "https://ntfy.sh/calwatch"}},
+		},
+	})
+
+	if len(manager.backends) != 2 {
+		t.Fatalf("Expected 2 backends, got %d", len(manager.backends))
+	}
+	if manager.backends[0].name != "phone" || manager.backends[1].name != "speech" {
+		t.Errorf("Expected backends [phone speech], got [%s %s]", manager.backends[0].name, manager.backends[1].name)
+	}
+	if _, ok := manager.backends[0].notifier.(*WebhookNotifier); !ok {
+		t.Errorf("Expected phone to be a WebhookNotifier, got %T", manager.backends[0].notifier)
+	}
+	if _, ok := manager.backends[1].notifier.(*ExecNotifier); !ok {
+		t.Errorf("Expected speech to be an ExecNotifier, got %T", manager.backends[1].notifier)
+	}
+}
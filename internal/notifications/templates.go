@@ -0,0 +1,218 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/adrg/xdg"
+)
+
+// alertPayload is the rendered notification with all template data, as
+// passed to commands (CALWATCH_* variables, JSON on stdin) and webhooks
+type alertPayload struct {
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	Urgency string `json:"urgency"` // "low", "normal" or "critical"
+	TemplateData
+}
+
+// Directory next to the templates holding partials shared by all templates
+const partialsDir = "partials"
+
+// templateCache loads notification templates by name and caches them
+type templateCache struct {
+	templates       map[string]*template.Template
+	defaultTemplate *template.Template
+	mutex           sync.Mutex
+
+	// Built-in templates for change alerts and digests without a template
+	defaultChangeTemplate *template.Template
+	defaultAgendaTemplate *template.Template
+
+	// Versions of the templates for servers with markup, see markupTemplate
+	markup map[*template.Template]*htmltemplate.Template
+}
+
+// markupFuncs override templateFuncs in templates rendered for servers with
+// markup, where escape marks text as escaped instead of escaping it twice
+var markupFuncs = htmltemplate.FuncMap{
+	"escape": func(s string) htmltemplate.HTML { return htmltemplate.HTML(html.EscapeString(s)) },
+}
+
+// newTemplateCache creates a cache that falls back to the built-in default template
+func newTemplateCache() *templateCache {
+	return &templateCache{
+		templates:       make(map[string]*template.Template),
+		markup:          make(map[*template.Template]*htmltemplate.Template),
+		defaultTemplate: template.Must(template.New("default").Funcs(templateFuncs).Parse(defaultTemplateText)),
+
+		defaultChangeTemplate: template.Must(template.New("change").Funcs(templateFuncs).Parse(defaultChangeTemplateText)),
+		defaultAgendaTemplate: template.Must(template.New("agenda").Funcs(templateFuncs).Parse(defaultAgendaTemplateText)),
+	}
+}
+
+// get retrieves or loads a template by name from the XDG config or the
+// bundled templates directory
+func (c *templateCache) get(templateName string) (*template.Template, error) {
+	if templateName == "" {
+		return c.defaultTemplate, nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if tmpl, exists := c.templates[templateName]; exists {
+		return tmpl, nil
+	}
+
+	templatePath, err := xdg.SearchConfigFile(filepath.Join("calwatch", "templates", templateName))
+	if err != nil {
+		templatePath = filepath.Join("templates", templateName)
+	}
+
+	tmpl, err := loadTemplateFile(templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template %s: %w", templateName, err)
+	}
+
+	c.templates[templateName] = tmpl
+	return tmpl, nil
+}
+
+// markupTemplate returns a version of a template for servers with markup,
+// which escapes event text and function results but keeps the markup
+// written in the template itself
+func (c *templateCache) markupTemplate(tmpl *template.Template) (*htmltemplate.Template, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if markup, exists := c.markup[tmpl]; exists {
+		return markup, nil
+	}
+
+	markup := htmltemplate.New(tmpl.Name()).Funcs(htmltemplate.FuncMap(templateFuncs)).Funcs(markupFuncs)
+	for _, associated := range tmpl.Templates() {
+		if associated.Tree == nil {
+			continue
+		}
+		// Escaping rewrites the parse tree, the plain template keeps its own
+		if _, err := markup.AddParseTree(associated.Name(), associated.Tree.Copy()); err != nil {
+			return nil, fmt.Errorf("failed to prepare template %s for markup: %w", tmpl.Name(), err)
+		}
+	}
+
+	// AddParseTree adds the template itself as a new template of the set
+	markup = markup.Lookup(tmpl.Name())
+	c.markup[tmpl] = markup
+	return markup, nil
+}
+
+// loadTemplateFile loads a template from a file path together with the
+// partials in the partials directory next to it
+func loadTemplateFile(path string) (*template.Template, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template file %s: %w", path, err)
+	}
+
+	tmpl := template.New(filepath.Base(path)).Funcs(templateFuncs)
+	if err := parsePartials(tmpl, filepath.Join(filepath.Dir(path), partialsDir)); err != nil {
+		return nil, err
+	}
+
+	// Parsed last, so definitions in the template override shared partials
+	if _, err := tmpl.Parse(string(content)); err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+
+	return tmpl, nil
+}
+
+// parsePartials adds the *.tpl files of a directory to a template, making the
+// templates they {{define}} available to {{template}}
+func parsePartials(tmpl *template.Template, dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tpl"))
+	if err != nil {
+		return fmt.Errorf("failed to list partials in %s: %w", dir, err)
+	}
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read partial %s: %w", path, err)
+		}
+		if _, err := tmpl.New(partialsDir + "/" + filepath.Base(path)).Parse(string(content)); err != nil {
+			return fmt.Errorf("failed to parse partial %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// executeTemplate renders the title and body of a notification. Templates
+// may {{define "title"}}, otherwise the event summary is the title, and may
+// {{define "body"}}, otherwise the template itself is the body.
+func executeTemplate(tmpl *template.Template, data TemplateData) (title, body string, err error) {
+	if title, err = executeTitle(tmpl, data); err != nil {
+		return "", "", err
+	}
+
+	bodyTemplate := tmpl
+	if defined := tmpl.Lookup("body"); defined != nil {
+		bodyTemplate = defined
+	}
+	var buf bytes.Buffer
+	if err := bodyTemplate.Execute(&buf, data); err != nil {
+		return "", "", err
+	}
+
+	return title, buf.String(), nil
+}
+
+// executeMarkupTemplate renders a notification like executeTemplate, with
+// the body rendered from the markup version of the template. The title is
+// plain text for all servers.
+func executeMarkupTemplate(tmpl *template.Template, markup *htmltemplate.Template, data TemplateData) (title, body string, err error) {
+	if title, err = executeTitle(tmpl, data); err != nil {
+		return "", "", err
+	}
+
+	bodyTemplate := markup
+	if defined := markup.Lookup("body"); defined != nil {
+		bodyTemplate = defined
+	}
+	var buf bytes.Buffer
+	if err := bodyTemplate.Execute(&buf, data); err != nil {
+		return "", "", err
+	}
+
+	return title, buf.String(), nil
+}
+
+// executeTitle renders the title a template defines, or returns the event summary
+func executeTitle(tmpl *template.Template, data TemplateData) (string, error) {
+	titleTemplate := tmpl.Lookup("title")
+	if titleTemplate == nil {
+		return data.Summary, nil
+	}
+
+	var buf bytes.Buffer
+	if err := titleTemplate.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// validateTemplate executes a template with sample data
+func validateTemplate(tmpl *template.Template, data TemplateData) error {
+	if _, _, err := executeTemplate(tmpl, data); err != nil {
+		return fmt.Errorf("template validation failed: %w", err)
+	}
+	return nil
+}
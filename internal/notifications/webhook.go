@@ -0,0 +1,252 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"calwatch/internal/config"
+)
+
+// Maximum number of response body bytes included in error messages
+const maxWebhookErrorBody = 512
+
+// webhookTemplateFuncs are available in webhook headers, fields and payloads,
+// in addition to templateFuncs
+var webhookTemplateFuncs = template.FuncMap{
+	// json encodes a value as JSON, e.g. {"title": {{json .Title}}}
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+// WebhookNotifier implements Notifier by sending alerts to an HTTP endpoint
+// such as ntfy, Gotify or a Matrix webhook bridge
+type WebhookNotifier struct {
+	*transportNotifier
+	webhookConfig config.WebhookConfig
+	client        *http.Client
+
+	// Parsed templates from the webhook configuration
+	headers map[string]*template.Template
+	fields  map[string]*template.Template
+	payload *template.Template
+}
+
+// NewWebhookNotifier creates a webhook notifier, parsing the configured templates
+func NewWebhookNotifier(webhookConfig config.WebhookConfig) (*WebhookNotifier, error) {
+	notifier := &WebhookNotifier{client: &http.Client{}}
+	notifier.transportNotifier = newTransportNotifier(notifier)
+	if err := notifier.setWebhookConfig(webhookConfig); err != nil {
+		return nil, err
+	}
+	return notifier, nil
+}
+
+// SetConfig sets the notification configuration
+func (w *WebhookNotifier) SetConfig(config config.NotificationConfig) {
+	w.transportNotifier.SetConfig(config)
+	if err := w.setWebhookConfig(config.Webhook); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: invalid webhook configuration: %v\n", err)
+	}
+}
+
+// setWebhookConfig applies the webhook configuration and parses its templates
+func (w *WebhookNotifier) setWebhookConfig(webhookConfig config.WebhookConfig) error {
+	headers, err := parseWebhookTemplates("header", webhookConfig.Headers)
+	if err != nil {
+		return err
+	}
+	fields, err := parseWebhookTemplates("field", webhookConfig.Fields)
+	if err != nil {
+		return err
+	}
+
+	var payload *template.Template
+	if webhookConfig.Payload != "" {
+		payload, err = template.New("payload").Funcs(templateFuncs).Funcs(webhookTemplateFuncs).Parse(webhookConfig.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to parse webhook payload template: %w", err)
+		}
+	}
+
+	timeout, err := webhookConfig.Timeout.ToDuration()
+	if err != nil {
+		timeout = 10 * time.Second
+	}
+
+	w.webhookConfig = webhookConfig
+	w.headers, w.fields, w.payload = headers, fields, payload
+	w.client.Timeout = timeout
+	return nil
+}
+
+// parseWebhookTemplates parses a map of named templates
+func parseWebhookTemplates(kind string, sources map[string]string) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template, len(sources))
+	for name, source := range sources {
+		tmpl, err := template.New(name).Funcs(templateFuncs).Funcs(webhookTemplateFuncs).Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse webhook %s %s: %w", kind, name, err)
+		}
+		templates[name] = tmpl
+	}
+	return templates, nil
+}
+
+// Deliver sends a notification to the webhook. Failed requests are retried
+// by the outbox, unless the webhook rejected them.
+func (w *WebhookNotifier) Deliver(notification Notification) error {
+	if w.webhookConfig.ImportantOnly && !notification.Important {
+		return nil
+	}
+
+	payload := notification.payload()
+	body, contentType, err := w.renderBody(payload)
+	if err != nil {
+		return err
+	}
+
+	headers, err := w.renderHeaders(payload)
+	if err != nil {
+		return err
+	}
+
+	if err := w.send(body, contentType, headers); err != nil {
+		return fmt.Errorf("webhook notification failed: %w", err)
+	}
+	return nil
+}
+
+// send performs a single request. Errors that won't go away on retry, e.g.
+// an invalid URL or client errors other than rate limiting, are permanent.
+func (w *WebhookNotifier) send(body []byte, contentType string, headers map[string]string) error {
+	method := w.webhookConfig.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequest(method, w.webhookConfig.URL, bytes.NewReader(body))
+	if err != nil {
+		return &PermanentError{Err: fmt.Errorf("failed to create request: %w", err)}
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "calwatch")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", req.URL.Redacted(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookErrorBody))
+	err = fmt.Errorf("%s returned %s", req.URL.Redacted(), resp.Status)
+	if text := strings.TrimSpace(string(message)); text != "" {
+		err = fmt.Errorf("%w: %s", err, text)
+	}
+
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return &PermanentError{Err: err}
+	}
+	return err
+}
+
+// renderBody renders the request body and returns it with its content type
+func (w *WebhookNotifier) renderBody(payload alertPayload) ([]byte, string, error) {
+	contentType := map[string]string{
+		"json": "application/json",
+		"form": "application/x-www-form-urlencoded",
+		"text": "text/plain; charset=utf-8",
+	}[w.webhookConfig.Format]
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	// A payload template defines the whole body
+	if w.payload != nil {
+		var buf bytes.Buffer
+		if err := w.payload.Execute(&buf, payload); err != nil {
+			return nil, "", fmt.Errorf("failed to render webhook payload: %w", err)
+		}
+		if contentType == "application/json" && !json.Valid(buf.Bytes()) {
+			return nil, "", fmt.Errorf("webhook payload is not valid JSON: %s", buf.String())
+		}
+		return buf.Bytes(), contentType, nil
+	}
+
+	switch w.webhookConfig.Format {
+	case "text":
+		return []byte(payload.Body), contentType, nil
+
+	case "form":
+		fields, err := w.renderFields(payload, map[string]string{"title": payload.Title, "message": payload.Body})
+		if err != nil {
+			return nil, "", err
+		}
+		values := url.Values{}
+		for name, value := range fields {
+			values.Set(name, value)
+		}
+		return []byte(values.Encode()), contentType, nil
+
+	default:
+		// Without fields the complete alert data is sent
+		var data []byte
+		var err error
+		if len(w.fields) == 0 {
+			data, err = json.Marshal(payload)
+		} else {
+			var fields map[string]string
+			if fields, err = w.renderFields(payload, nil); err != nil {
+				return nil, "", err
+			}
+			data, err = json.Marshal(fields)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode webhook payload: %w", err)
+		}
+		return data, contentType, nil
+	}
+}
+
+// renderFields renders the configured fields, or returns the defaults if none are configured
+func (w *WebhookNotifier) renderFields(payload alertPayload, defaults map[string]string) (map[string]string, error) {
+	if len(w.fields) == 0 {
+		return defaults, nil
+	}
+	return renderTemplateMap("field", w.fields, payload)
+}
+
+// renderHeaders renders the configured header templates
+func (w *WebhookNotifier) renderHeaders(payload alertPayload) (map[string]string, error) {
+	return renderTemplateMap("header", w.headers, payload)
+}
+
+// renderTemplateMap executes a map of named templates
+func renderTemplateMap(kind string, templates map[string]*template.Template, payload alertPayload) (map[string]string, error) {
+	rendered := make(map[string]string, len(templates))
+	for name, tmpl := range templates {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, payload); err != nil {
+			return nil, fmt.Errorf("failed to render webhook %s %s: %w", kind, name, err)
+		}
+		rendered[name] = buf.String()
+	}
+	return rendered, nil
+}
@@ -0,0 +1,283 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"calwatch/internal/config"
+)
+
+// webhookRecorder is an httptest handler that records requests and answers
+// with the configured status codes in order (200 once exhausted)
+type webhookRecorder struct {
+	mutex    sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (r *webhookRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, string(body))
+
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+	if status != http.StatusOK {
+		w.Write([]byte("try again later"))
+	}
+}
+
+func newTestWebhookNotifier(t *testing.T, webhookConfig config.WebhookConfig) *WebhookNotifier {
+	t.Helper()
+	notifier, err := NewWebhookNotifier(webhookConfig)
+	if err != nil {
+		t.Fatalf("NewWebhookNotifier() error = %v", err)
+	}
+	return notifier
+}
+
+func TestWebhookNotifier_DefaultJSONPayload(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	notifier := newTestWebhookNotifier(t, config.WebhookConfig{
+		URL: server.URL,
+		Headers: map[string]string{
+			"Authorization": "Bearer secret",
+			"X-Title":       "{{.Title}}",
+			"X-Priority":    "{{if .Important}}urgent{{else}}default{{end}}",
+		},
+	})
+
+	if err := notifier.SendNotification(newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+
+	if len(recorder.requests) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(recorder.requests))
+	}
+	req := recorder.requests[0]
+
+	if req.Method != http.MethodPost {
+		t.Errorf("Expected POST, got %s", req.Method)
+	}
+	if req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", req.Header.Get("Content-Type"))
+	}
+	expectedHeaders := map[string]string{
+		"Authorization": "Bearer secret",
+		"X-Title":       "Team Meeting",
+		"X-Priority":    "urgent",
+	}
+	for name, value := range expectedHeaders {
+		if req.Header.Get(name) != value {
+			t.Errorf("Expected header %s=%q, got %q", name, value, req.Header.Get(name))
+		}
+	}
+
+	var payload alertPayload
+	if err := json.Unmarshal([]byte(recorder.bodies[0]), &payload); err != nil {
+		t.Fatalf("Failed to decode payload %q: %v", recorder.bodies[0], err)
+	}
+	if payload.Title != "Team Meeting" || !payload.Important || payload.AlertOffset != "15 minutes" {
+		t.Errorf("Unexpected payload: %+v", payload)
+	}
+	if !strings.Contains(payload.Body, "Team Meeting at Room 1") {
+		t.Errorf("Expected rendered body, got %q", payload.Body)
+	}
+}
+
+func TestWebhookNotifier_Formats(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      config.WebhookConfig
+		contentType string
+		check       func(t *testing.T, body string)
+	}{
+		{
+			name: "json fields",
+			config: config.WebhookConfig{
+				Format: "json",
+				Fields: map[string]string{"text": "{{.Title}} in {{.AlertOffset}}"},
+			},
+			contentType: "application/json",
+			check: func(t *testing.T, body string) {
+				if body != `{"text":"Team Meeting in 15 minutes"}` {
+					t.Errorf("Unexpected body %s", body)
+				}
+			},
+		},
+		{
+			name: "json payload template",
+			config: config.WebhookConfig{
+				Payload: `{"title": {{json .Title}}, "message": {{json .Body}}, "priority": {{if .Important}}8{{else}}4{{end}}}`,
+			},
+			contentType: "application/json",
+			check: func(t *testing.T, body string) {
+				var message struct {
+					Title    string `json:"title"`
+					Message  string `json:"message"`
+					Priority int    `json:"priority"`
+				}
+				if err := json.Unmarshal([]byte(body), &message); err != nil {
+					t.Fatalf("Failed to decode body %q: %v", body, err)
+				}
+				if message.Title != "Team Meeting" || message.Priority != 8 || !strings.Contains(message.Message, "\n") {
+					t.Errorf("Unexpected message: %+v", message)
+				}
+			},
+		},
+		{
+			name:        "form defaults",
+			config:      config.WebhookConfig{Format: "form"},
+			contentType: "application/x-www-form-urlencoded",
+			check: func(t *testing.T, body string) {
+				values, err := url.ParseQuery(body)
+				if err != nil {
+					t.Fatalf("Failed to parse form %q: %v", body, err)
+				}
+				if values.Get("title") != "Team Meeting" || !strings.HasPrefix(values.Get("message"), "Team Meeting at Room 1") {
+					t.Errorf("Unexpected form values: %v", values)
+				}
+			},
+		},
+		{
+			name:        "text",
+			config:      config.WebhookConfig{Format: "text"},
+			contentType: "text/plain; charset=utf-8",
+			check: func(t *testing.T, body string) {
+				if !strings.HasPrefix(body, "Team Meeting at Room 1\n") {
+					t.Errorf("Unexpected body %q", body)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &webhookRecorder{}
+			server := httptest.NewServer(recorder)
+			defer server.Close()
+
+			tt.config.URL = server.URL
+			notifier := newTestWebhookNotifier(t, tt.config)
+			if err := notifier.SendNotification(newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)); err != nil {
+				t.Fatalf("SendNotification() error = %v", err)
+			}
+
+			if len(recorder.requests) != 1 {
+				t.Fatalf("Expected 1 request, got %d", len(recorder.requests))
+			}
+			if contentType := recorder.requests[0].Header.Get("Content-Type"); contentType != tt.contentType {
+				t.Errorf("Expected content type %q, got %q", tt.contentType, contentType)
+			}
+			tt.check(t, recorder.bodies[0])
+		})
+	}
+}
+
+func TestWebhookNotifier_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		wantErr       bool
+		wantPermanent bool
+	}{
+		{"success", http.StatusOK, false, false},
+		{"server error", http.StatusServiceUnavailable, true, false},
+		{"rate limited", http.StatusTooManyRequests, true, false},
+		{"client error", http.StatusBadRequest, true, true},
+		{"unauthorized", http.StatusUnauthorized, true, true},
+		{"not found", http.StatusNotFound, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &webhookRecorder{statuses: []int{tt.status}}
+			server := httptest.NewServer(recorder)
+			defer server.Close()
+
+			notifier := newTestWebhookNotifier(t, config.WebhookConfig{URL: server.URL})
+
+			err := notifier.SendNotification(newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SendNotification() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "try again later") {
+				t.Errorf("Expected response body in error, got %v", err)
+			}
+			var permanent *PermanentError
+			if errors.As(err, &permanent) != tt.wantPermanent {
+				t.Errorf("Expected permanent error %v, got %v", tt.wantPermanent, err)
+			}
+			// Failed requests are retried by the outbox, not the notifier
+			if len(recorder.requests) != 1 {
+				t.Errorf("Expected a single request, got %d", len(recorder.requests))
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_InvalidURL(t *testing.T) {
+	notifier := newTestWebhookNotifier(t, config.WebhookConfig{URL: "http://[::1"})
+
+	err := notifier.SendNotification(newTestAlertRequest("/test/path", "Team Meeting"))
+	var permanent *PermanentError
+	if !errors.As(err, &permanent) {
+		t.Errorf("Expected permanent error for an invalid URL, got %v", err)
+	}
+}
+
+func TestWebhookNotifier_ImportantOnly(t *testing.T) {
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	notifier := newTestWebhookNotifier(t, config.WebhookConfig{URL: server.URL, ImportantOnly: true})
+
+	request := newTestAlertRequest("/test/path", "Team Meeting", withDetails("Weekly sync", "Room 1"), asImportant)
+	request.Important = false
+	if err := notifier.SendNotification(request); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+	if len(recorder.requests) != 0 {
+		t.Errorf("Expected normal alert to be filtered, got %d requests", len(recorder.requests))
+	}
+
+	request.Important = true
+	if err := notifier.SendNotification(request); err != nil {
+		t.Fatalf("SendNotification() error = %v", err)
+	}
+	if len(recorder.requests) != 1 {
+		t.Errorf("Expected important alert to be sent, got %d requests", len(recorder.requests))
+	}
+}
+
+func TestNewWebhookNotifier_InvalidTemplates(t *testing.T) {
+	configs := []config.WebhookConfig{
+		{URL: "http://localhost", Headers: map[string]string{"X-Title": "{{.Title"}},
+		{URL: "http://localhost", Fields: map[string]string{"text": "{{if}}"}},
+		{URL: "http://localhost", Payload: "{{json}"},
+	}
+
+	for _, webhookConfig := range configs {
+		if _, err := NewWebhookNotifier(webhookConfig); err == nil {
+			t.Errorf("Expected error for config %+v", webhookConfig)
+		}
+	}
+}
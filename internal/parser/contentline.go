@@ -0,0 +1,405 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Property is a single unfolded content line (NAME;PARAM=value:VALUE)
+type Property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Param returns the value of a property parameter
+func (p Property) Param(name string) string {
+	return p.Params[strings.ToUpper(name)]
+}
+
+// Text returns the property value with TEXT escaping removed
+func (p Property) Text() string {
+	return unescapeText(p.Value)
+}
+
+// Component is a BEGIN/END block with its properties and sub-components
+type Component struct {
+	Name       string
+	Properties []Property
+	Components []*Component
+}
+
+// Property returns the first property with the given name, or nil
+func (c *Component) Property(name string) *Property {
+	name = strings.ToUpper(name)
+	for i := range c.Properties {
+		if c.Properties[i].Name == name {
+			return &c.Properties[i]
+		}
+	}
+	return nil
+}
+
+// PropertiesNamed returns all properties with the given name
+func (c *Component) PropertiesNamed(name string) []Property {
+	name = strings.ToUpper(name)
+	var properties []Property
+	for _, property := range c.Properties {
+		if property.Name == name {
+			properties = append(properties, property)
+		}
+	}
+	return properties
+}
+
+// Value returns the raw value of the first property with the given name
+func (c *Component) Value(name string) string {
+	if property := c.Property(name); property != nil {
+		return property.Value
+	}
+	return ""
+}
+
+// Text returns the unescaped TEXT value of the first property with the given name
+func (c *Component) Text(name string) string {
+	if property := c.Property(name); property != nil {
+		return property.Text()
+	}
+	return ""
+}
+
+// Children returns the sub-components with the given name
+func (c *Component) Children(name string) []*Component {
+	name = strings.ToUpper(name)
+	var children []*Component
+	for _, child := range c.Components {
+		if child.Name == name {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// Maximum length of a single physical line
+const maxLineLength = 10 * 1024 * 1024
+
+// ContentLineReader reads unfolded RFC 5545 content lines from a stream
+type ContentLineReader struct {
+	scanner    *bufio.Scanner
+	pending    string // Physical line read ahead while unfolding
+	hasPending bool
+	lineNumber int // Line number of the last physical line read
+}
+
+// NewContentLineReader creates a reader for ICS data
+func NewContentLineReader(reader io.Reader) *ContentLineReader {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+	return &ContentLineReader{scanner: scanner}
+}
+
+// Line returns the physical line number of the most recently read line
+func (r *ContentLineReader) Line() int {
+	return r.lineNumber
+}
+
+// Next returns the next content line, joining folded continuation lines.
+// It returns io.EOF when the stream is exhausted.
+func (r *ContentLineReader) Next() (Property, error) {
+	line, err := r.nextLogicalLine()
+	if err != nil {
+		return Property{}, err
+	}
+
+	property, err := parseContentLine(line)
+	if err != nil {
+		return Property{}, fmt.Errorf("line %d: %w", r.lineNumber, err)
+	}
+	return property, nil
+}
+
+// nextLogicalLine returns the next non-empty unfolded line
+func (r *ContentLineReader) nextLogicalLine() (string, error) {
+	var builder strings.Builder
+
+	for {
+		physical, ok, err := r.nextPhysicalLine()
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			if builder.Len() > 0 {
+				return builder.String(), nil
+			}
+			return "", io.EOF
+		}
+
+		// A line starting with a space or tab continues the previous line
+		if len(physical) > 0 && (physical[0] == ' ' || physical[0] == '\t') {
+			if builder.Len() > 0 {
+				builder.WriteString(physical[1:])
+			}
+			continue
+		}
+
+		if builder.Len() > 0 {
+			r.pending, r.hasPending = physical, true
+			return builder.String(), nil
+		}
+
+		if strings.TrimSpace(physical) == "" {
+			continue
+		}
+		builder.WriteString(physical)
+	}
+}
+
+// nextPhysicalLine returns the next line of input without its line ending
+func (r *ContentLineReader) nextPhysicalLine() (string, bool, error) {
+	if r.hasPending {
+		r.hasPending = false
+		return r.pending, true, nil
+	}
+
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", false, fmt.Errorf("failed to read line %d: %w", r.lineNumber+1, err)
+		}
+		return "", false, nil
+	}
+
+	r.lineNumber++
+	return strings.TrimRight(r.scanner.Text(), "\r"), true, nil
+}
+
+// parseContentLine splits an unfolded line into name, parameters and value
+func parseContentLine(line string) (Property, error) {
+	property := Property{Params: make(map[string]string)}
+
+	// The name ends at the first ';' or ':'
+	nameEnd := strings.IndexAny(line, ";:")
+	if nameEnd <= 0 {
+		return Property{}, fmt.Errorf("invalid content line: %q", line)
+	}
+	property.Name = strings.ToUpper(strings.TrimSpace(line[:nameEnd]))
+
+	rest := line[nameEnd:]
+	for strings.HasPrefix(rest, ";") {
+		rest = rest[1:]
+
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return Property{}, fmt.Errorf("invalid parameter in content line: %q", line)
+		}
+		paramName := strings.ToUpper(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+
+		// Parameter values may be a comma separated list of (quoted) values
+		var values []string
+		for {
+			var value string
+			if strings.HasPrefix(rest, `"`) {
+				end := strings.IndexByte(rest[1:], '"')
+				if end < 0 {
+					return Property{}, fmt.Errorf("unterminated quoted parameter in content line: %q", line)
+				}
+				value, rest = rest[1:end+1], rest[end+2:]
+			} else {
+				end := strings.IndexAny(rest, ",;:")
+				if end < 0 {
+					return Property{}, fmt.Errorf("missing value in content line: %q", line)
+				}
+				value, rest = rest[:end], rest[end:]
+			}
+			values = append(values, value)
+
+			if !strings.HasPrefix(rest, ",") {
+				break
+			}
+			rest = rest[1:]
+		}
+		property.Params[paramName] = strings.Join(values, ",")
+	}
+
+	if !strings.HasPrefix(rest, ":") {
+		return Property{}, fmt.Errorf("missing value in content line: %q", line)
+	}
+	property.Value = rest[1:]
+
+	return property, nil
+}
+
+// unescapeText resolves TEXT escapes (\n, \N, \, \; and \\)
+func unescapeText(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+
+	var builder strings.Builder
+	builder.Grow(len(value))
+
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			builder.WriteByte(value[i])
+			continue
+		}
+
+		i++
+		switch value[i] {
+		case 'n', 'N':
+			builder.WriteByte('\n')
+		default:
+			builder.WriteByte(value[i])
+		}
+	}
+
+	return builder.String()
+}
+
+// splitListValue splits a comma separated value list such as EXDATE
+func splitListValue(value string) []string {
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+// splitTextList splits a comma separated TEXT list such as CATEGORIES,
+// keeping escaped commas in the unescaped values
+func splitTextList(value string) []string {
+	var values []string
+	start := 0
+	for i := 0; i <= len(value); i++ {
+		switch {
+		case i < len(value) && value[i] == '\\':
+			i++ // Skip the escaped character
+		case i == len(value) || value[i] == ',':
+			if part := strings.TrimSpace(unescapeText(value[start:i])); part != "" {
+				values = append(values, part)
+			}
+			start = i + 1
+		}
+	}
+	return values
+}
+
+// ReadComponents reads an iCalendar stream in a single pass and calls fn for
+// every component nested directly in a VCALENDAR (VEVENT, VTODO, VTIMEZONE, ...)
+// as soon as it is complete
+func ReadComponents(reader io.Reader, fn func(*Component) error) error {
+	lines := NewContentLineReader(reader)
+	var stack []*Component
+
+	for {
+		property, err := lines.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch property.Name {
+		case "BEGIN":
+			component := &Component{Name: strings.ToUpper(strings.TrimSpace(property.Value))}
+			if len(stack) == 0 && component.Name != "VCALENDAR" {
+				return fmt.Errorf("line %d: expected BEGIN:VCALENDAR, got BEGIN:%s", lines.Line(), component.Name)
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, component)
+			}
+			stack = append(stack, component)
+
+		case "END":
+			name := strings.ToUpper(strings.TrimSpace(property.Value))
+			if len(stack) == 0 {
+				return fmt.Errorf("line %d: unexpected END:%s without matching BEGIN", lines.Line(), name)
+			}
+			current := stack[len(stack)-1]
+			if current.Name != name {
+				return fmt.Errorf("line %d: mismatched BEGIN/END: expected %s, got %s", lines.Line(), current.Name, name)
+			}
+			stack = stack[:len(stack)-1]
+
+			// Hand top-level components to the caller and release them from
+			// the calendar so memory use does not grow with the file
+			if len(stack) == 1 {
+				calendar := stack[0]
+				calendar.Components = calendar.Components[:0]
+				if err := fn(current); err != nil {
+					return err
+				}
+			}
+
+		default:
+			if len(stack) == 0 {
+				return fmt.Errorf("line %d: property %s outside of VCALENDAR", lines.Line(), property.Name)
+			}
+			current := stack[len(stack)-1]
+			current.Properties = append(current.Properties, property)
+		}
+	}
+
+	if len(stack) > 0 {
+		names := make([]string, len(stack))
+		for i, component := range stack {
+			names[i] = component.Name
+		}
+		return fmt.Errorf("unclosed BEGIN statements: %v", names)
+	}
+
+	return nil
+}
+
+// parseComponent parses a single component (e.g. a VALARM block) from text
+func parseComponent(data string) (*Component, error) {
+	lines := NewContentLineReader(strings.NewReader(data))
+	var stack []*Component
+	var root *Component
+
+	for {
+		property, err := lines.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch property.Name {
+		case "BEGIN":
+			component := &Component{Name: strings.ToUpper(property.Value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, component)
+			} else if root == nil {
+				root = component
+			}
+			stack = append(stack, component)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(property.Value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", lines.Line(), property.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: property %s outside of component", lines.Line(), property.Name)
+			}
+			stack[len(stack)-1].Properties = append(stack[len(stack)-1].Properties, property)
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("no component found")
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unclosed component %s", stack[len(stack)-1].Name)
+	}
+	return root, nil
+}
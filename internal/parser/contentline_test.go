@@ -0,0 +1,217 @@
+package parser
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestContentLineReader_Unfolding(t *testing.T) {
+	data := "BEGIN:VCALENDAR\r\n" +
+		"DESCRIPTION:This is a long\r\n" +
+		"  description that was\r\n" +
+		"\tfolded twice\r\n" +
+		"\r\n" +
+		"END:VCALENDAR\r\n"
+
+	reader := NewContentLineReader(strings.NewReader(data))
+
+	var properties []Property
+	for {
+		property, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		properties = append(properties, property)
+	}
+
+	if len(properties) != 3 {
+		t.Fatalf("Expected 3 content lines, got %d", len(properties))
+	}
+
+	expected := "This is a long description that wasfolded twice"
+	if properties[1].Value != expected {
+		t.Errorf("Expected unfolded value %q, got %q", expected, properties[1].Value)
+	}
+}
+
+func TestParseContentLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected Property
+		wantErr  bool
+	}{
+		{
+			name:     "simple",
+			line:     "SUMMARY:Team meeting",
+			expected: Property{Name: "SUMMARY", Params: map[string]string{}, Value: "Team meeting"},
+		},
+		{
+			name:     "lowercase name",
+			line:     "summary:Team meeting",
+			expected: Property{Name: "SUMMARY", Params: map[string]string{}, Value: "Team meeting"},
+		},
+		{
+			name:     "parameter",
+			line:     "DTSTART;TZID=Europe/Berlin:20240115T090000",
+			expected: Property{Name: "DTSTART", Params: map[string]string{"TZID": "Europe/Berlin"}, Value: "20240115T090000"},
+		},
+		{
+			name: "quoted parameter with colon",
+			line: `ATTENDEE;CN="Doe, Jane: Lead";ROLE=CHAIR:mailto:jane@example.com`,
+			expected: Property{Name: "ATTENDEE", Params: map[string]string{"CN": "Doe, Jane: Lead", "ROLE": "CHAIR"},
+				Value: "mailto:jane@example.com"},
+		},
+		{
+			name:     "parameter list",
+			line:     `ATTENDEE;MEMBER="mailto:a@example.com","mailto:b@example.com":mailto:c@example.com`,
+			expected: Property{Name: "ATTENDEE", Params: map[string]string{"MEMBER": "mailto:a@example.com,mailto:b@example.com"}, Value: "mailto:c@example.com"},
+		},
+		{
+			name:     "empty value",
+			line:     "LOCATION:",
+			expected: Property{Name: "LOCATION", Params: map[string]string{}, Value: ""},
+		},
+		{
+			name:    "missing colon",
+			line:    "SUMMARY",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated quote",
+			line:    `ATTENDEE;CN="Jane:mailto:jane@example.com`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			property, err := parseContentLine(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if property.Name != tt.expected.Name || property.Value != tt.expected.Value {
+				t.Errorf("Expected %s:%q, got %s:%q", tt.expected.Name, tt.expected.Value, property.Name, property.Value)
+			}
+			if len(property.Params) != len(tt.expected.Params) {
+				t.Errorf("Expected params %v, got %v", tt.expected.Params, property.Params)
+			}
+			for key, value := range tt.expected.Params {
+				if property.Params[key] != value {
+					t.Errorf("Expected param %s=%q, got %q", key, value, property.Params[key])
+				}
+			}
+		})
+	}
+}
+
+func TestUnescapeText(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`plain text`, "plain text"},
+		{`Line one\nLine two`, "Line one\nLine two"},
+		{`Upper\NCase`, "Upper\nCase"},
+		{`Room 1\, Floor 2\; Building A`, "Room 1, Floor 2; Building A"},
+		{`C:\\Temp`, `C:\Temp`},
+		{`trailing\`, `trailing\`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := unescapeText(tt.input); got != tt.expected {
+				t.Errorf("unescapeText(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadComponents(t *testing.T) {
+	data := `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VTIMEZONE
+TZID:Europe/Berlin
+BEGIN:STANDARD
+DTSTART:19701025T030000
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0100
+END:STANDARD
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:event@example.com
+SUMMARY:Event
+BEGIN:VALARM
+TRIGGER:-PT15M
+END:VALARM
+END:VEVENT
+BEGIN:VTODO
+UID:todo@example.com
+END:VTODO
+END:VCALENDAR`
+
+	var components []*Component
+	err := ReadComponents(strings.NewReader(data), func(component *Component) error {
+		components = append(components, component)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadComponents() error = %v", err)
+	}
+
+	if len(components) != 3 {
+		t.Fatalf("Expected 3 top-level components, got %d", len(components))
+	}
+
+	expectedNames := []string{"VTIMEZONE", "VEVENT", "VTODO"}
+	for i, name := range expectedNames {
+		if components[i].Name != name {
+			t.Errorf("Expected component %d to be %s, got %s", i, name, components[i].Name)
+		}
+	}
+
+	if tz := components[0].Children("STANDARD"); len(tz) != 1 {
+		t.Errorf("Expected 1 STANDARD observance, got %d", len(tz))
+	}
+
+	event := components[1]
+	if event.Value("UID") != "event@example.com" {
+		t.Errorf("Expected UID event@example.com, got %q", event.Value("UID"))
+	}
+	if alarms := event.Children("VALARM"); len(alarms) != 1 || alarms[0].Value("TRIGGER") != "-PT15M" {
+		t.Errorf("Expected one VALARM with TRIGGER -PT15M, got %v", alarms)
+	}
+}
+
+func TestReadComponents_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"mismatched END", "BEGIN:VCALENDAR\nBEGIN:VEVENT\nEND:VTODO\nEND:VCALENDAR"},
+		{"unclosed component", "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:x\n"},
+		{"END without BEGIN", "END:VCALENDAR"},
+		{"property outside calendar", "VERSION:2.0\nBEGIN:VCALENDAR\nEND:VCALENDAR"},
+		{"not a calendar", "BEGIN:VCARD\nEND:VCARD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ReadComponents(strings.NewReader(tt.data), func(*Component) error { return nil })
+			if err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
@@ -5,12 +5,10 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/apognu/gocal"
 	"calwatch/internal/storage"
 	"calwatch/internal/recurrence"
 )
@@ -23,33 +21,47 @@ type CalDAVParser interface {
 	ValidateICS(data []byte) error
 }
 
-// GocalParser implements CalDAVParser using the gocal library
-type GocalParser struct {
+// ICSParser implements CalDAVParser with a single-pass RFC 5545 parser
+type ICSParser struct {
 	// Configuration options
 	maxEvents int
 	timeZone  *time.Location
+	calendar  *storage.Calendar // Calendar the parsed events belong to
 }
 
-// NewGocalParser creates a new parser instance
-func NewGocalParser() *GocalParser {
-	return &GocalParser{
+// NewICSParser creates a new parser instance
+func NewICSParser() *ICSParser {
+	return &ICSParser{
 		maxEvents: 10000, // Reasonable limit to prevent memory issues
 		timeZone:  time.Local,
 	}
 }
 
 // SetMaxEvents sets the maximum number of events to parse from a single file
-func (p *GocalParser) SetMaxEvents(max int) {
+func (p *ICSParser) SetMaxEvents(max int) {
 	p.maxEvents = max
 }
 
 // SetTimeZone sets the default timezone for parsing
-func (p *GocalParser) SetTimeZone(tz *time.Location) {
+func (p *ICSParser) SetTimeZone(tz *time.Location) {
 	p.timeZone = tz
 }
 
+// SetCalendar sets the calendar (template and automatic alerts) parsed events belong to
+func (p *ICSParser) SetCalendar(calendar *storage.Calendar) {
+	p.calendar = calendar
+}
+
+// eventCalendar returns the calendar for parsed events, or an empty one if none is set
+func (p *ICSParser) eventCalendar() *storage.Calendar {
+	if p.calendar == nil {
+		return storage.NewCalendar("", "", []storage.Alert{})
+	}
+	return p.calendar
+}
+
 // ParseFile parses a single ICS file and returns events
-func (p *GocalParser) ParseFile(filePath string) ([]storage.Event, error) {
+func (p *ICSParser) ParseFile(filePath string) ([]storage.Event, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file %s: %w", filePath, err)
@@ -60,7 +72,12 @@ func (p *GocalParser) ParseFile(filePath string) ([]storage.Event, error) {
 }
 
 // ParseDirectory parses all ICS files in a directory
-func (p *GocalParser) ParseDirectory(dirPath string) ([]storage.Event, error) {
+func (p *ICSParser) ParseDirectory(dirPath string) ([]storage.Event, error) {
+	return parseDirectory(dirPath, ".ics", p.ParseFile)
+}
+
+// parseDirectory parses all files with the given extension in a directory tree
+func parseDirectory(dirPath, extension string, parseFile func(string) ([]storage.Event, error)) ([]storage.Event, error) {
 	var allEvents []storage.Event
 
 	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
@@ -69,16 +86,16 @@ func (p *GocalParser) ParseDirectory(dirPath string) ([]storage.Event, error) {
 			return nil
 		}
 
-		// Skip directories and non-ICS files
+		// Skip directories and files of other types
 		if info.IsDir() {
 			return nil
 		}
 
-		if !strings.HasSuffix(strings.ToLower(info.Name()), ".ics") {
+		if !strings.HasSuffix(strings.ToLower(info.Name()), extension) {
 			return nil
 		}
 
-		events, parseErr := p.ParseFile(path)
+		events, parseErr := parseFile(path)
 		if parseErr != nil {
 			// Log error but continue processing other files
 			fmt.Fprintf(os.Stderr, "Error parsing file %s: %v\n", path, parseErr)
@@ -96,53 +113,107 @@ func (p *GocalParser) ParseDirectory(dirPath string) ([]storage.Event, error) {
 	return allEvents, nil
 }
 
-// ParseReader parses ICS data from an io.Reader
-func (p *GocalParser) ParseReader(reader io.Reader) ([]storage.Event, error) {
-	// Read all data first so we can use it for both gocal and VALARM parsing
-	icsData, err := io.ReadAll(reader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read ICS data: %w", err)
-	}
-
-	// Create gocal parser with minimal time bounds
-	// We set a very narrow window to prevent RRULE expansion while still allowing parsing
-	start := time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
-	end := time.Date(2100, 12, 31, 23, 59, 59, 0, time.UTC)
+// recurrenceOverride records a modified instance (RECURRENCE-ID) of a recurring event
+type recurrenceOverride struct {
+	uid          string
+	recurrenceID time.Time
+}
 
-	cal := gocal.NewParser(strings.NewReader(string(icsData)))
-	cal.Start, cal.End = &start, &end
+// ParseReader parses ICS data from an io.Reader in a single pass.
+// Components (VEVENT and VTODO) are converted as soon as they are complete;
+// those referencing a VTIMEZONE that is only defined later in the stream are
+// converted at the end.
+func (p *ICSParser) ParseReader(reader io.Reader) ([]storage.Event, error) {
+	resolver := NewTimezoneResolver(p.timeZone)
 
-	// Parse the calendar
-	if err := cal.Parse(); err != nil {
-		return nil, fmt.Errorf("failed to parse ICS data: %w", err)
-	}
-
-	// Convert gocal events to our Event interface
 	var events []storage.Event
-	eventCount := 0
+	var deferred []*Component
+	var overrides []recurrenceOverride
+	masters := make(map[string]*storage.CalendarEvent)
+	var firstErr error
+	limitReached := false
 
-	for _, gocalEvent := range cal.Events {
+	handleComponent := func(component *Component) {
 		// Prevent memory issues with too many events
-		if eventCount >= p.maxEvents {
-			fmt.Fprintf(os.Stderr, "Warning: Reached maximum event limit (%d), skipping remaining events\n", p.maxEvents)
-			break
+		if len(events) >= p.maxEvents {
+			if !limitReached {
+				fmt.Fprintf(os.Stderr, "Warning: Reached maximum event limit (%d), skipping remaining events\n", p.maxEvents)
+				limitReached = true
+			}
+			return
 		}
 
-		event, err := p.convertGocalEvent(gocalEvent, string(icsData))
+		event, base, err := p.convertComponent(component, resolver)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error converting event %s: %v\n", gocalEvent.Uid, err)
-			continue
+			fmt.Fprintf(os.Stderr, "Error converting %s %s: %v\n", component.Name, component.Value("UID"), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+
+		// Modified instances replace the matching occurrence of the master event
+		if rid := component.Property("RECURRENCE-ID"); rid != nil {
+			recurrenceID, _, err := resolver.ParseDateTime(rid.Value, rid.Params)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error converting %s %s: invalid RECURRENCE-ID: %v\n", component.Name, component.Value("UID"), err)
+				return
+			}
+			overrides = append(overrides, recurrenceOverride{uid: component.Value("UID"), recurrenceID: recurrenceID})
+
+			if base == nil || strings.EqualFold(component.Value("STATUS"), "CANCELLED") {
+				return
+			}
+			base.UID = fmt.Sprintf("%s/%s", base.UID, recurrenceID.UTC().Format("20060102T150405Z"))
+		} else if base != nil {
+			masters[base.UID] = base
+		}
+
+		// Completed tasks need no reminders
+		if base == nil {
+			return
 		}
 
 		events = append(events, event)
-		eventCount++
+	}
+
+	err := ReadComponents(reader, func(component *Component) error {
+		switch component.Name {
+		case "VTIMEZONE":
+			p.addTimezoneDefinition(resolver, component)
+		case "VEVENT", "VTODO":
+			if hasUnresolvedTZID(component, resolver) {
+				deferred = append(deferred, component)
+				return nil
+			}
+			handleComponent(component)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ICS data: %w", err)
+	}
+
+	for _, component := range deferred {
+		handleComponent(component)
+	}
+
+	for _, override := range overrides {
+		if master, exists := masters[override.uid]; exists {
+			master.AddExceptionDate(override.recurrenceID)
+		}
+	}
+
+	// A file in which no event could be converted is treated as malformed
+	if len(events) == 0 && firstErr != nil {
+		return nil, fmt.Errorf("failed to parse ICS data: %w", firstErr)
 	}
 
 	return events, nil
 }
 
-// ValidateICS validates ICS data without fully parsing it
-func (p *GocalParser) ValidateICS(data []byte) error {
+// ValidateICS validates ICS data without converting its components
+func (p *ICSParser) ValidateICS(data []byte) error {
 	content := string(data)
 
 	// Basic validation checks
@@ -154,86 +225,84 @@ func (p *GocalParser) ValidateICS(data []byte) error {
 		return fmt.Errorf("missing END:VCALENDAR")
 	}
 
-	// More sophisticated validation - check for matching BEGIN/END pairs
-	lines := strings.Split(content, "\n")
-	stack := make([]string, 0)
-	
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "BEGIN:") {
-			component := strings.TrimPrefix(line, "BEGIN:")
-			stack = append(stack, component)
-		} else if strings.HasPrefix(line, "END:") {
-			component := strings.TrimPrefix(line, "END:")
-			if len(stack) == 0 {
-				return fmt.Errorf("unexpected END:%s without matching BEGIN", component)
-			}
-			if stack[len(stack)-1] != component {
-				return fmt.Errorf("mismatched BEGIN/END: expected %s, got %s", stack[len(stack)-1], component)
-			}
-			stack = stack[:len(stack)-1]
-		}
+	// Check the component structure (matching BEGIN/END pairs, content line syntax)
+	return ReadComponents(strings.NewReader(content), func(*Component) error {
+		return nil
+	})
+}
+
+// addTimezoneDefinition registers an embedded VTIMEZONE with the resolver
+func (p *ICSParser) addTimezoneDefinition(resolver *TimezoneResolver, component *Component) {
+	vtz := vtimezoneFromComponent(component)
+	if vtz.tzid == "" {
+		return
 	}
-	
-	if len(stack) > 0 {
-		return fmt.Errorf("unclosed BEGIN statements: %v", stack)
+
+	loc, err := vtz.Location()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to build timezone %s: %v\n", vtz.tzid, err)
+		return
 	}
+	resolver.AddDefinition(vtz.tzid, loc)
+}
 
-	return nil
+// hasUnresolvedTZID reports whether a component references a TZID the resolver
+// does not know (yet)
+func hasUnresolvedTZID(component *Component, resolver *TimezoneResolver) bool {
+	for _, property := range component.Properties {
+		if tzid := property.Param("TZID"); tzid != "" {
+			if _, err := resolver.Lookup(tzid); err != nil {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-// convertGocalEvent converts a gocal.Event to our storage.Event interface
-func (p *GocalParser) convertGocalEvent(gocalEvent gocal.Event, icsData string) (storage.Event, error) {
-	// TODO: This needs to be updated to accept a Calendar parameter when Calendar integration is complete
-	// For now, create a default calendar to make the code compile
-	defaultCalendar := storage.NewCalendar("", "", []storage.Alert{})
+// convertEvent converts a VEVENT component to a storage event
+func (p *ICSParser) convertEvent(component *Component, resolver *TimezoneResolver) (*storage.CalendarEvent, error) {
 	// Extract basic event information
-	uid := gocalEvent.Uid
+	uid := component.Value("UID")
 	if uid == "" {
 		return nil, fmt.Errorf("event missing UID")
 	}
 
-	summary := gocalEvent.Summary
-	description := gocalEvent.Description
-	location := gocalEvent.Location
+	summary := component.Text("SUMMARY")
+	description := component.Text("DESCRIPTION")
+	location := component.Text("LOCATION")
 
 	// Handle start and end times
-	startTime := *gocalEvent.Start
-	endTime := *gocalEvent.End
+	dtstart := component.Property("DTSTART")
+	if dtstart == nil {
+		return nil, fmt.Errorf("event missing DTSTART")
+	}
+	startTime, allDay, err := resolver.ParseDateTime(dtstart.Value, dtstart.Params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DTSTART: %w", err)
+	}
 
-	// Determine timezone
-	timezone := startTime.Location()
-	if timezone == nil {
-		timezone = p.timeZone
+	endTime, err := parseEventEnd(component, startTime, allDay, resolver)
+	if err != nil {
+		return nil, err
 	}
 
+	timezone := startTime.Location()
+
 	// Parse recurrence rule if present
-	var rec recurrence.Recurrence
-	var err error
-	if len(gocalEvent.RecurrenceRule) > 0 {
-		// Convert map to RRULE string format
-		var parts []string
-		for key, value := range gocalEvent.RecurrenceRule {
-			parts = append(parts, fmt.Sprintf("%s=%s", key, value))
-		}
-		rruleStr := strings.Join(parts, ";")
-		
-		// Parse RRULE string into Recurrence instance
-		rec, err = recurrence.ParseRRule(rruleStr)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse RRULE '%s': %w", rruleStr, err)
-		}
-	} else {
-		// No recurrence rule
-		rec = &recurrence.NoRecurrence{}
+	rec, err := parseRecurrence(component)
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse VALARM components for this event
-	valarmAlerts, err := p.parseVALARMs(icsData, gocalEvent.Uid)
-	if err != nil {
-		// Log warning but don't fail the entire event
-		fmt.Fprintf(os.Stderr, "Warning: Failed to parse VALARMs for event %s: %v\n", gocalEvent.Uid, err)
-		valarmAlerts = []storage.Alert{} // Use empty slice on error
+	valarmAlerts := []storage.Alert{}
+	for _, valarm := range component.Children("VALARM") {
+		alert, err := p.parseVALARMBlock(valarm)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to parse VALARM for event %s: %v\n", uid, err)
+			continue
+		}
+		valarmAlerts = append(valarmAlerts, alert)
 	}
 
 	// Create enhanced calendar event with recurrence support
@@ -246,135 +315,246 @@ func (p *GocalParser) convertGocalEvent(gocalEvent gocal.Event, icsData string)
 		endTime,
 		timezone,
 		rec,
-		defaultCalendar,
+		p.eventCalendar(),
 		valarmAlerts,
 	)
 
-	// Add exception dates if present
-	for _, exDate := range gocalEvent.ExcludeDates {
-		event.AddExceptionDate(exDate)
+	event.AllDay = allDay
+	event.Transparent = strings.EqualFold(component.Value("TRANSP"), "TRANSPARENT")
+	for _, categories := range component.PropertiesNamed("CATEGORIES") {
+		event.Categories = append(event.Categories, splitTextList(categories.Value)...)
 	}
 
+	// Add exception dates if present
+	addExceptionDates(event, component, resolver, allDay)
+
 	return event, nil
 }
 
-// parseVALARMs extracts VALARM components for a specific event from raw ICS data
-func (p *GocalParser) parseVALARMs(icsData, eventUID string) ([]storage.Alert, error) {
-	var alerts []storage.Alert
-
-	lines := strings.Split(icsData, "\n")
-	var currentEvent strings.Builder
-	var inTargetEvent bool
-	var inEvent bool
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		
-		if line == "BEGIN:VEVENT" {
-			inEvent = true
-			inTargetEvent = false
-			currentEvent.Reset()
-			currentEvent.WriteString(line + "\n")
-		} else if line == "END:VEVENT" {
-			if inEvent {
-				currentEvent.WriteString(line + "\n")
-				
-				// If this was our target event, parse VALARMs
-				if inTargetEvent {
-					eventBlock := currentEvent.String()
-					eventAlerts := p.extractVALARMsFromEventBlock(eventBlock)
-					alerts = append(alerts, eventAlerts...)
-				}
-				
-				inEvent = false
-				inTargetEvent = false
-			}
-		} else if inEvent {
-			currentEvent.WriteString(line + "\n")
-			
-			// Check if this is the UID line for our target event
-			if strings.HasPrefix(line, "UID:") {
-				uid := strings.TrimPrefix(line, "UID:")
-				if uid == eventUID {
-					inTargetEvent = true
-				}
-			}
+// convertComponent converts a VEVENT or VTODO component and also returns the
+// underlying calendar event. Both are nil for tasks that need no reminders.
+func (p *ICSParser) convertComponent(component *Component, resolver *TimezoneResolver) (storage.Event, *storage.CalendarEvent, error) {
+	if component.Name == "VTODO" {
+		task, err := p.convertTask(component, resolver)
+		if err != nil || task == nil {
+			return nil, nil, err
 		}
+		return task, task.CalendarEvent, nil
 	}
 
-	return alerts, nil
+	event, err := p.convertEvent(component, resolver)
+	if err != nil {
+		return nil, nil, err
+	}
+	return event, event, nil
 }
 
-// extractVALARMsFromEventBlock extracts all VALARM blocks from a single event block
-func (p *GocalParser) extractVALARMsFromEventBlock(eventBlock string) []storage.Alert {
-	var alerts []storage.Alert
-	
-	lines := strings.Split(eventBlock, "\n")
-	var currentVALARM strings.Builder
-	var inVALARM bool
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		
-		if line == "BEGIN:VALARM" {
-			inVALARM = true
-			currentVALARM.Reset()
-		} else if line == "END:VALARM" {
-			if inVALARM {
-				valarmBlock := currentVALARM.String()
-				alert, err := p.parseVALARMBlock(valarmBlock)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Warning: Failed to parse VALARM block: %v\n", err)
-				} else {
-					alerts = append(alerts, alert)
-				}
-				inVALARM = false
-			}
-		} else if inVALARM {
-			currentVALARM.WriteString(line + "\n")
+// convertTask converts a VTODO component to a storage task. Reminders are
+// anchored at DUE, or at DTSTART for tasks without a due date. Completed or
+// cancelled tasks and tasks without any date yield nil.
+func (p *ICSParser) convertTask(component *Component, resolver *TimezoneResolver) (*storage.TaskEvent, error) {
+	uid := component.Value("UID")
+	if uid == "" {
+		return nil, fmt.Errorf("task missing UID")
+	}
+
+	var start, due *time.Time
+	allDay := false
+
+	if dtstart := component.Property("DTSTART"); dtstart != nil {
+		startTime, dateOnly, err := resolver.ParseDateTime(dtstart.Value, dtstart.Params)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DTSTART: %w", err)
 		}
+		start, allDay = &startTime, dateOnly
 	}
 
-	return alerts
+	if dueProperty := component.Property("DUE"); dueProperty != nil {
+		dueTime, dateOnly, err := resolver.ParseDateTime(dueProperty.Value, dueProperty.Params)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DUE: %w", err)
+		}
+		due, allDay = &dueTime, dateOnly
+	} else if value := component.Value("DURATION"); value != "" && start != nil {
+		duration, err := parseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DURATION: %w", err)
+		}
+		dueTime := start.Add(duration)
+		due = &dueTime
+	}
+
+	anchor := due
+	if anchor == nil {
+		anchor = start
+	}
+	if anchor == nil {
+		// Nothing to remind about
+		return nil, nil
+	}
+
+	percentComplete, _ := strconv.Atoi(component.Value("PERCENT-COMPLETE"))
+	priority, _ := strconv.Atoi(component.Value("PRIORITY"))
+
+	rec, err := parseRecurrence(component)
+	if err != nil {
+		return nil, err
+	}
+
+	event := storage.NewCalendarEvent(
+		uid,
+		component.Text("SUMMARY"),
+		component.Text("DESCRIPTION"),
+		component.Text("LOCATION"),
+		*anchor,
+		*anchor,
+		anchor.Location(),
+		rec,
+		p.eventCalendar(),
+		p.parseTaskAlarms(component, uid, start, due),
+	)
+	event.AllDay = allDay
+	addExceptionDates(event, component, resolver, allDay)
+
+	task := storage.NewTaskEvent(event, due, start, component.Value("STATUS"), percentComplete, priority)
+	if task.IsCompleted() || component.Property("COMPLETED") != nil {
+		return nil, nil
+	}
+
+	return task, nil
 }
 
-// parseVALARMBlock parses a single VALARM block and returns an Alert
-func (p *GocalParser) parseVALARMBlock(valarmBlock string) (storage.Alert, error) {
-	lines := strings.Split(valarmBlock, "\n")
-	
-	var trigger string
-	var description string
-	var action string
+// parseTaskAlarms parses the VALARMs of a task. Triggers related to the start
+// are converted to offsets before the due date, which anchors the task.
+func (p *ICSParser) parseTaskAlarms(component *Component, uid string, start, due *time.Time) []storage.Alert {
+	alerts := []storage.Alert{}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
+	for _, valarm := range component.Children("VALARM") {
+		alert, relatedEnd, err := p.parseAlarm(valarm)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to parse VALARM for task %s: %v\n", uid, err)
 			continue
 		}
 
-		if strings.HasPrefix(line, "TRIGGER:") {
-			trigger = strings.TrimPrefix(line, "TRIGGER:")
-		} else if strings.HasPrefix(line, "DESCRIPTION:") {
-			description = strings.TrimPrefix(line, "DESCRIPTION:")
-		} else if strings.HasPrefix(line, "ACTION:") {
-			action = strings.TrimPrefix(line, "ACTION:")
+		if !relatedEnd && start != nil && due != nil {
+			alert.Offset += due.Sub(*start)
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts
+}
+
+// parseRecurrence parses the RRULE of a component, if any
+func parseRecurrence(component *Component) (recurrence.Recurrence, error) {
+	rrule := component.Value("RRULE")
+	if rrule == "" {
+		// No recurrence rule
+		return &recurrence.NoRecurrence{}, nil
+	}
+
+	rec, err := recurrence.ParseRRule(rrule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse RRULE '%s': %w", rrule, err)
+	}
+	return rec, nil
+}
+
+// addExceptionDates adds the EXDATE values of a component to the event
+func addExceptionDates(event *storage.CalendarEvent, component *Component, resolver *TimezoneResolver, allDay bool) {
+	for _, exdate := range component.PropertiesNamed("EXDATE") {
+		for _, value := range splitListValue(exdate.Value) {
+			exDate, dateOnly, err := resolver.ParseDateTime(value, exdate.Params)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Ignoring invalid EXDATE %q for %s: %v\n", value, event.UID, err)
+				continue
+			}
+			if dateOnly && !allDay {
+				// A DATE exception excludes the occurrence on that day
+				exDate = time.Date(exDate.Year(), exDate.Month(), exDate.Day(),
+					event.StartTime.Hour(), event.StartTime.Minute(), event.StartTime.Second(), 0, event.GetTimezone())
+			}
+			event.AddExceptionDate(exDate)
+		}
+	}
+}
+
+// parseEventEnd determines the end of an event from DTEND or DURATION.
+// Without either, all-day events last one day and timed events are instantaneous.
+func parseEventEnd(component *Component, startTime time.Time, allDay bool, resolver *TimezoneResolver) (time.Time, error) {
+	if dtend := component.Property("DTEND"); dtend != nil {
+		endTime, _, err := resolver.ParseDateTime(dtend.Value, dtend.Params)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid DTEND: %w", err)
 		}
+		return endTime, nil
 	}
 
+	if value := component.Value("DURATION"); value != "" {
+		duration, err := parseDuration(value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid DURATION: %w", err)
+		}
+		if allDay && duration%(24*time.Hour) == 0 {
+			// Whole days keep local midnight across DST changes
+			return startTime.AddDate(0, 0, int(duration/(24*time.Hour))), nil
+		}
+		return startTime.Add(duration), nil
+	}
+
+	if allDay {
+		return startTime.AddDate(0, 0, 1), nil
+	}
+	return startTime, nil
+}
+
+// parseVALARMBlock parses a single VALARM component of an event and returns an Alert
+func (p *ICSParser) parseVALARMBlock(valarm *Component) (storage.Alert, error) {
+	alert, relatedEnd, err := p.parseAlarm(valarm)
+	if err != nil {
+		return storage.Alert{}, err
+	}
+	switch {
+	case relatedEnd && alert.Offset == 0:
+		alert.Kind = storage.AlertAtEnd
+	case relatedEnd:
+		alert.Kind = storage.AlertBeforeEnd
+	case alert.Offset == 0:
+		alert.Kind = storage.AlertAtStart
+	}
+	return alert, nil
+}
+
+// parseAlarm parses a VALARM component and reports whether its trigger is
+// related to the end (DTEND/DUE) rather than the start of the component
+func (p *ICSParser) parseAlarm(valarm *Component) (storage.Alert, bool, error) {
+	var trigger string
+	relatedEnd := false
+	if property := valarm.Property("TRIGGER"); property != nil {
+		if strings.EqualFold(property.Param("VALUE"), "DATE-TIME") {
+			return storage.Alert{}, false, fmt.Errorf("unsupported absolute TRIGGER: %s", property.Value)
+		}
+		relatedEnd = strings.EqualFold(property.Param("RELATED"), "END")
+		trigger = property.Value
+	}
+
+	description := valarm.Text("DESCRIPTION")
+	action := strings.ToUpper(valarm.Value("ACTION"))
+
 	// Parse TRIGGER to get offset duration
 	offset, err := p.parseTrigger(trigger)
 	if err != nil {
-		return storage.Alert{}, fmt.Errorf("failed to parse TRIGGER '%s': %w", trigger, err)
+		return storage.Alert{}, false, fmt.Errorf("failed to parse TRIGGER '%s': %w", trigger, err)
 	}
 
 	// Only support DISPLAY action for now
 	if action != "DISPLAY" && action != "" {
-		return storage.Alert{}, fmt.Errorf("unsupported VALARM action: %s", action)
+		return storage.Alert{}, false, fmt.Errorf("unsupported VALARM action: %s", action)
 	}
 
 	// Use VALARM description or generate default
 	if description == "" {
-		description = fmt.Sprintf("Alert %v before", offset)
+		description = alarmDescription(offset, relatedEnd)
 	}
 
 	return storage.Alert{
@@ -383,16 +563,36 @@ func (p *GocalParser) parseVALARMBlock(valarmBlock string) (storage.Alert, error
 		Source:      storage.AlertSourceVALARM,
 		Description: description,
 		Action:      storage.AlertActionDisplay,
-	}, nil
+	}, relatedEnd, nil
+}
+
+// alarmDescription describes a VALARM without DESCRIPTION by its trigger
+func alarmDescription(offset time.Duration, relatedEnd bool) string {
+	anchor := "start"
+	if relatedEnd {
+		anchor = "end"
+	}
+
+	switch {
+	case offset > 0 && !relatedEnd:
+		return fmt.Sprintf("Alert %v before", offset)
+	case offset > 0:
+		return fmt.Sprintf("Alert %v before the %s", offset, anchor)
+	case offset < 0:
+		return fmt.Sprintf("Alert %v after the %s", -offset, anchor)
+	default:
+		return "Alert at the " + anchor
+	}
 }
 
 // parseTrigger parses TRIGGER field to extract time.Duration
-// Supports duration format like -PT15M, -PT1H, -P1DT2H30M
-func (p *GocalParser) parseTrigger(trigger string) (time.Duration, error) {
+// Supports signed durations like -PT15M, PT0S, +PT5M or -P1DT2H30M. Offsets
+// are positive before the start or end and negative after it.
+func (p *ICSParser) parseTrigger(trigger string) (time.Duration, error) {
 	trigger = strings.TrimSpace(trigger)
 	
 	// Handle relative triggers (duration format)
-	if strings.HasPrefix(trigger, "-P") {
+	if strings.HasPrefix(strings.TrimLeft(trigger, "+-"), "P") {
 		return p.parseDurationTrigger(trigger)
 	}
 	
@@ -404,87 +604,78 @@ func (p *GocalParser) parseTrigger(trigger string) (time.Duration, error) {
 	return 0, fmt.Errorf("unsupported TRIGGER format: %s", trigger)
 }
 
-// parseDurationTrigger parses ISO 8601 duration format like -PT15M, -P1DT2H30M
-func (p *GocalParser) parseDurationTrigger(trigger string) (time.Duration, error) {
-	// Remove leading -P
-	if !strings.HasPrefix(trigger, "-P") {
-		return 0, fmt.Errorf("duration trigger must start with -P")
+// parseDurationTrigger parses ISO 8601 duration format like -PT15M, PT0S, +P1DT2H30M
+func (p *ICSParser) parseDurationTrigger(trigger string) (time.Duration, error) {
+	duration, err := parseDuration(trigger)
+	if err != nil {
+		return 0, err
 	}
-	
-	durationStr := strings.TrimPrefix(trigger, "-P")
-	
-	var days, hours, minutes, seconds int
-	var err error
-	
-	// Check for time component (T)
-	if strings.Contains(durationStr, "T") {
-		parts := strings.Split(durationStr, "T")
-		
-		// Parse date part (days)
-		if len(parts[0]) > 0 {
-			if strings.HasSuffix(parts[0], "D") {
-				dayStr := strings.TrimSuffix(parts[0], "D")
-				days, err = strconv.Atoi(dayStr)
-				if err != nil {
-					return 0, fmt.Errorf("invalid days in duration: %s", dayStr)
-				}
-			}
+
+	// Offsets are stored as durations before the event, negative after it
+	return -duration, nil
+}
+
+// parseDuration parses an RFC 5545 duration such as P1W, -PT15M or P1DT2H30M
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	sign := time.Duration(1)
+	if strings.HasPrefix(value, "-") {
+		sign = -1
+		value = value[1:]
+	} else if strings.HasPrefix(value, "+") {
+		value = value[1:]
+	}
+
+	if !strings.HasPrefix(value, "P") || len(value) == 1 {
+		return 0, fmt.Errorf("invalid duration: %s", value)
+	}
+
+	var total time.Duration
+	inTime := false
+	number := ""
+
+	for _, r := range value[1:] {
+		if r >= '0' && r <= '9' {
+			number += string(r)
+			continue
 		}
-		
-		// Parse time part
-		if len(parts) > 1 {
-			timePart := parts[1]
-			
-			// Parse hours
-			if strings.Contains(timePart, "H") {
-				hourPattern := regexp.MustCompile(`(\d+)H`)
-				if match := hourPattern.FindStringSubmatch(timePart); len(match) > 1 {
-					hours, err = strconv.Atoi(match[1])
-					if err != nil {
-						return 0, fmt.Errorf("invalid hours in duration: %s", match[1])
-					}
-				}
-			}
-			
-			// Parse minutes
-			if strings.Contains(timePart, "M") {
-				minutePattern := regexp.MustCompile(`(\d+)M`)
-				if match := minutePattern.FindStringSubmatch(timePart); len(match) > 1 {
-					minutes, err = strconv.Atoi(match[1])
-					if err != nil {
-						return 0, fmt.Errorf("invalid minutes in duration: %s", match[1])
-					}
-				}
-			}
-			
-			// Parse seconds
-			if strings.Contains(timePart, "S") {
-				secondPattern := regexp.MustCompile(`(\d+)S`)
-				if match := secondPattern.FindStringSubmatch(timePart); len(match) > 1 {
-					seconds, err = strconv.Atoi(match[1])
-					if err != nil {
-						return 0, fmt.Errorf("invalid seconds in duration: %s", match[1])
-					}
-				}
+
+		if r == 'T' {
+			if inTime || number != "" {
+				return 0, fmt.Errorf("invalid duration: %s", value)
 			}
+			inTime = true
+			continue
 		}
-	} else {
-		// Only days, no time component
-		if strings.HasSuffix(durationStr, "D") {
-			dayStr := strings.TrimSuffix(durationStr, "D")
-			days, err = strconv.Atoi(dayStr)
-			if err != nil {
-				return 0, fmt.Errorf("invalid days in duration: %s", dayStr)
-			}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number in duration: %s", value)
+		}
+		number = ""
+
+		var unit time.Duration
+		switch {
+		case r == 'W' && !inTime:
+			unit = 7 * 24 * time.Hour
+		case r == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case r == 'H' && inTime:
+			unit = time.Hour
+		case r == 'M' && inTime:
+			unit = time.Minute
+		case r == 'S' && inTime:
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("invalid unit %q in duration: %s", r, value)
 		}
+		total += time.Duration(n) * unit
 	}
-	
-	// Convert to time.Duration
-	duration := time.Duration(days)*24*time.Hour +
-		time.Duration(hours)*time.Hour +
-		time.Duration(minutes)*time.Minute +
-		time.Duration(seconds)*time.Second
-	
-	return duration, nil
-}
 
+	if number != "" {
+		return 0, fmt.Errorf("missing unit in duration: %s", value)
+	}
+
+	return sign * total, nil
+}
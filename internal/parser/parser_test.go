@@ -4,10 +4,12 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"calwatch/internal/storage"
 )
 
-func TestGocalParser_ValidateICS(t *testing.T) {
-	parser := NewGocalParser()
+func TestICSParser_ValidateICS(t *testing.T) {
+	parser := NewICSParser()
 
 	tests := []struct {
 		name    string
@@ -59,8 +61,8 @@ END:VCALENDAR`,
 	}
 }
 
-func TestGocalParser_ParseReader(t *testing.T) {
-	parser := NewGocalParser()
+func TestICSParser_ParseReader(t *testing.T) {
+	parser := NewICSParser()
 
 	icsData := `BEGIN:VCALENDAR
 VERSION:2.0
@@ -129,8 +131,8 @@ END:VCALENDAR`
 	}
 }
 
-func TestGocalParser_ParseRecurringEvent(t *testing.T) {
-	parser := NewGocalParser()
+func TestICSParser_ParseRecurringEvent(t *testing.T) {
+	parser := NewICSParser()
 
 	icsData := `BEGIN:VCALENDAR
 VERSION:2.0
@@ -166,8 +168,8 @@ END:VCALENDAR`
 	}
 }
 
-func TestGocalParser_ParseInvalidICS(t *testing.T) {
-	parser := NewGocalParser()
+func TestICSParser_ParseInvalidICS(t *testing.T) {
+	parser := NewICSParser()
 
 	// Test with completely invalid data first
 	invalidData := `This is not a valid ICS file`
@@ -194,8 +196,8 @@ END:VCALENDAR`
 	}
 }
 
-func TestGocalParser_MaxEventsLimit(t *testing.T) {
-	parser := NewGocalParser()
+func TestICSParser_MaxEventsLimit(t *testing.T) {
+	parser := NewICSParser()
 	parser.SetMaxEvents(1) // Set limit to 1 event
 
 	icsData := `BEGIN:VCALENDAR
@@ -230,8 +232,8 @@ END:VCALENDAR`
 	}
 }
 
-func TestGocalParser_EmptyCalendar(t *testing.T) {
-	parser := NewGocalParser()
+func TestICSParser_EmptyCalendar(t *testing.T) {
+	parser := NewICSParser()
 
 	icsData := `BEGIN:VCALENDAR
 VERSION:2.0
@@ -248,4 +250,269 @@ END:VCALENDAR`
 	if len(events) != 0 {
 		t.Errorf("Expected 0 events for empty calendar, got %d", len(events))
 	}
-}
\ No newline at end of file
+}
+func TestICSParser_ParseFoldedAndEscapedText(t *testing.T) {
+	parser := NewICSParser()
+
+	icsData := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:folded@example.com\r\n" +
+		"DTSTART:20231015T140000Z\r\n" +
+		"DTEND:20231015T150000Z\r\n" +
+		"SUMMARY:Planning\\, review\\; and\r\n" +
+		"  retro\r\n" +
+		"DESCRIPTION:First line\\nSecond line\r\n" +
+		"LOCATION;ALTREP=\"http://example.com/room\":Room 1\\, Floor 2\r\n" +
+		"TRANSP:OPAQUE\r\n" +
+		"CATEGORIES:Meeting,Screen\\, sharing\r\n" +
+		"CATEGORIES:Work\r\n" +
+		"BEGIN:VALARM\r\n" +
+		"ACTION:DISPLAY\r\n" +
+		"TRIGGER;RELATED=START:-PT10M\r\n" +
+		"END:VALARM\r\n" +
+		"BEGIN:VALARM\r\n" +
+		"ACTION:DISPLAY\r\n" +
+		"TRIGGER;RELATED=END:-PT5M\r\n" +
+		"END:VALARM\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	events, err := parser.ParseReader(strings.NewReader(icsData))
+	if err != nil {
+		t.Fatalf("ParseReader() error = %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+
+	event := events[0]
+	if event.GetSummary() != "Planning, review; and retro" {
+		t.Errorf("Unexpected summary %q", event.GetSummary())
+	}
+	if event.GetDescription() != "First line\nSecond line" {
+		t.Errorf("Unexpected description %q", event.GetDescription())
+	}
+	if event.GetLocation() != "Room 1, Floor 2" {
+		t.Errorf("Unexpected location %q", event.GetLocation())
+	}
+	calendarEvent := event.(*storage.CalendarEvent)
+	if calendarEvent.Transparent || len(calendarEvent.Categories) != 3 || calendarEvent.Categories[1] != "Screen, sharing" ||
+		!calendarEvent.HasCategory("work") {
+		t.Errorf("Expected an opaque event with 3 categories, got %q", calendarEvent.Categories)
+	}
+
+	alerts := event.GetIntrinsicAlerts()
+	if len(alerts) != 2 || alerts[0].Offset != 10*time.Minute || alerts[0].Kind != storage.AlertBeforeStart {
+		t.Fatalf("Expected a 10 minute VALARM and one before the end, got %v", alerts)
+	}
+	if alerts[1].Offset != 5*time.Minute || alerts[1].Kind != storage.AlertBeforeEnd {
+		t.Errorf("Expected a VALARM 5 minutes before the end, got %v", alerts[1])
+	}
+}
+
+func TestICSParser_ParseRecurrenceDetails(t *testing.T) {
+	parser := NewICSParser()
+	parser.SetTimeZone(time.UTC)
+
+	icsData := `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTART:20231016T090000Z
+DURATION:PT15M
+RRULE:FREQ=DAILY;COUNT=5
+EXDATE:20231017T090000Z,20231018T090000Z
+SUMMARY:Standup
+END:VEVENT
+BEGIN:VEVENT
+UID:standup@example.com
+RECURRENCE-ID:20231019T090000Z
+DTSTART:20231019T100000Z
+DURATION:PT15M
+SUMMARY:Standup (moved)
+END:VEVENT
+END:VCALENDAR`
+
+	events, err := parser.ParseReader(strings.NewReader(icsData))
+	if err != nil {
+		t.Fatalf("ParseReader() error = %v", err)
+	}
+
+	// The recurring event is returned once (not expanded) plus the moved instance
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+
+	master := events[0]
+	if master.GetUID() != "standup@example.com" {
+		t.Fatalf("Expected master event first, got %s", master.GetUID())
+	}
+	if duration := master.GetEndTime().Sub(master.GetStartTime()); duration != 15*time.Minute {
+		t.Errorf("Expected 15 minute duration, got %v", duration)
+	}
+
+	occurrences := master.OccurredWithin(
+		time.Date(2023, 10, 16, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 10, 31, 0, 0, 0, 0, time.UTC),
+	)
+	// 5 occurrences minus two EXDATEs minus the overridden instance
+	if len(occurrences) != 2 {
+		t.Errorf("Expected 2 remaining occurrences, got %d: %v", len(occurrences), occurrences)
+	}
+
+	moved := events[1]
+	if moved.GetUID() != "standup@example.com/20231019T090000Z" {
+		t.Errorf("Unexpected UID for moved instance: %s", moved.GetUID())
+	}
+	if expected := time.Date(2023, 10, 19, 10, 0, 0, 0, time.UTC); !moved.GetStartTime().Equal(expected) {
+		t.Errorf("Expected moved start %v, got %v", expected, moved.GetStartTime())
+	}
+}
+
+func TestICSParser_VTIMEZONEAfterEvent(t *testing.T) {
+	parser := NewICSParser()
+	parser.SetTimeZone(time.UTC)
+
+	// VTIMEZONE definitions may follow the events that reference them
+	icsData := `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:late-tz@example.com
+DTSTART;TZID=Office:20240115T090000
+DTEND;TZID=Office:20240115T100000
+SUMMARY:Office hours
+END:VEVENT
+BEGIN:VTIMEZONE
+TZID:Office
+BEGIN:STANDARD
+DTSTART:19700101T000000
+TZOFFSETFROM:+0300
+TZOFFSETTO:+0300
+END:STANDARD
+END:VTIMEZONE
+END:VCALENDAR`
+
+	events, err := parser.ParseReader(strings.NewReader(icsData))
+	if err != nil {
+		t.Fatalf("ParseReader() error = %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+
+	if expected := time.Date(2024, 1, 15, 6, 0, 0, 0, time.UTC); !events[0].GetStartTime().Equal(expected) {
+		t.Errorf("Expected start %v, got %v", expected, events[0].GetStartTime().UTC())
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"PT15M", 15 * time.Minute, false},
+		{"-PT1H", -time.Hour, false},
+		{"+P1D", 24 * time.Hour, false},
+		{"P1W", 7 * 24 * time.Hour, false},
+		{"P1DT2H30M15S", 26*time.Hour + 30*time.Minute + 15*time.Second, false},
+		{"PT", 0, false},
+		{"P", 0, true},
+		{"15M", 0, true},
+		{"PT15", 0, true},
+		{"P1H", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseDuration(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDuration(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.expected {
+				t.Errorf("parseDuration(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestICSParser_ParseTodo(t *testing.T) {
+	parser := NewICSParser()
+	parser.SetTimeZone(time.UTC)
+
+	icsData := `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VTODO
+UID:report@example.com
+SUMMARY:Submit report
+DTSTART:20231015T090000Z
+DUE:20231015T170000Z
+STATUS:IN-PROCESS
+PERCENT-COMPLETE:40
+PRIORITY:1
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER;RELATED=END:-PT30M
+END:VALARM
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-PT15M
+END:VALARM
+END:VTODO
+BEGIN:VTODO
+UID:done@example.com
+SUMMARY:Already done
+DUE:20231015T170000Z
+STATUS:COMPLETED
+END:VTODO
+BEGIN:VTODO
+UID:checked@example.com
+SUMMARY:Checked off without status
+DUE:20231015T170000Z
+COMPLETED:20231014T120000Z
+END:VTODO
+BEGIN:VTODO
+UID:someday@example.com
+SUMMARY:No dates at all
+END:VTODO
+END:VCALENDAR`
+
+	events, err := parser.ParseReader(strings.NewReader(icsData))
+	if err != nil {
+		t.Fatalf("ParseReader() error = %v", err)
+	}
+
+	// Completed tasks and tasks without dates are skipped
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 task, got %d", len(events))
+	}
+
+	task, ok := events[0].(*storage.TaskEvent)
+	if !ok {
+		t.Fatalf("Expected *storage.TaskEvent, got %T", events[0])
+	}
+
+	due := time.Date(2023, 10, 15, 17, 0, 0, 0, time.UTC)
+	if task.Due == nil || !task.Due.Equal(due) {
+		t.Errorf("Expected due %v, got %v", due, task.Due)
+	}
+	if !task.GetStartTime().Equal(due) {
+		t.Errorf("Expected task to be anchored at due date, got %v", task.GetStartTime())
+	}
+	if task.Status != storage.TaskStatusInProcess || task.PercentComplete != 40 || task.Priority != 1 {
+		t.Errorf("Unexpected task fields: status %s, percent %d, priority %d", task.Status, task.PercentComplete, task.Priority)
+	}
+
+	// RELATED=END is relative to DUE; start-related triggers are shifted to DUE
+	offsets := make(map[time.Duration]bool)
+	for _, alert := range task.GetIntrinsicAlerts() {
+		offsets[alert.Offset] = true
+	}
+	if !offsets[30*time.Minute] {
+		t.Errorf("Expected 30m alert before due, got %v", offsets)
+	}
+	if !offsets[8*time.Hour+15*time.Minute] {
+		t.Errorf("Expected start-related alert 8h15m before due, got %v", offsets)
+	}
+}
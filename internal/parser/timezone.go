@@ -0,0 +1,674 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TimezoneResolver maps TZID values found in ICS files to Go locations.
+// Resolution order: IANA names, Windows/legacy names, embedded VTIMEZONE
+// definitions, and finally the default (local) zone.
+type TimezoneResolver struct {
+	defaultZone *time.Location
+	definitions map[string]*time.Location // Locations built from embedded VTIMEZONE components
+	cache       map[string]*time.Location
+	mutex       sync.RWMutex
+}
+
+// NewTimezoneResolver creates a resolver that falls back to the given zone
+func NewTimezoneResolver(defaultZone *time.Location) *TimezoneResolver {
+	if defaultZone == nil {
+		defaultZone = time.Local
+	}
+	return &TimezoneResolver{
+		defaultZone: defaultZone,
+		definitions: make(map[string]*time.Location),
+		cache:       make(map[string]*time.Location),
+	}
+}
+
+// DefaultZone returns the zone used for floating times and unknown TZIDs
+func (r *TimezoneResolver) DefaultZone() *time.Location {
+	return r.defaultZone
+}
+
+// AddDefinition registers a location built from an embedded VTIMEZONE component
+func (r *TimezoneResolver) AddDefinition(tzid string, loc *time.Location) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.definitions[normalizeTZID(tzid)] = loc
+	delete(r.cache, normalizeTZID(tzid))
+}
+
+// Resolve returns the location for a TZID, falling back to the default zone
+func (r *TimezoneResolver) Resolve(tzid string) *time.Location {
+	loc, err := r.Lookup(tzid)
+	if err != nil {
+		return r.defaultZone
+	}
+	return loc
+}
+
+// Lookup returns the location for a TZID or an error if it cannot be resolved.
+// An empty TZID denotes floating time and resolves to the default zone.
+func (r *TimezoneResolver) Lookup(tzid string) (*time.Location, error) {
+	tzid = normalizeTZID(tzid)
+	if tzid == "" {
+		return r.defaultZone, nil
+	}
+
+	r.mutex.RLock()
+	if loc, exists := r.cache[tzid]; exists {
+		r.mutex.RUnlock()
+		return loc, nil
+	}
+	definition := r.definitions[tzid]
+	r.mutex.RUnlock()
+
+	loc := lookupKnownZone(tzid)
+	if loc == nil {
+		loc = definition
+	}
+	if loc == nil {
+		return nil, fmt.Errorf("unknown timezone: %s", tzid)
+	}
+
+	r.mutex.Lock()
+	r.cache[tzid] = loc
+	r.mutex.Unlock()
+
+	return loc, nil
+}
+
+// ParseDateTime parses a DATE or DATE-TIME value with its property parameters.
+// UTC values (trailing Z) stay in UTC, TZID values use the resolved zone, and
+// floating times and dates are interpreted in the default (local) zone.
+// The returned flag reports whether the value was a DATE (all-day) value.
+func (r *TimezoneResolver) ParseDateTime(value string, params map[string]string) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+
+	if strings.EqualFold(params["VALUE"], "DATE") || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, r.defaultZone)
+		if err != nil {
+			return time.Time{}, true, fmt.Errorf("invalid date %q: %w", value, err)
+		}
+		return t, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.ParseInLocation("20060102T150405Z", value, time.UTC)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid UTC date-time %q: %w", value, err)
+		}
+		return t, false, nil
+	}
+
+	loc := r.Resolve(params["TZID"])
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date-time %q: %w", value, err)
+	}
+	return t, false, nil
+}
+
+// normalizeTZID strips quoting and surrounding whitespace from a TZID
+func normalizeTZID(tzid string) string {
+	return strings.Trim(strings.TrimSpace(tzid), `"`)
+}
+
+// lookupKnownZone resolves IANA, Windows and prefixed (e.g. Mozilla) TZIDs
+func lookupKnownZone(tzid string) *time.Location {
+	if tzid == "" {
+		return nil
+	}
+
+	// IANA names (including backward-compatible aliases like US/Eastern)
+	if loc, err := time.LoadLocation(tzid); err == nil {
+		return loc
+	}
+
+	// Windows names used by Exchange and Outlook
+	if iana, exists := windowsZones[tzid]; exists {
+		if loc, err := time.LoadLocation(iana); err == nil {
+			return loc
+		}
+	}
+
+	// Prefixed names such as /mozilla.org/20050126_1/Europe/Berlin
+	parts := strings.Split(strings.Trim(tzid, "/"), "/")
+	for i := 1; i < len(parts)-1; i++ {
+		candidate := strings.Join(parts[i:], "/")
+		if loc, err := time.LoadLocation(candidate); err == nil {
+			return loc
+		}
+	}
+
+	return nil
+}
+
+// vtimezone holds the observances of an embedded VTIMEZONE component
+type vtimezone struct {
+	tzid        string
+	observances []tzObservance
+}
+
+// tzObservance is a STANDARD or DAYLIGHT sub-component of a VTIMEZONE
+type tzObservance struct {
+	daylight   bool
+	start      time.Time         // DTSTART as local wall-clock time (stored in UTC)
+	offsetFrom int               // TZOFFSETFROM in seconds east of UTC
+	offsetTo   int               // TZOFFSETTO in seconds east of UTC
+	name       string            // TZNAME
+	rrule      map[string]string // Recurrence rule, if any
+	rdates     []time.Time       // Additional onsets as local wall-clock times
+}
+
+// Range of years for which VTIMEZONE transitions are generated
+const (
+	vtimezoneFirstYear = 1970
+	vtimezoneLastYear  = 2037
+)
+
+// tzTransition is a single UTC instant at which an observance takes effect
+type tzTransition struct {
+	at         int64
+	observance int
+}
+
+// Location builds a Go location from the VTIMEZONE observances
+func (v *vtimezone) Location() (*time.Location, error) {
+	if len(v.observances) == 0 {
+		return nil, fmt.Errorf("VTIMEZONE %s has no observances", v.tzid)
+	}
+
+	// A single observance without rules is a fixed offset
+	if len(v.observances) == 1 && v.observances[0].rrule == nil && len(v.observances[0].rdates) == 0 {
+		obs := v.observances[0]
+		return time.FixedZone(obs.displayName(), obs.offsetTo), nil
+	}
+
+	var transitions []tzTransition
+	for i, obs := range v.observances {
+		for _, onset := range obs.onsets() {
+			// Onsets are expressed in the wall-clock time in effect before the transition
+			at := onset.Unix() - int64(obs.offsetFrom)
+			if at < math.MinInt32 || at > math.MaxInt32 {
+				// TZif version 1 only holds 32-bit transition times
+				continue
+			}
+			transitions = append(transitions, tzTransition{at: at, observance: i})
+		}
+	}
+
+	if len(transitions) == 0 {
+		// Only out-of-range onsets (e.g. Outlook's DTSTART:16010101T000000),
+		// so the latest observance applies throughout
+		latest := v.observances[0]
+		for _, obs := range v.observances[1:] {
+			if obs.start.After(latest.start) {
+				latest = obs
+			}
+		}
+		return time.FixedZone(latest.displayName(), latest.offsetTo), nil
+	}
+
+	sort.Slice(transitions, func(i, j int) bool {
+		return transitions[i].at < transitions[j].at
+	})
+
+	data := encodeTZif(v.observances, transitions)
+	return time.LoadLocationFromTZData(v.tzid, data)
+}
+
+// displayName returns the abbreviation for the observance
+func (o tzObservance) displayName() string {
+	if o.name != "" {
+		return o.name
+	}
+	return formatUTCOffset(o.offsetTo)
+}
+
+// onsets returns the local wall-clock times at which the observance starts
+func (o tzObservance) onsets() []time.Time {
+	var onsets []time.Time
+
+	if o.rrule == nil {
+		onsets = append(onsets, o.start)
+	} else {
+		onsets = append(onsets, o.yearlyOnsets()...)
+	}
+	onsets = append(onsets, o.rdates...)
+
+	return onsets
+}
+
+// yearlyOnsets expands a FREQ=YEARLY rule with BYMONTH and BYDAY (e.g. -1SU)
+func (o tzObservance) yearlyOnsets() []time.Time {
+	if o.rrule["FREQ"] != "YEARLY" {
+		return []time.Time{o.start}
+	}
+
+	month := o.start.Month()
+	if byMonth, err := strconv.Atoi(o.rrule["BYMONTH"]); err == nil && byMonth >= 1 && byMonth <= 12 {
+		month = time.Month(byMonth)
+	}
+
+	var until *time.Time
+	if untilStr, exists := o.rrule["UNTIL"]; exists {
+		if t, err := time.Parse("20060102T150405Z", untilStr); err == nil {
+			until = &t
+		} else if t, err := time.Parse("20060102T150405", untilStr); err == nil {
+			until = &t
+		} else if t, err := time.Parse("20060102", untilStr); err == nil {
+			until = &t
+		}
+	}
+
+	firstYear := o.start.Year()
+	if firstYear < vtimezoneFirstYear {
+		firstYear = vtimezoneFirstYear
+	}
+
+	var onsets []time.Time
+	for year := firstYear; year <= vtimezoneLastYear; year++ {
+		day := o.start.Day()
+		if byDay := o.rrule["BYDAY"]; byDay != "" {
+			var ok bool
+			day, ok = nthWeekdayOfMonth(year, month, byDay)
+			if !ok {
+				continue
+			}
+		}
+
+		onset := time.Date(year, month, day, o.start.Hour(), o.start.Minute(), o.start.Second(), 0, time.UTC)
+		if onset.Before(o.start) {
+			continue
+		}
+		// UNTIL is given in UTC, compare against the UTC instant of the onset
+		if until != nil && onset.Add(-time.Duration(o.offsetFrom)*time.Second).After(*until) {
+			break
+		}
+		onsets = append(onsets, onset)
+	}
+
+	return onsets
+}
+
+// nthWeekdayOfMonth resolves a BYDAY value like 2SU or -1SU to a day of the month
+func nthWeekdayOfMonth(year int, month time.Month, byDay string) (int, bool) {
+	byDay = strings.TrimSpace(strings.SplitN(byDay, ",", 2)[0])
+	if len(byDay) < 2 {
+		return 0, false
+	}
+
+	weekday, ok := weekdayCodes[byDay[len(byDay)-2:]]
+	if !ok {
+		return 0, false
+	}
+
+	n := 1
+	if prefix := byDay[:len(byDay)-2]; prefix != "" {
+		parsed, err := strconv.Atoi(prefix)
+		if err != nil || parsed == 0 {
+			return 0, false
+		}
+		n = parsed
+	}
+
+	daysInMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if n > 0 {
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		day := 1 + (int(weekday)-int(first.Weekday())+7)%7 + (n-1)*7
+		if day > daysInMonth {
+			return 0, false
+		}
+		return day, true
+	}
+
+	last := time.Date(year, month, daysInMonth, 0, 0, 0, 0, time.UTC)
+	day := daysInMonth - (int(last.Weekday())-int(weekday)+7)%7 + (n+1)*7
+	if day < 1 {
+		return 0, false
+	}
+	return day, true
+}
+
+// weekdayCodes maps iCalendar weekday codes to Go weekdays
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// encodeTZif encodes observances and transitions as TZif (version 1) data
+// so that the standard library can build a proper *time.Location from it
+func encodeTZif(observances []tzObservance, transitions []tzTransition) []byte {
+	// Abbreviation table
+	var abbrevs bytes.Buffer
+	abbrevIndex := make([]int, len(observances))
+	for i, obs := range observances {
+		abbrevIndex[i] = abbrevs.Len()
+		abbrevs.WriteString(obs.displayName())
+		abbrevs.WriteByte(0)
+	}
+
+	// Local time type 0 describes the time before the first transition, which
+	// is the "offset from" of the earliest observance
+	first := observances[transitions[0].observance]
+	types := []struct {
+		offset int
+		isDST  bool
+		abbrev int
+	}{{offset: first.offsetFrom, isDST: false, abbrev: abbrevs.Len()}}
+	abbrevs.WriteString(formatUTCOffset(first.offsetFrom))
+	abbrevs.WriteByte(0)
+
+	for i, obs := range observances {
+		types = append(types, struct {
+			offset int
+			isDST  bool
+			abbrev int
+		}{offset: obs.offsetTo, isDST: obs.daylight, abbrev: abbrevIndex[i]})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("TZif")
+	buf.WriteByte(0)                    // Version 1
+	buf.Write(make([]byte, 15))         // Reserved
+	writeUint32(&buf, 0)                // isutcnt
+	writeUint32(&buf, 0)                // isstdcnt
+	writeUint32(&buf, 0)                // leapcnt
+	writeUint32(&buf, len(transitions)) // timecnt
+	writeUint32(&buf, len(types))       // typecnt
+	writeUint32(&buf, abbrevs.Len())    // charcnt
+
+	for _, transition := range transitions {
+		binary.Write(&buf, binary.BigEndian, int32(transition.at))
+	}
+	for _, transition := range transitions {
+		buf.WriteByte(byte(transition.observance + 1))
+	}
+	for _, t := range types {
+		binary.Write(&buf, binary.BigEndian, int32(t.offset))
+		if t.isDST {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		buf.WriteByte(byte(t.abbrev))
+	}
+	buf.Write(abbrevs.Bytes())
+
+	return buf.Bytes()
+}
+
+// writeUint32 writes a big-endian 32-bit count
+func writeUint32(buf *bytes.Buffer, value int) {
+	binary.Write(buf, binary.BigEndian, uint32(value))
+}
+
+// parseUTCOffset parses TZOFFSETFROM/TZOFFSETTO values like +0100 or -053000
+func parseUTCOffset(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if len(value) != 5 && len(value) != 7 {
+		return 0, fmt.Errorf("invalid UTC offset: %s", value)
+	}
+
+	sign := 1
+	switch value[0] {
+	case '+':
+	case '-':
+		sign = -1
+	default:
+		return 0, fmt.Errorf("invalid UTC offset sign: %s", value)
+	}
+
+	hours, err := strconv.Atoi(value[1:3])
+	if err != nil {
+		return 0, fmt.Errorf("invalid UTC offset hours: %s", value)
+	}
+	minutes, err := strconv.Atoi(value[3:5])
+	if err != nil {
+		return 0, fmt.Errorf("invalid UTC offset minutes: %s", value)
+	}
+	seconds := 0
+	if len(value) == 7 {
+		seconds, err = strconv.Atoi(value[5:7])
+		if err != nil {
+			return 0, fmt.Errorf("invalid UTC offset seconds: %s", value)
+		}
+	}
+
+	return sign * (hours*3600 + minutes*60 + seconds), nil
+}
+
+// formatUTCOffset formats an offset in seconds as +HHMM
+func formatUTCOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, offset/3600, (offset%3600)/60)
+}
+
+// vtimezoneFromComponent builds the timezone model from a VTIMEZONE component
+func vtimezoneFromComponent(component *Component) *vtimezone {
+	vtz := &vtimezone{tzid: normalizeTZID(component.Value("TZID"))}
+
+	for _, child := range component.Components {
+		if child.Name != "STANDARD" && child.Name != "DAYLIGHT" {
+			continue
+		}
+
+		observance := tzObservance{daylight: child.Name == "DAYLIGHT"}
+		for _, property := range child.Properties {
+			observance.setProperty(property)
+		}
+		vtz.observances = append(vtz.observances, observance)
+	}
+
+	return vtz
+}
+
+// setProperty applies a single STANDARD/DAYLIGHT property to the observance
+func (o *tzObservance) setProperty(property Property) {
+	switch property.Name {
+	case "DTSTART":
+		if t, err := parseWallClock(property.Value); err == nil {
+			o.start = t
+		}
+	case "TZOFFSETFROM":
+		if offset, err := parseUTCOffset(property.Value); err == nil {
+			o.offsetFrom = offset
+		}
+	case "TZOFFSETTO":
+		if offset, err := parseUTCOffset(property.Value); err == nil {
+			o.offsetTo = offset
+		}
+	case "TZNAME":
+		o.name = property.Text()
+	case "RRULE":
+		o.rrule = make(map[string]string)
+		for _, part := range strings.Split(property.Value, ";") {
+			if kv := strings.SplitN(part, "=", 2); len(kv) == 2 {
+				o.rrule[strings.ToUpper(kv[0])] = kv[1]
+			}
+		}
+	case "RDATE":
+		for _, rdate := range splitListValue(property.Value) {
+			if t, err := parseWallClock(rdate); err == nil {
+				o.rdates = append(o.rdates, t)
+			}
+		}
+	}
+}
+
+// parseWallClock parses a local DATE-TIME or DATE value as wall-clock time
+func parseWallClock(value string) (time.Time, error) {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "Z")
+	if len(value) == 8 {
+		return time.Parse("20060102", value)
+	}
+	return time.Parse("20060102T150405", value)
+}
+
+// windowsZones maps Windows time zone names (as used by Exchange/Outlook) to
+// IANA zones, following the CLDR windowsZones "001" territory mapping
+var windowsZones = map[string]string{
+	"Dateline Standard Time":          "Etc/GMT+12",
+	"UTC-11":                          "Etc/GMT+11",
+	"Aleutian Standard Time":          "America/Adak",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Marquesas Standard Time":         "Pacific/Marquesas",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"UTC-09":                          "Etc/GMT+9",
+	"Pacific Standard Time (Mexico)":  "America/Tijuana",
+	"UTC-08":                          "Etc/GMT+8",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Mountain Standard Time (Mexico)": "America/Mazatlan",
+	"Mountain Standard Time":          "America/Denver",
+	"Yukon Standard Time":             "America/Whitehorse",
+	"Central America Standard Time":   "America/Guatemala",
+	"Central Standard Time":           "America/Chicago",
+	"Easter Island Standard Time":     "Pacific/Easter",
+	"Central Standard Time (Mexico)":  "America/Mexico_City",
+	"Canada Central Standard Time":    "America/Regina",
+	"SA Pacific Standard Time":        "America/Bogota",
+	"Eastern Standard Time (Mexico)":  "America/Cancun",
+	"Eastern Standard Time":           "America/New_York",
+	"Haiti Standard Time":             "America/Port-au-Prince",
+	"Cuba Standard Time":              "America/Havana",
+	"US Eastern Standard Time":        "America/Indiana/Indianapolis",
+	"Turks And Caicos Standard Time":  "America/Grand_Turk",
+	"Paraguay Standard Time":          "America/Asuncion",
+	"Atlantic Standard Time":          "America/Halifax",
+	"Venezuela Standard Time":         "America/Caracas",
+	"Central Brazilian Standard Time": "America/Cuiaba",
+	"SA Western Standard Time":        "America/La_Paz",
+	"Pacific SA Standard Time":        "America/Santiago",
+	"Newfoundland Standard Time":      "America/St_Johns",
+	"Tocantins Standard Time":         "America/Araguaina",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"SA Eastern Standard Time":        "America/Cayenne",
+	"Argentina Standard Time":         "America/Argentina/Buenos_Aires",
+	"Greenland Standard Time":         "America/Godthab",
+	"Montevideo Standard Time":        "America/Montevideo",
+	"Magallanes Standard Time":        "America/Punta_Arenas",
+	"Saint Pierre Standard Time":      "America/Miquelon",
+	"Bahia Standard Time":             "America/Bahia",
+	"UTC-02":                          "Etc/GMT+2",
+	"Mid-Atlantic Standard Time":      "Etc/GMT+2",
+	"Azores Standard Time":            "Atlantic/Azores",
+	"Cape Verde Standard Time":        "Atlantic/Cape_Verde",
+	"UTC":                             "Etc/UTC",
+	"Coordinated Universal Time":      "Etc/UTC",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"Sao Tome Standard Time":          "Africa/Sao_Tome",
+	"Morocco Standard Time":           "Africa/Casablanca",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Romance Standard Time":           "Europe/Paris",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"W. Central Africa Standard Time": "Africa/Lagos",
+	"Jordan Standard Time":            "Asia/Amman",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"Middle East Standard Time":       "Asia/Beirut",
+	"Egypt Standard Time":             "Africa/Cairo",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"Syria Standard Time":             "Asia/Damascus",
+	"West Bank Standard Time":         "Asia/Hebron",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"FLE Standard Time":               "Europe/Kiev",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"South Sudan Standard Time":       "Africa/Juba",
+	"Kaliningrad Standard Time":       "Europe/Kaliningrad",
+	"Sudan Standard Time":             "Africa/Khartoum",
+	"Libya Standard Time":             "Africa/Tripoli",
+	"Namibia Standard Time":           "Africa/Windhoek",
+	"Arabic Standard Time":            "Asia/Baghdad",
+	"Turkey Standard Time":            "Europe/Istanbul",
+	"Arab Standard Time":              "Asia/Riyadh",
+	"Belarus Standard Time":           "Europe/Minsk",
+	"Russian Standard Time":           "Europe/Moscow",
+	"E. Africa Standard Time":         "Africa/Nairobi",
+	"Volgograd Standard Time":         "Europe/Volgograd",
+	"Iran Standard Time":              "Asia/Tehran",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"Astrakhan Standard Time":         "Europe/Astrakhan",
+	"Azerbaijan Standard Time":        "Asia/Baku",
+	"Russia Time Zone 3":              "Europe/Samara",
+	"Mauritius Standard Time":         "Indian/Mauritius",
+	"Saratov Standard Time":           "Europe/Saratov",
+	"Georgian Standard Time":          "Asia/Tbilisi",
+	"Caucasus Standard Time":          "Asia/Yerevan",
+	"Afghanistan Standard Time":       "Asia/Kabul",
+	"West Asia Standard Time":         "Asia/Tashkent",
+	"Ekaterinburg Standard Time":      "Asia/Yekaterinburg",
+	"Pakistan Standard Time":          "Asia/Karachi",
+	"Qyzylorda Standard Time":         "Asia/Qyzylorda",
+	"India Standard Time":             "Asia/Kolkata",
+	"Sri Lanka Standard Time":         "Asia/Colombo",
+	"Nepal Standard Time":             "Asia/Kathmandu",
+	"Central Asia Standard Time":      "Asia/Almaty",
+	"Bangladesh Standard Time":        "Asia/Dhaka",
+	"Omsk Standard Time":              "Asia/Omsk",
+	"Myanmar Standard Time":           "Asia/Yangon",
+	"SE Asia Standard Time":           "Asia/Bangkok",
+	"Altai Standard Time":             "Asia/Barnaul",
+	"W. Mongolia Standard Time":       "Asia/Hovd",
+	"North Asia Standard Time":        "Asia/Krasnoyarsk",
+	"N. Central Asia Standard Time":   "Asia/Novosibirsk",
+	"Tomsk Standard Time":             "Asia/Tomsk",
+	"China Standard Time":             "Asia/Shanghai",
+	"North Asia East Standard Time":   "Asia/Irkutsk",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"Malay Peninsula Standard Time":   "Asia/Singapore",
+	"W. Australia Standard Time":      "Australia/Perth",
+	"Taipei Standard Time":            "Asia/Taipei",
+	"Ulaanbaatar Standard Time":       "Asia/Ulaanbaatar",
+	"Aus Central W. Standard Time":    "Australia/Eucla",
+	"Transbaikal Standard Time":       "Asia/Chita",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"North Korea Standard Time":       "Asia/Pyongyang",
+	"Korea Standard Time":             "Asia/Seoul",
+	"Yakutsk Standard Time":           "Asia/Yakutsk",
+	"Cen. Australia Standard Time":    "Australia/Adelaide",
+	"AUS Central Standard Time":       "Australia/Darwin",
+	"E. Australia Standard Time":      "Australia/Brisbane",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"West Pacific Standard Time":      "Pacific/Port_Moresby",
+	"Tasmania Standard Time":          "Australia/Hobart",
+	"Vladivostok Standard Time":       "Asia/Vladivostok",
+	"Lord Howe Standard Time":         "Australia/Lord_Howe",
+	"Bougainville Standard Time":      "Pacific/Bougainville",
+	"Russia Time Zone 10":             "Asia/Srednekolymsk",
+	"Magadan Standard Time":           "Asia/Magadan",
+	"Norfolk Standard Time":           "Pacific/Norfolk",
+	"Sakhalin Standard Time":          "Asia/Sakhalin",
+	"Central Pacific Standard Time":   "Pacific/Guadalcanal",
+	"Russia Time Zone 11":             "Asia/Kamchatka",
+	"Kamchatka Standard Time":         "Asia/Kamchatka",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+	"UTC+12":                          "Etc/GMT-12",
+	"Fiji Standard Time":              "Pacific/Fiji",
+	"Chatham Islands Standard Time":   "Pacific/Chatham",
+	"UTC+13":                          "Etc/GMT-13",
+	"Tonga Standard Time":             "Pacific/Tongatapu",
+	"Samoa Standard Time":             "Pacific/Apia",
+	"Line Islands Standard Time":      "Pacific/Kiritimati",
+}
@@ -0,0 +1,287 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+// parseSingleEvent parses a calendar containing exactly one event
+func parseSingleEvent(t *testing.T, parser *ICSParser, icsData string) (time.Time, time.Time) {
+	t.Helper()
+	events, err := parser.ParseReader(strings.NewReader(icsData))
+	if err != nil {
+		t.Fatalf("ParseReader() error = %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	return events[0].GetStartTime(), events[0].GetEndTime()
+}
+
+func TestTimezoneResolver_Lookup(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+	newYork := mustLoadLocation(t, "America/New_York")
+	resolver := NewTimezoneResolver(newYork)
+
+	tests := []struct {
+		name     string
+		tzid     string
+		expected string
+	}{
+		{"IANA name", "Europe/Berlin", berlin.String()},
+		{"quoted IANA name", `"Europe/Berlin"`, berlin.String()},
+		{"Windows name", "W. Europe Standard Time", "Europe/Berlin"},
+		{"Windows US name", "Pacific Standard Time", "America/Los_Angeles"},
+		{"Mozilla prefix", "/mozilla.org/20050126_1/Europe/Berlin", "Europe/Berlin"},
+		{"floating", "", newYork.String()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := resolver.Lookup(tt.tzid)
+			if err != nil {
+				t.Fatalf("Lookup(%q) error = %v", tt.tzid, err)
+			}
+			if loc.String() != tt.expected {
+				t.Errorf("Lookup(%q) = %s, want %s", tt.tzid, loc, tt.expected)
+			}
+		})
+	}
+
+	if _, err := resolver.Lookup("Nowhere Standard Time"); err == nil {
+		t.Error("Expected error for unknown TZID")
+	}
+	if loc := resolver.Resolve("Nowhere Standard Time"); loc != newYork {
+		t.Errorf("Resolve() of unknown TZID = %s, want default zone", loc)
+	}
+}
+
+func TestTimezoneResolver_ParseDateTime(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	mustLoadLocation(t, "Europe/Berlin")
+	resolver := NewTimezoneResolver(newYork)
+
+	tests := []struct {
+		name       string
+		value      string
+		params     map[string]string
+		expected   time.Time
+		wantAllDay bool
+	}{
+		{
+			name:     "UTC",
+			value:    "20240115T090000Z",
+			params:   map[string]string{},
+			expected: time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "TZID",
+			value:    "20240115T090000",
+			params:   map[string]string{"TZID": "Europe/Berlin"},
+			expected: time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "floating",
+			value:    "20240115T090000",
+			params:   map[string]string{},
+			expected: time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "date",
+			value:      "20240115",
+			params:     map[string]string{"VALUE": "DATE"},
+			expected:   time.Date(2024, 1, 15, 5, 0, 0, 0, time.UTC),
+			wantAllDay: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, allDay, err := resolver.ParseDateTime(tt.value, tt.params)
+			if err != nil {
+				t.Fatalf("ParseDateTime() error = %v", err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("ParseDateTime() = %v, want %v", got.UTC(), tt.expected)
+			}
+			if allDay != tt.wantAllDay {
+				t.Errorf("ParseDateTime() allDay = %v, want %v", allDay, tt.wantAllDay)
+			}
+		})
+	}
+}
+
+func TestICSParser_WindowsTimezone(t *testing.T) {
+	mustLoadLocation(t, "Europe/Berlin")
+	parser := NewICSParser()
+	parser.SetTimeZone(time.UTC)
+
+	start, end := parseSingleEvent(t, parser, `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:windows@example.com
+DTSTAMP:20240101T000000Z
+DTSTART;TZID=W. Europe Standard Time:20240715T090000
+DTEND;TZID=W. Europe Standard Time:20240715T100000
+SUMMARY:Outlook meeting
+END:VEVENT
+END:VCALENDAR`)
+
+	// Berlin is UTC+2 in July
+	if expected := time.Date(2024, 7, 15, 7, 0, 0, 0, time.UTC); !start.Equal(expected) {
+		t.Errorf("Expected start %v, got %v", expected, start.UTC())
+	}
+	if expected := time.Date(2024, 7, 15, 8, 0, 0, 0, time.UTC); !end.Equal(expected) {
+		t.Errorf("Expected end %v, got %v", expected, end.UTC())
+	}
+}
+
+func TestICSParser_EmbeddedVTIMEZONE(t *testing.T) {
+	parser := NewICSParser()
+	parser.SetTimeZone(time.UTC)
+
+	// Custom TZID that is neither an IANA nor a Windows name, using the
+	// Outlook convention of a 1601 DTSTART for the observances
+	calendar := `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VTIMEZONE
+TZID:Customized Time Zone
+BEGIN:STANDARD
+DTSTART:16010101T030000
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0100
+TZNAME:CET
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:16010101T020000
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0200
+TZNAME:CEST
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU
+END:DAYLIGHT
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:custom@example.com
+DTSTAMP:20240101T000000Z
+DTSTART;TZID="Customized Time Zone":%s
+DURATION:PT1H
+SUMMARY:Custom zone meeting
+END:VEVENT
+END:VCALENDAR`
+
+	tests := []struct {
+		name     string
+		dtstart  string
+		expected time.Time
+	}{
+		{"winter", "20240115T090000", time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)},
+		{"summer", "20240715T090000", time.Date(2024, 7, 15, 7, 0, 0, 0, time.UTC)},
+		{"day after spring forward", "20240401T090000", time.Date(2024, 4, 1, 7, 0, 0, 0, time.UTC)},
+		{"day before spring forward", "20240330T090000", time.Date(2024, 3, 30, 8, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := parseSingleEvent(t, parser, strings.Replace(calendar, "%s", tt.dtstart, 1))
+			if !start.Equal(tt.expected) {
+				t.Errorf("Expected start %v, got %v", tt.expected, start.UTC())
+			}
+			if end.Sub(start) != time.Hour {
+				t.Errorf("Expected 1h duration, got %v", end.Sub(start))
+			}
+		})
+	}
+}
+
+func TestICSParser_FloatingTimes(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	parser := NewICSParser()
+	parser.SetTimeZone(newYork)
+
+	t.Run("floating date-time", func(t *testing.T) {
+		start, _ := parseSingleEvent(t, parser, `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:floating@example.com
+DTSTAMP:20240101T000000Z
+DTSTART:20240115T090000
+DTEND:20240115T100000
+SUMMARY:Floating
+END:VEVENT
+END:VCALENDAR`)
+
+		if expected := time.Date(2024, 1, 15, 9, 0, 0, 0, newYork); !start.Equal(expected) {
+			t.Errorf("Expected start %v, got %v", expected, start)
+		}
+	})
+
+	t.Run("all-day date", func(t *testing.T) {
+		start, _ := parseSingleEvent(t, parser, `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:allday@example.com
+DTSTAMP:20240101T000000Z
+DTSTART;VALUE=DATE:20240115
+DTEND;VALUE=DATE:20240116
+SUMMARY:All day
+END:VEVENT
+END:VCALENDAR`)
+
+		// All-day events start at local midnight, not UTC midnight
+		if expected := time.Date(2024, 1, 15, 0, 0, 0, 0, newYork); !start.Equal(expected) {
+			t.Errorf("Expected start %v, got %v", expected, start)
+		}
+	})
+
+	t.Run("UTC is not floating", func(t *testing.T) {
+		start, _ := parseSingleEvent(t, parser, `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:utc@example.com
+DTSTAMP:20240101T000000Z
+DTSTART:20240115T090000Z
+DTEND:20240115T100000Z
+SUMMARY:UTC
+END:VEVENT
+END:VCALENDAR`)
+
+		if expected := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC); !start.Equal(expected) {
+			t.Errorf("Expected start %v, got %v", expected, start.UTC())
+		}
+	})
+}
+
+func TestNthWeekdayOfMonth(t *testing.T) {
+	tests := []struct {
+		byDay    string
+		year     int
+		month    time.Month
+		expected int
+	}{
+		{"-1SU", 2024, time.March, 31},
+		{"-1SU", 2024, time.October, 27},
+		{"2SU", 2024, time.March, 10},
+		{"1SU", 2024, time.November, 3},
+		{"SU", 2024, time.September, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.byDay, func(t *testing.T) {
+			day, ok := nthWeekdayOfMonth(tt.year, tt.month, tt.byDay)
+			if !ok || day != tt.expected {
+				t.Errorf("nthWeekdayOfMonth(%d, %v, %s) = %d, %v; want %d", tt.year, tt.month, tt.byDay, day, ok, tt.expected)
+			}
+		})
+	}
+}
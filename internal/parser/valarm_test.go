@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -8,7 +9,7 @@ import (
 )
 
 func TestParseTrigger(t *testing.T) {
-	parser := NewGocalParser()
+	parser := NewICSParser()
 
 	tests := []struct {
 		name     string
@@ -46,6 +47,24 @@ func TestParseTrigger(t *testing.T) {
 			expected: 30 * time.Second,
 			wantErr:  false,
 		},
+		{
+			name:     "at the start",
+			trigger:  "PT0S",
+			expected: 0,
+			wantErr:  false,
+		},
+		{
+			name:     "5 minutes after",
+			trigger:  "+PT5M",
+			expected: -5 * time.Minute,
+			wantErr:  false,
+		},
+		{
+			name:     "unsigned 10 minutes after",
+			trigger:  "PT10M",
+			expected: -10 * time.Minute,
+			wantErr:  false,
+		},
 		{
 			name:     "invalid format",
 			trigger:  "invalid",
@@ -84,7 +103,7 @@ func TestParseTrigger(t *testing.T) {
 }
 
 func TestParseVALARMBlock(t *testing.T) {
-	parser := NewGocalParser()
+	parser := NewICSParser()
 
 	tests := []struct {
 		name        string
@@ -138,6 +157,66 @@ TRIGGER:-PT30M
 			},
 			wantErr: false,
 		},
+		{
+			name: "VALARM at the start",
+			valarmBlock: `
+ACTION:DISPLAY
+TRIGGER:PT0S
+`,
+			expected: storage.Alert{
+				Kind:        storage.AlertAtStart,
+				Offset:      0,
+				Source:      storage.AlertSourceVALARM,
+				Description: "Alert at the start",
+				Action:      storage.AlertActionDisplay,
+			},
+			wantErr: false,
+		},
+		{
+			name: "VALARM after the start",
+			valarmBlock: `
+ACTION:DISPLAY
+TRIGGER:+PT5M
+`,
+			expected: storage.Alert{
+				Kind:        storage.AlertBeforeStart,
+				Offset:      -5 * time.Minute,
+				Source:      storage.AlertSourceVALARM,
+				Description: "Alert 5m0s after the start",
+				Action:      storage.AlertActionDisplay,
+			},
+			wantErr: false,
+		},
+		{
+			name: "VALARM at the end",
+			valarmBlock: `
+ACTION:DISPLAY
+TRIGGER;RELATED=END:PT0S
+`,
+			expected: storage.Alert{
+				Kind:        storage.AlertAtEnd,
+				Offset:      0,
+				Source:      storage.AlertSourceVALARM,
+				Description: "Alert at the end",
+				Action:      storage.AlertActionDisplay,
+			},
+			wantErr: false,
+		},
+		{
+			name: "VALARM before the end",
+			valarmBlock: `
+ACTION:DISPLAY
+TRIGGER;RELATED=END:-PT5M
+`,
+			expected: storage.Alert{
+				Kind:        storage.AlertBeforeEnd,
+				Offset:      5 * time.Minute,
+				Source:      storage.AlertSourceVALARM,
+				Description: "Alert 5m0s before the end",
+				Action:      storage.AlertActionDisplay,
+			},
+			wantErr: false,
+		},
 		{
 			name: "invalid TRIGGER",
 			valarmBlock: `
@@ -162,7 +241,12 @@ TRIGGER:-PT15M
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := parser.parseVALARMBlock(tt.valarmBlock)
+			valarm, err := parseComponent("BEGIN:VALARM\n" + tt.valarmBlock + "END:VALARM\n")
+			if err != nil {
+				t.Fatalf("Failed to parse VALARM component: %v", err)
+			}
+
+			result, err := parser.parseVALARMBlock(valarm)
 
 			if tt.wantErr {
 				if err == nil {
@@ -176,6 +260,10 @@ TRIGGER:-PT15M
 				return
 			}
 
+			if result.Kind != tt.expected.Kind {
+				t.Errorf("Expected kind %v, got %v", tt.expected.Kind, result.Kind)
+			}
+
 			if result.Offset != tt.expected.Offset {
 				t.Errorf("Expected offset %v, got %v", tt.expected.Offset, result.Offset)
 			}
@@ -200,7 +288,7 @@ TRIGGER:-PT15M
 }
 
 func TestParseVALARMs(t *testing.T) {
-	parser := NewGocalParser()
+	parser := NewICSParser()
 
 	icsData := `BEGIN:VCALENDAR
 VERSION:2.0
@@ -236,12 +324,19 @@ END:VALARM
 END:VEVENT
 END:VCALENDAR`
 
-	// Test parsing VALARMs for first event
-	alerts1, err := parser.parseVALARMs(icsData, "test-event-1@example.com")
+	events, err := parser.ParseReader(strings.NewReader(icsData))
 	if err != nil {
-		t.Fatalf("Unexpected error parsing VALARMs for event 1: %v", err)
+		t.Fatalf("Unexpected error parsing calendar: %v", err)
+	}
+
+	alertsByUID := make(map[string][]storage.Alert)
+	for _, event := range events {
+		alertsByUID[event.GetUID()] = event.GetIntrinsicAlerts()
 	}
 
+	// Test VALARMs of first event
+	alerts1 := alertsByUID["test-event-1@example.com"]
+
 	if len(alerts1) != 2 {
 		t.Errorf("Expected 2 alerts for event 1, got %d", len(alerts1))
 	}
@@ -270,11 +365,8 @@ END:VCALENDAR`
 		t.Error("Expected to find 1 hour alert")
 	}
 
-	// Test parsing VALARMs for second event
-	alerts2, err := parser.parseVALARMs(icsData, "test-event-2@example.com")
-	if err != nil {
-		t.Fatalf("Unexpected error parsing VALARMs for event 2: %v", err)
-	}
+	// Test VALARMs of second event
+	alerts2 := alertsByUID["test-event-2@example.com"]
 
 	if len(alerts2) != 1 {
 		t.Errorf("Expected 1 alert for event 2, got %d", len(alerts2))
@@ -283,14 +375,4 @@ END:VCALENDAR`
 	if alerts2[0].Offset != 30*time.Minute {
 		t.Errorf("Expected 30 minute offset, got %v", alerts2[0].Offset)
 	}
-
-	// Test parsing VALARMs for non-existent event
-	alerts3, err := parser.parseVALARMs(icsData, "non-existent@example.com")
-	if err != nil {
-		t.Fatalf("Unexpected error parsing VALARMs for non-existent event: %v", err)
-	}
-
-	if len(alerts3) != 0 {
-		t.Errorf("Expected 0 alerts for non-existent event, got %d", len(alerts3))
-	}
 }
\ No newline at end of file
@@ -0,0 +1,376 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"calwatch/internal/recurrence"
+	"calwatch/internal/storage"
+)
+
+// Base year for dates without a year (--MMDD). A leap year, so that
+// February 29 birthdays remain valid.
+const noYearBaseYear = 2000
+
+// Contact date kinds, used as UID suffix of the generated events
+const (
+	contactBirthday    = "birthday"
+	contactAnniversary = "anniversary"
+)
+
+// contactDateProperties maps vCard properties to the kind of date they hold
+var contactDateProperties = map[string]string{
+	"BDAY":                    contactBirthday,
+	"ANNIVERSARY":             contactAnniversary, // vCard 4.0
+	"X-ANNIVERSARY":           contactAnniversary, // KAddressBook, Thunderbird
+	"X-EVOLUTION-ANNIVERSARY": contactAnniversary, // Evolution
+}
+
+// VCardParser implements CalDAVParser for vCard address books. Birthdays and
+// anniversaries become yearly recurring all-day events.
+type VCardParser struct {
+	maxEvents int
+	timeZone  *time.Location
+	calendar  *storage.Calendar // Calendar the generated events belong to
+}
+
+// NewVCardParser creates a new vCard parser instance
+func NewVCardParser() *VCardParser {
+	return &VCardParser{
+		maxEvents: 10000,
+		timeZone:  time.Local,
+	}
+}
+
+// SetMaxEvents sets the maximum number of events to generate from a single file
+func (p *VCardParser) SetMaxEvents(max int) {
+	p.maxEvents = max
+}
+
+// SetTimeZone sets the timezone in which birthdays start at midnight
+func (p *VCardParser) SetTimeZone(tz *time.Location) {
+	p.timeZone = tz
+}
+
+// SetCalendar sets the calendar (template and automatic alerts) generated events belong to
+func (p *VCardParser) SetCalendar(calendar *storage.Calendar) {
+	p.calendar = calendar
+}
+
+// ParseFile parses a single vCard file and returns its birthday and anniversary events
+func (p *VCardParser) ParseFile(filePath string) ([]storage.Event, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	return p.parse(file, filePath)
+}
+
+// ParseDirectory parses all vCard files in a directory
+func (p *VCardParser) ParseDirectory(dirPath string) ([]storage.Event, error) {
+	return parseDirectory(dirPath, ".vcf", p.ParseFile)
+}
+
+// ParseReader parses vCard data from an io.Reader
+func (p *VCardParser) ParseReader(reader io.Reader) ([]storage.Event, error) {
+	return p.parse(reader, "")
+}
+
+// parse parses vCard data read from the given file, if any
+func (p *VCardParser) parse(reader io.Reader, filePath string) ([]storage.Event, error) {
+	calendar := p.calendar
+	if calendar == nil {
+		calendar = storage.NewCalendar("", "", []storage.Alert{})
+	}
+
+	var events []storage.Event
+	fallbacks := make(map[string]int)
+	err := ReadVCards(reader, func(card *Component) error {
+		for _, event := range p.convertCard(card, calendar, filePath, fallbacks) {
+			if len(events) >= p.maxEvents {
+				return fmt.Errorf("reached maximum event limit (%d)", p.maxEvents)
+			}
+			events = append(events, event)
+		}
+		return nil
+	})
+	if err != nil {
+		if len(events) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			return events, nil
+		}
+		return nil, fmt.Errorf("failed to parse vCard data: %w", err)
+	}
+
+	return events, nil
+}
+
+// ValidateICS checks the vCard structure of the data
+func (p *VCardParser) ValidateICS(data []byte) error {
+	content := string(data)
+
+	if !strings.Contains(strings.ToUpper(content), "BEGIN:VCARD") {
+		return fmt.Errorf("missing BEGIN:VCARD")
+	}
+
+	return ReadVCards(strings.NewReader(content), func(*Component) error {
+		return nil
+	})
+}
+
+// convertCard creates an event for every birthday and anniversary of a vCard
+func (p *VCardParser) convertCard(card *Component, calendar *storage.Calendar, filePath string, fallbacks map[string]int) []storage.Event {
+	name := contactName(card)
+	uid := card.Value("UID")
+	if uid == "" {
+		uid = fallbackUID(name, filePath, fallbacks)
+	}
+	if uid == "" {
+		fmt.Fprintf(os.Stderr, "Warning: Skipping vCard without UID and name\n")
+		return nil
+	}
+
+	var events []storage.Event
+	seen := make(map[string]bool)
+
+	for _, property := range card.Properties {
+		kind, ok := contactDateProperties[property.Name]
+		if !ok || seen[kind] {
+			continue
+		}
+
+		date, err := parseContactDate(property)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to parse %s for contact %s: %v\n", property.Name, uid, err)
+			continue
+		}
+
+		seen[kind] = true
+		events = append(events, p.newContactEvent(uid, name, kind, date, calendar))
+	}
+
+	return events
+}
+
+// fallbackUID identifies a vCard without UID by its file and contact name.
+// Cards of the same name within a file are numbered in order of appearance.
+func fallbackUID(name, filePath string, fallbacks map[string]int) string {
+	if name == "" {
+		return ""
+	}
+
+	uid := name
+	if filePath != "" {
+		uid = filePath + "#" + name
+	}
+	fallbacks[uid]++
+	if count := fallbacks[uid]; count > 1 {
+		uid = fmt.Sprintf("%s#%d", uid, count)
+	}
+	return uid
+}
+
+// newContactEvent creates a yearly all-day event for a contact date
+func (p *VCardParser) newContactEvent(uid, name, kind string, date contactDate, calendar *storage.Calendar) *storage.CalendarEvent {
+	year := date.year
+	if year == 0 {
+		year = noYearBaseYear
+	}
+
+	start := time.Date(year, date.month, date.day, 0, 0, 0, 0, p.timeZone)
+	end := start.AddDate(0, 0, 1)
+
+	var summary, description string
+	switch kind {
+	case contactBirthday:
+		summary = fmt.Sprintf("Birthday: %s", name)
+		if date.year != 0 {
+			description = fmt.Sprintf("Born %s", start.Format("2 January 2006"))
+		}
+	default:
+		summary = fmt.Sprintf("Anniversary: %s", name)
+		if date.year != 0 {
+			description = fmt.Sprintf("Since %s", start.Format("2 January 2006"))
+		}
+	}
+
+	event := storage.NewCalendarEvent(
+		uid+"/"+kind,
+		summary,
+		description,
+		"",
+		start,
+		end,
+		p.timeZone,
+		recurrence.NewYearlyRecurrence(1, nil, nil, nil, nil),
+		calendar,
+		[]storage.Alert{},
+	)
+	event.AllDay = true
+	return event
+}
+
+// contactName returns the display name of a vCard (FN, then N, then ORG)
+func contactName(card *Component) string {
+	if name := strings.TrimSpace(card.Text("FN")); name != "" {
+		return name
+	}
+
+	// N is Family;Given;Additional;Prefix;Suffix
+	if value := card.Value("N"); value != "" {
+		parts := strings.Split(value, ";")
+		var names []string
+		for _, index := range []int{3, 1, 2, 0, 4} {
+			if index < len(parts) {
+				if part := strings.TrimSpace(unescapeText(parts[index])); part != "" {
+					names = append(names, part)
+				}
+			}
+		}
+		if len(names) > 0 {
+			return strings.Join(names, " ")
+		}
+	}
+
+	if value := card.Value("ORG"); value != "" {
+		return strings.TrimSpace(unescapeText(strings.Split(value, ";")[0]))
+	}
+
+	return ""
+}
+
+// contactDate is a calendar date that may lack a year
+type contactDate struct {
+	year  int // 0 if the year is unknown
+	month time.Month
+	day   int
+}
+
+// parseContactDate parses a BDAY or ANNIVERSARY value. Supported are full
+// dates (19900515, 1990-05-15), dates without year (--0515, --05-15) and
+// date-times, whose time part is ignored.
+func parseContactDate(property Property) (contactDate, error) {
+	if strings.EqualFold(property.Param("VALUE"), "text") {
+		return contactDate{}, fmt.Errorf("free-form text is not a date: %q", property.Value)
+	}
+
+	value := strings.TrimSpace(property.Value)
+	if index := strings.IndexByte(value, 'T'); index >= 0 {
+		value = value[:index]
+	}
+
+	var date contactDate
+	var digits string
+	if strings.HasPrefix(value, "--") {
+		digits = "0000" + strings.ReplaceAll(value[2:], "-", "")
+	} else {
+		digits = strings.ReplaceAll(value, "-", "")
+	}
+
+	if len(digits) != 8 {
+		return contactDate{}, fmt.Errorf("invalid date: %q", property.Value)
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return contactDate{}, fmt.Errorf("invalid date: %q", property.Value)
+		}
+	}
+
+	date.year, _ = strconv.Atoi(digits[0:4])
+	month, _ := strconv.Atoi(digits[4:6])
+	date.month = time.Month(month)
+	date.day, _ = strconv.Atoi(digits[6:8])
+
+	// Apple Contacts stores dates without year with a placeholder year
+	if omitYear := property.Param("X-APPLE-OMIT-YEAR"); omitYear != "" && omitYear == digits[0:4] {
+		date.year = 0
+	}
+
+	// Reject dates such as 0230 or 1301
+	checkYear := date.year
+	if checkYear == 0 {
+		checkYear = noYearBaseYear
+	}
+	check := time.Date(checkYear, date.month, date.day, 0, 0, 0, 0, time.UTC)
+	if check.Month() != date.month || check.Day() != date.day {
+		return contactDate{}, fmt.Errorf("invalid date: %q", property.Value)
+	}
+
+	return date, nil
+}
+
+// ReadVCards reads a vCard stream and calls fn for every complete VCARD.
+// Group prefixes (item1.BDAY) are stripped from property names and lines that
+// are not valid content lines, such as vCard 2.1 parameters without a name,
+// are skipped.
+func ReadVCards(reader io.Reader, fn func(*Component) error) error {
+	lines := NewContentLineReader(reader)
+	var stack []*Component
+
+	for {
+		line, err := lines.nextLogicalLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		property, err := parseContentLine(line)
+		if err != nil {
+			continue
+		}
+		if index := strings.LastIndexByte(property.Name, '.'); index >= 0 {
+			property.Name = property.Name[index+1:]
+		}
+
+		switch property.Name {
+		case "BEGIN":
+			component := &Component{Name: strings.ToUpper(strings.TrimSpace(property.Value))}
+			if len(stack) == 0 && component.Name != "VCARD" {
+				return fmt.Errorf("line %d: expected BEGIN:VCARD, got BEGIN:%s", lines.Line(), component.Name)
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, component)
+			}
+			stack = append(stack, component)
+
+		case "END":
+			name := strings.ToUpper(strings.TrimSpace(property.Value))
+			if len(stack) == 0 {
+				return fmt.Errorf("line %d: unexpected END:%s without matching BEGIN", lines.Line(), name)
+			}
+			current := stack[len(stack)-1]
+			if current.Name != name {
+				return fmt.Errorf("line %d: mismatched BEGIN/END: expected %s, got %s", lines.Line(), current.Name, name)
+			}
+			stack = stack[:len(stack)-1]
+
+			if len(stack) == 0 {
+				if err := fn(current); err != nil {
+					return err
+				}
+			}
+
+		default:
+			if len(stack) == 0 {
+				return fmt.Errorf("line %d: property %s outside of VCARD", lines.Line(), property.Name)
+			}
+			current := stack[len(stack)-1]
+			current.Properties = append(current.Properties, property)
+		}
+	}
+
+	if len(stack) > 0 {
+		return fmt.Errorf("unclosed BEGIN:%s", stack[0].Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"calwatch/internal/localday"
+	"calwatch/internal/storage"
+)
+
+func TestParseContactDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected contactDate
+		wantErr  bool
+	}{
+		{"basic date", "BDAY:19900515", contactDate{1990, time.May, 15}, false},
+		{"extended date", "BDAY:1990-05-15", contactDate{1990, time.May, 15}, false},
+		{"date value", "BDAY;VALUE=DATE:19900515", contactDate{1990, time.May, 15}, false},
+		{"date-time", "BDAY:1990-05-15T13:30:00Z", contactDate{1990, time.May, 15}, false},
+		{"without year", "BDAY:--0515", contactDate{0, time.May, 15}, false},
+		{"without year extended", "BDAY:--05-15", contactDate{0, time.May, 15}, false},
+		{"leap day without year", "BDAY:--0229", contactDate{0, time.February, 29}, false},
+		{"apple omitted year", "BDAY;X-APPLE-OMIT-YEAR=1604:1604-05-15", contactDate{0, time.May, 15}, false},
+		{"free-form text", "BDAY;VALUE=text:circa 1800", contactDate{}, true},
+		{"day only", "BDAY:---15", contactDate{}, true},
+		{"invalid day", "BDAY:19900230", contactDate{}, true},
+		{"garbage", "BDAY:unknown", contactDate{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			property, err := parseContentLine(tt.line)
+			if err != nil {
+				t.Fatalf("parseContentLine() error = %v", err)
+			}
+
+			date, err := parseContactDate(property)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error but got %+v", date)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if date != tt.expected {
+				t.Errorf("parseContactDate(%q) = %+v, want %+v", tt.line, date, tt.expected)
+			}
+		})
+	}
+}
+
+func TestVCardParser_ParseReader(t *testing.T) {
+	parser := NewVCardParser()
+	// Dates are all-day events in the local zone, as set up by the daemon
+	loc := localday.Location()
+	parser.SetTimeZone(loc)
+	calendar := storage.NewCalendar("/contacts", "birthday.tpl", []storage.Alert{})
+	parser.SetCalendar(calendar)
+
+	data := `BEGIN:VCARD
+VERSION:4.0
+UID:urn:uuid:jane
+FN:Jane Doe
+item1.BDAY:1990-05-15
+ANNIVERSARY:--0620
+END:VCARD
+BEGIN:VCARD
+VERSION:2.1
+N:Smith;John;;;
+TEL;HOME;VOICE:555-1234
+BDAY:--0229
+END:VCARD
+BEGIN:VCARD
+VERSION:3.0
+UID:no-dates
+FN:Nobody
+END:VCARD
+`
+
+	events, err := parser.ParseReader(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseReader() error = %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+
+	byUID := make(map[string]storage.Event)
+	for _, event := range events {
+		byUID[event.GetUID()] = event
+	}
+
+	birthday, ok := byUID["urn:uuid:jane/birthday"]
+	if !ok {
+		t.Fatalf("Missing birthday event, got %v", byUID)
+	}
+	if birthday.GetSummary() != "Birthday: Jane Doe" {
+		t.Errorf("Expected summary 'Birthday: Jane Doe', got %q", birthday.GetSummary())
+	}
+	if birthday.GetDescription() != "Born 15 May 1990" {
+		t.Errorf("Expected description 'Born 15 May 1990', got %q", birthday.GetDescription())
+	}
+	if member, ok := birthday.(storage.CalendarMember); !ok || member.GetCalendar() != calendar {
+		t.Error("Expected birthday to belong to the parser's calendar")
+	}
+
+	// Yearly recurrence, all-day on the anniversary date
+	if !birthday.OccursOn(time.Date(2025, 5, 15, 12, 0, 0, 0, loc)) {
+		t.Error("Expected birthday to recur on May 15, 2025")
+	}
+	if birthday.OccursOn(time.Date(2025, 5, 16, 12, 0, 0, 0, loc)) {
+		t.Error("Expected birthday not to occur on May 16, 2025")
+	}
+
+	anniversary, ok := byUID["urn:uuid:jane/anniversary"]
+	if !ok {
+		t.Fatalf("Missing anniversary event, got %v", byUID)
+	}
+	if anniversary.GetDescription() != "" {
+		t.Errorf("Expected no description for date without year, got %q", anniversary.GetDescription())
+	}
+	if !anniversary.OccursOn(time.Date(2031, 6, 20, 0, 0, 0, 0, loc)) {
+		t.Error("Expected anniversary to recur on June 20, 2031")
+	}
+
+	// Without UID the contact name identifies the card; leap day birthdays
+	// fall on February 28 in other years
+	leapDay, ok := byUID["John Smith/birthday"]
+	if !ok {
+		t.Fatalf("Missing leap day birthday, got %v", byUID)
+	}
+	if !leapDay.OccursOn(time.Date(2028, 2, 29, 0, 0, 0, 0, loc)) {
+		t.Error("Expected leap day birthday on February 29, 2028")
+	}
+	if !leapDay.OccursOn(time.Date(2025, 2, 28, 0, 0, 0, 0, loc)) {
+		t.Error("Expected leap day birthday on February 28, 2025")
+	}
+}
+
+func TestVCardParser_SameNamedContacts(t *testing.T) {
+	card := "BEGIN:VCARD\nVERSION:3.0\nFN:John Smith\nBDAY:1980-01-01\nEND:VCARD\n"
+	dir := t.TempDir()
+	files := map[string]string{
+		"family.vcf": card,
+		"work.vcf":   card + card,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	parser := NewVCardParser()
+	uids := make(map[string]bool)
+	for name := range files {
+		events, err := parser.ParseFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("ParseFile(%s) error = %v", name, err)
+		}
+		for _, event := range events {
+			uids[event.GetUID()] = true
+		}
+	}
+
+	// Every card gets its own birthday, even with the same name
+	if len(uids) != 3 {
+		t.Errorf("Expected 3 distinct birthday UIDs, got %v", uids)
+	}
+	if !uids[filepath.Join(dir, "work.vcf")+"#John Smith#2/birthday"] {
+		t.Errorf("Expected the second card in work.vcf to be numbered, got %v", uids)
+	}
+}
+
+func TestReadVCards_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not a vCard", "BEGIN:VCALENDAR\nEND:VCALENDAR"},
+		{"unclosed vCard", "BEGIN:VCARD\nFN:Jane\n"},
+		{"mismatched END", "BEGIN:VCARD\nEND:VCALENDAR"},
+		{"property outside vCard", "FN:Jane\nBEGIN:VCARD\nEND:VCARD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ReadVCards(strings.NewReader(tt.data), func(*Component) error { return nil })
+			if err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
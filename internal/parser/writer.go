@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"calwatch/internal/recurrence"
+	"calwatch/internal/storage"
+)
+
+// Product identifier of generated iCalendar data
+const productID = "-//calwatch//calwatch//EN"
+
+// Maximum length of a content line in octets, longer lines are folded
+const maxContentLineOctets = 75
+
+// WriteEvent writes a stored event as a standalone iCalendar object: a VEVENT,
+// or a VTODO for tasks, wrapped in a VCALENDAR. Times are written with their
+// IANA zone as TZID, or in UTC; all-day events are written as DATE values.
+func WriteEvent(w io.Writer, event storage.Event) error {
+	writer := &contentLineWriter{}
+	writer.property("BEGIN", "VCALENDAR")
+	writer.property("VERSION", "2.0")
+	writer.property("PRODID", productID)
+	writer.property("METHOD", "PUBLISH")
+
+	component := "VEVENT"
+	task, isTask := event.(*storage.TaskEvent)
+	if isTask {
+		component = "VTODO"
+	}
+
+	writer.property("BEGIN", component)
+	writer.text("UID", event.GetUID())
+	writer.property("DTSTAMP", time.Now().UTC().Format("20060102T150405Z"))
+
+	calendarEvent := storage.BaseCalendarEvent(event)
+	allDay := calendarEvent != nil && calendarEvent.AllDay
+	if isTask {
+		if task.Start != nil {
+			writer.dateTime("DTSTART", *task.Start, allDay)
+		}
+		if task.Due != nil {
+			writer.dateTime("DUE", *task.Due, allDay)
+		}
+		writer.property("STATUS", task.Status)
+		if task.PercentComplete > 0 {
+			writer.property("PERCENT-COMPLETE", strconv.Itoa(task.PercentComplete))
+		}
+		if task.Priority > 0 {
+			writer.property("PRIORITY", strconv.Itoa(task.Priority))
+		}
+	} else {
+		start, end := event.GetStartTime(), event.GetEndTime()
+		writer.dateTime("DTSTART", start, allDay)
+		if end.After(start) {
+			writer.dateTime("DTEND", end, allDay)
+		}
+	}
+
+	writer.text("SUMMARY", event.GetSummary())
+	writer.text("DESCRIPTION", event.GetDescription())
+	writer.text("LOCATION", event.GetLocation())
+
+	if calendarEvent != nil {
+		if calendarEvent.Recurrence != nil {
+			if rrule := recurrence.FormatRRule(calendarEvent.Recurrence); rrule != "" {
+				writer.property("RRULE", rrule)
+			}
+		}
+		for _, exDate := range calendarEvent.ExDates {
+			writer.dateTime("EXDATE", exDate, allDay)
+		}
+		if calendarEvent.Transparent {
+			writer.property("TRANSP", "TRANSPARENT")
+		}
+		if len(calendarEvent.Categories) > 0 {
+			categories := make([]string, len(calendarEvent.Categories))
+			for i, category := range calendarEvent.Categories {
+				categories[i] = escapeText(category)
+			}
+			writer.property("CATEGORIES", strings.Join(categories, ","))
+		}
+	}
+
+	writer.property("END", component)
+	writer.property("END", "VCALENDAR")
+
+	if _, err := io.WriteString(w, writer.builder.String()); err != nil {
+		return fmt.Errorf("failed to write iCalendar data: %w", err)
+	}
+	return nil
+}
+
+// contentLineWriter builds folded iCalendar content lines
+type contentLineWriter struct {
+	builder strings.Builder
+}
+
+// property writes a content line, folding it after maxContentLineOctets
+func (w *contentLineWriter) property(name, value string) {
+	line := name + ":" + value
+	limit := maxContentLineOctets
+	for len(line) > limit {
+		// Fold at a rune boundary, continuation lines start with a space
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.builder.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = maxContentLineOctets - 1
+	}
+	w.builder.WriteString(line + "\r\n")
+}
+
+// text writes a TEXT property with escaping, skipping empty values
+func (w *contentLineWriter) text(name, value string) {
+	if value == "" {
+		return
+	}
+	w.property(name, escapeText(value))
+}
+
+// dateTime writes a DATE or DATE-TIME property
+func (w *contentLineWriter) dateTime(name string, t time.Time, allDay bool) {
+	zone := t.Location().String()
+	switch {
+	case allDay:
+		w.property(name+";VALUE=DATE", t.Format("20060102"))
+	case zone != "" && zone != "UTC" && zone != "Local":
+		w.property(name+";TZID="+zone, t.Format("20060102T150405"))
+	default:
+		w.property(name, t.UTC().Format("20060102T150405Z"))
+	}
+}
+
+// escapeText applies TEXT escaping, the inverse of unescapeText
+func escapeText(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	)
+	return replacer.Replace(value)
+}
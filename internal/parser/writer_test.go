@@ -0,0 +1,202 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"calwatch/internal/recurrence"
+	"calwatch/internal/storage"
+)
+
+// roundTrip writes an event and parses it back
+func roundTrip(t *testing.T, event storage.Event) (string, storage.Event) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := WriteEvent(&buf, event); err != nil {
+		t.Fatalf("WriteEvent() error = %v", err)
+	}
+
+	data := buf.String()
+	parser := NewICSParser()
+	parser.SetTimeZone(time.UTC)
+	events, err := parser.ParseReader(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to parse written event: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	return data, events[0]
+}
+
+func TestWriteEvent_RoundTrip(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("Europe/Berlin not available: %v", err)
+	}
+
+	startTime := time.Date(2024, 3, 4, 9, 30, 0, 0, berlin)
+	event := storage.NewCalendarEvent(
+		"weekly-sync@example.com",
+		"Sync; planning, review",
+		"Agenda:\n1. Status\n2. "+strings.Repeat("Long discussion ", 8),
+		"Room 1",
+		startTime,
+		startTime.Add(45*time.Minute),
+		berlin,
+		recurrence.NewWeeklyRecurrence(1, []time.Weekday{time.Monday}, nil, nil),
+		storage.NewCalendar("/calendars/work", "", []storage.Alert{}),
+		[]storage.Alert{},
+	)
+	event.AddExceptionDate(startTime.AddDate(0, 0, 7))
+	event.Transparent = true
+	event.Categories = []string{"Meeting", "Project, internal"}
+
+	data, parsed := roundTrip(t, event)
+
+	for _, line := range strings.Split(data, "\r\n") {
+		if len(line) > maxContentLineOctets {
+			t.Errorf("Line exceeds %d octets: %q", maxContentLineOctets, line)
+		}
+	}
+	if !strings.Contains(data, "DTSTART;TZID=Europe/Berlin:20240304T093000\r\n") {
+		t.Errorf("Expected DTSTART with TZID, got:\n%s", data)
+	}
+
+	if parsed.GetUID() != event.UID || parsed.GetSummary() != event.Summary ||
+		parsed.GetDescription() != event.Description || parsed.GetLocation() != event.Location {
+		t.Errorf("Text properties changed: %q %q %q %q", parsed.GetUID(), parsed.GetSummary(), parsed.GetDescription(), parsed.GetLocation())
+	}
+	if !parsed.GetStartTime().Equal(startTime) || !parsed.GetEndTime().Equal(event.EndTime) {
+		t.Errorf("Expected %v - %v, got %v - %v", startTime, event.EndTime, parsed.GetStartTime(), parsed.GetEndTime())
+	}
+
+	// Recurrence and exception date survive
+	if !parsed.OccursOn(startTime.AddDate(0, 0, 14)) {
+		t.Error("Expected event to recur two weeks later")
+	}
+	if categories := parsed.(*storage.CalendarEvent).Categories; !parsed.(*storage.CalendarEvent).Transparent ||
+		len(categories) != 2 || categories[0] != "Meeting" || categories[1] != "Project, internal" {
+		t.Errorf("Expected a transparent event with 2 categories, got %q", categories)
+	}
+	if len(parsed.(*storage.CalendarEvent).ExDates) != 1 {
+		t.Errorf("Expected 1 exception date, got %v", parsed.(*storage.CalendarEvent).ExDates)
+	}
+}
+
+func TestWriteEvent_AllDayEvent(t *testing.T) {
+	startTime := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+	event := storage.NewCalendarEvent(
+		"birthday/anniversary",
+		"Birthday: Jane Doe",
+		"",
+		"",
+		startTime,
+		startTime.AddDate(0, 0, 1),
+		time.UTC,
+		recurrence.NewYearlyRecurrence(1, nil, nil, nil, nil),
+		nil,
+		[]storage.Alert{},
+	)
+	event.AllDay = true
+
+	data, parsed := roundTrip(t, event)
+
+	expectedLines := []string{
+		"DTSTART;VALUE=DATE:20240515",
+		"DTEND;VALUE=DATE:20240516",
+		"RRULE:FREQ=YEARLY;INTERVAL=1",
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(data, line+"\r\n") {
+			t.Errorf("Expected line %q in:\n%s", line, data)
+		}
+	}
+	if !parsed.GetStartTime().Equal(startTime) {
+		t.Errorf("Expected start %v, got %v", startTime, parsed.GetStartTime())
+	}
+	if calendarEvent, ok := parsed.(*storage.CalendarEvent); !ok || !calendarEvent.AllDay {
+		t.Error("Expected parsed event to be all-day")
+	}
+}
+
+func TestWriteEvent_MidnightToMidnight(t *testing.T) {
+	// A timed event that happens to span midnight to midnight is not all-day
+	startTime := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+	event := storage.NewCalendarEvent("night-shift", "Night shift", "", "", startTime, startTime.AddDate(0, 0, 1),
+		time.UTC, &recurrence.NoRecurrence{}, nil, []storage.Alert{})
+
+	data, parsed := roundTrip(t, event)
+
+	if !strings.Contains(data, "DTSTART:20240515T000000Z\r\n") || !strings.Contains(data, "DTEND:20240516T000000Z\r\n") {
+		t.Errorf("Expected DATE-TIME values, got:\n%s", data)
+	}
+	if parsed.(*storage.CalendarEvent).AllDay {
+		t.Error("Expected parsed event not to be all-day")
+	}
+}
+
+func TestWriteEvent_Task(t *testing.T) {
+	due := time.Date(2024, 1, 15, 17, 0, 0, 0, time.UTC)
+	task := storage.NewTaskEvent(
+		storage.NewCalendarEvent("task-1", "Submit report", "", "", due, due, time.UTC,
+			&recurrence.NoRecurrence{}, nil, []storage.Alert{}),
+		&due, nil, storage.TaskStatusInProcess, 50, 1,
+	)
+
+	data, parsed := roundTrip(t, task)
+
+	if !strings.Contains(data, "BEGIN:VTODO\r\n") || !strings.Contains(data, "DUE:20240115T170000Z\r\n") {
+		t.Errorf("Expected VTODO with DUE, got:\n%s", data)
+	}
+
+	parsedTask, ok := parsed.(*storage.TaskEvent)
+	if !ok {
+		t.Fatalf("Expected a task, got %T", parsed)
+	}
+	if parsedTask.Due == nil || !parsedTask.Due.Equal(due) {
+		t.Errorf("Expected due %v, got %v", due, parsedTask.Due)
+	}
+	if parsedTask.Status != storage.TaskStatusInProcess || parsedTask.PercentComplete != 50 || parsedTask.Priority != 1 {
+		t.Errorf("Unexpected task properties: %s %d %d", parsedTask.Status, parsedTask.PercentComplete, parsedTask.Priority)
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	value := "a,b;c\\d\nend"
+	escaped := escapeText(value)
+	if escaped != `a\,b\;c\\d\nend` {
+		t.Errorf("escapeText() = %q", escaped)
+	}
+	if unescapeText(escaped) != value {
+		t.Errorf("unescapeText(escapeText()) = %q, want %q", unescapeText(escaped), value)
+	}
+}
+
+func TestWriteEvent_TaskDueAtMidnight(t *testing.T) {
+	tests := []struct {
+		name     string
+		allDay   bool
+		expected string
+	}{
+		{"timed", false, "DUE:20240115T000000Z\r\n"},
+		{"all-day", true, "DUE;VALUE=DATE:20240115\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+			event := storage.NewCalendarEvent("task-1", "Submit report", "", "", due, due, time.UTC,
+				&recurrence.NoRecurrence{}, nil, []storage.Alert{})
+			event.AllDay = tt.allDay
+
+			data, _ := roundTrip(t, storage.NewTaskEvent(event, &due, nil, "", 0, 0))
+			if !strings.Contains(data, tt.expected) {
+				t.Errorf("Expected %q in:\n%s", tt.expected, data)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package recurrence
 import (
 	"fmt"
 	"time"
+
+	"calwatch/internal/localday"
 )
 
 // DailyRecurrence represents a daily recurring event
@@ -24,34 +26,7 @@ func NewDailyRecurrence(interval int, until *time.Time, count *int) *DailyRecurr
 }
 
 func (dr *DailyRecurrence) OccursOn(date time.Time, baseTime time.Time) bool {
-	checkDate := date.Truncate(24 * time.Hour)
-	baseDate := baseTime.Truncate(24 * time.Hour)
-	
-	// Event can't occur before the base date
-	if checkDate.Before(baseDate) {
-		return false
-	}
-	
-	// Check if this date falls on the interval
-	daysDiff := int(checkDate.Sub(baseDate).Hours() / 24)
-	if daysDiff%dr.Interval != 0 {
-		return false
-	}
-	
-	// Check until date if specified (UNTIL is inclusive per RFC 5545)
-	if dr.Until != nil && checkDate.After(*dr.Until) {
-		return false
-	}
-	
-	// Check count if specified
-	if dr.Count != nil {
-		occurrenceNumber := (daysDiff / dr.Interval) + 1
-		if occurrenceNumber > *dr.Count {
-			return false
-		}
-	}
-	
-	return true
+	return occursOnLocalDay(dr, date, baseTime)
 }
 
 func (dr *DailyRecurrence) OccurredWithin(start, end time.Time, baseTime time.Time, exDates []time.Time) []time.Time {
@@ -61,7 +36,7 @@ func (dr *DailyRecurrence) OccurredWithin(start, end time.Time, baseTime time.Ti
 	current := baseTime
 	if start.After(baseTime) {
 		// Fast forward to the first occurrence within the range
-		daysDiff := int(start.Sub(baseTime).Hours() / 24)
+		daysDiff := localday.DaysBetween(eventDay(baseTime, baseTime), eventDay(start, baseTime))
 		intervalStart := (daysDiff / dr.Interval) * dr.Interval
 		current = baseTime.AddDate(0, 0, intervalStart)
 		
@@ -86,7 +61,7 @@ func (dr *DailyRecurrence) OccurredWithin(start, end time.Time, baseTime time.Ti
 		
 		// Check count if specified
 		if dr.Count != nil {
-			daysDiff := int(current.Sub(baseTime).Hours() / 24)
+			daysDiff := localday.DaysBetween(eventDay(baseTime, baseTime), eventDay(current, baseTime))
 			occurrenceNumber := (daysDiff / dr.Interval) + 1
 			if occurrenceNumber > *dr.Count {
 				break
@@ -117,7 +92,7 @@ func (dr *DailyRecurrence) NextOccurrence(after time.Time, baseTime time.Time, e
 	
 	if after.After(baseTime) {
 		// Fast forward to the first potential occurrence after 'after'
-		daysDiff := int(after.Sub(baseTime).Hours() / 24)
+		daysDiff := localday.DaysBetween(eventDay(baseTime, baseTime), eventDay(after, baseTime))
 		intervalStart := ((daysDiff / dr.Interval) + 1) * dr.Interval
 		current = baseTime.AddDate(0, 0, intervalStart)
 	}
@@ -138,7 +113,7 @@ func (dr *DailyRecurrence) NextOccurrence(after time.Time, baseTime time.Time, e
 		
 		// Check count if specified
 		if dr.Count != nil {
-			daysDiff := int(current.Sub(baseTime).Hours() / 24)
+			daysDiff := localday.DaysBetween(eventDay(baseTime, baseTime), eventDay(current, baseTime))
 			occurrenceNumber := (daysDiff / dr.Interval) + 1
 			if occurrenceNumber > *dr.Count {
 				return nil
@@ -0,0 +1,104 @@
+package recurrence
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"calwatch/internal/localday"
+)
+
+// Describe returns an English description of a recurrence such as
+// "every week on Monday and Wednesday", or "" for single events. Rules
+// without explicit days repeat on the day of baseTime.
+func Describe(rec Recurrence, baseTime time.Time) string {
+	local := baseTime.In(localday.Location())
+
+	switch r := rec.(type) {
+	case *DailyRecurrence:
+		return every(r.Interval, "day", "days")
+
+	case *WeeklyRecurrence:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{local.Weekday()}
+		}
+		names := make([]string, len(days))
+		for i, day := range days {
+			names[i] = day.String()
+		}
+		return every(r.Interval, "week", "weeks") + " on " + joinList(names)
+
+	case *MonthlyRecurrence:
+		monthDays := r.ByMonthDay
+		if len(monthDays) == 0 {
+			monthDays = []int{local.Day()}
+		}
+		return every(r.Interval, "month", "months") + " on the " + describeMonthDays(monthDays)
+
+	case *YearlyRecurrence:
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{local.Month()}
+		}
+		monthDays := r.ByMonthDay
+		if len(monthDays) == 0 {
+			monthDays = []int{local.Day()}
+		}
+		names := make([]string, len(months))
+		for i, month := range months {
+			names[i] = month.String()
+		}
+		return every(r.Interval, "year", "years") + " on the " + describeMonthDays(monthDays) + " of " + joinList(names)
+	}
+
+	return ""
+}
+
+// every formats an interval, e.g. "every week" or "every 2 weeks"
+func every(interval int, singular, plural string) string {
+	if interval <= 1 {
+		return "every " + singular
+	}
+	return fmt.Sprintf("every %d %s", interval, plural)
+}
+
+// describeMonthDays formats days of the month, e.g. "1st and 15th" or "last day"
+func describeMonthDays(monthDays []int) string {
+	names := make([]string, len(monthDays))
+	for i, day := range monthDays {
+		switch {
+		case day == -1:
+			names[i] = "last day"
+		case day < 0:
+			names[i] = ordinal(-day) + " to last day"
+		default:
+			names[i] = ordinal(day)
+		}
+	}
+	return joinList(names)
+}
+
+// ordinal formats a positive number as English ordinal, e.g. "1st", "22nd", "13th"
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+// joinList joins words as English list, e.g. "Monday, Tuesday and Friday"
+func joinList(words []string) string {
+	if len(words) <= 1 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
@@ -0,0 +1,45 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"calwatch/internal/localday"
+)
+
+func TestDescribe(t *testing.T) {
+	localday.SetLocation(time.UTC)
+	defer localday.SetLocation(time.Local)
+
+	// Wednesday, 15 March 2023
+	baseTime := time.Date(2023, 3, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		rrule    string
+		expected string
+	}{
+		{"", ""},
+		{"FREQ=DAILY", "every day"},
+		{"FREQ=DAILY;INTERVAL=3", "every 3 days"},
+		{"FREQ=WEEKLY", "every week on Wednesday"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE,FR", "every 2 weeks on Monday, Wednesday and Friday"},
+		{"FREQ=MONTHLY", "every month on the 15th"},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,2,3,11,22", "every month on the 1st, 2nd, 3rd, 11th and 22nd"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "every month on the last day"},
+		{"FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=-2", "every 3 months on the 2nd to last day"},
+		{"FREQ=YEARLY", "every year on the 15th of March"},
+		{"FREQ=YEARLY;BYMONTH=1,7;BYMONTHDAY=1", "every year on the 1st of January and July"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rrule, func(t *testing.T) {
+			rec, err := ParseRRule(tt.rrule)
+			if err != nil {
+				t.Fatalf("ParseRRule() error = %v", err)
+			}
+			if got := Describe(rec, baseTime); got != tt.expected {
+				t.Errorf("Describe(%s) = %q, expected %q", tt.rrule, got, tt.expected)
+			}
+		})
+	}
+}
@@ -26,64 +26,7 @@ func NewMonthlyRecurrence(interval int, byMonthDay []int, until *time.Time, coun
 }
 
 func (mr *MonthlyRecurrence) OccursOn(date time.Time, baseTime time.Time) bool {
-	checkDate := date.Truncate(24 * time.Hour)
-	baseDate := baseTime.Truncate(24 * time.Hour)
-	
-	// Event can't occur before the base date
-	if checkDate.Before(baseDate) {
-		return false
-	}
-	
-	// Get target month days
-	targetDays := mr.ByMonthDay
-	if len(targetDays) == 0 {
-		targetDays = []int{baseTime.Day()}
-	}
-	
-	// Check if the check date is on one of the target days
-	validDay := false
-	for _, day := range targetDays {
-		// Handle last day of month (negative values)
-		actualDay := day
-		if day < 0 {
-			daysInMonth := getDaysInMonth(checkDate.Year(), checkDate.Month())
-			actualDay = daysInMonth + day + 1
-		}
-		
-		// Handle cases where the target day doesn't exist in this month
-		if actualDay > getDaysInMonth(checkDate.Year(), checkDate.Month()) {
-			actualDay = getDaysInMonth(checkDate.Year(), checkDate.Month())
-		}
-		
-		if checkDate.Day() == actualDay {
-			validDay = true
-			break
-		}
-	}
-	if !validDay {
-		return false
-	}
-	
-	// Check if this date falls on the correct month interval
-	monthsDiff := getMonthsDiff(baseDate, checkDate)
-	if monthsDiff%mr.Interval != 0 {
-		return false
-	}
-	
-	// Check until date if specified
-	if mr.Until != nil && checkDate.After(*mr.Until) {
-		return false
-	}
-	
-	// Check count if specified
-	if mr.Count != nil {
-		totalOccurrences := mr.countOccurrencesUntil(checkDate, baseTime)
-		if totalOccurrences > *mr.Count {
-			return false
-		}
-	}
-	
-	return true
+	return occursOnLocalDay(mr, date, baseTime)
 }
 
 func (mr *MonthlyRecurrence) OccurredWithin(start, end time.Time, baseTime time.Time, exDates []time.Time) []time.Time {
@@ -94,9 +37,9 @@ func (mr *MonthlyRecurrence) OccurredWithin(start, end time.Time, baseTime time.
 		targetDays = []int{baseTime.Day()}
 	}
 	
-	baseDate := baseTime.Truncate(24 * time.Hour)
-	startDate := start.Truncate(24 * time.Hour)
-	endDate := end.Truncate(24 * time.Hour)
+	baseDate := eventDay(baseTime, baseTime).Start()
+	startDate := eventDay(start, baseTime).Start()
+	endDate := eventDay(end, baseTime).Start()
 	
 	// Start from the base month or the month containing start, whichever is later
 	current := time.Date(baseDate.Year(), baseDate.Month(), 1, 0, 0, 0, 0, baseDate.Location())
@@ -183,8 +126,8 @@ func (mr *MonthlyRecurrence) NextOccurrence(after time.Time, baseTime time.Time,
 		targetDays = []int{baseTime.Day()}
 	}
 	
-	baseDate := baseTime.Truncate(24 * time.Hour)
-	afterDate := after.Truncate(24 * time.Hour)
+	baseDate := eventDay(baseTime, baseTime).Start()
+	afterDate := eventDay(after, baseTime).Start()
 	
 	// Start from the base month or the month containing 'after', whichever is later
 	current := time.Date(baseDate.Year(), baseDate.Month(), 1, 0, 0, 0, 0, baseDate.Location())
@@ -293,7 +236,7 @@ func (mr *MonthlyRecurrence) countOccurrencesUntil(untilDate time.Time, baseTime
 		targetDays = []int{baseTime.Day()}
 	}
 	
-	baseDate := baseTime.Truncate(24 * time.Hour)
+	baseDate := eventDay(baseTime, baseTime).Start()
 	current := time.Date(baseDate.Year(), baseDate.Month(), 1, 0, 0, 0, 0, baseDate.Location())
 	
 	for {
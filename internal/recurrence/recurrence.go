@@ -2,6 +2,8 @@ package recurrence
 
 import (
 	"time"
+
+	"calwatch/internal/localday"
 )
 
 // Recurrence defines the interface for handling recurring events
@@ -23,9 +25,7 @@ type Recurrence interface {
 type NoRecurrence struct{}
 
 func (nr *NoRecurrence) OccursOn(date time.Time, baseTime time.Time) bool {
-	baseDate := baseTime.Truncate(24 * time.Hour)
-	checkDate := date.Truncate(24 * time.Hour)
-	return baseDate.Equal(checkDate)
+	return localday.SameDay(baseTime, date)
 }
 
 func (nr *NoRecurrence) OccurredWithin(start, end time.Time, baseTime time.Time, exDates []time.Time) []time.Time {
@@ -49,6 +49,26 @@ func (nr *NoRecurrence) String() string {
 	return "No recurrence"
 }
 
+// occursOnLocalDay checks whether any occurrence falls within the local day containing date.
+// Day boundaries follow the configured local zone, so events near midnight land in the
+// correct day regardless of the event's own timezone.
+func occursOnLocalDay(rec Recurrence, date time.Time, baseTime time.Time) bool {
+	day := localday.Of(date)
+	occurrences := rec.OccurredWithin(day.Start(), day.End().Add(-time.Nanosecond), baseTime, nil)
+	return len(occurrences) > 0
+}
+
+// eventDay returns the calendar day of t in the event's own timezone
+func eventDay(t time.Time, baseTime time.Time) localday.Day {
+	return localday.In(t, baseTime.Location())
+}
+
+// occurrenceOn returns the occurrence on the given day at the base event's wall-clock time
+func occurrenceOn(day localday.Day, baseTime time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.DayOfMonth(),
+		baseTime.Hour(), baseTime.Minute(), baseTime.Second(), 0, baseTime.Location())
+}
+
 // isExceptionDate checks if a given time is in the exception dates list
 func isExceptionDate(checkTime time.Time, exDates []time.Time) bool {
 	for _, exDate := range exDates {
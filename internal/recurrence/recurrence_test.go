@@ -3,6 +3,8 @@ package recurrence
 import (
 	"testing"
 	"time"
+
+	"calwatch/internal/localday"
 )
 
 // Helper functions for testing
@@ -394,4 +396,60 @@ func TestYearlyRecurrence(t *testing.T) {
 			t.Error("Bi-yearly recurrence should occur in 2 years")
 		}
 	})
-}
\ No newline at end of file
+}
+// Test recurrences across DST transitions in the configured local zone
+func TestRecurrenceAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("Europe/Berlin not available: %v", err)
+	}
+	previous := localday.Location()
+	localday.SetLocation(berlin)
+	defer localday.SetLocation(previous)
+	
+	// Daily 09:00 Berlin, starting the day before clocks spring forward
+	baseTime := time.Date(2024, 3, 30, 9, 0, 0, 0, berlin)
+	
+	t.Run("Daily count survives 23-hour day", func(t *testing.T) {
+		count := 3
+		dr := NewDailyRecurrence(1, nil, &count)
+		
+		occurrences := dr.OccurredWithin(baseTime, baseTime.AddDate(0, 0, 5), baseTime, nil)
+		if len(occurrences) != 3 {
+			t.Fatalf("Expected 3 occurrences, got %d", len(occurrences))
+		}
+		
+		for i, occurrence := range occurrences {
+			if occurrence.Hour() != 9 {
+				t.Errorf("Occurrence %d should be at 09:00 local time, got %v", i, occurrence)
+			}
+		}
+	})
+	
+	t.Run("Weekly keeps wall-clock time", func(t *testing.T) {
+		wr := NewWeeklyRecurrence(1, nil, nil, nil)
+		
+		occurrences := wr.OccurredWithin(baseTime, baseTime.AddDate(0, 0, 8), baseTime, nil)
+		if len(occurrences) != 2 {
+			t.Fatalf("Expected 2 occurrences, got %d", len(occurrences))
+		}
+		
+		expected := time.Date(2024, 4, 6, 9, 0, 0, 0, berlin)
+		if !occurrences[1].Equal(expected) {
+			t.Errorf("Expected second occurrence %v, got %v", expected, occurrences[1])
+		}
+	})
+	
+	t.Run("OccursOn uses local day boundaries", func(t *testing.T) {
+		// 00:30 Berlin is the previous day in UTC
+		lateBase := time.Date(2024, 6, 10, 0, 30, 0, 0, berlin)
+		nr := &NoRecurrence{}
+		
+		if !nr.OccursOn(time.Date(2024, 6, 10, 12, 0, 0, 0, berlin), lateBase) {
+			t.Error("Event should occur on its local day")
+		}
+		if nr.OccursOn(time.Date(2024, 6, 9, 12, 0, 0, 0, berlin), lateBase) {
+			t.Error("Event should not occur on the previous local day")
+		}
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"calwatch/internal/localday"
 )
 
 // WeeklyRecurrence represents a weekly recurring event
@@ -27,54 +29,7 @@ func NewWeeklyRecurrence(interval int, byDay []time.Weekday, until *time.Time, c
 }
 
 func (wr *WeeklyRecurrence) OccursOn(date time.Time, baseTime time.Time) bool {
-	checkDate := date.Truncate(24 * time.Hour)
-	baseDate := baseTime.Truncate(24 * time.Hour)
-	
-	// Event can't occur before the base date
-	if checkDate.Before(baseDate) {
-		return false
-	}
-	
-	// Get the target weekdays (if empty, use the base event's weekday)
-	targetWeekdays := wr.ByDay
-	if len(targetWeekdays) == 0 {
-		targetWeekdays = []time.Weekday{baseTime.Weekday()}
-	}
-	
-	// Check if the check date is on one of the target weekdays
-	checkWeekday := checkDate.Weekday()
-	validWeekday := false
-	for _, weekday := range targetWeekdays {
-		if checkWeekday == weekday {
-			validWeekday = true
-			break
-		}
-	}
-	if !validWeekday {
-		return false
-	}
-	
-	// Check if this date falls on the correct week interval
-	weeksDiff := int(checkDate.Sub(baseDate).Hours() / (24 * 7))
-	if weeksDiff%wr.Interval != 0 {
-		return false
-	}
-	
-	// Check until date if specified
-	if wr.Until != nil && checkDate.After(*wr.Until) {
-		return false
-	}
-	
-	// Check count if specified
-	if wr.Count != nil {
-		// Count total occurrences up to this point
-		totalOccurrences := wr.countOccurrencesUntil(checkDate, baseTime)
-		if totalOccurrences > *wr.Count {
-			return false
-		}
-	}
-	
-	return true
+	return occursOnLocalDay(wr, date, baseTime)
 }
 
 func (wr *WeeklyRecurrence) OccurredWithin(start, end time.Time, baseTime time.Time, exDates []time.Time) []time.Time {
@@ -87,15 +42,15 @@ func (wr *WeeklyRecurrence) OccurredWithin(start, end time.Time, baseTime time.T
 	}
 	
 	// Start from the beginning of the base week or start time, whichever is later
-	baseDate := baseTime.Truncate(24 * time.Hour)
-	startDate := start.Truncate(24 * time.Hour)
+	baseDay := eventDay(baseTime, baseTime)
+	startDay := eventDay(start, baseTime)
 	
-	current := baseDate
-	if startDate.After(baseDate) {
+	current := baseDay
+	if startDay.After(baseDay) {
 		// Fast forward to the first week in range
-		weeksDiff := int(startDate.Sub(baseDate).Hours() / (24 * 7))
+		weeksDiff := localday.DaysBetween(baseDay, startDay) / 7
 		intervalStart := (weeksDiff / wr.Interval) * wr.Interval
-		current = baseDate.AddDate(0, 0, intervalStart*7)
+		current = baseDay.AddDays(intervalStart * 7)
 	}
 	
 	// Get the start of the current week (Monday)
@@ -105,12 +60,7 @@ func (wr *WeeklyRecurrence) OccurredWithin(start, end time.Time, baseTime time.T
 	for {
 		// Check each target weekday in this week
 		for _, weekday := range targetWeekdays {
-			dayOffset := int(weekday - time.Monday)
-			if dayOffset < 0 {
-				dayOffset += 7 // Handle Sunday
-			}
-			
-			candidate := weekStart.AddDate(0, 0, dayOffset)
+			candidate := occurrenceOn(weekStart.AddDays(weekdayOffset(weekday)), baseTime)
 			
 			// Must be within our time range
 			if candidate.Before(start) || candidate.After(end) {
@@ -118,7 +68,7 @@ func (wr *WeeklyRecurrence) OccurredWithin(start, end time.Time, baseTime time.T
 			}
 			
 			// Must be at or after the base time
-			if candidate.Before(baseDate) {
+			if candidate.Before(baseTime) {
 				continue
 			}
 			
@@ -142,11 +92,11 @@ func (wr *WeeklyRecurrence) OccurredWithin(start, end time.Time, baseTime time.T
 		}
 		
 		// Move to next interval week
-		weekStart = weekStart.AddDate(0, 0, wr.Interval*7)
+		weekStart = weekStart.AddDays(wr.Interval * 7)
 		occurrenceCount++
 		
 		// Break if we've moved past the end time
-		if weekStart.After(end.AddDate(0, 0, 7)) {
+		if weekStart.Start().After(end.AddDate(0, 0, 7)) {
 			break
 		}
 		
@@ -166,16 +116,16 @@ func (wr *WeeklyRecurrence) NextOccurrence(after time.Time, baseTime time.Time,
 		targetWeekdays = []time.Weekday{baseTime.Weekday()}
 	}
 	
-	baseDate := baseTime.Truncate(24 * time.Hour)
-	afterDate := after.Truncate(24 * time.Hour)
+	baseDay := eventDay(baseTime, baseTime)
+	afterDay := eventDay(after, baseTime)
 	
 	// Start from the base week or the week containing 'after', whichever is later
-	current := baseDate
-	if afterDate.After(baseDate) {
+	current := baseDay
+	if afterDay.After(baseDay) {
 		// Fast forward to the appropriate week
-		weeksDiff := int(afterDate.Sub(baseDate).Hours() / (24 * 7))
+		weeksDiff := localday.DaysBetween(baseDay, afterDay) / 7
 		intervalStart := (weeksDiff / wr.Interval) * wr.Interval
-		current = baseDate.AddDate(0, 0, intervalStart*7)
+		current = baseDay.AddDays(intervalStart * 7)
 	}
 	
 	weekStart := getWeekStart(current)
@@ -184,12 +134,7 @@ func (wr *WeeklyRecurrence) NextOccurrence(after time.Time, baseTime time.Time,
 	for {
 		// Check each target weekday in this week
 		for _, weekday := range targetWeekdays {
-			dayOffset := int(weekday - time.Monday)
-			if dayOffset < 0 {
-				dayOffset += 7 // Handle Sunday
-			}
-			
-			candidate := weekStart.AddDate(0, 0, dayOffset)
+			candidate := occurrenceOn(weekStart.AddDays(weekdayOffset(weekday)), baseTime)
 			
 			// Must be after the 'after' time
 			if !candidate.After(after) {
@@ -197,7 +142,7 @@ func (wr *WeeklyRecurrence) NextOccurrence(after time.Time, baseTime time.Time,
 			}
 			
 			// Must be at or after the base time
-			if candidate.Before(baseDate) {
+			if candidate.Before(baseTime) {
 				continue
 			}
 			
@@ -221,7 +166,7 @@ func (wr *WeeklyRecurrence) NextOccurrence(after time.Time, baseTime time.Time,
 		}
 		
 		// Move to next interval week
-		weekStart = weekStart.AddDate(0, 0, wr.Interval*7)
+		weekStart = weekStart.AddDays(wr.Interval * 7)
 		occurrenceCount++
 		
 		// Safety check to prevent infinite loops
@@ -249,13 +194,17 @@ func (wr *WeeklyRecurrence) String() string {
 }
 
 // Helper function to get the start of the week (Monday)
-func getWeekStart(date time.Time) time.Time {
-	weekday := date.Weekday()
-	daysSinceMonday := int(weekday - time.Monday)
-	if daysSinceMonday < 0 {
-		daysSinceMonday += 7 // Handle Sunday
+func getWeekStart(day localday.Day) localday.Day {
+	return day.AddDays(-weekdayOffset(day.Weekday()))
+}
+
+// Helper function to get the number of days between Monday and the given weekday
+func weekdayOffset(weekday time.Weekday) int {
+	offset := int(weekday - time.Monday)
+	if offset < 0 {
+		offset += 7 // Handle Sunday
 	}
-	return date.AddDate(0, 0, -daysSinceMonday)
+	return offset
 }
 
 // Helper function to count occurrences up to a specific date
@@ -266,34 +215,28 @@ func (wr *WeeklyRecurrence) countOccurrencesUntil(untilDate time.Time, baseTime
 		targetWeekdays = []time.Weekday{baseTime.Weekday()}
 	}
 	
-	baseDate := baseTime.Truncate(24 * time.Hour)
-	weekStart := getWeekStart(baseDate)
+	weekStart := getWeekStart(eventDay(baseTime, baseTime))
 	
 	for {
 		for _, weekday := range targetWeekdays {
-			dayOffset := int(weekday - time.Monday)
-			if dayOffset < 0 {
-				dayOffset += 7
-			}
-			
-			candidate := weekStart.AddDate(0, 0, dayOffset)
+			candidate := occurrenceOn(weekStart.AddDays(weekdayOffset(weekday)), baseTime)
 			
 			if candidate.After(untilDate) {
 				return count
 			}
 			
-			if candidate.Equal(baseDate) || candidate.After(baseDate) {
+			if !candidate.Before(baseTime) {
 				count++
 			}
 		}
 		
-		weekStart = weekStart.AddDate(0, 0, wr.Interval*7)
+		weekStart = weekStart.AddDays(wr.Interval * 7)
 		
 		// Safety check
-		if weekStart.After(untilDate.AddDate(1, 0, 0)) {
+		if weekStart.Start().After(untilDate.AddDate(1, 0, 0)) {
 			break
 		}
 	}
 	
 	return count
-}
\ No newline at end of file
+}
@@ -28,81 +28,7 @@ func NewYearlyRecurrence(interval int, byMonth []time.Month, byMonthDay []int, u
 }
 
 func (yr *YearlyRecurrence) OccursOn(date time.Time, baseTime time.Time) bool {
-	checkDate := date.Truncate(24 * time.Hour)
-	baseDate := baseTime.Truncate(24 * time.Hour)
-	
-	// Event can't occur before the base date
-	if checkDate.Before(baseDate) {
-		return false
-	}
-	
-	// Get target months
-	targetMonths := yr.ByMonth
-	if len(targetMonths) == 0 {
-		targetMonths = []time.Month{baseTime.Month()}
-	}
-	
-	// Check if the check date is in one of the target months
-	validMonth := false
-	for _, month := range targetMonths {
-		if checkDate.Month() == month {
-			validMonth = true
-			break
-		}
-	}
-	if !validMonth {
-		return false
-	}
-	
-	// Get target days
-	targetDays := yr.ByMonthDay
-	if len(targetDays) == 0 {
-		targetDays = []int{baseTime.Day()}
-	}
-	
-	// Check if the check date is on one of the target days
-	validDay := false
-	for _, day := range targetDays {
-		actualDay := day
-		if day < 0 {
-			daysInMonth := getDaysInMonth(checkDate.Year(), checkDate.Month())
-			actualDay = daysInMonth + day + 1
-		}
-		
-		// Handle cases where the target day doesn't exist in this month
-		if actualDay > getDaysInMonth(checkDate.Year(), checkDate.Month()) {
-			actualDay = getDaysInMonth(checkDate.Year(), checkDate.Month())
-		}
-		
-		if checkDate.Day() == actualDay {
-			validDay = true
-			break
-		}
-	}
-	if !validDay {
-		return false
-	}
-	
-	// Check if this date falls on the correct year interval
-	yearsDiff := checkDate.Year() - baseDate.Year()
-	if yearsDiff%yr.Interval != 0 {
-		return false
-	}
-	
-	// Check until date if specified
-	if yr.Until != nil && checkDate.After(*yr.Until) {
-		return false
-	}
-	
-	// Check count if specified
-	if yr.Count != nil {
-		totalOccurrences := yr.countOccurrencesUntil(checkDate, baseTime)
-		if totalOccurrences > *yr.Count {
-			return false
-		}
-	}
-	
-	return true
+	return occursOnLocalDay(yr, date, baseTime)
 }
 
 func (yr *YearlyRecurrence) OccurredWithin(start, end time.Time, baseTime time.Time, exDates []time.Time) []time.Time {
@@ -118,9 +44,9 @@ func (yr *YearlyRecurrence) OccurredWithin(start, end time.Time, baseTime time.T
 		targetDays = []int{baseTime.Day()}
 	}
 	
-	baseDate := baseTime.Truncate(24 * time.Hour)
-	startDate := start.Truncate(24 * time.Hour)
-	endDate := end.Truncate(24 * time.Hour)
+	baseDate := eventDay(baseTime, baseTime).Start()
+	startDate := eventDay(start, baseTime).Start()
+	endDate := eventDay(end, baseTime).Start()
 	
 	// Start from the base year or the year containing start, whichever is later
 	currentYear := baseDate.Year()
@@ -213,8 +139,8 @@ func (yr *YearlyRecurrence) NextOccurrence(after time.Time, baseTime time.Time,
 		targetDays = []int{baseTime.Day()}
 	}
 	
-	baseDate := baseTime.Truncate(24 * time.Hour)
-	afterDate := after.Truncate(24 * time.Hour)
+	baseDate := eventDay(baseTime, baseTime).Start()
+	afterDate := eventDay(after, baseTime).Start()
 	
 	// Start from the base year or the year containing 'after', whichever is later
 	currentYear := baseDate.Year()
@@ -319,7 +245,7 @@ func (yr *YearlyRecurrence) countOccurrencesUntil(untilDate time.Time, baseTime
 		targetDays = []int{baseTime.Day()}
 	}
 	
-	baseDate := baseTime.Truncate(24 * time.Hour)
+	baseDate := eventDay(baseTime, baseTime).Start()
 	currentYear := baseDate.Year()
 	
 	for {
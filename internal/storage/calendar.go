@@ -2,8 +2,13 @@ package storage
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+
+	"calwatch/internal/localday"
 )
 
 // Calendar represents a calendar entity that manages its events and alert policies
@@ -11,8 +16,9 @@ type Calendar struct {
 	Path            string            // Directory path
 	Template        string            // Notification template
 	AutomaticAlerts []Alert           // Live, updateable alert policies
+	AllDayAlerts    []Alert           // Alert policies replacing AutomaticAlerts for all-day events
 	events          map[string]Event  // Events belonging to this calendar
-	mutex           sync.RWMutex      // Protects AutomaticAlerts and events
+	mutex           sync.RWMutex      // Protects AutomaticAlerts, AllDayAlerts and events
 	
 	// Cache for performance optimization (added later if needed)
 	alertDayCache   map[string]bool   // "YYYY-MM-DD:UID" -> occurs on day
@@ -42,6 +48,17 @@ func (c *Calendar) UpdateAutomaticAlerts(newAlerts []Alert) {
 	c.invalidateCache()
 }
 
+// UpdateAllDayAlerts updates the calendar's alert policies for all-day events
+func (c *Calendar) UpdateAllDayAlerts(newAlerts []Alert) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	
+	c.AllDayAlerts = newAlerts
+	
+	// Invalidate cache since alert policies changed
+	c.invalidateCache()
+}
+
 // UpdateTemplate updates the calendar's notification template
 func (c *Calendar) UpdateTemplate(template string) {
 	c.mutex.Lock()
@@ -104,6 +121,16 @@ func (c *Calendar) GetAutomaticAlerts() []Alert {
 	return alerts
 }
 
+// GetAllDayAlerts returns a copy of the current alerts for all-day events
+func (c *Calendar) GetAllDayAlerts() []Alert {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	
+	alerts := make([]Alert, len(c.AllDayAlerts))
+	copy(alerts, c.AllDayAlerts)
+	return alerts
+}
+
 // GetTemplate returns the current notification template
 func (c *Calendar) GetTemplate() string {
 	c.mutex.RLock()
@@ -117,6 +144,24 @@ func (c *Calendar) GetPath() string {
 	return c.Path // Immutable, no lock needed
 }
 
+// DisplayName returns the calendar's name from the vdir "displayname"
+// metadata file written by vdirsyncer, or the directory name
+func (c *Calendar) DisplayName() string {
+	if content, err := os.ReadFile(filepath.Join(c.Path, "displayname")); err == nil {
+		if name := strings.TrimSpace(string(content)); name != "" {
+			return name
+		}
+	}
+	return filepath.Base(c.Path)
+}
+
+// Matches reports whether the calendar is the one given by directory or by
+// directory name, e.g. "work" for ~/.calendars/work
+func (c *Calendar) Matches(name string) bool {
+	path := filepath.Clean(c.Path)
+	return filepath.Clean(name) == path || name == filepath.Base(path)
+}
+
 // GetEventsForDay returns all events from this calendar that occur on the given date
 // This includes events whose alerts fire on the given date
 func (c *Calendar) GetEventsForDay(date time.Time) []Event {
@@ -144,7 +189,7 @@ func (c *Calendar) invalidateCache() {
 
 // cacheKey generates a cache key for a date and event UID
 func (c *Calendar) cacheKey(date time.Time, uid string) string {
-	return fmt.Sprintf("%s:%s", date.Format("2006-01-02"), uid)
+	return fmt.Sprintf("%s:%s", localday.Of(date).Key(), uid)
 }
 
 // String returns a string representation of the calendar
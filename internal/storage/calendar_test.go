@@ -2,6 +2,8 @@ package storage
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -79,6 +81,53 @@ func TestCalendar_UpdateTemplate(t *testing.T) {
 	}
 }
 
+func TestCalendar_DisplayName(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a1b2c3")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Failed to create calendar directory: %v", err)
+	}
+	calendar := NewCalendar(dir, "", []Alert{})
+
+	if name := calendar.DisplayName(); name != "a1b2c3" {
+		t.Errorf("Expected directory name without metadata, got %q", name)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "displayname"), []byte("Work\n"), 0644); err != nil {
+		t.Fatalf("Failed to write displayname: %v", err)
+	}
+	if name := calendar.DisplayName(); name != "Work" {
+		t.Errorf("Expected display name from vdir metadata, got %q", name)
+	}
+}
+
+func TestInCalendars(t *testing.T) {
+	work := NewCalendar("/calendars/work", "", []Alert{})
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	meeting := NewCalendarEvent("meeting", "Meeting", "", "", start, start.Add(time.Hour), time.UTC, nil, work, []Alert{})
+	digest := NewDigestEvent("calwatch-digest/2024-01-15", "Agenda", start)
+
+	tests := []struct {
+		name      string
+		event     Event
+		calendars []string
+		expected  bool
+	}{
+		{"by directory", meeting, []string{"/calendars/work/"}, true},
+		{"by name", meeting, []string{"personal", "work"}, true},
+		{"other calendar", meeting, []string{"personal"}, false},
+		{"no calendars", meeting, nil, false},
+		{"without calendar", digest, []string{"work"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InCalendars(tt.event, tt.calendars); got != tt.expected {
+				t.Errorf("InCalendars(%v) = %v, want %v", tt.calendars, got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestCalendar_EventManagement(t *testing.T) {
 	calendar := NewCalendar("/test/path", "test.tpl", []Alert{})
 
@@ -285,11 +334,11 @@ func (m *MockEvent) GetAutomaticAlerts() []Alert {
 	return []Alert{}
 }
 
-func (m *MockEvent) GetAlertState(alertOffset time.Duration) AlertState {
+func (m *MockEvent) GetAlertState(key AlertKey) AlertState {
 	return AlertPending
 }
 
-func (m *MockEvent) SetAlertState(alertOffset time.Duration, state AlertState) {
+func (m *MockEvent) SetAlertState(key AlertKey, state AlertState) {
 }
 
 // Test Alert conversion utilities
@@ -313,9 +362,13 @@ func TestConvertConfigAlert(t *testing.T) {
 		{
 			name: "valid hours config",
 			alertConfig: config.AlertConfig{
-				Value:     2,
-				Unit:      "hours",
-				Important: true,
+				Value:             2,
+				Unit:              "hours",
+				Important:         true,
+				Template:          "heads-up.tpl",
+				LateTemplate:      "late.tpl",
+				ImportantTemplate: "important.tpl",
+				AllDayTemplate:    "all-day.tpl",
 			},
 			wantOffset: 2 * time.Hour,
 			wantErr:    false,
@@ -374,6 +427,16 @@ func TestConvertConfigAlert(t *testing.T) {
 				t.Errorf("Expected action %v, got %v", AlertActionDisplay, alert.Action)
 			}
 
+			expectedTemplates := AlertTemplates{
+				Default:   tt.alertConfig.Template,
+				Late:      tt.alertConfig.LateTemplate,
+				Important: tt.alertConfig.ImportantTemplate,
+				AllDay:    tt.alertConfig.AllDayTemplate,
+			}
+			if alert.Templates != expectedTemplates {
+				t.Errorf("Expected templates %+v, got %+v", expectedTemplates, alert.Templates)
+			}
+
 			expectedDesc := fmt.Sprintf("%d %s warning", tt.alertConfig.Value, tt.alertConfig.Unit)
 			if alert.Description != expectedDesc {
 				t.Errorf("Expected description %q, got %q", expectedDesc, alert.Description)
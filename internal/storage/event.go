@@ -2,10 +2,13 @@ package storage
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 	
 	"calwatch/internal/config"
+	"calwatch/internal/localday"
 	"calwatch/internal/recurrence"
 )
 
@@ -26,39 +29,225 @@ const (
 	AlertActionAudio                      // Audio notification (future)
 )
 
+// AlertKind specifies when an alert fires relative to its event
+type AlertKind int
+
+const (
+	AlertBeforeStart AlertKind = iota // Offset before the start (default)
+	AlertAtStart                      // When the event starts
+	AlertBeforeEnd                    // Offset before the end
+	AlertAtEnd                        // When the event is over
+	AlertOngoing                      // Daily at a time of day while a multi-day event is ongoing
+	AlertAllDay                       // At a time of day on or before the day of an all-day event
+)
+
+// String returns the configuration name of the alert kind
+func (k AlertKind) String() string {
+	switch k {
+	case AlertAtStart:
+		return config.AlertAtStart
+	case AlertBeforeEnd:
+		return config.AlertBeforeEnd
+	case AlertAtEnd:
+		return config.AlertAtEnd
+	case AlertOngoing:
+		return config.AlertOngoing
+	case AlertAllDay:
+		return "all_day"
+	default:
+		return config.AlertBeforeStart
+	}
+}
+
 // Alert represents a unified alert that can come from config or VALARM
 type Alert struct {
-	Offset      time.Duration // How far before event to trigger (e.g., 15 minutes)
+	Offset      time.Duration // How far before the start or end to trigger (e.g., 15 minutes)
 	Important   bool          // Whether this alert should use critical urgency
 	Source      AlertSource   // Whether from config or VALARM
 	Description string        // VALARM description or generated description
 	Action      AlertAction   // DISPLAY, EMAIL, AUDIO (for future extensibility)
+	Templates   AlertTemplates // Templates configured for this alert
+	Kind        AlertKind     // What the offset is relative to
+	DailyAt     time.Duration // Local time of day of ongoing reminders and all-day alerts
+	DaysBefore  int           // Days before an all-day event
+}
+
+// AlertKey identifies an alert of an event occurrence for state tracking
+type AlertKey struct {
+	EventTime time.Time // Start of the occurrence
+	Kind      AlertKind
+	Offset    time.Duration // Time from the alert to the event start, negative after the start
+}
+
+// Alert states are kept for this long after the last alert of an occurrence
+// could have fired, later occurrences prune them
+const alertStateRetention = 24 * time.Hour
+
+// alertTimes returns when an alert fires for the occurrence of the event
+// starting at eventTime, multiple times for ongoing reminders. Offsets of
+// all-day events are local wall clock time, e.g. 1 day before is midnight
+// even across a DST change.
+func (a Alert) alertTimes(eventTime time.Time, duration time.Duration, allDay bool) []time.Time {
+	eventEnd := eventTime.Add(duration)
+	before := func(t time.Time, offset time.Duration) time.Time {
+		if allDay {
+			return wallClockBefore(t, offset)
+		}
+		return t.Add(-offset)
+	}
+
+	switch a.Kind {
+	case AlertAtStart:
+		return []time.Time{eventTime}
+	case AlertBeforeEnd:
+		return []time.Time{before(eventEnd, a.Offset)}
+	case AlertAtEnd:
+		return []time.Time{eventEnd}
+	case AlertOngoing:
+		// Only events lasting beyond the day they start on
+		day := localday.Of(eventTime)
+		if !eventEnd.After(day.End()) {
+			return nil
+		}
+		var times []time.Time
+		for ; day.Start().Before(eventEnd); day = day.Next() {
+			if reminder := day.At(a.DailyAt); reminder.After(eventTime) && reminder.Before(eventEnd) {
+				times = append(times, reminder)
+			}
+		}
+		return times
+	case AlertAllDay:
+		return []time.Time{localday.Of(eventTime).AddDays(-a.DaysBefore).At(a.DailyAt)}
+	default:
+		return []time.Time{before(eventTime, a.Offset)}
+	}
+}
+
+// wallClockBefore returns the local time the offset before t on the wall clock
+func wallClockBefore(t time.Time, offset time.Duration) time.Time {
+	t = t.In(localday.Location())
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second()-int(offset/time.Second), 0, t.Location())
+}
+
+// AlertTemplates selects the notification template of an alert by context.
+// Empty names fall back to the calendar template.
+type AlertTemplates struct {
+	Default   string
+	Late      string
+	Important string
+	AllDay    string
+}
+
+// Select returns the template for an alert, preferring the late, important
+// and all-day templates in that order, or "" if none is configured
+func (t AlertTemplates) Select(late, important, allDay bool) string {
+	switch {
+	case late && t.Late != "":
+		return t.Late
+	case important && t.Important != "":
+		return t.Important
+	case allDay && t.AllDay != "":
+		return t.AllDay
+	}
+	return t.Default
 }
 
 // ConvertConfigAlert converts a config.AlertConfig to a storage.Alert
 func ConvertConfigAlert(alertConfig config.AlertConfig) (Alert, error) {
-	offset, err := alertConfig.Duration()
+	alert := Alert{
+		Important: alertConfig.Important,
+		Source:    AlertSourceConfig,
+		Action:    AlertActionDisplay,
+		Templates: AlertTemplates{
+			Default:   alertConfig.Template,
+			Late:      alertConfig.LateTemplate,
+			Important: alertConfig.ImportantTemplate,
+			AllDay:    alertConfig.AllDayTemplate,
+		},
+	}
+	
+	var err error
+	switch alertConfig.When {
+	case "", config.AlertBeforeStart:
+		alert.Offset, err = alertConfig.Duration()
+		alert.Description = fmt.Sprintf("%d %s warning", alertConfig.Value, alertConfig.Unit)
+	case config.AlertAtStart:
+		alert.Kind = AlertAtStart
+		alert.Description = "starting now"
+	case config.AlertBeforeEnd:
+		alert.Kind = AlertBeforeEnd
+		alert.Offset, err = alertConfig.Duration()
+		alert.Description = fmt.Sprintf("%d %s before the end", alertConfig.Value, alertConfig.Unit)
+	case config.AlertAtEnd:
+		alert.Kind = AlertAtEnd
+		alert.Description = "event over"
+	case config.AlertOngoing:
+		alert.Kind = AlertOngoing
+		alert.DailyAt, err = alertConfig.DailyAt()
+		alert.Description = "ongoing"
+	default:
+		err = fmt.Errorf("unsupported alert time: %s", alertConfig.When)
+	}
+	if err != nil {
+		return Alert{}, err
+	}
+	
+	return alert, nil
+}
+
+// ConvertConfigAlerts converts a slice of config.AlertConfig to storage.Alert
+func ConvertConfigAlerts(alertConfigs []config.AlertConfig) ([]Alert, error) {
+	alerts := make([]Alert, 0, len(alertConfigs))
+	
+	for _, alertConfig := range alertConfigs {
+		alert, err := ConvertConfigAlert(alertConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert alert config: %w", err)
+		}
+		alerts = append(alerts, alert)
+	}
+	
+	return alerts, nil
+}
+
+// ConvertAllDayAlert converts a config.AllDayAlertConfig to an Alert
+func ConvertAllDayAlert(alertConfig config.AllDayAlertConfig) (Alert, error) {
+	at, err := alertConfig.At()
 	if err != nil {
 		return Alert{}, err
 	}
 	
+	description := fmt.Sprintf("at %s on the day", alertConfig.Time)
+	if alertConfig.DaysBefore == 1 {
+		description = fmt.Sprintf("at %s the day before", alertConfig.Time)
+	} else if alertConfig.DaysBefore > 1 {
+		description = fmt.Sprintf("at %s %d days before", alertConfig.Time, alertConfig.DaysBefore)
+	}
+	
 	return Alert{
-		Offset:      offset,
 		Important:   alertConfig.Important,
 		Source:      AlertSourceConfig,
-		Description: fmt.Sprintf("%d %s warning", alertConfig.Value, alertConfig.Unit),
+		Description: description,
 		Action:      AlertActionDisplay,
+		Templates: AlertTemplates{
+			Default:   alertConfig.Template,
+			Late:      alertConfig.LateTemplate,
+			Important: alertConfig.ImportantTemplate,
+		},
+		Kind:       AlertAllDay,
+		DailyAt:    at,
+		DaysBefore: alertConfig.DaysBefore,
 	}, nil
 }
 
-// ConvertConfigAlerts converts a slice of config.AlertConfig to storage.Alert
-func ConvertConfigAlerts(alertConfigs []config.AlertConfig) ([]Alert, error) {
+// ConvertAllDayAlerts converts multiple all-day alert configs to Alerts
+func ConvertAllDayAlerts(alertConfigs []config.AllDayAlertConfig) ([]Alert, error) {
 	alerts := make([]Alert, 0, len(alertConfigs))
 	
 	for _, alertConfig := range alertConfigs {
-		alert, err := ConvertConfigAlert(alertConfig)
+		alert, err := ConvertAllDayAlert(alertConfig)
 		if err != nil {
-			return nil, fmt.Errorf("failed to convert alert config: %w", err)
+			return nil, fmt.Errorf("failed to convert all-day alert config: %w", err)
 		}
 		alerts = append(alerts, alert)
 	}
@@ -66,27 +255,35 @@ func ConvertConfigAlerts(alertConfigs []config.AlertConfig) ([]Alert, error) {
 	return alerts, nil
 }
 
-// DeduplicateAlerts removes duplicate alerts with the same offset
+// DeduplicateAlerts removes duplicate alerts with the same kind and offset
 // VALARM alerts take precedence over config alerts for the same offset
 func DeduplicateAlerts(alerts []Alert) []Alert {
-	seen := make(map[time.Duration]bool)
+	type alertIdentity struct {
+		kind       AlertKind
+		offset     time.Duration
+		dailyAt    time.Duration
+		daysBefore int
+	}
+	seen := make(map[alertIdentity]bool)
 	var unique []Alert
 	
 	// Process VALARM alerts first (they take precedence)
 	for _, alert := range alerts {
+		identity := alertIdentity{alert.Kind, alert.Offset, alert.DailyAt, alert.DaysBefore}
 		if alert.Source == AlertSourceVALARM {
-			if !seen[alert.Offset] {
+			if !seen[identity] {
 				unique = append(unique, alert)
-				seen[alert.Offset] = true
+				seen[identity] = true
 			}
 		}
 	}
 	
 	// Then process config alerts (only if offset not already seen)
 	for _, alert := range alerts {
-		if alert.Source == AlertSourceConfig && !seen[alert.Offset] {
+		identity := alertIdentity{alert.Kind, alert.Offset, alert.DailyAt, alert.DaysBefore}
+		if alert.Source == AlertSourceConfig && !seen[identity] {
 			unique = append(unique, alert)
-			seen[alert.Offset] = true
+			seen[identity] = true
 		}
 	}
 	
@@ -106,12 +303,20 @@ const (
 type Occurrence struct {
 	EventTime   time.Time     // When the event actually occurs
 	AlertTime   time.Time     // When this alert should fire
-	Offset      time.Duration // Alert offset (5m, 30m, etc.)
+	Offset      time.Duration // Time from the alert to the event start (5m, 30m, negative after the start)
+	Kind        AlertKind     // What the alert is relative to
 	Important   bool          // Whether this alert is marked important
 	Late        bool          // Whether this alert is firing late (past intended time)
+	Description string        // Description of the alert, e.g. from the VALARM
+	Templates   AlertTemplates // Templates configured for the alert
 	EventData   Event         // Reference to the full event
 }
 
+// Key returns the key the state of the alert is tracked with
+func (o Occurrence) Key() AlertKey {
+	return AlertKey{EventTime: o.EventTime.UTC(), Kind: o.Kind, Offset: o.Offset}
+}
+
 // Event represents a calendar event with alert tracking
 type Event interface {
 	GetUID() string
@@ -133,8 +338,39 @@ type Event interface {
 	GetAutomaticAlerts() []Alert                     // Config-based alerts only
 	
 	// Alert state tracking
-	GetAlertState(alertOffset time.Duration) AlertState
-	SetAlertState(alertOffset time.Duration, state AlertState)
+	GetAlertState(key AlertKey) AlertState
+	SetAlertState(key AlertKey, state AlertState)
+}
+
+// OccursAt reports whether an event has an occurrence starting at t
+func OccursAt(event Event, t time.Time) bool {
+	for _, occurrence := range event.OccurredWithin(t, t) {
+		if occurrence.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// CalendarMember is implemented by events that belong to a Calendar
+type CalendarMember interface {
+	GetCalendar() *Calendar
+}
+
+// InCalendars reports whether the event belongs to one of the calendars,
+// given as directory path or directory name
+func InCalendars(event Event, calendars []string) bool {
+	member, ok := event.(CalendarMember)
+	if !ok || member.GetCalendar() == nil {
+		return false
+	}
+
+	for _, calendar := range calendars {
+		if member.GetCalendar().Matches(calendar) {
+			return true
+		}
+	}
+	return false
 }
 
 // CalendarEvent implements the Event interface
@@ -148,13 +384,16 @@ type CalendarEvent struct {
 	Timezone    *time.Location
 	Recurrence  recurrence.Recurrence // Recurrence rule implementation
 	ExDates     []time.Time // Exception dates
+	AllDay      bool        // Whether the event has a date without time
+	Transparent bool        // TRANSP:TRANSPARENT, the event does not block time
+	Categories  []string    // CATEGORIES
 	
 	// Calendar context and alerts
 	Calendar        *Calendar // Pointer to shared calendar entity
 	IntrinsicAlerts []Alert   // VALARM-based alerts from ICS
 	
-	// Alert state tracking per offset
-	alertStates map[time.Duration]AlertState
+	// Alert state tracking per alert
+	alertStates map[AlertKey]AlertState
 	mutex       sync.RWMutex
 }
 
@@ -175,7 +414,7 @@ func NewCalendarEvent(uid, summary, description, location string,
 		ExDates:         make([]time.Time, 0),
 		Calendar:        calendar,
 		IntrinsicAlerts: intrinsicAlerts,
-		alertStates:     make(map[time.Duration]AlertState),
+		alertStates:     make(map[AlertKey]AlertState),
 	}
 }
 
@@ -192,6 +431,12 @@ func NewCalendarEventFromRRule(uid, summary, description, location string,
 	return NewCalendarEvent(uid, summary, description, location, startTime, endTime, timezone, rec, calendar, intrinsicAlerts), nil
 }
 
+// NewDigestEvent creates the placeholder event a digest of alerts is delivered
+// as, lasting from when it is due until the end of that day
+func NewDigestEvent(uid, summary string, from time.Time) *CalendarEvent {
+	return NewCalendarEvent(uid, summary, "", "", from, localday.Of(from).End(), from.Location(), nil, nil, []Alert{})
+}
+
 // GetUID returns the unique identifier of the event
 func (e *CalendarEvent) GetUID() string {
 	return e.UID
@@ -230,6 +475,16 @@ func (e *CalendarEvent) GetTimezone() *time.Location {
 	return time.UTC
 }
 
+// HasCategory reports whether the event has a category, ignoring case
+func (e *CalendarEvent) HasCategory(category string) bool {
+	for _, candidate := range e.Categories {
+		if strings.EqualFold(candidate, category) {
+			return true
+		}
+	}
+	return false
+}
+
 // GetCalendar returns the event's associated calendar
 func (e *CalendarEvent) GetCalendar() *Calendar {
 	return e.Calendar
@@ -243,10 +498,7 @@ func (e *CalendarEvent) GetAllAlerts() []Alert {
 	allAlerts = append(allAlerts, e.IntrinsicAlerts...)
 	
 	// Add automatic alerts from calendar (if calendar is set)
-	if e.Calendar != nil {
-		automaticAlerts := e.Calendar.GetAutomaticAlerts()
-		allAlerts = append(allAlerts, automaticAlerts...)
-	}
+	allAlerts = append(allAlerts, e.GetAutomaticAlerts()...)
 	
 	// VALARM alerts take precedence over config alerts with same offset
 	return DeduplicateAlerts(allAlerts)
@@ -260,12 +512,18 @@ func (e *CalendarEvent) GetIntrinsicAlerts() []Alert {
 	return alerts
 }
 
-// GetAutomaticAlerts returns the config-based alerts from the calendar
+// GetAutomaticAlerts returns the config-based alerts from the calendar,
+// the all-day alerts for all-day events if the calendar has any
 func (e *CalendarEvent) GetAutomaticAlerts() []Alert {
-	if e.Calendar != nil {
-		return e.Calendar.GetAutomaticAlerts()
+	if e.Calendar == nil {
+		return []Alert{}
 	}
-	return []Alert{}
+	if e.AllDay {
+		if allDayAlerts := e.Calendar.GetAllDayAlerts(); len(allDayAlerts) > 0 {
+			return allDayAlerts
+		}
+	}
+	return e.Calendar.GetAutomaticAlerts()
 }
 
 // OccursOn checks if the event occurs on a specific date (enhanced: considers alert days)
@@ -281,9 +539,10 @@ func (e *CalendarEvent) OccursOn(date time.Time) bool {
 		return false // No alerts, so only check event occurrence (already done above)
 	}
 	
-	// Get day boundaries
-	dayStart := date.Truncate(24 * time.Hour)
-	dayEnd := dayStart.Add(24 * time.Hour)
+	// Get local day boundaries (23 or 25 hours long on DST transitions)
+	day := localday.Of(date)
+	dayStart := day.Start()
+	dayEnd := day.End()
 	
 	// Use OccurrencesWithin to find alerts firing on this day
 	// Look ahead up to maximum alert offset to find events whose alerts fire today
@@ -293,7 +552,7 @@ func (e *CalendarEvent) OccursOn(date time.Time) bool {
 	occurrences := e.occurrencesWithin(dayStart, searchEnd)
 	for _, occ := range occurrences {
 		// Check if this occurrence's alert time falls on the target date
-		if occ.AlertTime.After(dayStart) && occ.AlertTime.Before(dayEnd) {
+		if day.Contains(occ.AlertTime) {
 			return true
 		}
 	}
@@ -305,9 +564,7 @@ func (e *CalendarEvent) OccursOn(date time.Time) bool {
 func (e *CalendarEvent) eventOccursOn(date time.Time) bool {
 	if e.Recurrence == nil {
 		// No recurrence, check only the base occurrence
-		eventDate := e.StartTime.In(e.GetTimezone()).Truncate(24 * time.Hour)
-		checkDate := date.In(e.GetTimezone()).Truncate(24 * time.Hour)
-		return eventDate.Equal(checkDate) && !e.isExceptionDate(e.StartTime)
+		return localday.SameDay(e.StartTime, date) && !e.isExceptionDate(e.StartTime)
 	}
 	
 	// Use recurrence logic to check if it occurs on this date
@@ -319,13 +576,36 @@ func (e *CalendarEvent) getMaxAlertOffset() time.Duration {
 	allAlerts := e.GetAllAlerts()
 	var maxOffset time.Duration
 	for _, alert := range allAlerts {
-		if alert.Offset > maxOffset {
-			maxOffset = alert.Offset
+		offset := alert.Offset
+		if alert.Kind == AlertAllDay {
+			offset = time.Duration(alert.DaysBefore+1) * 24 * time.Hour
+		}
+		if offset > maxOffset {
+			maxOffset = offset
 		}
 	}
+	if e.AllDay {
+		// Wall clock offsets are an hour longer across DST changes
+		maxOffset += time.Hour
+	}
 	return maxOffset
 }
 
+// getMaxAlertDelay returns how long after the start or end the latest alert of
+// this event fires, for VALARM triggers with positive durations
+func (e *CalendarEvent) getMaxAlertDelay() time.Duration {
+	var maxDelay time.Duration
+	for _, alert := range e.GetAllAlerts() {
+		if alert.Kind != AlertBeforeStart && alert.Kind != AlertBeforeEnd {
+			continue
+		}
+		if delay := -alert.Offset; delay > maxDelay {
+			maxDelay = delay
+		}
+	}
+	return maxDelay
+}
+
 // OccurredWithin returns all occurrences of the event within the given time range
 func (e *CalendarEvent) OccurredWithin(start, end time.Time) []time.Time {
 	if e.Recurrence == nil {
@@ -383,9 +663,11 @@ func (e *CalendarEvent) occurrencesWithin(start, end time.Time) []Occurrence {
 	
 	// Get maximum alert offset to extend search range
 	maxOffset := e.getMaxAlertOffset()
+	duration := e.EndTime.Sub(e.StartTime)
 	
-	// Extend search range to find events whose alerts might fall in our target range
-	searchStart := start
+	// Extend search range to find events whose alerts might fall in our target
+	// range, before the start, while they are ongoing or after they ended
+	searchStart := start.Add(-duration - e.getMaxAlertDelay())
 	searchEnd := end.Add(maxOffset)
 	
 	// Get all event occurrences in the extended range using the old method
@@ -394,22 +676,27 @@ func (e *CalendarEvent) occurrencesWithin(start, end time.Time) []Occurrence {
 	// For each event occurrence, generate alert occurrences
 	for _, eventTime := range eventOccurrences {
 		for _, alert := range allAlerts {
-			alertTime := eventTime.Add(-alert.Offset)
-			
-			// Check if this alert time falls within our target range [start, end]
-			if alertTime.After(start) && (alertTime.Before(end) || alertTime.Equal(end)) {
+			for _, alertTime := range alert.alertTimes(eventTime, duration, e.AllDay) {
+				// Check if this alert time falls within our target range [start, end]
+				if !alertTime.After(start) || alertTime.After(end) {
+					continue
+				}
+				
 				// Determine if this alert is late (should have fired more than 1 minute before 'end'/now)
 				// Since we check every minute, anything more than ~1 minute overdue is "late"
 				minuteThreshold := time.Minute
 				isLate := alertTime.Before(end.Add(-minuteThreshold))
 				
 				occurrence := Occurrence{
-					EventTime: eventTime,
-					AlertTime: alertTime,
-					Offset:    alert.Offset,
-					Important: alert.Important,
-					Late:      isLate,
-					EventData: e,
+					EventTime:   eventTime,
+					AlertTime:   alertTime,
+					Offset:      eventTime.Sub(alertTime),
+					Kind:        alert.Kind,
+					Important:   alert.Important,
+					Late:        isLate,
+					Description: alert.Description,
+					Templates:   alert.Templates,
+					EventData:   e,
 				}
 				occurrences = append(occurrences, occurrence)
 			}
@@ -419,6 +706,32 @@ func (e *CalendarEvent) occurrencesWithin(start, end time.Time) []Occurrence {
 	return occurrences
 }
 
+// AlertsFor returns the alerts of the occurrence starting at eventTime
+// sorted by time, whether they are due or not
+func (e *CalendarEvent) AlertsFor(eventTime time.Time) []Occurrence {
+	var occurrences []Occurrence
+	duration := e.EndTime.Sub(e.StartTime)
+	for _, alert := range e.GetAllAlerts() {
+		for _, alertTime := range alert.alertTimes(eventTime, duration, e.AllDay) {
+			occurrences = append(occurrences, Occurrence{
+				EventTime:   eventTime,
+				AlertTime:   alertTime,
+				Offset:      eventTime.Sub(alertTime),
+				Kind:        alert.Kind,
+				Important:   alert.Important,
+				Description: alert.Description,
+				Templates:   alert.Templates,
+				EventData:   e,
+			})
+		}
+	}
+
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].AlertTime.Before(occurrences[j].AlertTime)
+	})
+	return occurrences
+}
+
 // getEventOccurrences returns raw event times (extracted from old OccurredWithin logic)
 func (e *CalendarEvent) getEventOccurrences(start, end time.Time) []time.Time {
 	if e.Recurrence == nil {
@@ -446,23 +759,32 @@ func (e *CalendarEvent) getEventOccurrences(start, end time.Time) []time.Time {
 }
 
 
-// GetAlertState returns the current alert state for a specific offset
-func (e *CalendarEvent) GetAlertState(alertOffset time.Duration) AlertState {
+// GetAlertState returns the current alert state for a specific alert
+func (e *CalendarEvent) GetAlertState(key AlertKey) AlertState {
 	e.mutex.RLock()
 	defer e.mutex.RUnlock()
 	
-	if state, exists := e.alertStates[alertOffset]; exists {
+	if state, exists := e.alertStates[key]; exists {
 		return state
 	}
 	return AlertPending
 }
 
-// SetAlertState sets the alert state for a specific offset
-func (e *CalendarEvent) SetAlertState(alertOffset time.Duration, state AlertState) {
+// SetAlertState sets the alert state for a specific alert, forgetting the
+// states of occurrences whose alerts have all fired
+func (e *CalendarEvent) SetAlertState(key AlertKey, state AlertState) {
+	// Earlier occurrences have no alerts left after their end
+	retention := e.getMaxAlertOffset() + e.getMaxAlertDelay() + e.EndTime.Sub(e.StartTime) + alertStateRetention
+
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 	
-	e.alertStates[alertOffset] = state
+	for existing := range e.alertStates {
+		if existing.EventTime.Before(key.EventTime.Add(-retention)) {
+			delete(e.alertStates, existing)
+		}
+	}
+	e.alertStates[key] = state
 }
 
 // AddExceptionDate adds a date to the exception list
@@ -485,5 +807,5 @@ func (e *CalendarEvent) ResetAlertStates() {
 	e.mutex.Lock()
 	defer e.mutex.Unlock()
 	
-	e.alertStates = make(map[time.Duration]AlertState)
+	e.alertStates = make(map[AlertKey]AlertState)
 }
\ No newline at end of file
@@ -15,13 +15,31 @@ import (
 type DaemonState struct {
 	LastAlertTick time.Time `json:"last_alert_tick"`
 	Version       string    `json:"version"`
+	Pauses        []Pause   `json:"pauses,omitempty"` // Set with "calwatch pause" and "calwatch mute"
 	// Future: could add more persistent state like alert states per event
 }
 
+// AllCalendars is the calendar of pauses holding back the alerts of all calendars
+const AllCalendars = "*"
+
+// Pause holds back the alerts of a calendar until it ends or is resumed
+type Pause struct {
+	Calendar string    `json:"calendar"`        // Calendar name or directory, AllCalendars for all
+	Since    time.Time `json:"since"`
+	Until    time.Time `json:"until,omitempty"` // Zero until resumed
+}
+
+// ActiveAt reports whether the pause holds back alerts at t
+func (p Pause) ActiveAt(t time.Time) bool {
+	return !t.Before(p.Since) && (p.Until.IsZero() || t.Before(p.Until))
+}
+
 // StateManager handles persistent state operations
 type StateManager interface {
 	GetLastAlertTick() time.Time
 	SetLastAlertTick(tick time.Time) error
+	GetPauses() []Pause
+	SetPauses(pauses []Pause) error
 	Load() error
 	Save() error
 }
@@ -66,6 +84,23 @@ func (s *XDGStateManager) SetLastAlertTick(tick time.Time) error {
 	return s.saveLocked()
 }
 
+// GetPauses returns the recorded pauses, including ended ones
+func (s *XDGStateManager) GetPauses() []Pause {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return append([]Pause(nil), s.state.Pauses...)
+}
+
+// SetPauses replaces the recorded pauses and saves to disk
+func (s *XDGStateManager) SetPauses(pauses []Pause) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.state.Pauses = pauses
+	return s.saveLocked()
+}
+
 // Load reads the state from disk
 func (s *XDGStateManager) Load() error {
 	s.mutex.Lock()
@@ -3,6 +3,8 @@ package storage
 import (
 	"sync"
 	"time"
+
+	"calwatch/internal/localday"
 )
 
 // EventStorage manages in-memory event storage with efficient indexing
@@ -12,12 +14,15 @@ type EventStorage interface {
 	UpsertEventWithFile(event Event, filename string) error
 	DeleteEvent(uid string) error
 	DeleteEventByFile(filename string) error
+	ReplaceEventsForFile(filename string, events []Event) error
 	GetEventsForDay(date time.Time) []Event
 	GetEventsWithinRange(start, end time.Time) []Event
 	GetUpcomingEvents(from time.Time, duration time.Duration) []Event
 	RegenerateIndex(date time.Time) error
+	GetEvent(uid string) (Event, bool)
 	GetAllEvents() []Event
 	Clear() error
+	SetChangeListener(listener func(changes []EventChange))
 	
 	// Calendar management (new)
 	EnsureCalendar(path string, template string, automaticAlerts []Alert) *Calendar
@@ -36,25 +41,36 @@ type MemoryEventStorage struct {
 	dailyIndex map[string][]Event
 	
 	// File tracking - bidirectional mapping between filenames and UIDs
-	fileToUID map[string]string  // filename -> UID
-	uidToFile map[string]string  // UID -> filename
+	// (a single file may contain several events and tasks)
+	fileToUIDs map[string]map[string]bool // filename -> set of UIDs
+	uidToFile  map[string]string          // UID -> filename
 	
 	// Calendar management - path -> *Calendar
 	calendars map[string]*Calendar
 	
-	// Current indexed date
-	currentIndexDate time.Time
+	// Current indexed local day
+	currentIndexDay localday.Day
+	
+	// Called with the changed events after updates
+	changeListener func(changes []EventChange)
 	
 	// Mutex for thread safety
 	mutex sync.RWMutex
 }
 
+// EventChange is a stored event that was added, replaced or removed. Old is
+// nil for added events and New is nil for removed ones.
+type EventChange struct {
+	Old Event
+	New Event
+}
+
 // NewMemoryEventStorage creates a new in-memory event storage
 func NewMemoryEventStorage() *MemoryEventStorage {
 	return &MemoryEventStorage{
 		events:     make(map[string]Event),
 		dailyIndex: make(map[string][]Event),
-		fileToUID:  make(map[string]string),
+		fileToUIDs: make(map[string]map[string]bool),
 		uidToFile:  make(map[string]string),
 		calendars:  make(map[string]*Calendar),
 		mutex:      sync.RWMutex{},
@@ -69,13 +85,63 @@ func (s *MemoryEventStorage) UpsertEvent(event Event) error {
 // UpsertEventWithFile adds or updates an event in storage with file tracking
 func (s *MemoryEventStorage) UpsertEventWithFile(event Event, filename string) error {
 	s.mutex.Lock()
+	
+	changes := []EventChange{{Old: s.events[event.GetUID()], New: event}}
+	s.upsertEventLocked(event, filename)
+	
+	// Regenerate daily index if needed
+	s.regenerateIndexLocked()
+	
+	s.mutex.Unlock()
+	s.notifyChanges(changes)
+	return nil
+}
+
+// SetChangeListener sets the function called with the changed events after
+// every update, nil to stop reporting changes
+func (s *MemoryEventStorage) SetChangeListener(listener func(changes []EventChange)) {
+	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	
+	s.changeListener = listener
+}
+
+// notifyChanges reports changes to the listener, called without holding the
+// lock so the listener can query the storage
+func (s *MemoryEventStorage) notifyChanges(changes []EventChange) {
+	s.mutex.RLock()
+	listener := s.changeListener
+	s.mutex.RUnlock()
+	
+	var changed []EventChange
+	for _, change := range changes {
+		if change.Old != change.New {
+			changed = append(changed, change)
+		}
+	}
+	if listener != nil && len(changed) > 0 {
+		listener(changed)
+	}
+}
+
+// removedChangesLocked returns the removal of the events of a file (caller holds the lock)
+func (s *MemoryEventStorage) removedChangesLocked(filename string) []EventChange {
+	var changes []EventChange
+	for uid := range s.fileToUIDs[filename] {
+		if event, exists := s.events[uid]; exists {
+			changes = append(changes, EventChange{Old: event})
+		}
+	}
+	return changes
+}
+
+// upsertEventLocked stores an event and updates file tracking (caller holds the lock)
+func (s *MemoryEventStorage) upsertEventLocked(event Event, filename string) {
 	uid := event.GetUID()
 	
-	// Remove old file mapping if event already exists
-	if oldFilename, exists := s.uidToFile[uid]; exists && oldFilename != "" {
-		delete(s.fileToUID, oldFilename)
+	// Remove old file mapping if the event moved to another file
+	if oldFilename, exists := s.uidToFile[uid]; exists && oldFilename != filename {
+		s.untrackLocked(uid)
 	}
 	
 	// Store event by UID
@@ -83,64 +149,111 @@ func (s *MemoryEventStorage) UpsertEventWithFile(event Event, filename string) e
 	
 	// Update file mappings if filename provided
 	if filename != "" {
-		// Remove any existing mapping for this filename (in case file was overwritten)
-		if oldUID, exists := s.fileToUID[filename]; exists {
-			delete(s.uidToFile, oldUID)
-			// Note: we don't delete the old event as it might be from the same file
+		if s.fileToUIDs[filename] == nil {
+			s.fileToUIDs[filename] = make(map[string]bool)
 		}
-		
-		s.fileToUID[filename] = uid
+		s.fileToUIDs[filename][uid] = true
 		s.uidToFile[uid] = filename
 	}
+}
+
+// untrackLocked removes the file mapping of a UID (caller holds the lock)
+func (s *MemoryEventStorage) untrackLocked(uid string) {
+	filename, exists := s.uidToFile[uid]
+	if !exists {
+		return
+	}
 	
-	// Regenerate daily index if needed
-	s.regenerateIndexLocked()
-	
-	return nil
+	delete(s.uidToFile, uid)
+	if uids := s.fileToUIDs[filename]; uids != nil {
+		delete(uids, uid)
+		if len(uids) == 0 {
+			delete(s.fileToUIDs, filename)
+		}
+	}
+}
+
+// deleteEventsByFileLocked removes all events of a file (caller holds the lock)
+func (s *MemoryEventStorage) deleteEventsByFileLocked(filename string) {
+	for uid := range s.fileToUIDs[filename] {
+		delete(s.uidToFile, uid)
+		delete(s.events, uid)
+	}
+	delete(s.fileToUIDs, filename)
 }
 
 // DeleteEvent removes an event from storage
 func (s *MemoryEventStorage) DeleteEvent(uid string) error {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
 	
-	// Remove file mapping if exists
-	if filename, exists := s.uidToFile[uid]; exists {
-		delete(s.fileToUID, filename)
-		delete(s.uidToFile, uid)
+	var changes []EventChange
+	if event, exists := s.events[uid]; exists {
+		changes = append(changes, EventChange{Old: event})
 	}
 	
+	// Remove file mapping if exists
+	s.untrackLocked(uid)
+	
 	// Remove from main storage
 	delete(s.events, uid)
 	
 	// Regenerate daily index
 	s.regenerateIndexLocked()
 	
+	s.mutex.Unlock()
+	s.notifyChanges(changes)
 	return nil
 }
 
-// DeleteEventByFile removes an event from storage by filename
+// DeleteEventByFile removes all events stored from the given file
 func (s *MemoryEventStorage) DeleteEventByFile(filename string) error {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
 	
-	// Find the UID for this filename
-	uid, exists := s.fileToUID[filename]
-	if !exists {
+	if _, exists := s.fileToUIDs[filename]; !exists {
 		// File not found, nothing to delete
+		s.mutex.Unlock()
 		return nil
 	}
 	
-	// Remove file mappings
-	delete(s.fileToUID, filename)
-	delete(s.uidToFile, uid)
+	changes := s.removedChangesLocked(filename)
+	s.deleteEventsByFileLocked(filename)
 	
-	// Remove from main storage
-	delete(s.events, uid)
+	// Regenerate daily index
+	s.regenerateIndexLocked()
+	
+	s.mutex.Unlock()
+	s.notifyChanges(changes)
+	return nil
+}
+
+// ReplaceEventsForFile replaces all events of a file with the given events, so
+// that events removed from the file (or tasks that were completed) disappear
+func (s *MemoryEventStorage) ReplaceEventsForFile(filename string, events []Event) error {
+	s.mutex.Lock()
+	
+	// Pair the new events with the versions they replace
+	replaced := make(map[string]bool, len(events))
+	var changes []EventChange
+	for _, event := range events {
+		changes = append(changes, EventChange{Old: s.events[event.GetUID()], New: event})
+		replaced[event.GetUID()] = true
+	}
+	for _, change := range s.removedChangesLocked(filename) {
+		if !replaced[change.Old.GetUID()] {
+			changes = append(changes, change)
+		}
+	}
+	
+	s.deleteEventsByFileLocked(filename)
+	for _, event := range events {
+		s.upsertEventLocked(event, filename)
+	}
 	
 	// Regenerate daily index
 	s.regenerateIndexLocked()
 	
+	s.mutex.Unlock()
+	s.notifyChanges(changes)
 	return nil
 }
 
@@ -149,10 +262,11 @@ func (s *MemoryEventStorage) GetEventsForDay(date time.Time) []Event {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 	
-	dateKey := formatDateKey(date)
+	day := localday.Of(date)
+	dateKey := day.Key()
 	
 	// Check if we need to regenerate the index
-	if !s.currentIndexDate.Equal(date.Truncate(24 * time.Hour)) {
+	if !s.currentIndexDay.Equal(day) {
 		s.mutex.RUnlock()
 		s.RegenerateIndex(date)
 		s.mutex.RLock()
@@ -185,11 +299,8 @@ func (s *MemoryEventStorage) GetEventsWithinRange(start, end time.Time) []Event
 		
 		// If no occurrences found (no alerts), fallback to checking event dates directly
 		if len(eventTimes) == 0 {
-			if calEvent, ok := event.(*CalendarEvent); ok {
-				rawEventTimes := calEvent.getEventOccurrences(start, end)
-				for _, eventTime := range rawEventTimes {
-					eventTimes[eventTime] = true
-				}
+			for _, eventTime := range event.OccurredWithin(start, end) {
+				eventTimes[eventTime] = true
 			}
 		}
 		
@@ -220,12 +331,9 @@ func (s *MemoryEventStorage) GetUpcomingEvents(from time.Time, duration time.Dur
 		
 		// If no occurrences found (no alerts), fallback to checking event dates directly
 		if len(eventTimes) == 0 {
-			// Use getEventOccurrences if it were public, or check if event occurs in date range
-			if calEvent, ok := event.(*CalendarEvent); ok {
-				rawEventTimes := calEvent.getEventOccurrences(from, until)
-				for _, eventTime := range rawEventTimes {
-					eventTimes[eventTime] = true
-				}
+			// Works for every Event implementation (calendar events and tasks)
+			for _, eventTime := range event.OccurredWithin(from, until) {
+				eventTimes[eventTime] = true
 			}
 		}
 		
@@ -242,7 +350,7 @@ func (s *MemoryEventStorage) RegenerateIndex(date time.Time) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	
-	s.currentIndexDate = date.Truncate(24 * time.Hour)
+	s.currentIndexDay = localday.Of(date)
 	return s.regenerateIndexLocked()
 }
 
@@ -252,21 +360,21 @@ func (s *MemoryEventStorage) regenerateIndexLocked() error {
 	s.dailyIndex = make(map[string][]Event)
 	
 	// Generate index for current date and surrounding days (7-day window)
-	baseDate := s.currentIndexDate
-	if baseDate.IsZero() {
-		baseDate = time.Now().Truncate(24 * time.Hour)
+	baseDay := s.currentIndexDay
+	if baseDay == (localday.Day{}) {
+		baseDay = localday.Today()
 	}
 	
 	// Index 7 days: today and 6 days ahead
 	for i := 0; i < 7; i++ {
-		indexDate := baseDate.AddDate(0, 0, i)
-		dateKey := formatDateKey(indexDate)
+		indexDay := baseDay.AddDays(i)
+		dateKey := indexDay.Key()
 		
 		var dayEvents []Event
 		
 		// Check each event to see if it occurs on this date
 		for _, event := range s.events {
-			if event.OccursOn(indexDate) {
+			if event.OccursOn(indexDay.Start()) {
 				dayEvents = append(dayEvents, event)
 			}
 		}
@@ -279,6 +387,15 @@ func (s *MemoryEventStorage) regenerateIndexLocked() error {
 	return nil
 }
 
+// GetEvent returns the event with the given UID
+func (s *MemoryEventStorage) GetEvent(uid string) (Event, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	
+	event, exists := s.events[uid]
+	return event, exists
+}
+
 // GetAllEvents returns all events in storage
 func (s *MemoryEventStorage) GetAllEvents() []Event {
 	s.mutex.RLock()
@@ -299,10 +416,10 @@ func (s *MemoryEventStorage) Clear() error {
 	
 	s.events = make(map[string]Event)
 	s.dailyIndex = make(map[string][]Event)
-	s.fileToUID = make(map[string]string)
+	s.fileToUIDs = make(map[string]map[string]bool)
 	s.uidToFile = make(map[string]string)
 	s.calendars = make(map[string]*Calendar)
-	s.currentIndexDate = time.Time{}
+	s.currentIndexDay = localday.Day{}
 	
 	return nil
 }
@@ -315,11 +432,6 @@ func (s *MemoryEventStorage) GetEventCount() int {
 	return len(s.events)
 }
 
-// formatDateKey formats a date as YYYY-MM-DD for use as map key
-func formatDateKey(date time.Time) string {
-	return date.Format("2006-01-02")
-}
-
 // EnsureCalendar creates or returns existing Calendar for the given path
 func (s *MemoryEventStorage) EnsureCalendar(path string, template string, automaticAlerts []Alert) *Calendar {
 	s.mutex.Lock()
@@ -1,9 +1,12 @@
 package storage
 
 import (
+	"sort"
 	"testing"
 	"time"
 	
+	"calwatch/internal/config"
+	"calwatch/internal/localday"
 	"calwatch/internal/recurrence"
 )
 
@@ -42,6 +45,14 @@ func TestMemoryEventStorage_UpsertAndGet(t *testing.T) {
 	if events[0].GetUID() != "test-uid-1" {
 		t.Errorf("Expected UID 'test-uid-1', got '%s'", events[0].GetUID())
 	}
+	
+	// Test lookup by UID
+	if found, ok := storage.GetEvent("test-uid-1"); !ok || found != Event(event) {
+		t.Errorf("Expected GetEvent to return the stored event, got %v, %v", found, ok)
+	}
+	if _, ok := storage.GetEvent("missing"); ok {
+		t.Error("Expected GetEvent to report a missing event")
+	}
 }
 
 func TestMemoryEventStorage_Delete(t *testing.T) {
@@ -84,6 +95,11 @@ func TestMemoryEventStorage_Delete(t *testing.T) {
 }
 
 func TestMemoryEventStorage_GetEventsForDay(t *testing.T) {
+	// Days in this test are UTC days, independent of the host zone
+	previous := localday.Location()
+	localday.SetLocation(time.UTC)
+	defer localday.SetLocation(previous)
+	
 	storage := NewMemoryEventStorage()
 	
 	// Create test calendar
@@ -226,24 +242,30 @@ func TestCalendarEvent_AlertStates(t *testing.T) {
 		[]Alert{},
 	)
 	
-	alertOffset := 5 * time.Minute
+	alertKey := AlertKey{Offset: 5 * time.Minute}
 	
 	// Test initial state
-	if state := event.GetAlertState(alertOffset); state != AlertPending {
+	if state := event.GetAlertState(alertKey); state != AlertPending {
 		t.Errorf("Expected AlertPending, got %v", state)
 	}
 	
 	// Test setting state
-	event.SetAlertState(alertOffset, AlertSent)
+	event.SetAlertState(alertKey, AlertSent)
 	
-	if state := event.GetAlertState(alertOffset); state != AlertSent {
+	if state := event.GetAlertState(alertKey); state != AlertSent {
 		t.Errorf("Expected AlertSent, got %v", state)
 	}
 	
 	// Test different offset has different state
-	if state := event.GetAlertState(10 * time.Minute); state != AlertPending {
+	if state := event.GetAlertState(AlertKey{Offset: 10 * time.Minute}); state != AlertPending {
 		t.Errorf("Expected AlertPending for different offset, got %v", state)
 	}
+	
+	// Test the at start alert is tracked apart from a zero offset
+	event.SetAlertState(AlertKey{}, AlertSent)
+	if state := event.GetAlertState(AlertKey{Kind: AlertAtStart}); state != AlertPending {
+		t.Errorf("Expected AlertPending for the at start alert, got %v", state)
+	}
 }
 
 func TestCalendarEvent_OccurrencesWithin(t *testing.T) {
@@ -297,4 +319,261 @@ func TestCalendarEvent_OccurrencesWithin(t *testing.T) {
 			t.Errorf("Alert should not be late when fired exactly on time")
 		}
 	}
-}
\ No newline at end of file
+}
+func TestCalendarEvent_OccurrencesWithinAlertKinds(t *testing.T) {
+	monday := localday.Date(2024, 1, 15)
+	start := monday.Start().Add(9 * time.Hour)
+	meetingEnd := start.Add(time.Hour)
+	conferenceEnd := monday.AddDays(2).Start().Add(17 * time.Hour)
+	reminder := func(day localday.Day) time.Time {
+		return day.Start().Add(8 * time.Hour)
+	}
+	
+	tests := []struct {
+		name     string
+		alert    Alert
+		end      time.Time
+		from     time.Time
+		to       time.Time
+		expected []time.Time
+	}{
+		{"at start", Alert{Kind: AlertAtStart}, meetingEnd,
+			start.Add(-time.Minute), start, []time.Time{start}},
+		{"before end", Alert{Kind: AlertBeforeEnd, Offset: 15 * time.Minute}, meetingEnd,
+			start, meetingEnd, []time.Time{meetingEnd.Add(-15 * time.Minute)}},
+		{"at end", Alert{Kind: AlertAtEnd}, meetingEnd,
+			start, meetingEnd, []time.Time{meetingEnd}},
+		{"after start", Alert{Offset: -2 * time.Hour}, meetingEnd,
+			meetingEnd.Add(time.Minute), start.Add(2 * time.Hour), []time.Time{start.Add(2 * time.Hour)}},
+		{"after end", Alert{Kind: AlertBeforeEnd, Offset: -2 * time.Hour}, meetingEnd,
+			meetingEnd.Add(time.Hour), meetingEnd.Add(2 * time.Hour), []time.Time{meetingEnd.Add(2 * time.Hour)}},
+		{"at end of a multi-day event", Alert{Kind: AlertAtEnd}, conferenceEnd,
+			conferenceEnd.Add(-time.Minute), conferenceEnd, []time.Time{conferenceEnd}},
+		{"ongoing", Alert{Kind: AlertOngoing, DailyAt: 8 * time.Hour}, conferenceEnd,
+			monday.Start(), conferenceEnd, []time.Time{reminder(monday.Next()), reminder(monday.AddDays(2))}},
+		{"ongoing single-day event", Alert{Kind: AlertOngoing, DailyAt: 8 * time.Hour}, meetingEnd,
+			monday.Start(), monday.End(), nil},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calendar := NewCalendar("/test/path", "test.tpl", []Alert{tt.alert})
+			event := NewCalendarEvent("test-uid", "Test Event", "", "", start, tt.end,
+				localday.Location(), &recurrence.NoRecurrence{}, calendar, []Alert{})
+			
+			occurrences := event.OccurrencesWithin(tt.from, tt.to)
+			if len(occurrences) != len(tt.expected) {
+				t.Fatalf("Expected %d occurrences, got %d", len(tt.expected), len(occurrences))
+			}
+			seen := make(map[AlertKey]bool)
+			for i, occurrence := range occurrences {
+				if !occurrence.AlertTime.Equal(tt.expected[i]) || !occurrence.EventTime.Equal(start) {
+					t.Errorf("Expected alert at %v for %v, got %v for %v", tt.expected[i], start, occurrence.AlertTime, occurrence.EventTime)
+				}
+				if occurrence.Kind != tt.alert.Kind || occurrence.Offset != start.Sub(occurrence.AlertTime) {
+					t.Errorf("Unexpected kind %v or offset %v", occurrence.Kind, occurrence.Offset)
+				}
+				// Daily reminders are tracked separately
+				if seen[occurrence.Key()] {
+					t.Errorf("Duplicate alert key %+v", occurrence.Key())
+				}
+				seen[occurrence.Key()] = true
+			}
+		})
+	}
+}
+
+func TestCalendarEvent_AllDayAlerts(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("Europe/Berlin zone not available")
+	}
+	localday.SetLocation(berlin)
+	defer localday.SetLocation(time.Local)
+	
+	calendar := NewCalendar("/test/path", "test.tpl", []Alert{{Offset: time.Hour, Source: AlertSourceConfig}})
+	allDayAlerts, err := ConvertAllDayAlerts([]config.AllDayAlertConfig{
+		{Time: "09:00"},
+		{Time: "18:00", DaysBefore: 1},
+	})
+	if err != nil {
+		t.Fatalf("ConvertAllDayAlerts() error = %v", err)
+	}
+	calendar.UpdateAllDayAlerts(allDayAlerts)
+	
+	// The day after the switch to summer time
+	day := localday.Date(2024, 4, 1)
+	newEvent := func(start time.Time, allDay bool, valarms []Alert) *CalendarEvent {
+		event := NewCalendarEvent("uid", "Holiday", "", "", start, start.Add(24*time.Hour),
+			berlin, &recurrence.NoRecurrence{}, calendar, valarms)
+		event.AllDay = allDay
+		return event
+	}
+	local := func(month time.Month, dayOfMonth, hour int) time.Time {
+		return time.Date(2024, month, dayOfMonth, hour, 0, 0, 0, berlin)
+	}
+	
+	tests := []struct {
+		name     string
+		event    *CalendarEvent
+		expected []time.Time
+	}{
+		{"all-day alerts replace automatic ones", newEvent(day.Start(), true, nil),
+			[]time.Time{local(time.March, 31, 18), local(time.April, 1, 9)}},
+		{"timed events keep automatic alerts", newEvent(day.Start().Add(10*time.Hour), false, nil),
+			[]time.Time{local(time.April, 1, 9)}},
+		{"VALARMs in local wall clock time", newEvent(day.Start(), true, []Alert{{Offset: 48 * time.Hour, Source: AlertSourceVALARM}}),
+			[]time.Time{local(time.March, 30, 0), local(time.March, 31, 18), local(time.April, 1, 9)}},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			occurrences := tt.event.OccurrencesWithin(day.AddDays(-3).Start(), day.End())
+			sort.Slice(occurrences, func(i, j int) bool {
+				return occurrences[i].AlertTime.Before(occurrences[j].AlertTime)
+			})
+			if len(occurrences) != len(tt.expected) {
+				t.Fatalf("Expected %d alerts, got %d", len(tt.expected), len(occurrences))
+			}
+			for i, occurrence := range occurrences {
+				if !occurrence.AlertTime.Equal(tt.expected[i]) {
+					t.Errorf("Expected alert at %v, got %v", tt.expected[i], occurrence.AlertTime)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryEventStorage_GetEventsForDay_LocalMidnight(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("Europe/Berlin not available: %v", err)
+	}
+	previous := localday.Location()
+	localday.SetLocation(berlin)
+	defer localday.SetLocation(previous)
+	
+	storage := NewMemoryEventStorage()
+	calendar := NewCalendar("/test/path", "test.tpl", []Alert{})
+	
+	// 00:30 Berlin time on Oct 16 is still Oct 15 in UTC
+	eventTime := time.Date(2023, 10, 16, 0, 30, 0, 0, berlin)
+	event := NewCalendarEvent(
+		"early-event",
+		"Early Event",
+		"",
+		"",
+		eventTime,
+		eventTime.Add(time.Hour),
+		berlin,
+		&recurrence.NoRecurrence{},
+		calendar,
+		[]Alert{},
+	)
+	storage.UpsertEvent(event)
+	
+	day := time.Date(2023, 10, 16, 0, 0, 0, 0, berlin)
+	if events := storage.GetEventsForDay(day); len(events) != 1 {
+		t.Errorf("Expected event in Oct 16 local bucket, got %d events", len(events))
+	}
+	
+	previousDay := time.Date(2023, 10, 15, 0, 0, 0, 0, berlin)
+	if events := storage.GetEventsForDay(previousDay); len(events) != 0 {
+		t.Errorf("Expected no events in Oct 15 local bucket, got %d events", len(events))
+	}
+}
+
+func TestMemoryEventStorage_ReplaceEventsForFile(t *testing.T) {
+	storage := NewMemoryEventStorage()
+	calendar := NewCalendar("/test/path", "test.tpl", []Alert{})
+
+	newEvent := func(uid string) *CalendarEvent {
+		start := time.Date(2023, 10, 15, 14, 0, 0, 0, time.UTC)
+		return NewCalendarEvent(uid, uid, "", "", start, start.Add(time.Hour), time.UTC,
+			&recurrence.NoRecurrence{}, calendar, []Alert{})
+	}
+
+	// A single file may hold several events
+	storage.UpsertEventWithFile(newEvent("a"), "/cal/multi.ics")
+	storage.UpsertEventWithFile(newEvent("b"), "/cal/multi.ics")
+	storage.UpsertEventWithFile(newEvent("c"), "/cal/other.ics")
+
+	if storage.GetEventCount() != 3 {
+		t.Fatalf("Expected 3 events, got %d", storage.GetEventCount())
+	}
+
+	// Replacing drops events that are no longer in the file
+	if err := storage.ReplaceEventsForFile("/cal/multi.ics", []Event{newEvent("b")}); err != nil {
+		t.Fatalf("ReplaceEventsForFile failed: %v", err)
+	}
+	if _, exists := storage.events["a"]; exists {
+		t.Error("Expected event a to be removed")
+	}
+	if storage.GetEventCount() != 2 {
+		t.Errorf("Expected 2 events after replace, got %d", storage.GetEventCount())
+	}
+
+	// Deleting a file removes all of its events but leaves other files alone
+	storage.UpsertEventWithFile(newEvent("d"), "/cal/multi.ics")
+	if err := storage.DeleteEventByFile("/cal/multi.ics"); err != nil {
+		t.Fatalf("DeleteEventByFile failed: %v", err)
+	}
+	if storage.GetEventCount() != 1 {
+		t.Errorf("Expected only event c to remain, got %d events", storage.GetEventCount())
+	}
+	if _, exists := storage.events["c"]; !exists {
+		t.Error("Expected event c from other file to remain")
+	}
+}
+
+func TestMemoryEventStorage_ChangeListener(t *testing.T) {
+	storage := NewMemoryEventStorage()
+	calendar := NewCalendar("/test/path", "test.tpl", []Alert{})
+
+	newEvent := func(uid string) *CalendarEvent {
+		start := time.Date(2023, 10, 15, 14, 0, 0, 0, time.UTC)
+		return NewCalendarEvent(uid, uid, "", "", start, start.Add(time.Hour), time.UTC,
+			&recurrence.NoRecurrence{}, calendar, []Alert{})
+	}
+
+	// Changes before a listener is set are not reported
+	a := newEvent("a")
+	storage.UpsertEventWithFile(a, "/cal/multi.ics")
+
+	var reported [][]EventChange
+	storage.SetChangeListener(func(changes []EventChange) {
+		// Listeners may query the storage
+		storage.GetEventCount()
+		reported = append(reported, changes)
+	})
+
+	b := newEvent("b")
+	storage.UpsertEventWithFile(b, "/cal/multi.ics")
+
+	// Replacing reports the updated event and the removed one
+	updated := newEvent("b")
+	storage.ReplaceEventsForFile("/cal/multi.ics", []Event{updated})
+
+	storage.DeleteEventByFile("/cal/multi.ics")
+	storage.DeleteEventByFile("/cal/unknown.ics")
+
+	expected := [][]EventChange{
+		{{New: b}},
+		{{Old: b, New: updated}, {Old: a}},
+		{{Old: updated}},
+	}
+	if len(reported) != len(expected) {
+		t.Fatalf("Expected %d change reports, got %d: %v", len(expected), len(reported), reported)
+	}
+	for i, changes := range expected {
+		if len(reported[i]) != len(changes) {
+			t.Errorf("Report %d: expected %d changes, got %d", i, len(changes), len(reported[i]))
+			continue
+		}
+		for j, change := range changes {
+			if reported[i][j] != change {
+				t.Errorf("Report %d change %d: expected %v, got %v", i, j, change, reported[i][j])
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"strings"
+	"time"
+)
+
+// Task status values (VTODO STATUS property)
+const (
+	TaskStatusNeedsAction = "NEEDS-ACTION"
+	TaskStatusInProcess   = "IN-PROCESS"
+	TaskStatusCompleted   = "COMPLETED"
+	TaskStatusCancelled   = "CANCELLED"
+)
+
+// TaskEvent represents a VTODO task. The embedded CalendarEvent is anchored at
+// the due date (or the start date for tasks without DUE), so the task is
+// indexed and alerted through the same code paths as regular events.
+type TaskEvent struct {
+	*CalendarEvent
+
+	Due             *time.Time // DUE, nil if the task has no due date
+	Start           *time.Time // DTSTART, nil if the task has no start date
+	Status          string     // NEEDS-ACTION, IN-PROCESS, COMPLETED or CANCELLED
+	PercentComplete int        // PERCENT-COMPLETE (0-100)
+	Priority        int        // PRIORITY: 1 (highest) to 9 (lowest), 0 if undefined
+}
+
+// NewTaskEvent creates a task around an event anchored at the due (or start) date
+func NewTaskEvent(event *CalendarEvent, due, start *time.Time, status string, percentComplete, priority int) *TaskEvent {
+	if status == "" {
+		status = TaskStatusNeedsAction
+	}
+
+	return &TaskEvent{
+		CalendarEvent:   event,
+		Due:             due,
+		Start:           start,
+		Status:          strings.ToUpper(status),
+		PercentComplete: percentComplete,
+		Priority:        priority,
+	}
+}
+
+// BaseCalendarEvent returns the calendar event behind an event or task, or nil
+func BaseCalendarEvent(event Event) *CalendarEvent {
+	switch e := event.(type) {
+	case *CalendarEvent:
+		return e
+	case *TaskEvent:
+		return e.CalendarEvent
+	}
+	return nil
+}
+
+// IsCompleted reports whether the task no longer needs reminders
+func (t *TaskEvent) IsCompleted() bool {
+	return t.Status == TaskStatusCompleted || t.Status == TaskStatusCancelled || t.PercentComplete >= 100
+}
+
+// IsHighPriority reports whether the task has a high PRIORITY (1-4)
+func (t *TaskEvent) IsHighPriority() bool {
+	return t.Priority >= 1 && t.Priority <= 4
+}
+
+// PriorityLabel returns a human readable label for the task priority
+func (t *TaskEvent) PriorityLabel() string {
+	switch {
+	case t.Priority >= 1 && t.Priority <= 4:
+		return "high"
+	case t.Priority == 5:
+		return "medium"
+	case t.Priority >= 6 && t.Priority <= 9:
+		return "low"
+	default:
+		return ""
+	}
+}
+
+// OccurrencesWithin returns alert occurrences that reference the task itself
+func (t *TaskEvent) OccurrencesWithin(start, end time.Time) []Occurrence {
+	occurrences := t.CalendarEvent.OccurrencesWithin(start, end)
+	for i := range occurrences {
+		occurrences[i].EventData = t
+	}
+	return occurrences
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"calwatch/internal/recurrence"
+)
+
+func newTestTask(status string, percentComplete, priority int, alerts []Alert) *TaskEvent {
+	due := time.Date(2023, 10, 15, 17, 0, 0, 0, time.UTC)
+	event := NewCalendarEvent(
+		"task-1",
+		"Submit report",
+		"",
+		"",
+		due,
+		due,
+		time.UTC,
+		&recurrence.NoRecurrence{},
+		NewCalendar("/test/tasks", "", []Alert{}),
+		alerts,
+	)
+	return NewTaskEvent(event, &due, nil, status, percentComplete, priority)
+}
+
+func TestTaskEvent_IsCompleted(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          string
+		percentComplete int
+		expected        bool
+	}{
+		{"default status", "", 0, false},
+		{"in process", "in-process", 50, false},
+		{"completed", "COMPLETED", 100, true},
+		{"cancelled", "CANCELLED", 0, true},
+		{"fully done without status", "NEEDS-ACTION", 100, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := newTestTask(tt.status, tt.percentComplete, 0, []Alert{})
+			if got := task.IsCompleted(); got != tt.expected {
+				t.Errorf("IsCompleted() = %v, want %v (status %q)", got, tt.expected, task.Status)
+			}
+		})
+	}
+}
+
+func TestTaskEvent_PriorityLabel(t *testing.T) {
+	tests := []struct {
+		priority int
+		expected string
+	}{
+		{0, ""},
+		{1, "high"},
+		{4, "high"},
+		{5, "medium"},
+		{9, "low"},
+	}
+
+	for _, tt := range tests {
+		task := newTestTask("", 0, tt.priority, []Alert{})
+		if got := task.PriorityLabel(); got != tt.expected {
+			t.Errorf("PriorityLabel() for %d = %q, want %q", tt.priority, got, tt.expected)
+		}
+	}
+}
+
+func TestTaskEvent_OccurrencesReferenceTask(t *testing.T) {
+	task := newTestTask("", 0, 0, []Alert{{Offset: 30 * time.Minute, Source: AlertSourceVALARM}})
+
+	occurrences := task.OccurrencesWithin(
+		time.Date(2023, 10, 15, 16, 0, 0, 0, time.UTC),
+		time.Date(2023, 10, 15, 16, 45, 0, 0, time.UTC),
+	)
+	if len(occurrences) != 1 {
+		t.Fatalf("Expected 1 occurrence, got %d", len(occurrences))
+	}
+
+	if occurrences[0].EventData != Event(task) {
+		t.Error("Expected occurrence to reference the task, not the embedded event")
+	}
+
+	expectedAlert := time.Date(2023, 10, 15, 16, 30, 0, 0, time.UTC)
+	if !occurrences[0].AlertTime.Equal(expectedAlert) {
+		t.Errorf("Expected alert at %v, got %v", expectedAlert, occurrences[0].AlertTime)
+	}
+}
@@ -244,8 +244,8 @@ func (fw *FSNotifyWatcher) handleEvent(event fsnotify.Event) {
 		// Check if this file is in a watched directory
 		dir := filepath.Dir(event.Name)
 		if cb, exists := fw.callbacks[dir]; exists {
-			// Only handle .ics files in watched directories
-			if strings.HasSuffix(strings.ToLower(event.Name), ".ics") {
+			// Only handle calendar and contact files in watched directories
+			if isCollectionFile(event.Name) {
 				callback = cb
 				found = true
 			}
@@ -338,11 +338,17 @@ func (cw *CalDAVWatcher) GetWatchedDirectories() []string {
 
 // handleCalDAVEvent handles file system events for CalDAV files
 func (cw *CalDAVWatcher) handleCalDAVEvent(event FileChangeEvent) {
-	// Only handle .ics files
-	if !strings.HasSuffix(strings.ToLower(event.Path), ".ics") {
+	// Only handle .ics and .vcf files
+	if !isCollectionFile(event.Path) {
 		return
 	}
 
 	// Forward the event to the main callback
 	cw.callback(event)
+}
+
+// isCollectionFile returns true for vdir item files (.ics calendars, .vcf contacts)
+func isCollectionFile(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasSuffix(lower, ".ics") || strings.HasSuffix(lower, ".vcf")
 }
\ No newline at end of file
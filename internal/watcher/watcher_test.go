@@ -264,6 +264,15 @@ func TestCalDAVWatcher(t *testing.T) {
 	file.WriteString("BEGIN:VCALENDAR\nEND:VCALENDAR")
 	file.Close()
 
+	// Create vCard file (should trigger event)
+	vcfFile := filepath.Join(tempDir, "contact.vcf")
+	file, err = os.Create(vcfFile)
+	if err != nil {
+		t.Fatalf("Failed to create vCard file: %v", err)
+	}
+	file.WriteString("BEGIN:VCARD\nEND:VCARD")
+	file.Close()
+
 	// Create non-ICS file (should not trigger event)
 	txtFile := filepath.Join(tempDir, "notes.txt")
 	file, err = os.Create(txtFile)
@@ -280,14 +289,18 @@ func TestCalDAVWatcher(t *testing.T) {
 	eventsMutex.Lock()
 	defer eventsMutex.Unlock()
 
-	// Should only receive event for .ics file
+	// Should only receive events for .ics and .vcf files
 	icsEventFound := false
+	vcfEventFound := false
 	txtEventFound := false
 
 	for _, event := range events {
 		if event.Path == icsFile {
 			icsEventFound = true
 		}
+		if event.Path == vcfFile {
+			vcfEventFound = true
+		}
 		if event.Path == txtFile {
 			txtEventFound = true
 		}
@@ -297,6 +310,10 @@ func TestCalDAVWatcher(t *testing.T) {
 		t.Error("Expected event for .ics file")
 	}
 
+	if !vcfEventFound {
+		t.Error("Expected event for .vcf file")
+	}
+
 	if txtEventFound {
 		t.Error("Should not receive event for non-.ics file")
 	}
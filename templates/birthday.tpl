@@ -0,0 +1,3 @@
+🎂 {{.Summary}}{{if .Description}}
+{{.Description}}{{end}}
+In {{.AlertOffset}}
\ No newline at end of file